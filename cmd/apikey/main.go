@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ardanlabs/conf/v3"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/loggerx"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+// config selects the same API keys store as the HTTP service.
+type config struct {
+	StateStoreURL    string `conf:"env:STATE_STORE_URL,default:memory://,mask"`
+	AuthAPIKeysStore string `conf:"env:AUTH_API_KEYS_STORE,default:file"`
+	AuthAPIKeysFile  string `conf:"env:AUTH_API_KEYS_FILE,default:api_keys.json"`
+
+	Name   string   `conf:"flag:name,required"`
+	Scopes []string `conf:"flag:scopes,default:tokens:request"`
+}
+
+func main() {
+	logger := loggerx.NewDevelopment()
+	zerolog.DefaultContextLogger = &logger
+
+	ctx := context.Background()
+	ctx = logger.WithContext(ctx)
+
+	if err := createAPIKey(ctx); err != nil {
+		log.Ctx(ctx).Fatal().Err(err).Send()
+	}
+}
+
+// createAPIKey stores a new API key and prints it, which is the only time it is
+// displayed. It is meant to create the first admin key, the following ones can be
+// managed through the HTTP service admin endpoints.
+func createAPIKey(ctx context.Context) error {
+	var cfg config
+	help, err := conf.Parse("", &cfg)
+	if err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			return fmt.Errorf("usage: apikey --name <name> [--scopes '<scope>;<scope>']\n%s", help)
+		}
+
+		return fmt.Errorf("error parsing the configuration: %w", err)
+	}
+
+	var store authx.APIKeyStore
+	switch cfg.AuthAPIKeysStore {
+	case "file":
+		store, err = authx.NewFileAPIKeyStore(cfg.AuthAPIKeysFile)
+		if err != nil {
+			return fmt.Errorf("opening api keys file: %w", err)
+		}
+	case "state":
+		stateStore, err := statestore.Open(cfg.StateStoreURL)
+		if err != nil {
+			return fmt.Errorf("opening state store: %w", err)
+		}
+		defer stateStore.Close()
+		store = authx.NewStateAPIKeyStore(stateStore)
+	default:
+		return fmt.Errorf("unknown api keys store %q", cfg.AuthAPIKeysStore)
+	}
+
+	plaintext, key, err := authx.NewAPIKeys(store).CreateAPIKey(ctx, cfg.Name, cfg.Scopes)
+	if err != nil {
+		return err
+	}
+
+	log.Ctx(ctx).Info().Str("api_key_id", key.ID).Strs("scopes", key.Scopes).Msg("API key created")
+	_, err = fmt.Fprintln(os.Stdout, plaintext)
+
+	return err
+}
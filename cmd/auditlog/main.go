@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/ardanlabs/conf/v3"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/extensions/loggerx"
+	"github.com/werbersondev/token-generator-test/gateway/auditlog"
+)
+
+const usage = "usage: auditlog verify [<file>...]"
+
+type config struct {
+	// Args are the command followed by the audit files, the standard input being read
+	// when there is none.
+	Args conf.Args
+}
+
+func main() {
+	logger := loggerx.NewDevelopment()
+	zerolog.DefaultContextLogger = &logger
+
+	ctx := context.Background()
+	ctx = logger.WithContext(ctx)
+
+	if err := run(ctx); err != nil {
+		log.Ctx(ctx).Fatal().Err(err).Send()
+	}
+}
+
+func run(ctx context.Context) error {
+	var cfg config
+	help, err := conf.Parse("", &cfg)
+	if err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			return fmt.Errorf("%s\n%s", usage, help)
+		}
+
+		return fmt.Errorf("error parsing the configuration: %w", err)
+	}
+
+	if cfg.Args.Num(0) != "verify" {
+		return errors.New(usage)
+	}
+
+	return verify(ctx, cfg.Args[1:])
+}
+
+// verify checks the chains of the audit files, each on its own since a chain resumes
+// in the same file, and prints the head of every chain for them to be compared with
+// another sink.
+func verify(ctx context.Context, files []string) error {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	valid := true
+	for _, file := range files {
+		report, err := verifyFile(file)
+		if err != nil {
+			return err
+		}
+
+		for _, problem := range report.Problems {
+			log.Ctx(ctx).Error().Str("file", file).Msg(problem.String())
+		}
+
+		sources := make([]string, 0, len(report.Heads))
+		for source := range report.Heads {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+		for _, source := range sources {
+			head := report.Heads[source]
+			fmt.Fprintf(os.Stdout, "%s\t%s\tchains=%d\thead=%d\t%s\n", file, source, report.Chains[source], head.Sequence, head.Hash)
+		}
+
+		log.Ctx(ctx).Info().Str("file", file).
+			Int("records", report.Records).
+			Int("problems", len(report.Problems)).
+			Msg("audit log verified")
+		valid = valid && report.Valid()
+	}
+
+	if !valid {
+		return errors.New("the audit log chain is broken")
+	}
+
+	return nil
+}
+
+func verifyFile(path string) (auditlog.Report, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return auditlog.Report{}, fmt.Errorf("opening audit file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	report, err := auditlog.Verify(r)
+	if err != nil {
+		return auditlog.Report{}, fmt.Errorf("reading audit file %s: %w", path, err)
+	}
+
+	return report, nil
+}
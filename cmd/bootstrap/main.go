@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/cmd/bootstrap/provision"
+	"github.com/werbersondev/token-generator-test/extensions/loggerx"
+	"github.com/werbersondev/token-generator-test/gateway/sonarclient"
+)
+
+type config struct {
+	SonarAPIAddress        string        `conf:"env:SONAR_API_ADDRESS,default:http://localhost:9000"`
+	SonarAPITimeout        time.Duration `conf:"env:SONAR_API_TIMEOUT,default:30s"`
+	SonarTLSCAFile         string        `conf:"env:SONAR_TLS_CA_FILE"`
+	SonarTLSCertFile       string        `conf:"env:SONAR_TLS_CERT_FILE"`
+	SonarTLSKeyFile        string        `conf:"env:SONAR_TLS_KEY_FILE"`
+	SonarTLSMinVersion     string        `conf:"env:SONAR_TLS_MIN_VERSION,default:1.2"`
+	SonarTLSReloadInterval time.Duration `conf:"env:SONAR_TLS_RELOAD_INTERVAL,default:30s"`
+	SonarProxyURL          string        `conf:"env:SONAR_PROXY_URL"`
+	SonarNoProxy           string        `conf:"env:SONAR_NO_PROXY"`
+	SonarAdminLogin        string        `conf:"env:SONAR_ADMIN_LOGIN,default:admin"`
+	SonarAdminPassword     string        `conf:"env:SONAR_ADMIN_PASSWORD,required,mask"`
+
+	ManifestPath string        `conf:"env:BOOTSTRAP_MANIFEST,default:cmd/bootstrap/manifest.yaml"`
+	WaitTimeout  time.Duration `conf:"env:BOOTSTRAP_WAIT_TIMEOUT,default:5m"`
+	PollInterval time.Duration `conf:"env:BOOTSTRAP_POLL_INTERVAL,default:5s"`
+	TokenFile    string        `conf:"env:BOOTSTRAP_TOKEN_FILE,default:.env.local"`
+	TokenFormat  string        `conf:"env:BOOTSTRAP_TOKEN_FORMAT,default:env"`
+}
+
+func main() {
+	logger := loggerx.NewDevelopment()
+	zerolog.DefaultContextLogger = &logger
+
+	ctx := context.Background()
+	ctx = logger.WithContext(ctx)
+
+	if err := runBootstrap(ctx); err != nil {
+		log.Ctx(ctx).Fatal().Err(err).Send()
+	}
+}
+
+func runBootstrap(ctx context.Context) error {
+	var cfg config
+	help, err := conf.Parse("", &cfg)
+	if err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			return fmt.Errorf("usage: bootstrap [config]\n%s", help)
+		}
+
+		return fmt.Errorf("error parsing the configuration: %w", err)
+	}
+
+	manifest, err := provision.LoadManifest(cfg.ManifestPath)
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := sonarclient.New(sonarclient.Config{
+		Timeout:  cfg.SonarAPITimeout,
+		BaseURL:  cfg.SonarAPIAddress,
+		Login:    cfg.SonarAdminLogin,
+		Password: cfg.SonarAdminPassword,
+		TLS: sonarclient.TLSConfig{
+			CAFile:         cfg.SonarTLSCAFile,
+			CertFile:       cfg.SonarTLSCertFile,
+			KeyFile:        cfg.SonarTLSKeyFile,
+			MinVersion:     cfg.SonarTLSMinVersion,
+			ReloadInterval: cfg.SonarTLSReloadInterval,
+		},
+		Proxy: sonarclient.ProxyConfig{
+			URL:     cfg.SonarProxyURL,
+			NoProxy: cfg.SonarNoProxy,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("creating sonar client: %w", err)
+	}
+
+	bootstrapper := provision.NewBootstrapper(httpClient, cfg.PollInterval)
+
+	waitCtx, cancel := context.WithTimeout(ctx, cfg.WaitTimeout)
+	defer cancel()
+
+	if err := bootstrapper.WaitUntilUp(waitCtx); err != nil {
+		return err
+	}
+	log.Ctx(ctx).Info().Str("address", cfg.SonarAPIAddress).Msg("sonar is up")
+
+	token, err := bootstrapper.Run(ctx, manifest)
+	if err != nil {
+		return err
+	}
+
+	if err := provision.WriteSecret(cfg.TokenFile, provision.SecretFormat(cfg.TokenFormat), "SONAR_AUTH_TOKEN", token); err != nil {
+		return fmt.Errorf("writing service token: %w", err)
+	}
+
+	log.Ctx(ctx).Info().Str("login", manifest.ServiceUser.Login).
+		Str("token_file", cfg.TokenFile).
+		Int("projects", len(manifest.Projects)).
+		Msg("bootstrap completed")
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package provision
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/gateway/sonarclient"
+)
+
+//go:generate moq -stub -pkg mocks -out mocks/sonar_client.go . SonarClient
+type SonarClient interface {
+	SystemStatus(ctx context.Context) (string, error)
+	UserExists(ctx context.Context, login string) (bool, error)
+	CreateUser(ctx context.Context, params sonarclient.CreateUserParams) error
+	ProjectExists(ctx context.Context, projectKey string) (bool, error)
+	CreateProject(ctx context.Context, projectKey, name string) error
+	AddUserPermission(ctx context.Context, projectID, login, permission string) error
+	GenerateToken(ctx context.Context, params sonarclient.TokenGenerationParams) (string, error)
+}
+
+type Bootstrapper struct {
+	client       SonarClient
+	pollInterval time.Duration
+}
+
+func NewBootstrapper(client SonarClient, pollInterval time.Duration) *Bootstrapper {
+	return &Bootstrapper{
+		client:       client,
+		pollInterval: pollInterval,
+	}
+}
+
+// WaitUntilUp polls the Sonar system status until it reports UP or ctx is done.
+func (b *Bootstrapper) WaitUntilUp(ctx context.Context) error {
+	ticker := time.NewTicker(b.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		status, err := b.client.SystemStatus(ctx)
+		if err == nil && status == sonarclient.SystemStatusUp {
+			return nil
+		}
+
+		log.Ctx(ctx).Info().Err(err).Str("status", status).Msg("waiting for sonar to be up")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for sonar to be up: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run provisions the technical user and the projects of the manifest, and returns a
+// freshly generated user token for the technical user. Resources that already exist
+// are left untouched so the command can be run repeatedly.
+func (b *Bootstrapper) Run(ctx context.Context, manifest Manifest) (string, error) {
+	user := manifest.ServiceUser
+
+	if err := b.ensureUser(ctx, user); err != nil {
+		return "", err
+	}
+
+	for _, project := range manifest.Projects {
+		if err := b.ensureProject(ctx, project); err != nil {
+			return "", err
+		}
+
+		for _, permission := range user.ProjectPermissions {
+			if err := b.client.AddUserPermission(ctx, project.Key, user.Login, permission); err != nil {
+				return "", fmt.Errorf("granting %s on project %s: %w", permission, project.Key, err)
+			}
+		}
+	}
+
+	token, err := b.client.GenerateToken(ctx, sonarclient.TokenGenerationParams{
+		Name:  sonarclient.ServiceTokenName(user.Login, time.Now()),
+		Login: user.Login,
+		Type:  sonarclient.UserTokenType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("generating service token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (b *Bootstrapper) ensureUser(ctx context.Context, user ServiceUser) error {
+	exists, err := b.client.UserExists(ctx, user.Login)
+	if err != nil {
+		return err
+	}
+	if exists {
+		log.Ctx(ctx).Info().Str("login", user.Login).Msg("service user already exists")
+		return nil
+	}
+
+	// The technical user only ever authenticates with tokens, so its password is
+	// random and never stored or displayed.
+	password, err := randomPassword()
+	if err != nil {
+		return err
+	}
+
+	if err := b.client.CreateUser(ctx, sonarclient.CreateUserParams{
+		Login:    user.Login,
+		Name:     user.Name,
+		Password: password,
+	}); err != nil {
+		return err
+	}
+
+	log.Ctx(ctx).Info().Str("login", user.Login).Msg("service user created")
+
+	return nil
+}
+
+func (b *Bootstrapper) ensureProject(ctx context.Context, project Project) error {
+	exists, err := b.client.ProjectExists(ctx, project.Key)
+	if err != nil {
+		return err
+	}
+	if exists {
+		log.Ctx(ctx).Info().Str("project_key", project.Key).Msg("project already exists")
+		return nil
+	}
+
+	if err := b.client.CreateProject(ctx, project.Key, project.Name); err != nil {
+		return err
+	}
+
+	log.Ctx(ctx).Info().Str("project_key", project.Key).Msg("project created")
+
+	return nil
+}
+
+func randomPassword() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generating password: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
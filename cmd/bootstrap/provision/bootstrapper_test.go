@@ -0,0 +1,89 @@
+package provision_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/werbersondev/token-generator-test/cmd/bootstrap/provision"
+	"github.com/werbersondev/token-generator-test/cmd/bootstrap/provision/mocks"
+	"github.com/werbersondev/token-generator-test/gateway/sonarclient"
+)
+
+func TestBootstrapper_Run(t *testing.T) {
+	manifest := provision.Manifest{
+		ServiceUser: provision.ServiceUser{
+			Login:              "token-generator",
+			Name:               "Token Generator",
+			ProjectPermissions: []string{sonarclient.ScanPermission},
+		},
+		Projects: []provision.Project{
+			{Key: "existing-project", Name: "Existing"},
+			{Key: "new-project", Name: "New"},
+		},
+	}
+
+	client := &mocks.SonarClientMock{
+		UserExistsFunc: func(ctx context.Context, login string) (bool, error) {
+			return false, nil
+		},
+		ProjectExistsFunc: func(ctx context.Context, projectKey string) (bool, error) {
+			return projectKey == "existing-project", nil
+		},
+		GenerateTokenFunc: func(ctx context.Context, params sonarclient.TokenGenerationParams) (string, error) {
+			return "service-token", nil
+		},
+	}
+
+	token, err := provision.NewBootstrapper(client, time.Millisecond).Run(context.Background(), manifest)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "service-token", token)
+
+	if assert.Len(t, client.CreateUserCalls(), 1) {
+		params := client.CreateUserCalls()[0].Params
+		assert.Equal(t, "token-generator", params.Login)
+		assert.NotEmpty(t, params.Password)
+	}
+
+	if assert.Len(t, client.CreateProjectCalls(), 1) {
+		assert.Equal(t, "new-project", client.CreateProjectCalls()[0].ProjectKey)
+	}
+
+	assert.Len(t, client.AddUserPermissionCalls(), 2)
+	for _, call := range client.AddUserPermissionCalls() {
+		assert.Equal(t, "token-generator", call.Login)
+		assert.Equal(t, sonarclient.ScanPermission, call.Permission)
+	}
+
+	if assert.Len(t, client.GenerateTokenCalls(), 1) {
+		params := client.GenerateTokenCalls()[0].Params
+		assert.Equal(t, "token-generator", params.Login)
+		assert.Equal(t, sonarclient.UserTokenType, params.Type)
+	}
+}
+
+func TestBootstrapper_WaitUntilUp(t *testing.T) {
+	calls := 0
+	client := &mocks.SonarClientMock{
+		SystemStatusFunc: func(ctx context.Context) (string, error) {
+			calls++
+			switch calls {
+			case 1:
+				return "", errors.New("connection refused")
+			case 2:
+				return "STARTING", nil
+			default:
+				return sonarclient.SystemStatusUp, nil
+			}
+		},
+	}
+
+	err := provision.NewBootstrapper(client, time.Millisecond).WaitUntilUp(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
@@ -0,0 +1,72 @@
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/werbersondev/token-generator-test/gateway/sonarclient"
+)
+
+// Manifest describes the Sonar resources the bootstrap command provisions.
+type Manifest struct {
+	ServiceUser ServiceUser `yaml:"service_user"`
+	Projects    []Project   `yaml:"projects"`
+}
+
+// ServiceUser is the technical user the token generator services authenticate as.
+type ServiceUser struct {
+	Login string `yaml:"login"`
+	Name  string `yaml:"name"`
+	// ProjectPermissions are granted to the user on every project of the manifest.
+	ProjectPermissions []string `yaml:"project_permissions"`
+}
+
+type Project struct {
+	Key  string `yaml:"key"`
+	Name string `yaml:"name"`
+}
+
+// LoadManifest reads and validates the YAML manifest at path.
+func LoadManifest(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("decoding manifest: %w", err)
+	}
+
+	if err := manifest.validate(); err != nil {
+		return Manifest{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+func (m *Manifest) validate() error {
+	if m.ServiceUser.Login == "" {
+		return errors.New("service_user.login cannot be blank")
+	}
+	if m.ServiceUser.Name == "" {
+		m.ServiceUser.Name = m.ServiceUser.Login
+	}
+	if len(m.ServiceUser.ProjectPermissions) == 0 {
+		m.ServiceUser.ProjectPermissions = []string{sonarclient.ScanPermission}
+	}
+
+	for i, project := range m.Projects {
+		if project.Key == "" {
+			return fmt.Errorf("projects[%d].key cannot be blank", i)
+		}
+		if project.Name == "" {
+			m.Projects[i].Name = project.Key
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package provision
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/werbersondev/token-generator-test/extensions/filex"
+)
+
+// SecretFormat selects how a secret is written to its destination file.
+type SecretFormat string
+
+const (
+	// SecretFormatRaw writes the secret value alone, replacing the file content.
+	SecretFormatRaw SecretFormat = "raw"
+	// SecretFormatEnv sets KEY=value in a dotenv file, keeping every other line.
+	SecretFormatEnv SecretFormat = "env"
+)
+
+const secretFileMode = 0o600
+
+// WriteSecret atomically writes the secret value to path with owner-only permissions.
+func WriteSecret(path string, format SecretFormat, key, value string) error {
+	var content []byte
+	switch format {
+	case SecretFormatRaw:
+		content = []byte(value + "\n")
+	case SecretFormatEnv:
+		existing, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		content = setEnvValue(existing, key, value)
+	default:
+		return fmt.Errorf("unknown secret format %q", format)
+	}
+
+	if err := filex.WriteFileAtomic(path, content, secretFileMode); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func setEnvValue(content []byte, key, value string) []byte {
+	var out bytes.Buffer
+	replaced := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), key+"=") {
+			if replaced {
+				continue
+			}
+			line = key + "=" + value
+			replaced = true
+		}
+		out.WriteString(line + "\n")
+	}
+
+	if !replaced {
+		out.WriteString(key + "=" + value + "\n")
+	}
+
+	return out.Bytes()
+}
@@ -0,0 +1,57 @@
+package provision_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/werbersondev/token-generator-test/cmd/bootstrap/provision"
+)
+
+func TestWriteSecret(t *testing.T) {
+	tests := []struct {
+		name            string
+		format          provision.SecretFormat
+		existingContent string
+		expectedContent string
+	}{
+		{
+			name:            "raw file",
+			format:          provision.SecretFormatRaw,
+			existingContent: "old-token\n",
+			expectedContent: "new-token\n",
+		},
+		{
+			name:            "env file without the key",
+			format:          provision.SecretFormatEnv,
+			existingContent: "PUBSUB_EMULATOR_HOST=localhost:8085\n",
+			expectedContent: "PUBSUB_EMULATOR_HOST=localhost:8085\nSONAR_AUTH_TOKEN=new-token\n",
+		},
+		{
+			name:            "env file with the key",
+			format:          provision.SecretFormatEnv,
+			existingContent: "SONAR_AUTH_TOKEN=old-token\nPUBSUB_EMULATOR_HOST=localhost:8085\n",
+			expectedContent: "SONAR_AUTH_TOKEN=new-token\nPUBSUB_EMULATOR_HOST=localhost:8085\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secret")
+			assert.NoError(t, os.WriteFile(path, []byte(tt.existingContent), 0o644))
+
+			err := provision.WriteSecret(path, tt.format, "SONAR_AUTH_TOKEN", "new-token")
+			assert.NoError(t, err)
+
+			content, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedContent, string(content))
+
+			info, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		})
+	}
+}
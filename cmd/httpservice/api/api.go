@@ -2,27 +2,293 @@ package api
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
+	"github.com/werbersondev/token-generator-test/extensions/openapix"
+	"github.com/werbersondev/token-generator-test/extensions/ratelimit"
 )
 
+// legacyRoutesDeprecatedAt is when the unversioned routes were superseded by /v1.
+var legacyRoutesDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
 type API struct {
-	LivenessHandler               http.HandlerFunc
-	RequestTokenGenerationHandler http.HandlerFunc
+	LivenessHandler                http.HandlerFunc
+	ReadinessHandler               http.HandlerFunc
+	OpenAPIHandler                 http.HandlerFunc
+	MetricsHandler                 http.HandlerFunc
+	RequestTokenGenerationHandler  http.HandlerFunc
+	RequestTokenGenerationsHandler http.HandlerFunc
+	GetRequestStatusHandler        http.HandlerFunc
+	RequestEventsHandler           http.HandlerFunc
+	GetBatchStatusHandler          http.HandlerFunc
+
+	CreateAPIKeyHandler  http.HandlerFunc
+	ListAPIKeysHandler   http.HandlerFunc
+	DisableAPIKeyHandler http.HandlerFunc
+
+	EvaluatePolicyHandler http.HandlerFunc
+
+	ListRequestsHandler   http.HandlerFunc
+	GetRequestHandler     http.HandlerFunc
+	CancelRequestHandler  http.HandlerFunc
+	RequeueRequestHandler http.HandlerFunc
+
+	ExchangeTokenHandler http.HandlerFunc
+
+	CallerHandler           http.HandlerFunc
+	ListOwnedTokensHandler  http.HandlerFunc
+	RetrieveTokenHandler    http.HandlerFunc
+	RevokeOwnedTokenHandler http.HandlerFunc
+
+	authenticators         []authx.Authenticator
+	exchangeAuthenticators []authx.Authenticator
+
+	requestStatuses RequestStatusUseCase
+	maxWait         time.Duration
+
+	validationOptions []openapix.Option
+
+	limiter    *ratelimit.Limiter
+	rateLimits RateLimits
+
+	metrics *metricsx.Metrics
+}
+
+type Option func(*API)
+
+// WithAuthentication requires the protected routes to be called with credentials
+// recognized by one of the authenticators.
+func WithAuthentication(authenticators ...authx.Authenticator) Option {
+	return func(a *API) {
+		a.authenticators = append(a.authenticators, authenticators...)
+	}
+}
+
+// WithAPIKeyAdministration exposes the API keys management endpoints.
+func WithAPIKeyAdministration(manager APIKeyManager) Option {
+	return func(a *API) {
+		a.CreateAPIKeyHandler = CreateAPIKeyHandler(manager)
+		a.ListAPIKeysHandler = ListAPIKeysHandler(manager)
+		a.DisableAPIKeyHandler = DisableAPIKeyHandler(manager)
+	}
+}
+
+// WithPolicyDryRun exposes the access policy evaluation endpoint.
+func WithPolicyDryRun(evaluator AccessPolicyEvaluator) Option {
+	return func(a *API) {
+		a.EvaluatePolicyHandler = EvaluatePolicyHandler(evaluator)
+	}
+}
+
+// WithRequestAdministration exposes the endpoints listing, inspecting, canceling and
+// requeueing the token generation requests.
+func WithRequestAdministration(uc RequestAdministrationUseCase) Option {
+	return func(a *API) {
+		a.ListRequestsHandler = ListRequestsHandler(uc)
+		a.GetRequestHandler = GetRequestHandler(uc)
+		a.CancelRequestHandler = CancelRequestHandler(uc)
+		a.RequeueRequestHandler = RequeueRequestHandler(uc)
+	}
+}
+
+// WithTokenExchange exposes the token exchange endpoint, authenticating identity
+// tokens with its own authenticators.
+func WithTokenExchange(uc TokenExchangeUseCase, authenticators ...authx.Authenticator) Option {
+	return func(a *API) {
+		a.ExchangeTokenHandler = ExchangeTokenHandler(uc)
+		a.exchangeAuthenticators = authenticators
+	}
+}
+
+// WithSelfService exposes the endpoints letting callers learn what they may request,
+// retrieve their tokens once, list their active tokens and revoke them.
+func WithSelfService(uc SelfServiceUseCase) Option {
+	return func(a *API) {
+		a.CallerHandler = CallerHandler(uc)
+		a.ListOwnedTokensHandler = ListOwnedTokensHandler(uc)
+		a.RetrieveTokenHandler = RetrieveTokenHandler(uc)
+		a.RevokeOwnedTokenHandler = RevokeOwnedTokenHandler(uc)
+	}
+}
+
+// WithRequestStatus exposes the status and events of the token generation requests and
+// batches, and lets callers wait up to maxWait for the token when requesting it.
+func WithRequestStatus(uc RequestStatusUseCase, maxWait time.Duration) Option {
+	return func(a *API) {
+		a.GetRequestStatusHandler = GetRequestStatusHandler(uc)
+		a.RequestEventsHandler = RequestEventsHandler(uc)
+		a.GetBatchStatusHandler = GetBatchStatusHandler(uc)
+		a.requestStatuses = uc
+		a.maxWait = maxWait
+	}
+}
+
+// WithReadiness exposes the readiness of the service, failing while its dependencies
+// are unavailable.
+func WithReadiness(readiness *httpx.Readiness) Option {
+	return func(a *API) {
+		a.ReadinessHandler = readiness.Handler()
+	}
 }
 
-func New(service RequestTokenGenerationUseCase) *API {
+// WithValidationOptions configures the validation of the requests and responses
+// against the OpenAPI document, such as the largest request body accepted.
+func WithValidationOptions(opts ...openapix.Option) Option {
+	return func(a *API) {
+		a.validationOptions = append(a.validationOptions, opts...)
+	}
+}
+
+// WithRateLimits limits the rate of the requests, counted by limiter.
+func WithRateLimits(limiter *ratelimit.Limiter, limits RateLimits) Option {
+	return func(a *API) {
+		a.limiter = limiter
+		a.rateLimits = limits
+	}
+}
+
+func New(service RequestTokenGenerationUseCase, opts ...Option) *API {
 	api := API{
-		LivenessHandler:               LivenessHandler(),
-		RequestTokenGenerationHandler: RequestTokenGenerationHandler(service),
+		LivenessHandler:                LivenessHandler(),
+		OpenAPIHandler:                 OpenAPIHandler(),
+		RequestTokenGenerationsHandler: RequestTokenGenerationsHandler(service),
+	}
+
+	for _, opt := range opts {
+		opt(&api)
 	}
 
+	api.RequestTokenGenerationHandler = RequestTokenGenerationHandler(service, api.requestStatuses, api.maxWait)
+
 	return &api
 }
 
+// Routes registers the routes of the API. Requests are validated against the OpenAPI
+// document once authorized, so that callers learn nothing of the operations they may
+// not call. Clients are rate limited before authentication, and token requests once
+// validated, by principal and by project. The outcome of the token requests is counted
+// before any of them.
 func (a *API) Routes(router *chi.Mux) {
-	router.HandleFunc("/liveness", a.LivenessHandler)
-	router.HandleFunc("/generate_token", a.RequestTokenGenerationHandler)
+	validate := openapix.Middleware(OpenAPI, a.validationOptions...)
+	limitClients := a.rateLimit(rateLimitClient, a.rateLimits.Client, ratelimit.ClientIP)
+	limitPrincipals := a.rateLimit(rateLimitPrincipal, a.rateLimits.Principal, ratelimit.Principal)
+
+	router.NotFound(httpx.NotFoundHandler)
+	router.MethodNotAllowed(httpx.MethodNotAllowedHandler)
+
+	router.Get("/liveness", a.LivenessHandler)
+	if a.ReadinessHandler != nil {
+		router.Get("/readiness", a.ReadinessHandler)
+	}
+	router.Get("/openapi.json", a.OpenAPIHandler)
+	if a.MetricsHandler != nil {
+		router.Get("/metrics", a.MetricsHandler)
+	}
+
+	router.Route("/v1", func(r chi.Router) {
+		r.Use(a.countTokenRequests, limitClients)
+
+		if a.ExchangeTokenHandler != nil {
+			r.With(authx.Middleware(a.exchangeAuthenticators...), validate, limitPrincipals).Post("/exchange", a.ExchangeTokenHandler)
+		}
+
+		r.Group(func(r chi.Router) {
+			r.Use(a.authenticate())
+
+			r.With(a.requireScope(model.ScopeRequestTokens), validate, limitPrincipals, a.limitProjects()).Post("/generate-token", a.RequestTokenGenerationHandler)
+			r.With(a.requireScope(model.ScopeRequestTokens), validate, limitPrincipals, a.limitProjects()).Post("/generate-tokens", a.RequestTokenGenerationsHandler)
+
+			if a.GetRequestStatusHandler != nil || a.RetrieveTokenHandler != nil {
+				r.With(a.requireScope(model.ScopeRequestTokens), validate).Route("/requests/{id}", func(r chi.Router) {
+					if a.GetRequestStatusHandler != nil {
+						r.Get("/", a.GetRequestStatusHandler)
+						r.Get("/events", a.RequestEventsHandler)
+					}
+					if a.RetrieveTokenHandler != nil {
+						r.Post("/token", a.RetrieveTokenHandler)
+						r.Post("/revoke", a.RevokeOwnedTokenHandler)
+					}
+				})
+			}
+			if a.GetRequestStatusHandler != nil {
+				r.With(a.requireScope(model.ScopeRequestTokens), validate).Get("/batches/{id}", a.GetBatchStatusHandler)
+			}
+
+			if a.CallerHandler != nil {
+				r.With(a.requireScope(model.ScopeRequestTokens), validate).Get("/me", a.CallerHandler)
+				r.With(a.requireScope(model.ScopeRequestTokens), validate).Get("/tokens", a.ListOwnedTokensHandler)
+			}
+
+			if a.CreateAPIKeyHandler != nil {
+				r.With(a.requireScope(model.ScopeAdmin), validate).Route("/admin/api-keys", func(r chi.Router) {
+					r.Get("/", a.ListAPIKeysHandler)
+					r.Post("/", a.CreateAPIKeyHandler)
+					r.Post("/{id}/disable", a.DisableAPIKeyHandler)
+				})
+			}
+
+			if a.ListRequestsHandler != nil {
+				r.With(a.requireScope(model.ScopeAdmin), validate).Route("/admin/requests", func(r chi.Router) {
+					r.Get("/", a.ListRequestsHandler)
+					r.Get("/{id}", a.GetRequestHandler)
+					r.Post("/{id}/cancel", a.CancelRequestHandler)
+					r.Post("/{id}/requeue", a.RequeueRequestHandler)
+				})
+			}
+
+			if a.EvaluatePolicyHandler != nil {
+				r.With(a.requireScope(model.ScopeAdmin), validate).Post("/admin/policy/evaluate", a.EvaluatePolicyHandler)
+			}
+		})
+	})
+
+	// Unversioned routes predating /v1, kept for existing clients.
+	router.With(deprecated("/v1/generate-token"), a.countTokenRequests, limitClients, a.authenticate(), a.requireScope(model.ScopeRequestTokens), validate, limitPrincipals, a.limitProjects()).
+		Post("/generate_token", a.RequestTokenGenerationHandler)
+	if a.ExchangeTokenHandler != nil {
+		router.With(deprecated("/v1/exchange"), a.countTokenRequests, limitClients, authx.Middleware(a.exchangeAuthenticators...), validate, limitPrincipals).
+			Post("/exchange", a.ExchangeTokenHandler)
+	}
+}
+
+// deprecated flags the responses of a legacy route as deprecated in favor of successor,
+// with the Deprecation header of RFC 9745.
+func deprecated(successor string) func(http.Handler) http.Handler {
+	deprecation := "@" + strconv.FormatInt(legacyRoutesDeprecatedAt.Unix(), 10)
+	link := "<" + successor + `>; rel="successor-version"`
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Add("Link", link)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authenticate requires credentials only when authentication is enabled.
+func (a *API) authenticate() func(http.Handler) http.Handler {
+	if len(a.authenticators) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return authx.Middleware(a.authenticators...)
+}
+
+// requireScope enforces scope only when authentication is enabled.
+func (a *API) requireScope(scope string) func(http.Handler) http.Handler {
+	if len(a.authenticators) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return authx.RequireScope(scope)
 }
 
 func LivenessHandler() func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+//go:generate moq -stub -pkg mocks -out mocks/api_key_manager.go . APIKeyManager
+type APIKeyManager interface {
+	CreateAPIKey(ctx context.Context, name string, scopes []string) (string, authx.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]authx.APIKey, error)
+	DisableAPIKey(ctx context.Context, id string) error
+}
+
+type CreateAPIKeyInput struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type APIKeyOutput struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"created_at"`
+	// Key is only returned once, when the key is created.
+	Key string `json:"key,omitempty"`
+}
+
+func newAPIKeyOutput(key authx.APIKey) APIKeyOutput {
+	return APIKeyOutput{
+		ID:        key.ID,
+		Name:      key.Name,
+		Scopes:    key.Scopes,
+		Disabled:  key.Disabled,
+		CreatedAt: key.CreatedAt,
+	}
+}
+
+func CreateAPIKeyHandler(manager APIKeyManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var body CreateAPIKeyInput
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			httpx.WriteProblem(w, r, http.StatusBadRequest, codeInvalidBody, "the request body is not valid JSON")
+			return
+		}
+
+		if body.Name == "" || len(body.Scopes) == 0 {
+			httpx.WriteProblem(w, r, http.StatusUnprocessableEntity, codeValidationFailed, "missing required parameters: name, scopes")
+			return
+		}
+
+		plaintext, key, err := manager.CreateAPIKey(ctx, body.Name, body.Scopes)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("creating api key")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to create the API key")
+			return
+		}
+
+		log.Ctx(ctx).Info().Str("api_key_id", key.ID).Strs("scopes", key.Scopes).Msg("API key created")
+
+		output := newAPIKeyOutput(key)
+		output.Key = plaintext
+		writeJSON(ctx, w, http.StatusCreated, output)
+	}
+}
+
+func ListAPIKeysHandler(manager APIKeyManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		keys, err := manager.ListAPIKeys(ctx)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("listing api keys")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to list the API keys")
+			return
+		}
+
+		output := make([]APIKeyOutput, 0, len(keys))
+		for _, key := range keys {
+			output = append(output, newAPIKeyOutput(key))
+		}
+
+		writeJSON(ctx, w, http.StatusOK, output)
+	}
+}
+
+func DisableAPIKeyHandler(manager APIKeyManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := chi.URLParam(r, "id")
+
+		err := manager.DisableAPIKey(ctx, id)
+		if errors.Is(err, authx.ErrAPIKeyNotFound) {
+			httpx.WriteProblem(w, r, http.StatusNotFound, codeNotFound, "API key "+id+" not found")
+			return
+		}
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("disabling api key")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to disable the API key")
+			return
+		}
+
+		log.Ctx(ctx).Info().Str("api_key_id", id).Msg("API key disabled")
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeJSON(ctx context.Context, w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("encoding response body")
+	}
+}
@@ -0,0 +1,195 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+// setupAuthenticatedAPITest serves an API protected by API keys and returns an admin
+// key and a key only allowed to request tokens.
+func setupAuthenticatedAPITest(t *testing.T, uc api.RequestTokenGenerationUseCase, manager api.APIKeyManager) (string, string, string, func()) {
+	t.Helper()
+
+	apiKeys := authx.NewAPIKeys(authx.NewStateAPIKeyStore(statestore.NewMemory()))
+	adminKey, _, err := apiKeys.CreateAPIKey(context.Background(), "admin", []string{model.ScopeAdmin})
+	require.NoError(t, err)
+	requesterKey, _, err := apiKeys.CreateAPIKey(context.Background(), "ci", []string{model.ScopeRequestTokens})
+	require.NoError(t, err)
+
+	if manager == nil {
+		manager = apiKeys
+	}
+
+	server, tearDownFn := setupAPITest(t, api.New(uc,
+		api.WithAuthentication(apiKeys),
+		api.WithAPIKeyAdministration(manager),
+	))
+
+	return server.URL, adminKey, requesterKey, tearDownFn
+}
+
+func doRequest(t *testing.T, method, url, apiKey, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, bytes.NewReader([]byte(body)))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	return resp
+}
+
+func TestAuthentication_GenerateToken(t *testing.T) {
+	useCase := &mocks.RequestTokenGenerationUseCaseMock{}
+	serverURL, adminKey, requesterKey, tearDownFn := setupAuthenticatedAPITest(t, useCase, nil)
+	defer tearDownFn()
+
+	tests := []struct {
+		name           string
+		apiKey         string
+		expectedStatus int
+	}{
+		{name: "Missing Credentials", apiKey: "", expectedStatus: http.StatusUnauthorized},
+		{name: "Invalid Credentials", apiKey: "tgk_000000000000_unknown", expectedStatus: http.StatusUnauthorized},
+		{name: "Missing Scope", apiKey: adminKey, expectedStatus: http.StatusForbidden},
+		{name: "Authorized", apiKey: requesterKey, expectedStatus: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := doRequest(t, http.MethodPost, serverURL+"/v1/generate-token", tt.apiKey, `{"project_id": "project-id"}`)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+
+	// The authenticated principal reaches the use case.
+	calls := useCase.RequestTokenGenerationCalls()
+	if assert.Len(t, calls, 1) {
+		principal, ok := model.PrincipalFromContext(calls[0].Ctx)
+		assert.True(t, ok)
+		assert.Equal(t, model.AuthMethodAPIKey, principal.Method)
+		assert.Equal(t, "ci", principal.Name)
+	}
+
+	// Liveness stays public.
+	resp := doRequest(t, http.MethodGet, serverURL+"/liveness", "", "")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAPIKeyAdministration(t *testing.T) {
+	serverURL, adminKey, requesterKey, tearDownFn := setupAuthenticatedAPITest(t, &mocks.RequestTokenGenerationUseCaseMock{}, nil)
+	defer tearDownFn()
+
+	// Only admins manage keys.
+	resp := doRequest(t, http.MethodGet, serverURL+"/v1/admin/api-keys", requesterKey, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, serverURL+"/v1/admin/api-keys", adminKey, `{"name": "pipeline", "scopes": ["tokens:request"]}`)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var created api.APIKeyOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.NotEmpty(t, created.Key)
+	assert.Equal(t, "pipeline", created.Name)
+
+	// The created key authenticates until it is disabled.
+	resp = doRequest(t, http.MethodPost, serverURL+"/v1/generate-token", created.Key, `{"project_id": "project-id"}`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, serverURL+"/v1/admin/api-keys/"+created.ID+"/disable", adminKey, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = doRequest(t, http.MethodPost, serverURL+"/v1/generate-token", created.Key, `{"project_id": "project-id"}`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Listing never discloses the keys.
+	resp = doRequest(t, http.MethodGet, serverURL+"/v1/admin/api-keys", adminKey, "")
+	defer resp.Body.Close()
+	var keys []api.APIKeyOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&keys))
+	assert.Len(t, keys, 3)
+	for _, key := range keys {
+		assert.Empty(t, key.Key)
+	}
+}
+
+func TestAPIKeyAdministration_Failure(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		manager        *mocks.APIKeyManagerMock
+		expectedStatus int
+	}{
+		{
+			name:           "Create Without Scopes",
+			method:         http.MethodPost,
+			path:           "/v1/admin/api-keys",
+			body:           `{"name": "pipeline"}`,
+			manager:        &mocks.APIKeyManagerMock{},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "Create Error",
+			method: http.MethodPost,
+			path:   "/v1/admin/api-keys",
+			body:   `{"name": "pipeline", "scopes": ["tokens:request"]}`,
+			manager: &mocks.APIKeyManagerMock{
+				CreateAPIKeyFunc: func(ctx context.Context, name string, scopes []string) (string, authx.APIKey, error) {
+					return "", authx.APIKey{}, errors.New("store unavailable")
+				},
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:   "Disable Unknown Key",
+			method: http.MethodPost,
+			path:   "/v1/admin/api-keys/unknown/disable",
+			manager: &mocks.APIKeyManagerMock{
+				DisableAPIKeyFunc: func(ctx context.Context, id string) error {
+					return authx.ErrAPIKeyNotFound
+				},
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverURL, adminKey, _, tearDownFn := setupAuthenticatedAPITest(t, &mocks.RequestTokenGenerationUseCaseMock{}, tt.manager)
+			defer tearDownFn()
+
+			resp := doRequest(t, tt.method, serverURL+tt.path, adminKey, tt.body)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+		})
+	}
+}
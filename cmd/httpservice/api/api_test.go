@@ -0,0 +1,116 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/correlation"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+	"github.com/werbersondev/token-generator-test/extensions/openapix"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+func TestRoutes_Problems(t *testing.T) {
+	apiKeys := authx.NewAPIKeys(authx.NewStateAPIKeyStore(statestore.NewMemory()))
+	adminKey, _, err := apiKeys.CreateAPIKey(context.Background(), "admin", []string{model.ScopeAdmin})
+	require.NoError(t, err)
+	requesterKey, _, err := apiKeys.CreateAPIKey(context.Background(), "ci", []string{model.ScopeRequestTokens})
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(correlation.Middleware)
+	api.New(&mocks.RequestTokenGenerationUseCaseMock{}, api.WithAuthentication(apiKeys), api.WithValidationOptions(openapix.WithMaxBodyBytes(64))).Routes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		apiKey         string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "Invalid Body", method: http.MethodPost, path: "/v1/generate-token", apiKey: requesterKey, body: `{"project_id": "`, expectedStatus: http.StatusBadRequest, expectedCode: "invalid_body"},
+		{name: "Validation Failed", method: http.MethodPost, path: "/v1/generate-token", apiKey: requesterKey, body: `{"project_id": ""}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "validation_failed"},
+		{name: "Duplicate Key", method: http.MethodPost, path: "/v1/generate-token", apiKey: requesterKey, body: `{"project_id": "a", "project_id": "b"}`, expectedStatus: http.StatusBadRequest, expectedCode: "invalid_body"},
+		{name: "Trailing Data", method: http.MethodPost, path: "/v1/generate-token", apiKey: requesterKey, body: `{"project_id": "a"} {}`, expectedStatus: http.StatusBadRequest, expectedCode: "invalid_body"},
+		{name: "Unknown Field", method: http.MethodPost, path: "/v1/generate-token", apiKey: requesterKey, body: `{"project_id": "a", "ttl_hours": 1}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "validation_failed"},
+		{name: "Invalid Project Key", method: http.MethodPost, path: "/v1/generate-token", apiKey: requesterKey, body: `{"project_id": "my project"}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: "validation_failed"},
+		{name: "Body Too Large", method: http.MethodPost, path: "/v1/generate-token", apiKey: requesterKey, body: `{"project_id": "` + strings.Repeat("a", 64) + `"}`, expectedStatus: http.StatusRequestEntityTooLarge, expectedCode: httpx.CodeBodyTooLarge},
+		{name: "Missing Credentials", method: http.MethodPost, path: "/v1/generate-token", body: `{}`, expectedStatus: http.StatusUnauthorized, expectedCode: authx.CodeUnauthenticated},
+		{name: "Insufficient Scope", method: http.MethodPost, path: "/v1/generate-token", apiKey: adminKey, body: `{}`, expectedStatus: http.StatusForbidden, expectedCode: authx.CodeInsufficientScope},
+		{name: "Method Not Allowed", method: http.MethodGet, path: "/v1/generate-token", apiKey: requesterKey, expectedStatus: http.StatusMethodNotAllowed, expectedCode: httpx.CodeMethodNotAllowed},
+		{name: "Legacy Route Method Not Allowed", method: http.MethodGet, path: "/generate_token", apiKey: requesterKey, expectedStatus: http.StatusMethodNotAllowed, expectedCode: httpx.CodeMethodNotAllowed},
+		{name: "Unknown Route", method: http.MethodGet, path: "/v2/generate-token", expectedStatus: http.StatusNotFound, expectedCode: httpx.CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := doRequest(t, tt.method, server.URL+tt.path, tt.apiKey, tt.body)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Equal(t, httpx.ProblemContentType, resp.Header.Get("Content-Type"))
+
+			var problem httpx.Problem
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+			assert.Equal(t, tt.expectedStatus, problem.Status)
+			assert.Equal(t, tt.expectedCode, problem.Code)
+			assert.Equal(t, tt.path, problem.Instance)
+			assert.NotEmpty(t, problem.RequestID)
+		})
+	}
+}
+
+func TestRoutes_UnsupportedMediaType(t *testing.T) {
+	server, tearDownFn := setupAPITest(t, api.New(&mocks.RequestTokenGenerationUseCaseMock{}))
+	defer tearDownFn()
+
+	resp, err := http.Post(server.URL+"/v1/generate-token", "application/x-www-form-urlencoded", strings.NewReader("project_id=project-id"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var problem httpx.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	assert.Equal(t, httpx.CodeUnsupportedMediaType, problem.Code)
+}
+
+func TestRoutes_LegacyAlias(t *testing.T) {
+	useCase := &mocks.RequestTokenGenerationUseCaseMock{
+		RequestTokenGenerationFunc: func(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
+			return model.RequestStatus{ID: "0123", State: model.RequestStateQueued}, nil
+		},
+	}
+
+	server, tearDownFn := setupAPITest(t, api.New(useCase))
+	defer tearDownFn()
+
+	resp := doRequest(t, http.MethodPost, server.URL+"/generate_token", "", `{"project_id": "project-id"}`)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "@1792368000", resp.Header.Get("Deprecation"))
+	assert.Equal(t, `</v1/generate-token>; rel="successor-version"`, resp.Header.Get("Link"))
+	assert.Equal(t, "/v1/requests/0123", resp.Header.Get("Location"))
+
+	resp = doRequest(t, http.MethodPost, server.URL+"/v1/generate-token", "", `{"project_id": "project-id"}`)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Deprecation"))
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+// maxBatchSize is the largest number of requests accepted in a batch.
+const maxBatchSize = 100
+
+type RequestTokenGenerationsInput struct {
+	Requests []RequestTokenGenerationInput `json:"requests"`
+}
+
+type BatchItemOutput struct {
+	Index     int                `json:"index"`
+	RequestID string             `json:"request_id,omitempty"`
+	State     model.RequestState `json:"state,omitempty"`
+	StatusURL string             `json:"status_url,omitempty"`
+	// Error explains why the request was not queued.
+	Error string `json:"error,omitempty"`
+}
+
+type RequestTokenGenerationsOutput struct {
+	BatchID   string            `json:"batch_id"`
+	StatusURL string            `json:"status_url"`
+	Accepted  int               `json:"accepted"`
+	Rejected  int               `json:"rejected"`
+	Items     []BatchItemOutput `json:"items"`
+}
+
+type BatchStatusOutput struct {
+	BatchID   string                     `json:"batch_id"`
+	State     model.BatchState           `json:"state"`
+	CreatedAt time.Time                  `json:"created_at"`
+	Counts    map[model.RequestState]int `json:"counts"`
+	Rejected  int                        `json:"rejected"`
+	// Requests lists the queued requests of the batch, without their token.
+	Requests []BatchItemOutput `json:"requests"`
+}
+
+func batchStatusPath(id string) string {
+	return "/v1/batches/" + id
+}
+
+// RequestTokenGenerationsHandler queues a batch of token generation requests. Every
+// request is validated on its own, the response holding the result of each in order.
+func RequestTokenGenerationsHandler(uc RequestTokenGenerationUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var body RequestTokenGenerationsInput
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			httpx.WriteProblem(w, r, http.StatusBadRequest, codeInvalidBody, "the request body is not valid JSON")
+			return
+		}
+
+		if len(body.Requests) == 0 || len(body.Requests) > maxBatchSize {
+			httpx.WriteProblem(w, r, http.StatusUnprocessableEntity, codeValidationFailed, fmt.Sprintf("requests must hold between 1 and %d items", maxBatchSize))
+			return
+		}
+
+		items := make([]BatchItemOutput, len(body.Requests))
+		requests := make([]model.TokenGenerationRequest, 0, len(body.Requests))
+		indexes := make([]int, 0, len(body.Requests))
+		for i, input := range body.Requests {
+			items[i].Index = i
+
+			request, err := input.tokenGenerationRequest()
+			if err != nil {
+				items[i].Error = err.Error()
+				continue
+			}
+			requests = append(requests, request)
+			indexes = append(indexes, i)
+		}
+
+		batch, results, err := uc.RequestTokenGenerations(ctx, requests)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("Failed to queue the token generation batch")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codePublishFailed, "failed to queue the requests")
+			return
+		}
+
+		for j, i := range indexes {
+			items[i] = batchItemOutput(ctx, i, results[j])
+		}
+
+		output := RequestTokenGenerationsOutput{
+			BatchID:   batch.ID,
+			StatusURL: batchStatusPath(batch.ID),
+			Accepted:  len(batch.RequestIDs),
+			Rejected:  len(body.Requests) - len(batch.RequestIDs),
+			Items:     items,
+		}
+
+		log.Ctx(ctx).Info().Str("batch_id", batch.ID).
+			Int("accepted", output.Accepted).
+			Int("rejected", output.Rejected).
+			Msg("Token generation batch sent")
+
+		code := http.StatusAccepted
+		if output.Accepted == 0 {
+			code = http.StatusUnprocessableEntity
+		} else {
+			w.Header().Set("Location", output.StatusURL)
+		}
+		writeJSON(ctx, w, code, output)
+	}
+}
+
+func batchItemOutput(ctx context.Context, index int, result model.BatchItemResult) BatchItemOutput {
+	var deniedErr *model.AccessDeniedError
+	switch {
+	case errors.As(result.Err, &deniedErr):
+		return BatchItemOutput{Index: index, Error: "forbidden: " + deniedErr.Decision.Reason}
+	case result.Err != nil:
+		log.Ctx(ctx).Error().Err(result.Err).Int("index", index).Msg("Failed to queue a token generation request of a batch")
+		return BatchItemOutput{Index: index, Error: "failed to queue the request"}
+	}
+
+	return BatchItemOutput{
+		Index:     index,
+		RequestID: result.Status.ID,
+		State:     result.Status.State,
+		StatusURL: requestStatusPath(result.Status.ID),
+	}
+}
+
+// GetBatchStatusHandler returns the aggregate status of a batch of requests. Batches
+// are only visible to their owner and administrators.
+func GetBatchStatusHandler(uc RequestStatusUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := chi.URLParam(r, "id")
+
+		status, err := uc.GetBatchStatus(ctx, id)
+		if errors.Is(err, model.ErrBatchNotFound) || (err == nil && !canReadOwned(ctx, status.Batch.Owner)) {
+			httpx.WriteProblem(w, r, http.StatusNotFound, codeNotFound, "batch "+id+" not found")
+			return
+		}
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("batch_id", id).Msg("Failed to get batch status")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to get the batch status")
+			return
+		}
+
+		output := BatchStatusOutput{
+			BatchID:   status.Batch.ID,
+			State:     status.State,
+			CreatedAt: status.Batch.CreatedAt,
+			Counts:    status.Counts,
+			Rejected:  status.Batch.Rejected,
+			Requests:  make([]BatchItemOutput, len(status.Requests)),
+		}
+		for i, request := range status.Requests {
+			output.Requests[i] = BatchItemOutput{
+				Index:     i,
+				RequestID: request.ID,
+				State:     request.State,
+				StatusURL: requestStatusPath(request.ID),
+			}
+		}
+
+		writeJSON(ctx, w, http.StatusOK, output)
+	}
+}
@@ -0,0 +1,140 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+func TestRequestTokenGenerationsHandler(t *testing.T) {
+	// queueAll queues the requests of projects starting with acme and denies the others.
+	queueAll := func(ctx context.Context, requests []model.TokenGenerationRequest) (model.RequestBatch, []model.BatchItemResult, error) {
+		batch := model.RequestBatch{ID: "batch"}
+		results := make([]model.BatchItemResult, len(requests))
+		for i, request := range requests {
+			if !strings.HasPrefix(request.ProjectID, "acme") {
+				results[i].Err = &model.AccessDeniedError{Decision: model.AccessDecision{Reason: "no rule allows it"}}
+				continue
+			}
+			results[i].Status = model.RequestStatus{ID: request.ProjectID + "-id", State: model.RequestStateQueued}
+			batch.RequestIDs = append(batch.RequestIDs, results[i].Status.ID)
+		}
+		return batch, results, nil
+	}
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		queue          func(context.Context, []model.TokenGenerationRequest) (model.RequestBatch, []model.BatchItemResult, error)
+		expectedStatus int
+		expectedItems  []api.BatchItemOutput
+	}{
+		{
+			name:           "Validates Each Request",
+			requestBody:    `{"requests": [{"project_id": "acme-app"}, {"project_id": ""}, {"project_id": "infra-dns"}, {"project_id": "acme-lib", "ttl": "24h"}]}`,
+			queue:          queueAll,
+			expectedStatus: http.StatusAccepted,
+			expectedItems: []api.BatchItemOutput{
+				{Index: 0, RequestID: "acme-app-id", State: model.RequestStateQueued, StatusURL: "/v1/requests/acme-app-id"},
+				{Index: 1, Error: "missing required parameter: project_id"},
+				{Index: 2, Error: "forbidden: no rule allows it"},
+				{Index: 3, RequestID: "acme-lib-id", State: model.RequestStateQueued, StatusURL: "/v1/requests/acme-lib-id"},
+			},
+		},
+		{
+			name:           "Every Request Rejected",
+			requestBody:    `{"requests": [{"project_id": "infra-dns"}]}`,
+			queue:          queueAll,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedItems: []api.BatchItemOutput{
+				{Index: 0, Error: "forbidden: no rule allows it"},
+			},
+		},
+		{
+			name:           "Empty Batch",
+			requestBody:    `{"requests": []}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Too Many Requests",
+			requestBody:    `{"requests": [` + strings.Repeat(`{"project_id": "acme-app"},`, 100) + `{"project_id": "acme-app"}]}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "UseCase Error",
+			requestBody: `{"requests": [{"project_id": "acme-app"}]}`,
+			queue: func(ctx context.Context, requests []model.TokenGenerationRequest) (model.RequestBatch, []model.BatchItemResult, error) {
+				return model.RequestBatch{}, nil, errors.New("mocked error from use case")
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &mocks.RequestTokenGenerationUseCaseMock{RequestTokenGenerationsFunc: tt.queue}
+
+			server, tearDownFn := setupAPITest(t, api.New(useCase))
+			defer tearDownFn()
+
+			resp := doRequest(t, http.MethodPost, server.URL+"/v1/generate-tokens", "", tt.requestBody)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedItems == nil {
+				return
+			}
+
+			var body api.RequestTokenGenerationsOutput
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, "batch", body.BatchID)
+			assert.Equal(t, "/v1/batches/batch", body.StatusURL)
+			assert.Equal(t, tt.expectedItems, body.Items)
+		})
+	}
+}
+
+func TestGetBatchStatusHandler(t *testing.T) {
+	createdAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	statuses := &mocks.RequestStatusUseCaseMock{
+		GetBatchStatusFunc: func(ctx context.Context, id string) (model.BatchStatus, error) {
+			if id != "batch" {
+				return model.BatchStatus{}, model.ErrBatchNotFound
+			}
+			return model.NewBatchStatus(
+				model.RequestBatch{ID: id, CreatedAt: createdAt, RequestIDs: []string{"0123", "4567"}, Rejected: 1},
+				[]model.RequestStatus{{ID: "0123", State: model.RequestStateIssued, Token: "sqp_token"}, {ID: "4567", State: model.RequestStateQueued}},
+			), nil
+		},
+	}
+
+	server, tearDownFn := setupAPITest(t, api.New(&mocks.RequestTokenGenerationUseCaseMock{}, api.WithRequestStatus(statuses, time.Second)))
+	defer tearDownFn()
+
+	resp := doRequest(t, http.MethodGet, server.URL+"/v1/batches/batch", "", "")
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "pending", body["state"])
+	assert.Equal(t, map[string]any{"issued": 1.0, "queued": 1.0}, body["counts"])
+	assert.Equal(t, 1.0, body["rejected"])
+	assert.NotContains(t, fmt.Sprint(body["requests"]), "sqp_token")
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/v1/batches/unknown", "", "")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+//go:generate moq -stub -pkg mocks -out mocks/token_exchange_uc.go . TokenExchangeUseCase
+type TokenExchangeUseCase interface {
+	ExchangeToken(ctx context.Context, principal model.Principal) (model.IssuedToken, error)
+}
+
+type ExchangeTokenOutput struct {
+	Token      string    `json:"token"`
+	ProjectKey string    `json:"project_key"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ExchangeTokenHandler issues a project analysis token to the CI job authenticated by
+// its identity token.
+func ExchangeTokenHandler(uc TokenExchangeUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		principal, ok := model.PrincipalFromContext(ctx)
+		if !ok {
+			httpx.WriteProblem(w, r, http.StatusUnauthorized, authx.CodeUnauthenticated, "missing credentials")
+			return
+		}
+
+		issued, err := uc.ExchangeToken(ctx, principal)
+		var deniedErr *model.AccessDeniedError
+		if errors.As(err, &deniedErr) {
+			log.Ctx(ctx).Warn().Str("subject", principal.Subject).Str("reason", deniedErr.Decision.Reason).Msg("Token exchange denied")
+			httpx.WriteProblem(w, r, http.StatusForbidden, codeAccessDenied, deniedErr.Decision.Reason)
+			return
+		}
+		if err != nil {
+			reason := model.FailureReasonOf(err)
+			log.Ctx(ctx).Error().Err(err).Str("subject", principal.Subject).Str("failure_reason", string(reason)).Msg("Token exchange failed")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeTokenGenerationFailed, "failed to generate the token: "+string(reason))
+			return
+		}
+
+		log.Ctx(ctx).Info().Str("subject", principal.Subject).Str("project_id", issued.ProjectID).Msg("Token exchanged")
+
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(ctx, w, http.StatusOK, ExchangeTokenOutput{
+			Token:      issued.Token,
+			ProjectKey: issued.ProjectID,
+			ExpiresAt:  issued.ExpiresAt,
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/authx/authxtest"
+)
+
+const exchangeAudience = "https://token-generator.example.com"
+
+func TestExchangeTokenHandler(t *testing.T) {
+	ciIssuer := authxtest.NewIssuer(t, "https://token.actions.githubusercontent.com")
+	otherIssuer := authxtest.NewIssuer(t, "https://other.example.com")
+
+	keys, err := authx.NewFileJWKS(ciIssuer.JWKSFile(t), time.Minute)
+	require.NoError(t, err)
+	authenticator, err := authx.NewJWT(authx.JWTConfig{
+		Issuer:   ciIssuer.URL,
+		Audience: exchangeAudience,
+		Claims:   authx.ClaimMapping{Attributes: []string{"*"}},
+	}, keys)
+	require.NoError(t, err)
+
+	expiresAt := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	ciClaims := map[string]any{"sub": "repo:acme/app:ref:refs/heads/main", "repository": "acme/app", "ref": "refs/heads/main"}
+
+	tests := []struct {
+		name           string
+		token          string
+		exchangeErr    error
+		expectedStatus int
+	}{
+		{
+			name:           "Exchanged",
+			token:          ciIssuer.Sign(t, exchangeAudience, ciClaims),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Missing Token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Untrusted Issuer",
+			token:          otherIssuer.Sign(t, exchangeAudience, ciClaims),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Other Audience",
+			token:          ciIssuer.Sign(t, "https://other.example.com", ciClaims),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Expired",
+			token:          ciIssuer.Sign(t, exchangeAudience, map[string]any{"sub": "repo:acme/app", "exp": time.Now().Add(-time.Hour).Unix()}),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Denied",
+			token:          ciIssuer.Sign(t, exchangeAudience, ciClaims),
+			exchangeErr:    &model.AccessDeniedError{Decision: model.AccessDecision{Reason: "no exchange rule matches"}},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Provider Error",
+			token:          ciIssuer.Sign(t, exchangeAudience, ciClaims),
+			exchangeErr:    &model.TokenGenerationError{Reason: model.FailureReasonProviderError, Err: errors.New("connection refused")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &mocks.TokenExchangeUseCaseMock{
+				ExchangeTokenFunc: func(ctx context.Context, principal model.Principal) (model.IssuedToken, error) {
+					if tt.exchangeErr != nil {
+						return model.IssuedToken{}, tt.exchangeErr
+					}
+					return model.IssuedToken{Token: "squ_token", ProjectID: "acme_app", ExpiresAt: expiresAt}, nil
+				},
+			}
+
+			server, tearDownFn := setupAPITest(t, api.New(&mocks.RequestTokenGenerationUseCaseMock{}, api.WithTokenExchange(useCase, authenticator)))
+			defer tearDownFn()
+
+			resp := doRequest(t, http.MethodPost, server.URL+"/v1/exchange", tt.token, "")
+			defer resp.Body.Close()
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var output api.ExchangeTokenOutput
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&output))
+			assert.Equal(t, api.ExchangeTokenOutput{Token: "squ_token", ProjectKey: "acme_app", ExpiresAt: expiresAt}, output)
+			assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+
+			calls := useCase.ExchangeTokenCalls()
+			require.Len(t, calls, 1)
+			assert.Equal(t, ciIssuer.URL, calls[0].Principal.Issuer)
+			assert.Equal(t, "repo:acme/app:ref:refs/heads/main", calls[0].Principal.Subject)
+			assert.Equal(t, "acme/app", calls[0].Principal.Attributes["repository"])
+			assert.Equal(t, "refs/heads/main", calls[0].Principal.Attributes["ref"])
+		})
+	}
+}
+
+func TestExchangeTokenHandler_Issuers(t *testing.T) {
+	githubIssuer := authxtest.NewIssuer(t, "https://token.actions.githubusercontent.com")
+	gitlabIssuer := authxtest.NewIssuer(t, "https://gitlab.example.com")
+
+	newAuthenticator := func(issuer *authxtest.Issuer) *authx.JWT {
+		keys, err := authx.NewFileJWKS(issuer.JWKSFile(t), time.Minute)
+		require.NoError(t, err)
+		authenticator, err := authx.NewJWT(authx.JWTConfig{
+			Issuer:   issuer.URL,
+			Audience: exchangeAudience,
+			Claims:   authx.ClaimMapping{Attributes: []string{"*"}},
+		}, keys)
+		require.NoError(t, err)
+		return authenticator
+	}
+	authenticators := []authx.Authenticator{newAuthenticator(githubIssuer), newAuthenticator(gitlabIssuer)}
+
+	tests := []struct {
+		name           string
+		issuer         *authxtest.Issuer
+		expectedStatus int
+	}{
+		{name: "First Issuer", issuer: githubIssuer, expectedStatus: http.StatusOK},
+		{name: "Second Issuer", issuer: gitlabIssuer, expectedStatus: http.StatusOK},
+		{name: "Untrusted Issuer", issuer: authxtest.NewIssuer(t, "https://other.example.com"), expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &mocks.TokenExchangeUseCaseMock{
+				ExchangeTokenFunc: func(ctx context.Context, principal model.Principal) (model.IssuedToken, error) {
+					return model.IssuedToken{Token: "squ_token", ProjectID: "acme_app"}, nil
+				},
+			}
+
+			server, tearDownFn := setupAPITest(t, api.New(&mocks.RequestTokenGenerationUseCaseMock{}, api.WithTokenExchange(useCase, authenticators...)))
+			defer tearDownFn()
+
+			token := tt.issuer.Sign(t, exchangeAudience, map[string]any{"sub": "project_path:acme/app"})
+			resp := doRequest(t, http.MethodPost, server.URL+"/v1/exchange", token, "")
+			defer resp.Body.Close()
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			calls := useCase.ExchangeTokenCalls()
+			require.Len(t, calls, 1)
+			assert.Equal(t, tt.issuer.URL, calls[0].Principal.Issuer)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
+)
+
+// tokenRequestRoutes are the routes requesting tokens, whose outcome is counted. None
+// has a path parameter, so that their path identifies them before being routed.
+var tokenRequestRoutes = map[string]bool{
+	"/v1/generate-token":  true,
+	"/v1/generate-tokens": true,
+	"/v1/exchange":        true,
+	"/generate_token":     true,
+	"/exchange":           true,
+}
+
+// WithMetrics exposes the metrics of the service, and counts the token requests
+// accepted and rejected by the reason of their rejection.
+func WithMetrics(metrics *metricsx.Metrics) Option {
+	return func(a *API) {
+		a.MetricsHandler = metrics.Handler().ServeHTTP
+		a.metrics = metrics
+	}
+}
+
+// countTokenRequests counts the token requests, rejected with the code of the problem
+// they were answered with, by any middleware or the handler itself.
+func (a *API) countTokenRequests(next http.Handler) http.Handler {
+	if a.metrics == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !tokenRequestRoutes[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, problem := httpx.RecordProblem(r.Context())
+		next.ServeHTTP(w, r.WithContext(ctx))
+		a.metrics.CountTokenRequest(r.URL.Path, problem())
+	})
+}
@@ -0,0 +1,64 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
+)
+
+func TestMetrics_TokenRequests(t *testing.T) {
+	metrics := metricsx.New()
+	c, httpAPI := newContractAPI(t, api.WithMetrics(metrics))
+	c.generation.RequestTokenGenerationFunc = func(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
+		if request.ProjectID == "denied" {
+			return model.RequestStatus{}, &model.AccessDeniedError{Decision: model.AccessDecision{Reason: "no rule matches"}}
+		}
+		return model.RequestStatus{ID: "0123", State: model.RequestStateQueued}, nil
+	}
+	c.statuses.GetRequestStatusFunc = func(ctx context.Context, id string) (model.RequestStatus, error) {
+		return model.RequestStatus{}, model.ErrRequestNotFound
+	}
+	server, tearDownFn := setupAPITest(t, httpAPI)
+	defer tearDownFn()
+
+	calls := []struct {
+		method         string
+		path           string
+		credentials    string
+		body           string
+		expectedStatus int
+	}{
+		{method: http.MethodPost, path: "/v1/generate-token", credentials: c.requesterKey, body: `{"project_id": "app"}`, expectedStatus: http.StatusAccepted},
+		{method: http.MethodPost, path: "/v1/generate-token", credentials: c.requesterKey, body: `{"project_id": "app"}`, expectedStatus: http.StatusAccepted},
+		{method: http.MethodPost, path: "/v1/generate-token", credentials: c.requesterKey, body: `{"project_id": "denied"}`, expectedStatus: http.StatusForbidden},
+		{method: http.MethodPost, path: "/v1/generate-token", credentials: c.requesterKey, body: `{"project_id": "app", "ttl": "forever"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{method: http.MethodPost, path: "/generate_token", body: `{"project_id": "app"}`, expectedStatus: http.StatusUnauthorized},
+		// Only the token requests are counted.
+		{method: http.MethodGet, path: "/v1/requests/0123", credentials: c.requesterKey, expectedStatus: http.StatusNotFound},
+	}
+
+	for i, call := range calls {
+		resp := doRequest(t, call.method, server.URL+call.path, call.credentials, call.body)
+		resp.Body.Close()
+		require.Equal(t, call.expectedStatus, resp.StatusCode, "call %d", i)
+	}
+
+	expected := `
+# HELP tokengen_token_requests_total Token requests received, by route, outcome and problem code when rejected.
+# TYPE tokengen_token_requests_total counter
+tokengen_token_requests_total{outcome="accepted",reason="",route="/v1/generate-token"} 2
+tokengen_token_requests_total{outcome="rejected",reason="access_denied",route="/v1/generate-token"} 1
+tokengen_token_requests_total{outcome="rejected",reason="validation_failed",route="/v1/generate-token"} 1
+tokengen_token_requests_total{outcome="rejected",reason="unauthenticated",route="/generate_token"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "tokengen_token_requests_total"))
+}
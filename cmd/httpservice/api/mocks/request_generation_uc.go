@@ -6,6 +6,7 @@ package mocks
 import (
 	"context"
 	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/domain/model"
 	"sync"
 )
 
@@ -19,9 +20,12 @@ var _ api.RequestTokenGenerationUseCase = &RequestTokenGenerationUseCaseMock{}
 //
 //		// make and configure a mocked api.RequestTokenGenerationUseCase
 //		mockedRequestTokenGenerationUseCase := &RequestTokenGenerationUseCaseMock{
-//			RequestTokenGenerationFunc: func(ctx context.Context, projectID string) error {
+//			RequestTokenGenerationFunc: func(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
 //				panic("mock out the RequestTokenGeneration method")
 //			},
+//			RequestTokenGenerationsFunc: func(ctx context.Context, requests []model.TokenGenerationRequest) (model.RequestBatch, []model.BatchItemResult, error) {
+//				panic("mock out the RequestTokenGenerations method")
+//			},
 //		}
 //
 //		// use mockedRequestTokenGenerationUseCase in code that requires api.RequestTokenGenerationUseCase
@@ -30,7 +34,10 @@ var _ api.RequestTokenGenerationUseCase = &RequestTokenGenerationUseCaseMock{}
 //	}
 type RequestTokenGenerationUseCaseMock struct {
 	// RequestTokenGenerationFunc mocks the RequestTokenGeneration method.
-	RequestTokenGenerationFunc func(ctx context.Context, projectID string) error
+	RequestTokenGenerationFunc func(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error)
+
+	// RequestTokenGenerationsFunc mocks the RequestTokenGenerations method.
+	RequestTokenGenerationsFunc func(ctx context.Context, requests []model.TokenGenerationRequest) (model.RequestBatch, []model.BatchItemResult, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -38,32 +45,41 @@ type RequestTokenGenerationUseCaseMock struct {
 		RequestTokenGeneration []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
-			// ProjectID is the projectID argument value.
-			ProjectID string
+			// Request is the request argument value.
+			Request model.TokenGenerationRequest
+		}
+		// RequestTokenGenerations holds details about calls to the RequestTokenGenerations method.
+		RequestTokenGenerations []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Requests is the requests argument value.
+			Requests []model.TokenGenerationRequest
 		}
 	}
-	lockRequestTokenGeneration sync.RWMutex
+	lockRequestTokenGeneration  sync.RWMutex
+	lockRequestTokenGenerations sync.RWMutex
 }
 
 // RequestTokenGeneration calls RequestTokenGenerationFunc.
-func (mock *RequestTokenGenerationUseCaseMock) RequestTokenGeneration(ctx context.Context, projectID string) error {
+func (mock *RequestTokenGenerationUseCaseMock) RequestTokenGeneration(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
 	callInfo := struct {
-		Ctx       context.Context
-		ProjectID string
+		Ctx     context.Context
+		Request model.TokenGenerationRequest
 	}{
-		Ctx:       ctx,
-		ProjectID: projectID,
+		Ctx:     ctx,
+		Request: request,
 	}
 	mock.lockRequestTokenGeneration.Lock()
 	mock.calls.RequestTokenGeneration = append(mock.calls.RequestTokenGeneration, callInfo)
 	mock.lockRequestTokenGeneration.Unlock()
 	if mock.RequestTokenGenerationFunc == nil {
 		var (
-			errOut error
+			requestStatusOut model.RequestStatus
+			errOut           error
 		)
-		return errOut
+		return requestStatusOut, errOut
 	}
-	return mock.RequestTokenGenerationFunc(ctx, projectID)
+	return mock.RequestTokenGenerationFunc(ctx, request)
 }
 
 // RequestTokenGenerationCalls gets all the calls that were made to RequestTokenGeneration.
@@ -71,15 +87,56 @@ func (mock *RequestTokenGenerationUseCaseMock) RequestTokenGeneration(ctx contex
 //
 //	len(mockedRequestTokenGenerationUseCase.RequestTokenGenerationCalls())
 func (mock *RequestTokenGenerationUseCaseMock) RequestTokenGenerationCalls() []struct {
-	Ctx       context.Context
-	ProjectID string
+	Ctx     context.Context
+	Request model.TokenGenerationRequest
 } {
 	var calls []struct {
-		Ctx       context.Context
-		ProjectID string
+		Ctx     context.Context
+		Request model.TokenGenerationRequest
 	}
 	mock.lockRequestTokenGeneration.RLock()
 	calls = mock.calls.RequestTokenGeneration
 	mock.lockRequestTokenGeneration.RUnlock()
 	return calls
 }
+
+// RequestTokenGenerations calls RequestTokenGenerationsFunc.
+func (mock *RequestTokenGenerationUseCaseMock) RequestTokenGenerations(ctx context.Context, requests []model.TokenGenerationRequest) (model.RequestBatch, []model.BatchItemResult, error) {
+	callInfo := struct {
+		Ctx      context.Context
+		Requests []model.TokenGenerationRequest
+	}{
+		Ctx:      ctx,
+		Requests: requests,
+	}
+	mock.lockRequestTokenGenerations.Lock()
+	mock.calls.RequestTokenGenerations = append(mock.calls.RequestTokenGenerations, callInfo)
+	mock.lockRequestTokenGenerations.Unlock()
+	if mock.RequestTokenGenerationsFunc == nil {
+		var (
+			requestBatchOut     model.RequestBatch
+			batchItemResultsOut []model.BatchItemResult
+			errOut              error
+		)
+		return requestBatchOut, batchItemResultsOut, errOut
+	}
+	return mock.RequestTokenGenerationsFunc(ctx, requests)
+}
+
+// RequestTokenGenerationsCalls gets all the calls that were made to RequestTokenGenerations.
+// Check the length with:
+//
+//	len(mockedRequestTokenGenerationUseCase.RequestTokenGenerationsCalls())
+func (mock *RequestTokenGenerationUseCaseMock) RequestTokenGenerationsCalls() []struct {
+	Ctx      context.Context
+	Requests []model.TokenGenerationRequest
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Requests []model.TokenGenerationRequest
+	}
+	mock.lockRequestTokenGenerations.RLock()
+	calls = mock.calls.RequestTokenGenerations
+	mock.lockRequestTokenGenerations.RUnlock()
+	return calls
+}
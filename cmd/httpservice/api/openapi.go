@@ -0,0 +1,24 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/werbersondev/token-generator-test/extensions/openapix"
+)
+
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// OpenAPI describes the API. Requests are validated against it, and so are the
+// responses of the handlers, which must not drift from it.
+var OpenAPI = openapix.MustLoad(openAPIDocument)
+
+// OpenAPIHandler serves the OpenAPI document of the API.
+func OpenAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_, _ = w.Write(openAPIDocument)
+	}
+}
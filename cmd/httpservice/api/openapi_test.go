@@ -0,0 +1,335 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/authx/authxtest"
+	"github.com/werbersondev/token-generator-test/extensions/correlation"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
+	"github.com/werbersondev/token-generator-test/extensions/openapix"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+// contractAPI configures every optional route of the API, so that the contract tests
+// cover them all.
+type contractAPI struct {
+	generation *mocks.RequestTokenGenerationUseCaseMock
+	statuses   *mocks.RequestStatusUseCaseMock
+	exchange   *mocks.TokenExchangeUseCaseMock
+	admin      *mocks.RequestAdministrationUseCaseMock
+	self       *mocks.SelfServiceUseCaseMock
+	ciIssuer   *authxtest.Issuer
+
+	adminKey     string
+	requesterKey string
+	// requester is the principal authenticated by requesterKey.
+	requester model.Principal
+}
+
+func newContractAPI(t *testing.T, opts ...api.Option) (*contractAPI, *api.API) {
+	t.Helper()
+
+	apiKeys := authx.NewAPIKeys(authx.NewStateAPIKeyStore(statestore.NewMemory()))
+	adminKey, _, err := apiKeys.CreateAPIKey(context.Background(), "admin", []string{model.ScopeAdmin})
+	require.NoError(t, err)
+	requesterKey, requester, err := apiKeys.CreateAPIKey(context.Background(), "ci", []string{model.ScopeRequestTokens})
+	require.NoError(t, err)
+
+	ciIssuer := authxtest.NewIssuer(t, "https://token.actions.githubusercontent.com")
+	keys, err := authx.NewFileJWKS(ciIssuer.JWKSFile(t), time.Minute)
+	require.NoError(t, err)
+	exchangeAuthenticator, err := authx.NewJWT(authx.JWTConfig{Issuer: ciIssuer.URL, Audience: exchangeAudience}, keys)
+	require.NoError(t, err)
+
+	c := &contractAPI{
+		generation:   &mocks.RequestTokenGenerationUseCaseMock{},
+		statuses:     &mocks.RequestStatusUseCaseMock{},
+		exchange:     &mocks.TokenExchangeUseCaseMock{},
+		admin:        &mocks.RequestAdministrationUseCaseMock{},
+		self:         &mocks.SelfServiceUseCaseMock{},
+		ciIssuer:     ciIssuer,
+		adminKey:     adminKey,
+		requesterKey: requesterKey,
+		requester:    model.Principal{Subject: "apikey:" + requester.ID, Method: model.AuthMethodAPIKey},
+	}
+
+	opts = append([]api.Option{
+		api.WithAuthentication(apiKeys),
+		api.WithAPIKeyAdministration(apiKeys),
+		api.WithPolicyDryRun(&mocks.AccessPolicyEvaluatorMock{
+			EvaluateAccessFunc: func(principal model.Principal, request model.TokenGenerationRequest) model.AccessDecision {
+				return model.AccessDecision{Allowed: true, Rule: "ci", Reason: "allowed by rule ci", TTL: time.Hour}
+			},
+		}),
+		api.WithTokenExchange(c.exchange, exchangeAuthenticator),
+		api.WithRequestStatus(c.statuses, time.Second),
+		api.WithRequestAdministration(c.admin),
+		api.WithSelfService(c.self),
+		api.WithReadiness(httpx.NewReadiness(time.Second, 0, httpx.Check{
+			Name:  "pubsub",
+			Check: func(ctx context.Context) error { return nil },
+		})),
+		api.WithMetrics(metricsx.New()),
+	}, opts...)
+
+	return c, api.New(c.generation, opts...)
+}
+
+func TestOpenAPI_RoutesMatchDocument(t *testing.T) {
+	_, httpAPI := newContractAPI(t)
+	router := chi.NewRouter()
+	httpAPI.Routes(router)
+
+	var routes []string
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routes = append(routes, method+" "+route)
+		return nil
+	})
+	require.NoError(t, err)
+	sort.Strings(routes)
+
+	assert.Equal(t, api.OpenAPI.Operations(), routes, "the routes and the OpenAPI document must match")
+}
+
+func TestOpenAPI_ProjectKeyPattern(t *testing.T) {
+	var doc struct {
+		Components struct {
+			Schemas map[string]struct {
+				Pattern   string `json:"pattern"`
+				MaxLength int    `json:"maxLength"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(mustGetOpenAPI(t), &doc))
+
+	projectKey := doc.Components.Schemas["ProjectKey"]
+	assert.Equal(t, model.ProjectKeyPattern, projectKey.Pattern)
+	assert.Equal(t, model.MaxProjectKeyLength, projectKey.MaxLength)
+}
+
+// TestOpenAPI_ResponsesMatchDocument calls every operation and fails when a response
+// does not match the OpenAPI document.
+func TestOpenAPI_ResponsesMatchDocument(t *testing.T) {
+	c, httpAPI := newContractAPI(t, api.WithValidationOptions(openapix.WithResponseViolationHandler(func(r *http.Request, status int, err error) {
+		t.Errorf("%s %s responded %d: %v", r.Method, r.URL.Path, status, err)
+	})))
+
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Hour)
+	issued := model.RequestStatus{ID: "issued", Owner: c.requester.Owner(), ProjectID: "app", TokenType: model.TokenTypeProjectAnalysis, State: model.RequestStateIssued, CreatedAt: now, UpdatedAt: now, Token: "squ_token", ExpiresAt: &expiresAt}
+	failed := model.RequestStatus{ID: "failed", ProjectID: "app", TokenType: model.TokenTypeProjectAnalysis, State: model.RequestStateFailed, CreatedAt: now, UpdatedAt: now, FailureReason: model.FailureReasonInvalidRequest}
+	queued := model.RequestStatus{ID: "queued", ProjectID: "app", TokenType: model.TokenTypeGlobalAnalysis, State: model.RequestStateQueued, CreatedAt: now, UpdatedAt: now}
+
+	c.generation.RequestTokenGenerationFunc = func(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
+		switch request.ProjectID {
+		case "denied":
+			return model.RequestStatus{}, &model.AccessDeniedError{Decision: model.AccessDecision{Reason: "no rule matches"}}
+		case "broken":
+			return model.RequestStatus{}, errors.New("publish failed")
+		}
+		return queued, nil
+	}
+	c.generation.RequestTokenGenerationsFunc = func(ctx context.Context, requests []model.TokenGenerationRequest) (model.RequestBatch, []model.BatchItemResult, error) {
+		results := make([]model.BatchItemResult, len(requests))
+		batch := model.RequestBatch{ID: "batch", CreatedAt: now}
+		for i := range requests {
+			results[i].Status = queued
+			batch.RequestIDs = append(batch.RequestIDs, queued.ID)
+		}
+		return batch, results, nil
+	}
+	c.statuses.GetRequestStatusFunc = func(ctx context.Context, id string) (model.RequestStatus, error) {
+		switch id {
+		case issued.ID:
+			return issued, nil
+		case failed.ID:
+			return failed, nil
+		}
+		return model.RequestStatus{}, model.ErrRequestNotFound
+	}
+	c.statuses.WaitForRequestFunc = func(ctx context.Context, id string, timeout time.Duration) (model.RequestStatus, error) {
+		return failed, nil
+	}
+	c.statuses.WatchRequestFunc = func(ctx context.Context, id string) (<-chan model.RequestStatus, error) {
+		statuses := make(chan model.RequestStatus)
+		close(statuses)
+		return statuses, nil
+	}
+	c.statuses.GetBatchStatusFunc = func(ctx context.Context, id string) (model.BatchStatus, error) {
+		return model.NewBatchStatus(model.RequestBatch{ID: id, Owner: c.requester.Owner(), CreatedAt: now, RequestIDs: []string{issued.ID}}, []model.RequestStatus{issued}), nil
+	}
+	c.exchange.ExchangeTokenFunc = func(ctx context.Context, principal model.Principal) (model.IssuedToken, error) {
+		return model.IssuedToken{Token: "squ_token", ProjectID: "app", ExpiresAt: expiresAt}, nil
+	}
+	c.admin.ListRequestsFunc = func(ctx context.Context, filter model.RequestFilter) (model.RequestPage, error) {
+		if filter.PageToken == "invalid" {
+			return model.RequestPage{}, model.ErrInvalidPageToken
+		}
+		return model.RequestPage{Requests: []model.RequestStatus{issued, failed, queued}, NextPageToken: "next"}, nil
+	}
+	c.admin.GetRequestFunc = func(ctx context.Context, id string) (model.RequestStatus, error) {
+		if id == failed.ID {
+			detail := failed
+			detail.Error = "sonar responded 400"
+			detail.TTL = time.Hour
+			detail.Principal = &c.requester
+			detail.Events = []model.RequestEvent{
+				{RequestID: id, Type: model.RequestEventQueued, Time: now},
+				{RequestID: id, Type: model.RequestEventFailed, Time: now, FailureReason: model.FailureReasonInvalidRequest, Error: "sonar responded 400"},
+			}
+			return detail, nil
+		}
+		return model.RequestStatus{}, model.ErrRequestNotFound
+	}
+	c.admin.CancelRequestFunc = func(ctx context.Context, id string) (model.RequestStatus, error) {
+		if id != queued.ID {
+			return model.RequestStatus{}, model.ErrRequestNotCancelable
+		}
+		canceled := queued
+		canceled.State = model.RequestStateCanceled
+		return canceled, nil
+	}
+	c.admin.RequeueRequestFunc = func(ctx context.Context, id string) (model.RequestStatus, error) {
+		if id != failed.ID {
+			return model.RequestStatus{}, model.ErrRequestNotRequeueable
+		}
+		return queued, nil
+	}
+	c.self.AccessGrantsFunc = func(ctx context.Context) ([]model.AccessGrant, bool) {
+		return []model.AccessGrant{{Rule: "ci", Projects: []string{"app", "app-*"}, TokenTypes: []model.TokenType{model.TokenTypeProjectAnalysis}, MaxTTL: 24 * time.Hour}}, true
+	}
+	c.self.TokenRevocationEnabledFunc = func() bool {
+		return true
+	}
+	c.self.ListActiveTokensFunc = func(ctx context.Context) ([]model.RequestStatus, error) {
+		return []model.RequestStatus{issued}, nil
+	}
+	c.self.RetrieveTokenFunc = func(ctx context.Context, id string) (model.RequestStatus, error) {
+		switch id {
+		case issued.ID:
+			return issued, nil
+		case queued.ID:
+			return model.RequestStatus{}, model.ErrTokenNotIssued
+		case "retrieved":
+			return model.RequestStatus{}, model.ErrTokenAlreadyRetrieved
+		}
+		return model.RequestStatus{}, model.ErrRequestNotFound
+	}
+	c.self.RevokeTokenFunc = func(ctx context.Context, id string) (model.RequestStatus, error) {
+		if id != issued.ID {
+			return model.RequestStatus{}, model.ErrTokenNotRevocable
+		}
+		revoked := issued
+		revoked.RevokedAt = &now
+		return revoked, nil
+	}
+
+	router := chi.NewRouter()
+	router.Use(correlation.Middleware)
+	httpAPI.Routes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ciToken := c.ciIssuer.Sign(t, exchangeAudience, map[string]any{"sub": "repo:acme/app"})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		credentials    string
+		body           string
+		expectedStatus int
+	}{
+		{name: "Liveness", method: http.MethodGet, path: "/liveness", expectedStatus: http.StatusOK},
+		{name: "Readiness", method: http.MethodGet, path: "/readiness", expectedStatus: http.StatusOK},
+		{name: "OpenAPI Document", method: http.MethodGet, path: "/openapi.json", expectedStatus: http.StatusOK},
+		{name: "Metrics", method: http.MethodGet, path: "/metrics", expectedStatus: http.StatusOK},
+		{name: "Request Token", method: http.MethodPost, path: "/v1/generate-token", credentials: c.requesterKey, body: `{"project_id": "app", "token_type": "global_analysis", "ttl": "1h"}`, expectedStatus: http.StatusAccepted},
+		{name: "Request Token And Wait", method: http.MethodPost, path: "/v1/generate-token?wait=1s", credentials: c.requesterKey, body: `{"project_id": "app"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Request Token Invalid", method: http.MethodPost, path: "/v1/generate-token", credentials: c.requesterKey, body: `{"project_id": "app", "ttl": "forever"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Request Token Denied", method: http.MethodPost, path: "/v1/generate-token", credentials: c.requesterKey, body: `{"project_id": "denied"}`, expectedStatus: http.StatusForbidden},
+		{name: "Request Token Publish Failed", method: http.MethodPost, path: "/v1/generate-token", credentials: c.requesterKey, body: `{"project_id": "broken"}`, expectedStatus: http.StatusInternalServerError},
+		{name: "Request Token Legacy", method: http.MethodPost, path: "/generate_token", credentials: c.requesterKey, body: `{"project_id": "app"}`, expectedStatus: http.StatusAccepted},
+		{name: "Request Tokens", method: http.MethodPost, path: "/v1/generate-tokens", credentials: c.requesterKey, body: `{"requests": [{"project_id": "app"}, {"project_id": ""}]}`, expectedStatus: http.StatusAccepted},
+		{name: "Request Tokens All Rejected", method: http.MethodPost, path: "/v1/generate-tokens", credentials: c.requesterKey, body: `{"requests": [{"project_id": "my app"}]}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Get Request", method: http.MethodGet, path: "/v1/requests/issued", credentials: c.requesterKey, expectedStatus: http.StatusOK},
+		{name: "Get Unknown Request", method: http.MethodGet, path: "/v1/requests/unknown", credentials: c.requesterKey, expectedStatus: http.StatusNotFound},
+		{name: "Watch Request", method: http.MethodGet, path: "/v1/requests/issued/events", credentials: c.requesterKey, expectedStatus: http.StatusOK},
+		{name: "Get Caller", method: http.MethodGet, path: "/v1/me", credentials: c.requesterKey, expectedStatus: http.StatusOK},
+		{name: "List Owned Tokens", method: http.MethodGet, path: "/v1/tokens", credentials: c.requesterKey, expectedStatus: http.StatusOK},
+		{name: "Retrieve Token", method: http.MethodPost, path: "/v1/requests/issued/token", credentials: c.requesterKey, expectedStatus: http.StatusOK},
+		{name: "Retrieve Token Not Issued", method: http.MethodPost, path: "/v1/requests/queued/token", credentials: c.requesterKey, expectedStatus: http.StatusConflict},
+		{name: "Retrieve Token Already Retrieved", method: http.MethodPost, path: "/v1/requests/retrieved/token", credentials: c.requesterKey, expectedStatus: http.StatusGone},
+		{name: "Revoke Owned Token", method: http.MethodPost, path: "/v1/requests/issued/revoke", credentials: c.requesterKey, expectedStatus: http.StatusOK},
+		{name: "Revoke Owned Token Not Issued", method: http.MethodPost, path: "/v1/requests/queued/revoke", credentials: c.requesterKey, expectedStatus: http.StatusConflict},
+		{name: "Get Batch", method: http.MethodGet, path: "/v1/batches/batch", credentials: c.requesterKey, expectedStatus: http.StatusOK},
+		{name: "Exchange Token", method: http.MethodPost, path: "/v1/exchange", credentials: ciToken, expectedStatus: http.StatusOK},
+		{name: "Exchange Token Legacy", method: http.MethodPost, path: "/exchange", credentials: ciToken, expectedStatus: http.StatusOK},
+		{name: "Create API Key", method: http.MethodPost, path: "/v1/admin/api-keys", credentials: c.adminKey, body: `{"name": "deploy", "scopes": ["tokens:request"]}`, expectedStatus: http.StatusCreated},
+		{name: "List API Keys", method: http.MethodGet, path: "/v1/admin/api-keys", credentials: c.adminKey, expectedStatus: http.StatusOK},
+		{name: "Disable Unknown API Key", method: http.MethodPost, path: "/v1/admin/api-keys/unknown/disable", credentials: c.adminKey, expectedStatus: http.StatusNotFound},
+		{name: "List Requests", method: http.MethodGet, path: "/v1/admin/requests?state=failed&project_id=app&limit=10", credentials: c.adminKey, expectedStatus: http.StatusOK},
+		{name: "List Requests Invalid Page Token", method: http.MethodGet, path: "/v1/admin/requests?page_token=invalid", credentials: c.adminKey, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Inspect Request", method: http.MethodGet, path: "/v1/admin/requests/failed", credentials: c.adminKey, expectedStatus: http.StatusOK},
+		{name: "Inspect Unknown Request", method: http.MethodGet, path: "/v1/admin/requests/unknown", credentials: c.adminKey, expectedStatus: http.StatusNotFound},
+		{name: "Cancel Request", method: http.MethodPost, path: "/v1/admin/requests/queued/cancel", credentials: c.adminKey, expectedStatus: http.StatusOK},
+		{name: "Cancel Processed Request", method: http.MethodPost, path: "/v1/admin/requests/issued/cancel", credentials: c.adminKey, expectedStatus: http.StatusConflict},
+		{name: "Requeue Request", method: http.MethodPost, path: "/v1/admin/requests/failed/requeue", credentials: c.adminKey, expectedStatus: http.StatusAccepted},
+		{name: "Requeue Issued Request", method: http.MethodPost, path: "/v1/admin/requests/issued/requeue", credentials: c.adminKey, expectedStatus: http.StatusConflict},
+		{name: "Evaluate Policy", method: http.MethodPost, path: "/v1/admin/policy/evaluate", credentials: c.adminKey, body: `{"project_id": "app", "principal": {"subject": "ci", "groups": ["ci"], "attributes": {"repository": "acme/app"}}}`, expectedStatus: http.StatusOK},
+	}
+
+	covered := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := doRequest(t, tt.method, server.URL+tt.path, tt.credentials, tt.body)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			operation, _ := api.OpenAPI.Find(tt.method, resp.Request.URL.Path)
+			require.NotNil(t, operation)
+			covered[operation.OperationID] = true
+		})
+	}
+
+	assert.Len(t, covered, len(api.OpenAPI.Operations()), "every operation of the OpenAPI document must be called")
+}
+
+func mustGetOpenAPI(t *testing.T) []byte {
+	t.Helper()
+
+	server, tearDownFn := setupAPITest(t, api.New(&mocks.RequestTokenGenerationUseCaseMock{}))
+	defer tearDownFn()
+
+	resp, err := http.Get(server.URL + "/openapi.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var doc json.RawMessage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+
+	return doc
+}
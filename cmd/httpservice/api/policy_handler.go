@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+//go:generate moq -stub -pkg mocks -out mocks/access_policy_evaluator.go . AccessPolicyEvaluator
+type AccessPolicyEvaluator interface {
+	EvaluateAccess(principal model.Principal, request model.TokenGenerationRequest) model.AccessDecision
+}
+
+type EvaluatePolicyInput struct {
+	RequestTokenGenerationInput
+	// Principal the request is evaluated for, the caller when omitted.
+	Principal *model.Principal `json:"principal,omitempty"`
+}
+
+type EvaluatePolicyOutput struct {
+	Allowed bool   `json:"allowed"`
+	Rule    string `json:"rule,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	TTL     string `json:"ttl,omitempty"`
+}
+
+// EvaluatePolicyHandler tells whether a token generation request would be allowed by
+// the access policy, without requesting anything.
+func EvaluatePolicyHandler(evaluator AccessPolicyEvaluator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var body EvaluatePolicyInput
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			httpx.WriteProblem(w, r, http.StatusBadRequest, codeInvalidBody, "the request body is not valid JSON")
+			return
+		}
+
+		request, err := body.tokenGenerationRequest()
+		if err != nil {
+			httpx.WriteProblem(w, r, http.StatusUnprocessableEntity, codeValidationFailed, err.Error())
+			return
+		}
+
+		var principal model.Principal
+		if body.Principal != nil {
+			principal = *body.Principal
+		} else if caller, ok := model.PrincipalFromContext(ctx); ok {
+			principal = caller
+		}
+
+		decision := evaluator.EvaluateAccess(principal, request)
+
+		output := EvaluatePolicyOutput{
+			Allowed: decision.Allowed,
+			Rule:    decision.Rule,
+			Reason:  decision.Reason,
+		}
+		if decision.TTL > 0 {
+			output.TTL = decision.TTL.String()
+		}
+		writeJSON(ctx, w, http.StatusOK, output)
+	}
+}
@@ -0,0 +1,65 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+func TestEvaluatePolicyHandler(t *testing.T) {
+	tests := []struct {
+		name              string
+		requestBody       string
+		expectedPrincipal model.Principal
+		expectedRequest   model.TokenGenerationRequest
+	}{
+		{
+			name:            "Caller Principal",
+			requestBody:     `{"project_id": "acme-app", "ttl": "24h"}`,
+			expectedRequest: model.TokenGenerationRequest{ProjectID: "acme-app", TokenType: model.TokenTypeProjectAnalysis, TTL: 24 * time.Hour},
+		},
+		{
+			name:              "Given Principal",
+			requestBody:       `{"project_id": "acme-app", "token_type": "global_analysis", "principal": {"subject": "repo:acme/app", "groups": ["ci"]}}`,
+			expectedPrincipal: model.Principal{Subject: "repo:acme/app", Groups: []string{"ci"}},
+			expectedRequest:   model.TokenGenerationRequest{ProjectID: "acme-app", TokenType: model.TokenTypeGlobalAnalysis},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluator := &mocks.AccessPolicyEvaluatorMock{
+				EvaluateAccessFunc: func(principal model.Principal, request model.TokenGenerationRequest) model.AccessDecision {
+					return model.AccessDecision{Allowed: true, Rule: "ci", TTL: 24 * time.Hour}
+				},
+			}
+
+			server, tearDownFn := setupAPITest(t, api.New(&mocks.RequestTokenGenerationUseCaseMock{}, api.WithPolicyDryRun(evaluator)))
+			defer tearDownFn()
+
+			resp, err := http.Post(server.URL+"/v1/admin/policy/evaluate", "application/json", bytes.NewReader([]byte(tt.requestBody)))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var output api.EvaluatePolicyOutput
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&output))
+			assert.Equal(t, api.EvaluatePolicyOutput{Allowed: true, Rule: "ci", TTL: "24h0m0s"}, output)
+
+			calls := evaluator.EvaluateAccessCalls()
+			require.Len(t, calls, 1)
+			assert.Equal(t, tt.expectedPrincipal, calls[0].Principal)
+			assert.Equal(t, tt.expectedRequest, calls[0].Request)
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package api
+
+import "github.com/werbersondev/token-generator-test/extensions/httpx"
+
+// Stable codes of the problems reported by the API, see httpx.Problem. Codes are part
+// of the API contract: add new ones rather than renaming existing ones.
+const (
+	codeInvalidBody                = httpx.CodeInvalidBody
+	codeValidationFailed           = httpx.CodeValidationFailed
+	codeAccessDenied               = "access_denied"
+	codeNotFound                   = httpx.CodeNotFound
+	codePublishFailed              = "publish_failed"
+	codeTokenGenerationFailed      = "token_generation_failed"
+	codeInternal                   = "internal_error"
+	codeRequestNotCancelable       = "request_not_cancelable"
+	codeRequestNotRequeueable      = "request_not_requeueable"
+	codeTokenNotIssued             = "token_not_issued"
+	codeTokenAlreadyRetrieved      = "token_already_retrieved"
+	codeTokenNotRevocable          = "token_not_revocable"
+	codeTokenRevocationUnavailable = "token_revocation_unavailable"
+)
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/ratelimit"
+)
+
+// Namespaces of the rate limits, named after what they key requests by.
+const (
+	rateLimitClient    = "client"
+	rateLimitPrincipal = "principal"
+	rateLimitProject   = "project"
+)
+
+// RateLimits are the limits of the requests, zero limits being disabled.
+type RateLimits struct {
+	// Client limits the requests of each client IP address, on every route but the
+	// health and documentation ones.
+	Client ratelimit.Limit
+	// Principal limits the token requests of each authenticated principal.
+	Principal ratelimit.Limit
+	// Project limits the tokens requested for each project, batches counting once per
+	// item.
+	Project ratelimit.Limit
+}
+
+// rateLimit limits the requests keyed by key, when rate limiting is enabled.
+func (a *API) rateLimit(scope string, limit ratelimit.Limit, key ratelimit.KeyFunc) func(http.Handler) http.Handler {
+	if a.limiter == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return ratelimit.Middleware(a.limiter, scope, limit, key)
+}
+
+// limitProjects limits the tokens requested for each project, read from the body of
+// token requests and batches. Requests are passed through when the body cannot be
+// decoded, the handler rejecting them.
+func (a *API) limitProjects() func(http.Handler) http.Handler {
+	limit := a.rateLimits.Project
+	if a.limiter == nil || !limit.Enabled() {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			counts, ok := requestedProjects(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var allowed []string
+			for _, project := range counts.keys {
+				result, err := a.limiter.Allow(ctx, rateLimitProject, project, limit, counts.requests[project])
+				if err != nil {
+					log.Ctx(ctx).Error().Err(err).Str("scope", rateLimitProject).Msg("rate limiting failed")
+					continue
+				}
+
+				ratelimit.WriteHeaders(w, result)
+				if !result.Allowed {
+					// The batch is rejected as a whole, the projects allowed so far are
+					// not charged for it.
+					for _, project := range allowed {
+						if err := a.limiter.Release(ctx, rateLimitProject, project, limit, counts.requests[project]); err != nil {
+							log.Ctx(ctx).Error().Err(err).Str("scope", rateLimitProject).Msg("releasing rate limit")
+						}
+					}
+					ratelimit.WriteProblem(w, r, rateLimitProject+" "+project, result)
+					return
+				}
+				allowed = append(allowed, project)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// projectCounts counts the tokens requested for each project, keys listing the projects
+// in the order of the request.
+type projectCounts struct {
+	keys     []string
+	requests map[string]int
+}
+
+// requestedProjects reads the projects of a token request or batch, leaving the body
+// to be read again by the handler.
+func requestedProjects(r *http.Request) (projectCounts, bool) {
+	data, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return projectCounts{}, false
+	}
+
+	var body struct {
+		RequestTokenGenerationInput
+		RequestTokenGenerationsInput
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return projectCounts{}, false
+	}
+
+	counts := projectCounts{requests: make(map[string]int)}
+	for _, input := range append(body.Requests, body.RequestTokenGenerationInput) {
+		// Invalid requests are rejected by the handler without issuing anything.
+		if !model.ValidProjectKey(input.ProjectID) {
+			continue
+		}
+		if counts.requests[input.ProjectID] == 0 {
+			counts.keys = append(counts.keys, input.ProjectID)
+		}
+		counts.requests[input.ProjectID]++
+	}
+
+	return counts, true
+}
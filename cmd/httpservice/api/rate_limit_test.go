@@ -0,0 +1,124 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+	"github.com/werbersondev/token-generator-test/extensions/openapix"
+	"github.com/werbersondev/token-generator-test/extensions/ratelimit"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+func TestRateLimits(t *testing.T) {
+	type call struct {
+		path           string
+		body           string
+		expectedStatus int
+		// expectedScope is the limit reported in the problem of rate limited calls.
+		expectedScope string
+	}
+
+	tests := []struct {
+		name   string
+		limits api.RateLimits
+		calls  []call
+	}{
+		{
+			name:   "Client",
+			limits: api.RateLimits{Client: ratelimit.Limit{Requests: 2, Window: time.Hour}},
+			calls: []call{
+				{path: "/v1/generate-token", body: `{"project_id": "app"}`, expectedStatus: http.StatusAccepted},
+				{path: "/v1/generate-token", body: `{"project_id": "other"}`, expectedStatus: http.StatusAccepted},
+				{path: "/generate_token", body: `{"project_id": "app"}`, expectedStatus: http.StatusTooManyRequests, expectedScope: "client"},
+			},
+		},
+		{
+			name:   "Principal",
+			limits: api.RateLimits{Principal: ratelimit.Limit{Requests: 1, Window: time.Hour}},
+			calls: []call{
+				{path: "/v1/generate-token", body: `{"project_id": "app"}`, expectedStatus: http.StatusAccepted},
+				{path: "/v1/generate-tokens", body: `{"requests": [{"project_id": "other"}]}`, expectedStatus: http.StatusTooManyRequests, expectedScope: "principal"},
+			},
+		},
+		{
+			name:   "Project",
+			limits: api.RateLimits{Project: ratelimit.Limit{Requests: 2, Window: time.Hour}},
+			calls: []call{
+				{path: "/v1/generate-token", body: `{"project_id": "app"}`, expectedStatus: http.StatusAccepted},
+				{path: "/v1/generate-token", body: `{"project_id": "other"}`, expectedStatus: http.StatusAccepted},
+				// The batch exceeds the limit of app, other must not be charged for it.
+				{path: "/v1/generate-tokens", body: `{"requests": [{"project_id": "other"}, {"project_id": "app"}, {"project_id": "app"}]}`, expectedStatus: http.StatusTooManyRequests, expectedScope: "project app"},
+				{path: "/v1/generate-token", body: `{"project_id": "other"}`, expectedStatus: http.StatusAccepted},
+				{path: "/v1/generate-token", body: `{"project_id": "app"}`, expectedStatus: http.StatusAccepted},
+				{path: "/v1/generate-token", body: `{"project_id": "app"}`, expectedStatus: http.StatusTooManyRequests, expectedScope: "project app"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, httpAPI := newContractAPI(t,
+				api.WithRateLimits(ratelimit.NewLimiter(statestore.NewMemory()), tt.limits),
+				api.WithValidationOptions(openapix.WithResponseViolationHandler(func(r *http.Request, status int, err error) {
+					t.Errorf("%s %s responded %d: %v", r.Method, r.URL.Path, status, err)
+				})),
+			)
+			c.generation.RequestTokenGenerationFunc = func(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
+				return model.RequestStatus{ID: "0123", State: model.RequestStateQueued}, nil
+			}
+			c.generation.RequestTokenGenerationsFunc = func(ctx context.Context, requests []model.TokenGenerationRequest) (model.RequestBatch, []model.BatchItemResult, error) {
+				batch := model.RequestBatch{ID: "batch"}
+				results := make([]model.BatchItemResult, len(requests))
+				for i := range requests {
+					results[i].Status = model.RequestStatus{ID: "0123", State: model.RequestStateQueued}
+					batch.RequestIDs = append(batch.RequestIDs, "0123")
+				}
+				return batch, results, nil
+			}
+			server, tearDownFn := setupAPITest(t, httpAPI)
+			defer tearDownFn()
+
+			for i, call := range tt.calls {
+				resp := doRequest(t, http.MethodPost, server.URL+call.path, c.requesterKey, call.body)
+				defer resp.Body.Close()
+
+				require.Equal(t, call.expectedStatus, resp.StatusCode, "call %d", i)
+				assert.NotEmpty(t, resp.Header.Get("RateLimit-Limit"), "call %d", i)
+				assert.NotEmpty(t, resp.Header.Get("RateLimit-Remaining"), "call %d", i)
+				assert.NotEmpty(t, resp.Header.Get("RateLimit-Reset"), "call %d", i)
+
+				if call.expectedStatus == http.StatusTooManyRequests {
+					assert.NotEmpty(t, resp.Header.Get("Retry-After"), "call %d", i)
+
+					var problem httpx.Problem
+					require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+					assert.Equal(t, ratelimit.CodeRateLimited, problem.Code)
+					assert.Contains(t, problem.Detail, "exceeded for the "+call.expectedScope)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimits_HealthNotLimited(t *testing.T) {
+	limits := api.RateLimits{Client: ratelimit.Limit{Requests: 1, Window: time.Hour}}
+	server, tearDownFn := setupAPITest(t, api.New(&mocks.RequestTokenGenerationUseCaseMock{}, api.WithRateLimits(ratelimit.NewLimiter(statestore.NewMemory()), limits)))
+	defer tearDownFn()
+
+	for i := 0; i < 3; i++ {
+		resp, err := http.Get(server.URL + "/liveness")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+}
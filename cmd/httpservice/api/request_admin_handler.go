@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+//go:generate moq -stub -pkg mocks -out mocks/request_admin_uc.go . RequestAdministrationUseCase
+type RequestAdministrationUseCase interface {
+	ListRequests(ctx context.Context, filter model.RequestFilter) (model.RequestPage, error)
+	GetRequest(ctx context.Context, id string) (model.RequestStatus, error)
+	CancelRequest(ctx context.Context, id string) (model.RequestStatus, error)
+	RequeueRequest(ctx context.Context, id string) (model.RequestStatus, error)
+}
+
+// AdminRequestOutput describes a token generation request to administrators. The token
+// itself is never returned, only its name on the provider.
+type AdminRequestOutput struct {
+	RequestID string             `json:"request_id"`
+	State     model.RequestState `json:"state"`
+	ProjectID string             `json:"project_id,omitempty"`
+	TokenType model.TokenType    `json:"token_type,omitempty"`
+	Owner     string             `json:"owner,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+
+	TokenName     string              `json:"token_name,omitempty"`
+	ExpiresAt     *time.Time          `json:"expires_at,omitempty"`
+	RevokedAt     *time.Time          `json:"revoked_at,omitempty"`
+	FailureReason model.FailureReason `json:"failure_reason,omitempty"`
+	Error         string              `json:"error,omitempty"`
+
+	// Principal, TTL and Events are only returned by the detail of a request.
+	Principal *model.Principal          `json:"principal,omitempty"`
+	TTL       string                    `json:"ttl,omitempty"`
+	Events    []AdminRequestEventOutput `json:"events,omitempty"`
+}
+
+type AdminRequestEventOutput struct {
+	Type          model.RequestEventType `json:"type"`
+	Time          time.Time              `json:"time"`
+	Attempt       int                    `json:"attempt,omitempty"`
+	FailureReason model.FailureReason    `json:"failure_reason,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+}
+
+type AdminRequestPageOutput struct {
+	Requests      []AdminRequestOutput `json:"requests"`
+	NextPageToken string               `json:"next_page_token,omitempty"`
+}
+
+func newAdminRequestOutput(status model.RequestStatus) AdminRequestOutput {
+	return AdminRequestOutput{
+		RequestID:     status.ID,
+		State:         status.State,
+		ProjectID:     status.ProjectID,
+		TokenType:     status.TokenType,
+		Owner:         status.Owner,
+		CreatedAt:     status.CreatedAt,
+		UpdatedAt:     status.UpdatedAt,
+		TokenName:     status.TokenName,
+		ExpiresAt:     status.ExpiresAt,
+		RevokedAt:     status.RevokedAt,
+		FailureReason: status.FailureReason,
+		Error:         status.Error,
+	}
+}
+
+func newAdminRequestDetailOutput(status model.RequestStatus) AdminRequestOutput {
+	output := newAdminRequestOutput(status)
+	output.Principal = status.Principal
+	if status.TTL > 0 {
+		output.TTL = status.TTL.String()
+	}
+
+	output.Events = make([]AdminRequestEventOutput, 0, len(status.Events))
+	for _, event := range status.Events {
+		output.Events = append(output.Events, AdminRequestEventOutput{
+			Type:          event.Type,
+			Time:          event.Time,
+			Attempt:       event.Attempt,
+			FailureReason: event.FailureReason,
+			Error:         event.Error,
+		})
+	}
+
+	return output
+}
+
+// ListRequestsHandler lists the token generation requests, the most recent first,
+// filtered by state, project and owner.
+func ListRequestsHandler(uc RequestAdministrationUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+
+		filter := model.RequestFilter{
+			State:     model.RequestState(query.Get("state")),
+			ProjectID: query.Get("project_id"),
+			Owner:     query.Get("owner"),
+			PageToken: query.Get("page_token"),
+		}
+		if limit := query.Get("limit"); limit != "" {
+			// The range is enforced by the OpenAPI document.
+			filter.Limit, _ = strconv.Atoi(limit)
+		}
+
+		page, err := uc.ListRequests(ctx, filter)
+		if errors.Is(err, model.ErrInvalidPageToken) {
+			httpx.WriteProblem(w, r, http.StatusUnprocessableEntity, codeValidationFailed, "invalid page_token")
+			return
+		}
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("listing requests")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to list the requests")
+			return
+		}
+
+		output := AdminRequestPageOutput{
+			Requests:      make([]AdminRequestOutput, 0, len(page.Requests)),
+			NextPageToken: page.NextPageToken,
+		}
+		for _, status := range page.Requests {
+			output.Requests = append(output.Requests, newAdminRequestOutput(status))
+		}
+
+		writeJSON(ctx, w, http.StatusOK, output)
+	}
+}
+
+// GetRequestHandler returns the detail of a token generation request, with its events
+// and errors but without its token.
+func GetRequestHandler(uc RequestAdministrationUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := chi.URLParam(r, "id")
+
+		status, err := uc.GetRequest(ctx, id)
+		if errors.Is(err, model.ErrRequestNotFound) {
+			httpx.WriteProblem(w, r, http.StatusNotFound, codeNotFound, "request "+id+" not found")
+			return
+		}
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("getting request")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to get the request")
+			return
+		}
+
+		writeJSON(ctx, w, http.StatusOK, newAdminRequestDetailOutput(status))
+	}
+}
+
+// CancelRequestHandler cancels a token generation request that was not processed yet.
+func CancelRequestHandler(uc RequestAdministrationUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := chi.URLParam(r, "id")
+
+		status, err := uc.CancelRequest(ctx, id)
+		switch {
+		case errors.Is(err, model.ErrRequestNotFound):
+			httpx.WriteProblem(w, r, http.StatusNotFound, codeNotFound, "request "+id+" not found")
+			return
+		case errors.Is(err, model.ErrRequestNotCancelable):
+			httpx.WriteProblem(w, r, http.StatusConflict, codeRequestNotCancelable, "request "+id+" is no longer queued")
+			return
+		case err != nil:
+			log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("canceling request")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to cancel the request")
+			return
+		}
+
+		log.Ctx(ctx).Info().Str("request_id", id).Msg("request canceled")
+
+		writeJSON(ctx, w, http.StatusOK, newAdminRequestDetailOutput(status))
+	}
+}
+
+// RequeueRequestHandler queues a failed token generation request again.
+func RequeueRequestHandler(uc RequestAdministrationUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := chi.URLParam(r, "id")
+
+		status, err := uc.RequeueRequest(ctx, id)
+		switch {
+		case errors.Is(err, model.ErrRequestNotFound):
+			httpx.WriteProblem(w, r, http.StatusNotFound, codeNotFound, "request "+id+" not found")
+			return
+		case errors.Is(err, model.ErrRequestNotRequeueable):
+			httpx.WriteProblem(w, r, http.StatusConflict, codeRequestNotRequeueable, "request "+id+" did not fail")
+			return
+		case err != nil:
+			log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("requeueing request")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codePublishFailed, "failed to queue the request again")
+			return
+		}
+
+		log.Ctx(ctx).Info().Str("request_id", id).Msg("request requeued")
+
+		w.Header().Set("Location", requestStatusPath(status.ID))
+		writeJSON(ctx, w, http.StatusAccepted, newAdminRequestDetailOutput(status))
+	}
+}
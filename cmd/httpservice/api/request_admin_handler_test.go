@@ -0,0 +1,247 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+// setupRequestAdminTest serves the request administration endpoints protected by API
+// keys and returns an admin key and a key only allowed to request tokens.
+func setupRequestAdminTest(t *testing.T, uc api.RequestAdministrationUseCase) (string, string, string, func()) {
+	t.Helper()
+
+	apiKeys := authx.NewAPIKeys(authx.NewStateAPIKeyStore(statestore.NewMemory()))
+	adminKey, _, err := apiKeys.CreateAPIKey(context.Background(), "admin", []string{model.ScopeAdmin})
+	require.NoError(t, err)
+	requesterKey, _, err := apiKeys.CreateAPIKey(context.Background(), "ci", []string{model.ScopeRequestTokens})
+	require.NoError(t, err)
+
+	server, tearDownFn := setupAPITest(t, api.New(&mocks.RequestTokenGenerationUseCaseMock{},
+		api.WithAuthentication(apiKeys),
+		api.WithRequestAdministration(uc),
+	))
+
+	return server.URL, adminKey, requesterKey, tearDownFn
+}
+
+func TestListRequestsHandler(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	issued := model.RequestStatus{ID: "issued", ProjectID: "app", State: model.RequestStateIssued, CreatedAt: now, UpdatedAt: now, Token: "squ_token", TokenName: "app-token"}
+
+	tests := []struct {
+		name           string
+		query          string
+		listErr        error
+		expectedStatus int
+		expectedFilter model.RequestFilter
+	}{
+		{
+			name:           "Filtered",
+			query:          "?state=issued&project_id=app&owner=ci&limit=10&page_token=abc",
+			expectedStatus: http.StatusOK,
+			expectedFilter: model.RequestFilter{State: model.RequestStateIssued, ProjectID: "app", Owner: "ci", Limit: 10, PageToken: "abc"},
+		},
+		{
+			name:           "Invalid Page Token",
+			query:          "?page_token=abc",
+			listErr:        model.ErrInvalidPageToken,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedFilter: model.RequestFilter{PageToken: "abc"},
+		},
+		{
+			name:           "Unknown State",
+			query:          "?state=unknown",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Failure",
+			listErr:        errors.New("state store unavailable"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &mocks.RequestAdministrationUseCaseMock{
+				ListRequestsFunc: func(ctx context.Context, filter model.RequestFilter) (model.RequestPage, error) {
+					return model.RequestPage{Requests: []model.RequestStatus{issued}, NextPageToken: "next"}, tt.listErr
+				},
+			}
+			serverURL, adminKey, _, tearDownFn := setupRequestAdminTest(t, useCase)
+			defer tearDownFn()
+
+			resp := doRequest(t, http.MethodGet, serverURL+"/v1/admin/requests"+tt.query, adminKey, "")
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if calls := useCase.ListRequestsCalls(); len(calls) > 0 {
+				assert.Equal(t, tt.expectedFilter, calls[0].Filter)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var page api.AdminRequestPageOutput
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+			assert.Equal(t, "next", page.NextPageToken)
+			if assert.Len(t, page.Requests, 1) {
+				assert.Equal(t, "app-token", page.Requests[0].TokenName)
+				assert.Empty(t, page.Requests[0].Events)
+			}
+		})
+	}
+}
+
+func TestGetRequestHandler(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	failed := model.RequestStatus{
+		ID: "failed", ProjectID: "app", State: model.RequestStateFailed, CreatedAt: now, UpdatedAt: now,
+		FailureReason: model.FailureReasonProviderError, Error: "sonar responded 503",
+		TTL:       time.Hour,
+		Principal: &model.Principal{Subject: "ci", Method: model.AuthMethodAPIKey},
+		Events: []model.RequestEvent{
+			{RequestID: "failed", Type: model.RequestEventQueued, Time: now},
+			{RequestID: "failed", Type: model.RequestEventFailed, Time: now, FailureReason: model.FailureReasonProviderError, Error: "sonar responded 503"},
+		},
+	}
+
+	useCase := &mocks.RequestAdministrationUseCaseMock{
+		GetRequestFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+			if id == failed.ID {
+				return failed, nil
+			}
+			return model.RequestStatus{}, model.ErrRequestNotFound
+		},
+	}
+	serverURL, adminKey, requesterKey, tearDownFn := setupRequestAdminTest(t, useCase)
+	defer tearDownFn()
+
+	t.Run("Detail", func(t *testing.T) {
+		resp := doRequest(t, http.MethodGet, serverURL+"/v1/admin/requests/failed", adminKey, "")
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var output api.AdminRequestOutput
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&output))
+		assert.Equal(t, "sonar responded 503", output.Error)
+		assert.Equal(t, "1h0m0s", output.TTL)
+		assert.Equal(t, "ci", output.Principal.Subject)
+		if assert.Len(t, output.Events, 2) {
+			assert.Equal(t, "sonar responded 503", output.Events[1].Error)
+		}
+	})
+
+	t.Run("Unknown Request", func(t *testing.T) {
+		resp := doRequest(t, http.MethodGet, serverURL+"/v1/admin/requests/unknown", adminKey, "")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Missing Admin Scope", func(t *testing.T) {
+		resp := doRequest(t, http.MethodGet, serverURL+"/v1/admin/requests/failed", requesterKey, "")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestCancelRequestHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		cancelErr      error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "Canceled", expectedStatus: http.StatusOK},
+		{name: "Not Cancelable", cancelErr: model.ErrRequestNotCancelable, expectedStatus: http.StatusConflict, expectedCode: "request_not_cancelable"},
+		{name: "Unknown Request", cancelErr: model.ErrRequestNotFound, expectedStatus: http.StatusNotFound, expectedCode: "not_found"},
+		{name: "Failure", cancelErr: errors.New("state store unavailable"), expectedStatus: http.StatusInternalServerError, expectedCode: "internal_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &mocks.RequestAdministrationUseCaseMock{
+				CancelRequestFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					if tt.cancelErr != nil {
+						return model.RequestStatus{}, tt.cancelErr
+					}
+					return model.RequestStatus{ID: id, State: model.RequestStateCanceled}, nil
+				},
+			}
+			serverURL, adminKey, _, tearDownFn := setupRequestAdminTest(t, useCase)
+			defer tearDownFn()
+
+			resp := doRequest(t, http.MethodPost, serverURL+"/v1/admin/requests/0123/cancel", adminKey, "")
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedCode != "" {
+				var problem struct {
+					Code string `json:"code"`
+				}
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+				assert.Equal(t, tt.expectedCode, problem.Code)
+			}
+			if assert.Len(t, useCase.CancelRequestCalls(), 1) {
+				assert.Equal(t, "0123", useCase.CancelRequestCalls()[0].Id)
+			}
+		})
+	}
+}
+
+func TestRequeueRequestHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		requeueErr       error
+		expectedStatus   int
+		expectedCode     string
+		expectedLocation string
+	}{
+		{name: "Requeued", expectedStatus: http.StatusAccepted, expectedLocation: "/v1/requests/0123"},
+		{name: "Not Requeueable", requeueErr: model.ErrRequestNotRequeueable, expectedStatus: http.StatusConflict, expectedCode: "request_not_requeueable"},
+		{name: "Unknown Request", requeueErr: model.ErrRequestNotFound, expectedStatus: http.StatusNotFound, expectedCode: "not_found"},
+		{name: "Publish Failed", requeueErr: errors.New("publish failed"), expectedStatus: http.StatusInternalServerError, expectedCode: "publish_failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &mocks.RequestAdministrationUseCaseMock{
+				RequeueRequestFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					if tt.requeueErr != nil {
+						return model.RequestStatus{}, tt.requeueErr
+					}
+					return model.RequestStatus{ID: id, State: model.RequestStateQueued}, nil
+				},
+			}
+			serverURL, adminKey, _, tearDownFn := setupRequestAdminTest(t, useCase)
+			defer tearDownFn()
+
+			resp := doRequest(t, http.MethodPost, serverURL+"/v1/admin/requests/0123/requeue", adminKey, "")
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Equal(t, tt.expectedLocation, resp.Header.Get("Location"))
+			if tt.expectedCode != "" {
+				var problem struct {
+					Code string `json:"code"`
+				}
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+				assert.Equal(t, tt.expectedCode, problem.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+// heartbeatInterval is how often a comment is sent on idle event streams, keeping
+// proxies from closing them and detecting disconnected clients.
+const heartbeatInterval = 15 * time.Second
+
+type RequestEventOutput struct {
+	RequestID     string                 `json:"request_id"`
+	Type          model.RequestEventType `json:"type"`
+	Time          time.Time              `json:"time"`
+	Attempt       int                    `json:"attempt,omitempty"`
+	ExpiresAt     *time.Time             `json:"expires_at,omitempty"`
+	FailureReason model.FailureReason    `json:"failure_reason,omitempty"`
+}
+
+// RequestEventsHandler streams the events of a token generation request as Server-Sent
+// Events, until the request completes or the client disconnects. Events are numbered
+// from 1, and a reconnecting client only receives the events following the one in its
+// Last-Event-ID header. The token itself is not streamed, it is retrieved with
+// RetrieveTokenHandler after the issued event.
+func RequestEventsHandler(uc RequestStatusUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := chi.URLParam(r, "id")
+
+		status, err := uc.GetRequestStatus(ctx, id)
+		if errors.Is(err, model.ErrRequestNotFound) || (err == nil && !canReadOwned(ctx, status.Owner)) {
+			httpx.WriteProblem(w, r, http.StatusNotFound, codeNotFound, "request "+id+" not found")
+			return
+		}
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("Failed to get request status")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to get the request status")
+			return
+		}
+
+		sent, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+
+		statuses, err := uc.WatchRequest(ctx, id)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("Failed to watch request status")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to watch the request status")
+			return
+		}
+
+		// The stream outlives the write timeout of the server, which only suits
+		// regular responses.
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("Failed to clear the write deadline of the event stream")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case status, ok := <-statuses:
+				if !ok {
+					return
+				}
+
+				for ; sent < len(status.Events); sent++ {
+					if err := writeEvent(w, sent+1, status.Events[sent]); err != nil {
+						return
+					}
+				}
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, id int, event model.RequestEvent) error {
+	data, err := json.Marshal(RequestEventOutput{
+		RequestID:     event.RequestID,
+		Type:          event.Type,
+		Time:          event.Time,
+		Attempt:       event.Attempt,
+		ExpiresAt:     event.ExpiresAt,
+		FailureReason: event.FailureReason,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event.Type, data)
+	return err
+}
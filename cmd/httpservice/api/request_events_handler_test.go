@@ -0,0 +1,111 @@
+package api_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+func TestRequestEventsHandler(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var queued, issued model.RequestStatus
+	queued.Apply(model.RequestEvent{RequestID: "0123", Type: model.RequestEventQueued, Time: now})
+	issued = queued
+	issued.Apply(model.RequestEvent{RequestID: "0123", Type: model.RequestEventPickedUp, Time: now})
+	issued.Apply(model.RequestEvent{RequestID: "0123", Type: model.RequestEventRetrying, Time: now, Attempt: 1})
+	issued.Apply(model.RequestEvent{RequestID: "0123", Type: model.RequestEventIssued, Time: now, Token: "sqp_token"})
+
+	tests := []struct {
+		name           string
+		id             string
+		lastEventID    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Streams Events Until Completion",
+			id:             "0123",
+			expectedStatus: http.StatusOK,
+			expectedBody: "id: 1\nevent: queued\ndata: {\"request_id\":\"0123\",\"type\":\"queued\",\"time\":\"2024-06-01T12:00:00Z\"}\n\n" +
+				"id: 2\nevent: picked_up\ndata: {\"request_id\":\"0123\",\"type\":\"picked_up\",\"time\":\"2024-06-01T12:00:00Z\"}\n\n" +
+				"id: 3\nevent: retrying\ndata: {\"request_id\":\"0123\",\"type\":\"retrying\",\"time\":\"2024-06-01T12:00:00Z\",\"attempt\":1}\n\n" +
+				"id: 4\nevent: issued\ndata: {\"request_id\":\"0123\",\"type\":\"issued\",\"time\":\"2024-06-01T12:00:00Z\"}\n\n",
+		},
+		{
+			name:           "Resumes After Last Event ID",
+			id:             "0123",
+			lastEventID:    "3",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "id: 4\nevent: issued\ndata: {\"request_id\":\"0123\",\"type\":\"issued\",\"time\":\"2024-06-01T12:00:00Z\"}\n\n",
+		},
+		{
+			name:           "Unknown Request",
+			id:             "4567",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := &mocks.RequestStatusUseCaseMock{
+				GetRequestStatusFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					if id != "0123" {
+						return model.RequestStatus{}, model.ErrRequestNotFound
+					}
+					return queued, nil
+				},
+				WatchRequestFunc: func(ctx context.Context, id string) (<-chan model.RequestStatus, error) {
+					updates := make(chan model.RequestStatus)
+					go func() {
+						defer close(updates)
+						updates <- queued
+						// Outlast the write timeout of the server.
+						time.Sleep(200 * time.Millisecond)
+						updates <- issued
+					}()
+					return updates, nil
+				},
+			}
+
+			router := chi.NewRouter()
+			api.New(&mocks.RequestTokenGenerationUseCaseMock{}, api.WithRequestStatus(statuses, time.Second)).Routes(router)
+			server := httptest.NewUnstartedServer(router)
+			server.Config.WriteTimeout = 100 * time.Millisecond
+			server.Start()
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/requests/"+tt.id+"/events", nil)
+			require.NoError(t, err)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedBody, string(body))
+		})
+	}
+}
@@ -3,43 +3,156 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
 )
 
 //go:generate moq -stub -pkg mocks -out mocks/request_generation_uc.go . RequestTokenGenerationUseCase
 type RequestTokenGenerationUseCase interface {
-	RequestTokenGeneration(ctx context.Context, projectID string) error
+	RequestTokenGeneration(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error)
+	RequestTokenGenerations(ctx context.Context, requests []model.TokenGenerationRequest) (model.RequestBatch, []model.BatchItemResult, error)
 }
 
 type RequestTokenGenerationInput struct {
 	ProjectID string `json:"project_id"`
+	// TokenType defaults to a project analysis token.
+	TokenType string `json:"token_type,omitempty"`
+	// TTL is a Go duration such as 720h, tokens never expire when omitted.
+	TTL string `json:"ttl,omitempty"`
+}
+
+// tokenGenerationRequest validates the input and converts it to a domain request.
+func (in RequestTokenGenerationInput) tokenGenerationRequest() (model.TokenGenerationRequest, error) {
+	if in.ProjectID == "" {
+		return model.TokenGenerationRequest{}, errors.New("missing required parameter: project_id")
+	}
+	if !model.ValidProjectKey(in.ProjectID) {
+		return model.TokenGenerationRequest{}, fmt.Errorf("invalid project_id: %q is not a valid project key", in.ProjectID)
+	}
+
+	tokenType, err := model.ParseTokenType(in.TokenType)
+	if err != nil {
+		return model.TokenGenerationRequest{}, fmt.Errorf("invalid token_type: %w", err)
+	}
+
+	var ttl time.Duration
+	if in.TTL != "" {
+		ttl, err = time.ParseDuration(in.TTL)
+		if err != nil || ttl <= 0 {
+			return model.TokenGenerationRequest{}, fmt.Errorf("invalid ttl: %q is not a positive duration", in.TTL)
+		}
+	}
+
+	return model.TokenGenerationRequest{
+		ProjectID: in.ProjectID,
+		TokenType: tokenType,
+		TTL:       ttl,
+	}, nil
 }
 
-func RequestTokenGenerationHandler(uc RequestTokenGenerationUseCase) http.HandlerFunc {
+// RequestTokenGenerationHandler queues the token generation request. When the caller
+// asks to wait, with the wait query parameter or a Prefer: wait header, and statuses
+// is set, the handler waits up to maxWait for the request to complete before falling
+// back to the asynchronous response. A token issued in time is taken, and returned in
+// the response only, later retrievals with RetrieveTokenHandler fail.
+func RequestTokenGenerationHandler(uc RequestTokenGenerationUseCase, statuses RequestStatusUseCase, maxWait time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
 		var body RequestTokenGenerationInput
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			httpx.WriteProblem(w, r, http.StatusBadRequest, codeInvalidBody, "the request body is not valid JSON")
 			return
 		}
 
-		if body.ProjectID == "" {
-			http.Error(w, "Missing required parameter: project_id", http.StatusUnprocessableEntity)
+		request, err := body.tokenGenerationRequest()
+		if err != nil {
+			httpx.WriteProblem(w, r, http.StatusUnprocessableEntity, codeValidationFailed, err.Error())
 			return
 		}
 
-		err := uc.RequestTokenGeneration(ctx, body.ProjectID)
+		wait, preferred, err := requestedWait(r)
 		if err != nil {
-			http.Error(w, "Failed to publish message", http.StatusInternalServerError)
+			httpx.WriteProblem(w, r, http.StatusUnprocessableEntity, codeValidationFailed, err.Error())
 			return
 		}
 
-		log.Ctx(ctx).Info().Str("project_id", body.ProjectID).Msg("Token generation request sent")
+		status, err := uc.RequestTokenGeneration(ctx, request)
+		var deniedErr *model.AccessDeniedError
+		if errors.As(err, &deniedErr) {
+			log.Ctx(ctx).Warn().Str("project_id", body.ProjectID).Str("reason", deniedErr.Decision.Reason).Msg("Token generation request denied")
+			httpx.WriteProblem(w, r, http.StatusForbidden, codeAccessDenied, deniedErr.Decision.Reason)
+			return
+		}
+		if err != nil {
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codePublishFailed, "failed to queue the request")
+			return
+		}
+
+		log.Ctx(ctx).Info().Str("project_id", body.ProjectID).Str("request_id", status.ID).Msg("Token generation request sent")
+
+		if wait > 0 && statuses != nil && maxWait > 0 {
+			wait = min(wait, maxWait)
+			if preferred {
+				w.Header().Set("Preference-Applied", "wait="+strconv.Itoa(int(wait.Seconds())))
+			}
+
+			status, err = statuses.WaitForRequest(ctx, status.ID, wait)
+			if err != nil && ctx.Err() == nil {
+				log.Ctx(ctx).Error().Err(err).Str("request_id", status.ID).Msg("Failed to wait for the token generation request")
+			}
 
-		w.WriteHeader(http.StatusAccepted)
+			if status.State == model.RequestStateIssued {
+				// The status is returned without the token when it cannot be taken, the
+				// caller may still retrieve it.
+				if delivered, err := statuses.DeliverToken(ctx, status); err != nil {
+					log.Ctx(ctx).Error().Err(err).Str("request_id", status.ID).Msg("Failed to take the issued token")
+				} else {
+					status = delivered
+				}
+			}
+		}
+
+		writeRequestStatus(ctx, w, status)
 	}
 }
+
+// requestedWait returns how long the caller is willing to wait for the token, from the
+// wait query parameter (a Go duration) or the wait preference (in seconds), and whether
+// it was given as a preference.
+func requestedWait(r *http.Request) (time.Duration, bool, error) {
+	if value := r.URL.Query().Get("wait"); value != "" {
+		wait, err := time.ParseDuration(value)
+		if err != nil || wait < 0 {
+			return 0, false, fmt.Errorf("invalid wait: %q is not a positive duration", value)
+		}
+		return wait, false, nil
+	}
+
+	for _, header := range r.Header.Values("Prefer") {
+		for _, preference := range strings.Split(header, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(preference), "=")
+			if !strings.EqualFold(name, "wait") {
+				continue
+			}
+
+			// Preferences that cannot be parsed are ignored, as per RFC 7240.
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil || seconds < 0 {
+				return 0, false, nil
+			}
+			return time.Duration(seconds) * time.Second, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
 	"github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/domain/model"
 )
 
 func setupAPITest(t *testing.T, httpApi *api.API) (*httptest.Server, func()) {
@@ -38,8 +39,12 @@ func TestRequestTokenGenerationHandler_Success(t *testing.T) {
 			projectID: "test-project-id",
 			setupUseCase: func(t *testing.T) api.RequestTokenGenerationUseCase {
 				return &mocks.RequestTokenGenerationUseCaseMock{
-					RequestTokenGenerationFunc: func(ctx context.Context, projectID string) error {
-						return nil
+					RequestTokenGenerationFunc: func(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
+						assert.Equal(t, model.TokenGenerationRequest{
+							ProjectID: "test-project-id",
+							TokenType: model.TokenTypeProjectAnalysis,
+						}, request)
+						return model.RequestStatus{ID: "0123", State: model.RequestStateQueued}, nil
 					},
 				}
 			},
@@ -62,7 +67,7 @@ func TestRequestTokenGenerationHandler_Success(t *testing.T) {
 			assert.NoError(t, err)
 
 			// Create HTTP request
-			req, err := http.NewRequest(http.MethodPost, server.URL+"/generate_token", bytes.NewReader(bodyBytes))
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/generate-token", bytes.NewReader(bodyBytes))
 			assert.NoError(t, err)
 			req.Header.Set("Content-Type", "application/json")
 
@@ -99,13 +104,41 @@ func TestRequestTokenGenerationHandler_Failure(t *testing.T) {
 			},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:        "Unknown Token Type",
+			requestBody: `{"project_id": "project-id", "token_type": "user"}`,
+			setupUseCase: func(t *testing.T) api.RequestTokenGenerationUseCase {
+				return &mocks.RequestTokenGenerationUseCaseMock{}
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "Invalid TTL",
+			requestBody: `{"project_id": "project-id", "ttl": "-1h"}`,
+			setupUseCase: func(t *testing.T) api.RequestTokenGenerationUseCase {
+				return &mocks.RequestTokenGenerationUseCaseMock{}
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "Access Denied",
+			requestBody: `{"project_id": "other-project-id"}`,
+			setupUseCase: func(t *testing.T) api.RequestTokenGenerationUseCase {
+				return &mocks.RequestTokenGenerationUseCaseMock{
+					RequestTokenGenerationFunc: func(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
+						return model.RequestStatus{}, &model.AccessDeniedError{Decision: model.AccessDecision{Reason: "no rule allows it"}}
+					},
+				}
+			},
+			expectedStatus: http.StatusForbidden,
+		},
 		{
 			name:        "UseCase Error",
 			requestBody: `{"project_id": "error-project-id"}`,
 			setupUseCase: func(t *testing.T) api.RequestTokenGenerationUseCase {
 				return &mocks.RequestTokenGenerationUseCaseMock{
-					RequestTokenGenerationFunc: func(ctx context.Context, projectID string) error {
-						return errors.New("mocked error from use case")
+					RequestTokenGenerationFunc: func(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
+						return model.RequestStatus{}, errors.New("mocked error from use case")
 					},
 				}
 			},
@@ -124,7 +157,7 @@ func TestRequestTokenGenerationHandler_Failure(t *testing.T) {
 			defer tearDownFn()
 
 			// Create HTTP request
-			req, err := http.NewRequest(http.MethodPost, server.URL+"/generate_token", bytes.NewReader([]byte(tt.requestBody)))
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/generate-token", bytes.NewReader([]byte(tt.requestBody)))
 			assert.NoError(t, err)
 			req.Header.Set("Content-Type", "application/json")
 
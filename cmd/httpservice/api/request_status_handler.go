@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+//go:generate moq -stub -pkg mocks -out mocks/request_status_uc.go . RequestStatusUseCase
+type RequestStatusUseCase interface {
+	GetRequestStatus(ctx context.Context, id string) (model.RequestStatus, error)
+	WaitForRequest(ctx context.Context, id string, timeout time.Duration) (model.RequestStatus, error)
+	// DeliverToken returns the status of an issued request, just waited for by the
+	// caller who made it, with its token, which is then no longer kept.
+	DeliverToken(ctx context.Context, status model.RequestStatus) (model.RequestStatus, error)
+	WatchRequest(ctx context.Context, id string) (<-chan model.RequestStatus, error)
+	GetBatchStatus(ctx context.Context, id string) (model.BatchStatus, error)
+}
+
+type RequestStatusOutput struct {
+	RequestID string             `json:"request_id"`
+	State     model.RequestState `json:"state"`
+	ProjectID string             `json:"project_id,omitempty"`
+	TokenType model.TokenType    `json:"token_type,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+	// StatusURL is where the status can be polled until the request completes.
+	StatusURL string `json:"status_url"`
+	// Token is only set in the synchronous responses, it is otherwise retrieved once
+	// with RetrieveTokenHandler.
+	Token         string              `json:"token,omitempty"`
+	ExpiresAt     *time.Time          `json:"expires_at,omitempty"`
+	FailureReason model.FailureReason `json:"failure_reason,omitempty"`
+}
+
+func newRequestStatusOutput(status model.RequestStatus) RequestStatusOutput {
+	return RequestStatusOutput{
+		RequestID:     status.ID,
+		State:         status.State,
+		ProjectID:     status.ProjectID,
+		TokenType:     status.TokenType,
+		CreatedAt:     status.CreatedAt,
+		UpdatedAt:     status.UpdatedAt,
+		StatusURL:     requestStatusPath(status.ID),
+		ExpiresAt:     status.ExpiresAt,
+		FailureReason: status.FailureReason,
+	}
+}
+
+func requestStatusPath(id string) string {
+	return "/v1/requests/" + id
+}
+
+// GetRequestStatusHandler returns the status of a token generation request, without the
+// token, which is only returned once by RetrieveTokenHandler. Requests are only visible
+// to their owner and administrators.
+func GetRequestStatusHandler(uc RequestStatusUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := chi.URLParam(r, "id")
+
+		status, err := uc.GetRequestStatus(ctx, id)
+		if errors.Is(err, model.ErrRequestNotFound) || (err == nil && !canReadOwned(ctx, status.Owner)) {
+			httpx.WriteProblem(w, r, http.StatusNotFound, codeNotFound, "request "+id+" not found")
+			return
+		}
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("Failed to get request status")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to get the request status")
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(ctx, w, http.StatusOK, newRequestStatusOutput(status))
+	}
+}
+
+// canReadOwned reports whether the authenticated principal, if any, may read a request
+// or batch owned by owner. Other principals get a not found response, so that IDs
+// cannot be probed.
+func canReadOwned(ctx context.Context, owner string) bool {
+	principal, ok := model.PrincipalFromContext(ctx)
+	if !ok {
+		return true
+	}
+
+	return principal.HasScope(model.ScopeAdmin) || owner == principal.Owner()
+}
+
+// writeRequestStatus responds to a token generation request with its status: the token
+// once issued and taken, the failure reason if it failed, or where to poll it otherwise.
+func writeRequestStatus(ctx context.Context, w http.ResponseWriter, status model.RequestStatus) {
+	code := http.StatusAccepted
+	switch status.State {
+	case model.RequestStateIssued:
+		code = http.StatusOK
+	case model.RequestStateFailed:
+		code = http.StatusBadGateway
+		if status.FailureReason == model.FailureReasonInvalidRequest {
+			code = http.StatusUnprocessableEntity
+		}
+	default:
+		w.Header().Set("Location", requestStatusPath(status.ID))
+	}
+
+	out := newRequestStatusOutput(status)
+	out.Token = status.Token
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(ctx, w, code, out)
+}
@@ -0,0 +1,220 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+func TestRequestTokenGenerationHandler_Wait(t *testing.T) {
+	expiresAt := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	queued := model.RequestStatus{ID: "0123", ProjectID: "project-id", State: model.RequestStateQueued}
+	issued := model.RequestStatus{ID: "0123", ProjectID: "project-id", State: model.RequestStateIssued, ExpiresAt: &expiresAt}
+	failed := model.RequestStatus{ID: "0123", ProjectID: "project-id", State: model.RequestStateFailed, FailureReason: model.FailureReasonInvalidRequest}
+
+	tests := []struct {
+		name                      string
+		query                     string
+		prefer                    string
+		status                    model.RequestStatus
+		deliverErr                error
+		expectedStatus            int
+		expectedWait              time.Duration
+		expectedPreferenceApplied string
+		expectedToken             string
+		expectedError             bool
+	}{
+		{
+			name:           "Without Wait",
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "Issued Within Wait",
+			query:          "?wait=5s",
+			status:         issued,
+			expectedStatus: http.StatusOK,
+			expectedWait:   5 * time.Second,
+			expectedToken:  "sqp_token",
+		},
+		{
+			name:           "Issued Token Already Retrieved",
+			query:          "?wait=5s",
+			status:         issued,
+			deliverErr:     model.ErrTokenAlreadyRetrieved,
+			expectedStatus: http.StatusOK,
+			expectedWait:   5 * time.Second,
+		},
+		{
+			name:           "Failed Within Wait",
+			query:          "?wait=5s",
+			status:         failed,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedWait:   5 * time.Second,
+		},
+		{
+			name:           "Still Queued After Wait",
+			query:          "?wait=5s",
+			status:         queued,
+			expectedStatus: http.StatusAccepted,
+			expectedWait:   5 * time.Second,
+		},
+		{
+			name:                      "Wait Preference Capped",
+			prefer:                    "respond-async, wait=30",
+			status:                    issued,
+			expectedStatus:            http.StatusOK,
+			expectedWait:              10 * time.Second,
+			expectedPreferenceApplied: "wait=10",
+			expectedToken:             "sqp_token",
+		},
+		{
+			name:           "Invalid Wait",
+			query:          "?wait=soon",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &mocks.RequestTokenGenerationUseCaseMock{
+				RequestTokenGenerationFunc: func(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
+					return queued, nil
+				},
+			}
+			statuses := &mocks.RequestStatusUseCaseMock{
+				WaitForRequestFunc: func(ctx context.Context, id string, timeout time.Duration) (model.RequestStatus, error) {
+					assert.Equal(t, queued.ID, id)
+					assert.Equal(t, tt.expectedWait, timeout)
+					return tt.status, nil
+				},
+				DeliverTokenFunc: func(ctx context.Context, status model.RequestStatus) (model.RequestStatus, error) {
+					if tt.deliverErr != nil {
+						return model.RequestStatus{}, tt.deliverErr
+					}
+					status.Token = "sqp_token"
+					return status, nil
+				},
+			}
+
+			server, tearDownFn := setupAPITest(t, api.New(useCase, api.WithRequestStatus(statuses, 10*time.Second)))
+			defer tearDownFn()
+
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/generate-token"+tt.query, strings.NewReader(`{"project_id": "project-id"}`))
+			require.NoError(t, err)
+			if tt.prefer != "" {
+				req.Header.Set("Prefer", tt.prefer)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Equal(t, tt.expectedPreferenceApplied, resp.Header.Get("Preference-Applied"))
+			if tt.expectedWait == 0 {
+				assert.Empty(t, statuses.WaitForRequestCalls())
+			}
+
+			if tt.expectedError {
+				assert.Empty(t, useCase.RequestTokenGenerationCalls())
+				return
+			}
+			if resp.StatusCode == http.StatusAccepted {
+				assert.Equal(t, "/v1/requests/0123", resp.Header.Get("Location"))
+			}
+
+			if tt.status.State != model.RequestStateIssued {
+				assert.Empty(t, statuses.DeliverTokenCalls())
+			}
+
+			var body api.RequestStatusOutput
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, "0123", body.RequestID)
+			assert.Equal(t, "/v1/requests/0123", body.StatusURL)
+			assert.Equal(t, tt.expectedToken, body.Token)
+		})
+	}
+}
+
+func TestGetRequestStatusHandler(t *testing.T) {
+	apiKeys := authx.NewAPIKeys(authx.NewStateAPIKeyStore(statestore.NewMemory()))
+	adminKey, _, err := apiKeys.CreateAPIKey(context.Background(), "admin", []string{model.ScopeAdmin, model.ScopeRequestTokens})
+	require.NoError(t, err)
+	ownerKey, owner, err := apiKeys.CreateAPIKey(context.Background(), "ci", []string{model.ScopeRequestTokens})
+	require.NoError(t, err)
+	otherKey, _, err := apiKeys.CreateAPIKey(context.Background(), "other", []string{model.ScopeRequestTokens})
+	require.NoError(t, err)
+
+	statuses := &mocks.RequestStatusUseCaseMock{
+		GetRequestStatusFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+			switch id {
+			case "0123":
+				principal := model.Principal{Subject: "apikey:" + owner.ID, Method: model.AuthMethodAPIKey}
+				return model.RequestStatus{ID: id, State: model.RequestStateIssued, Owner: principal.Owner(), Token: "sqp_token"}, nil
+			case "89ab":
+				// Requested by an identity provider principal whose subject is that of the
+				// API key.
+				principal := model.Principal{Subject: "apikey:" + owner.ID, Method: model.AuthMethodJWT, Issuer: "https://issuer.example.com"}
+				return model.RequestStatus{ID: id, State: model.RequestStateIssued, Owner: principal.Owner(), Token: "sqp_token"}, nil
+			default:
+				return model.RequestStatus{}, model.ErrRequestNotFound
+			}
+		},
+	}
+
+	server, tearDownFn := setupAPITest(t, api.New(&mocks.RequestTokenGenerationUseCaseMock{},
+		api.WithAuthentication(apiKeys),
+		api.WithRequestStatus(statuses, 10*time.Second),
+	))
+	defer tearDownFn()
+
+	tests := []struct {
+		name           string
+		apiKey         string
+		id             string
+		expectedStatus int
+	}{
+		{name: "Owner", apiKey: ownerKey, id: "0123", expectedStatus: http.StatusOK},
+		{name: "Administrator", apiKey: adminKey, id: "0123", expectedStatus: http.StatusOK},
+		{name: "Other Principal", apiKey: otherKey, id: "0123", expectedStatus: http.StatusNotFound},
+		{name: "Same Subject Other Method", apiKey: ownerKey, id: "89ab", expectedStatus: http.StatusNotFound},
+		{name: "Unknown Request", apiKey: ownerKey, id: "4567", expectedStatus: http.StatusNotFound},
+		{name: "Missing Credentials", id: "0123", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := doRequest(t, http.MethodGet, server.URL+"/v1/requests/"+tt.id, tt.apiKey, "")
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+
+			data, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.NotContains(t, string(data), "sqp_token", "the token is only returned once retrieved")
+
+			var body api.RequestStatusOutput
+			require.NoError(t, json.Unmarshal(data, &body))
+			assert.Equal(t, model.RequestStateIssued, body.State)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+//go:generate moq -stub -pkg mocks -out mocks/self_service_uc.go . SelfServiceUseCase
+type SelfServiceUseCase interface {
+	AccessGrants(ctx context.Context) ([]model.AccessGrant, bool)
+	TokenRevocationEnabled() bool
+	ListActiveTokens(ctx context.Context) ([]model.RequestStatus, error)
+	RetrieveToken(ctx context.Context, id string) (model.RequestStatus, error)
+	RevokeToken(ctx context.Context, id string) (model.RequestStatus, error)
+}
+
+// CallerOutput describes the caller and what it may request.
+type CallerOutput struct {
+	// Principal is the authenticated caller, omitted when authentication is disabled.
+	Principal *model.Principal `json:"principal,omitempty"`
+	// Restricted tells whether an access policy applies, Grants listing what it allows
+	// the caller. Project analysis tokens for any project may be requested otherwise.
+	Restricted      bool                `json:"restricted"`
+	Grants          []AccessGrantOutput `json:"grants"`
+	TokenRevocation bool                `json:"token_revocation"`
+}
+
+type AccessGrantOutput struct {
+	Rule       string            `json:"rule"`
+	Projects   []string          `json:"projects"`
+	TokenTypes []model.TokenType `json:"token_types"`
+	MaxTTL     string            `json:"max_ttl,omitempty"`
+}
+
+// OwnedTokenOutput describes an active token of the caller, without the token itself.
+type OwnedTokenOutput struct {
+	RequestID   string          `json:"request_id"`
+	ProjectID   string          `json:"project_id,omitempty"`
+	TokenType   model.TokenType `json:"token_type,omitempty"`
+	TokenName   string          `json:"token_name,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty"`
+	RetrievedAt *time.Time      `json:"retrieved_at,omitempty"`
+}
+
+type OwnedTokensOutput struct {
+	Tokens []OwnedTokenOutput `json:"tokens"`
+}
+
+type RetrievedTokenOutput struct {
+	RequestID string          `json:"request_id"`
+	ProjectID string          `json:"project_id,omitempty"`
+	TokenType model.TokenType `json:"token_type,omitempty"`
+	Token     string          `json:"token"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+}
+
+// CallerHandler returns the caller and what the access policy allows it to request.
+func CallerHandler(uc SelfServiceUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		grants, restricted := uc.AccessGrants(ctx)
+		output := CallerOutput{
+			Restricted:      restricted,
+			Grants:          make([]AccessGrantOutput, 0, len(grants)),
+			TokenRevocation: uc.TokenRevocationEnabled(),
+		}
+		if principal, ok := model.PrincipalFromContext(ctx); ok {
+			output.Principal = &principal
+		}
+		for _, grant := range grants {
+			grantOutput := AccessGrantOutput{
+				Rule:       grant.Rule,
+				Projects:   grant.Projects,
+				TokenTypes: grant.TokenTypes,
+			}
+			if grant.MaxTTL > 0 {
+				grantOutput.MaxTTL = grant.MaxTTL.String()
+			}
+			output.Grants = append(output.Grants, grantOutput)
+		}
+
+		writeJSON(ctx, w, http.StatusOK, output)
+	}
+}
+
+// ListOwnedTokensHandler lists the active tokens of the caller, the most recent first.
+func ListOwnedTokensHandler(uc SelfServiceUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		tokens, err := uc.ListActiveTokens(ctx)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("listing active tokens")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to list the tokens")
+			return
+		}
+
+		output := OwnedTokensOutput{Tokens: make([]OwnedTokenOutput, 0, len(tokens))}
+		for _, status := range tokens {
+			output.Tokens = append(output.Tokens, OwnedTokenOutput{
+				RequestID:   status.ID,
+				ProjectID:   status.ProjectID,
+				TokenType:   status.TokenType,
+				TokenName:   status.TokenName,
+				CreatedAt:   status.CreatedAt,
+				ExpiresAt:   status.ExpiresAt,
+				RetrievedAt: status.RetrievedAt,
+			})
+		}
+
+		writeJSON(ctx, w, http.StatusOK, output)
+	}
+}
+
+// RetrieveTokenHandler returns the token issued for a request of the caller, once: the
+// token is no longer kept afterwards.
+func RetrieveTokenHandler(uc SelfServiceUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := chi.URLParam(r, "id")
+
+		status, err := uc.RetrieveToken(ctx, id)
+		switch {
+		case errors.Is(err, model.ErrRequestNotFound):
+			httpx.WriteProblem(w, r, http.StatusNotFound, codeNotFound, "request "+id+" not found")
+			return
+		case errors.Is(err, model.ErrTokenNotIssued):
+			httpx.WriteProblem(w, r, http.StatusConflict, codeTokenNotIssued, "request "+id+" has no token yet")
+			return
+		case errors.Is(err, model.ErrTokenAlreadyRetrieved):
+			httpx.WriteProblem(w, r, http.StatusGone, codeTokenAlreadyRetrieved, "the token of request "+id+" was already retrieved or revoked")
+			return
+		case err != nil:
+			log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("retrieving token")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to retrieve the token")
+			return
+		}
+
+		log.Ctx(ctx).Info().Str("request_id", id).Msg("token retrieved")
+
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(ctx, w, http.StatusOK, RetrievedTokenOutput{
+			RequestID: status.ID,
+			ProjectID: status.ProjectID,
+			TokenType: status.TokenType,
+			Token:     status.Token,
+			ExpiresAt: status.ExpiresAt,
+		})
+	}
+}
+
+// RevokeOwnedTokenHandler revokes the token issued for a request of the caller.
+func RevokeOwnedTokenHandler(uc SelfServiceUseCase) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := chi.URLParam(r, "id")
+
+		status, err := uc.RevokeToken(ctx, id)
+		switch {
+		case errors.Is(err, model.ErrRequestNotFound):
+			httpx.WriteProblem(w, r, http.StatusNotFound, codeNotFound, "request "+id+" not found")
+			return
+		case errors.Is(err, model.ErrTokenNotRevocable):
+			httpx.WriteProblem(w, r, http.StatusConflict, codeTokenNotRevocable, "request "+id+" has no token to revoke")
+			return
+		case errors.Is(err, model.ErrTokenRevocationUnavailable):
+			httpx.WriteProblem(w, r, http.StatusNotImplemented, codeTokenRevocationUnavailable, "tokens cannot be revoked by this service")
+			return
+		case err != nil:
+			log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("revoking token")
+			httpx.WriteProblem(w, r, http.StatusInternalServerError, codeInternal, "failed to revoke the token")
+			return
+		}
+
+		log.Ctx(ctx).Info().Str("project_id", status.ProjectID).Str("request_id", id).Msg("token revoked")
+
+		writeJSON(ctx, w, http.StatusOK, newRequestStatusOutput(status))
+	}
+}
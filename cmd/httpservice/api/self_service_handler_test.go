@@ -0,0 +1,271 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+// setupSelfServiceTest serves the self-service endpoints protected by API keys and
+// returns a key allowed to request tokens, with the subject of its principal, and an
+// admin key which is not.
+func setupSelfServiceTest(t *testing.T, uc api.SelfServiceUseCase) (string, string, string, string, func()) {
+	t.Helper()
+
+	apiKeys := authx.NewAPIKeys(authx.NewStateAPIKeyStore(statestore.NewMemory()))
+	adminKey, _, err := apiKeys.CreateAPIKey(context.Background(), "admin", []string{model.ScopeAdmin})
+	require.NoError(t, err)
+	requesterKey, requester, err := apiKeys.CreateAPIKey(context.Background(), "ci", []string{model.ScopeRequestTokens})
+	require.NoError(t, err)
+
+	server, tearDownFn := setupAPITest(t, api.New(&mocks.RequestTokenGenerationUseCaseMock{},
+		api.WithAuthentication(apiKeys),
+		api.WithSelfService(uc),
+	))
+
+	return server.URL, requesterKey, "apikey:" + requester.ID, adminKey, tearDownFn
+}
+
+func TestCallerHandler(t *testing.T) {
+	useCase := &mocks.SelfServiceUseCaseMock{
+		AccessGrantsFunc: func(ctx context.Context) ([]model.AccessGrant, bool) {
+			return []model.AccessGrant{
+				{Rule: "ci", Projects: []string{"app-*"}, TokenTypes: []model.TokenType{model.TokenTypeProjectAnalysis}, MaxTTL: 24 * time.Hour},
+				{Rule: "platform", Projects: []string{"*"}, TokenTypes: []model.TokenType{model.TokenTypeProjectAnalysis, model.TokenTypeGlobalAnalysis}},
+			}, true
+		},
+		TokenRevocationEnabledFunc: func() bool {
+			return true
+		},
+	}
+	serverURL, requesterKey, requester, adminKey, tearDownFn := setupSelfServiceTest(t, useCase)
+	defer tearDownFn()
+
+	resp := doRequest(t, http.MethodGet, serverURL+"/v1/me", requesterKey, "")
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var caller api.CallerOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&caller))
+	if assert.NotNil(t, caller.Principal) {
+		assert.Equal(t, requester, caller.Principal.Subject)
+	}
+	assert.True(t, caller.Restricted)
+	assert.True(t, caller.TokenRevocation)
+	assert.Equal(t, []api.AccessGrantOutput{
+		{Rule: "ci", Projects: []string{"app-*"}, TokenTypes: []model.TokenType{model.TokenTypeProjectAnalysis}, MaxTTL: "24h0m0s"},
+		{Rule: "platform", Projects: []string{"*"}, TokenTypes: []model.TokenType{model.TokenTypeProjectAnalysis, model.TokenTypeGlobalAnalysis}},
+	}, caller.Grants)
+
+	denied := doRequest(t, http.MethodGet, serverURL+"/v1/me", adminKey, "")
+	defer denied.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, denied.StatusCode)
+}
+
+func TestListOwnedTokensHandler(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Hour)
+	issued := model.RequestStatus{ID: "issued", ProjectID: "app", TokenType: model.TokenTypeProjectAnalysis, State: model.RequestStateIssued, CreatedAt: now, UpdatedAt: now, Token: "squ_token", TokenName: "app-token", ExpiresAt: &expiresAt}
+
+	tests := []struct {
+		name           string
+		listErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Failure",
+			listErr:        errors.New("state store unavailable"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &mocks.SelfServiceUseCaseMock{
+				ListActiveTokensFunc: func(ctx context.Context) ([]model.RequestStatus, error) {
+					return []model.RequestStatus{issued}, tt.listErr
+				},
+			}
+			serverURL, requesterKey, _, _, tearDownFn := setupSelfServiceTest(t, useCase)
+			defer tearDownFn()
+
+			resp := doRequest(t, http.MethodGet, serverURL+"/v1/tokens", requesterKey, "")
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var body map[string][]map[string]any
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			if assert.Len(t, body["tokens"], 1) {
+				assert.Equal(t, "app-token", body["tokens"][0]["token_name"])
+				assert.NotContains(t, body["tokens"][0], "token", "the tokens themselves must never be listed")
+			}
+		})
+	}
+}
+
+func TestRetrieveTokenHandler(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	issued := model.RequestStatus{ID: "issued", ProjectID: "app", TokenType: model.TokenTypeProjectAnalysis, State: model.RequestStateIssued, CreatedAt: now, UpdatedAt: now, Token: "squ_token"}
+
+	tests := []struct {
+		name           string
+		retrieveErr    error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "Success",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Not Found",
+			retrieveErr:    model.ErrRequestNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "not_found",
+		},
+		{
+			name:           "Not Issued",
+			retrieveErr:    model.ErrTokenNotIssued,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "token_not_issued",
+		},
+		{
+			name:           "Already Retrieved",
+			retrieveErr:    model.ErrTokenAlreadyRetrieved,
+			expectedStatus: http.StatusGone,
+			expectedCode:   "token_already_retrieved",
+		},
+		{
+			name:           "Failure",
+			retrieveErr:    errors.New("state store unavailable"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "internal_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &mocks.SelfServiceUseCaseMock{
+				RetrieveTokenFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					if tt.retrieveErr != nil {
+						return model.RequestStatus{}, tt.retrieveErr
+					}
+					return issued, nil
+				},
+			}
+			serverURL, requesterKey, _, _, tearDownFn := setupSelfServiceTest(t, useCase)
+			defer tearDownFn()
+
+			resp := doRequest(t, http.MethodPost, serverURL+"/v1/requests/issued/token", requesterKey, "")
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if calls := useCase.RetrieveTokenCalls(); assert.Len(t, calls, 1) {
+				assert.Equal(t, "issued", calls[0].Id)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				var problem map[string]any
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+				assert.Equal(t, tt.expectedCode, problem["code"])
+				return
+			}
+
+			assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+			var token api.RetrievedTokenOutput
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+			assert.Equal(t, api.RetrievedTokenOutput{RequestID: "issued", ProjectID: "app", TokenType: model.TokenTypeProjectAnalysis, Token: "squ_token"}, token)
+		})
+	}
+}
+
+func TestRevokeOwnedTokenHandler(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	revoked := model.RequestStatus{ID: "issued", ProjectID: "app", State: model.RequestStateIssued, CreatedAt: now, UpdatedAt: now, RevokedAt: &now}
+
+	tests := []struct {
+		name           string
+		revokeErr      error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "Success",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Not Found",
+			revokeErr:      model.ErrRequestNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "not_found",
+		},
+		{
+			name:           "Not Revocable",
+			revokeErr:      model.ErrTokenNotRevocable,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "token_not_revocable",
+		},
+		{
+			name:           "Revocation Unavailable",
+			revokeErr:      model.ErrTokenRevocationUnavailable,
+			expectedStatus: http.StatusNotImplemented,
+			expectedCode:   "token_revocation_unavailable",
+		},
+		{
+			name:           "Failure",
+			revokeErr:      errors.New("sonar unavailable"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "internal_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &mocks.SelfServiceUseCaseMock{
+				RevokeTokenFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					if tt.revokeErr != nil {
+						return model.RequestStatus{}, tt.revokeErr
+					}
+					return revoked, nil
+				},
+			}
+			serverURL, requesterKey, _, _, tearDownFn := setupSelfServiceTest(t, useCase)
+			defer tearDownFn()
+
+			resp := doRequest(t, http.MethodPost, serverURL+"/v1/requests/issued/revoke", requesterKey, "")
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				var problem map[string]any
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+				assert.Equal(t, tt.expectedCode, problem["code"])
+				return
+			}
+
+			var status api.RequestStatusOutput
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+			assert.Equal(t, "issued", status.RequestID)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/correlation"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
+	"github.com/werbersondev/token-generator-test/extensions/tracex"
+)
+
+type RequestEventUseCase interface {
+	ApplyRequestEvent(ctx context.Context, event model.RequestEvent) error
+}
+
+// RequestEventConsumer applies the progress events reported by the worker to the
+// status of the requests.
+type RequestEventConsumer struct {
+	topicSubscription *pubsub.Subscription
+	useCase           RequestEventUseCase
+	metrics           *metricsx.Metrics
+	startCh, stopCh   chan struct{}
+}
+
+type Option func(*RequestEventConsumer)
+
+// WithMetrics records the age of the events at receipt and those being applied.
+func WithMetrics(metrics *metricsx.Metrics) Option {
+	return func(c *RequestEventConsumer) {
+		c.metrics = metrics
+	}
+}
+
+func NewRequestEventConsumer(topicSubscription *pubsub.Subscription, uc RequestEventUseCase, opts ...Option) *RequestEventConsumer {
+	c := &RequestEventConsumer{
+		topicSubscription: topicSubscription,
+		useCase:           uc,
+		startCh:           make(chan struct{}),
+		stopCh:            make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Start begins consuming messages from the Pub/Sub subscription and processing them.
+func (c *RequestEventConsumer) Start(ctx context.Context) error {
+	defer close(c.startCh)
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		<-c.stopCh
+		cancel()
+	}()
+
+	handler := c.metrics.ObserveMessages(c.topicSubscription.ID(), c.RequestEventHandler)
+	if err := c.topicSubscription.Receive(ctx, handler); err != nil && !errors.Is(err, context.Canceled) {
+		log.Ctx(ctx).Error().Err(err).Msg("Error receiving messages")
+		return err
+	}
+
+	return nil
+}
+
+// Stop signals the consumer to stop polling for new messages and waits for the
+// ongoing processing to finish.
+func (c *RequestEventConsumer) Stop(ctx context.Context) error {
+	close(c.stopCh)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.startCh:
+		return nil
+	}
+}
+
+func (c *RequestEventConsumer) RequestEventHandler(ctx context.Context, msg *pubsub.Message) {
+	ctx = correlation.Restore(ctx, msg.Attributes[correlation.Attribute])
+	ctx, span := tracex.StartConsumerSpan(ctx, c.topicSubscription.ID(), msg.Attributes, msg.PublishTime)
+	defer span.End()
+
+	var event model.RequestEvent
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("failed to unmarshal message")
+		msg.Ack()
+		return
+	}
+
+	if err := c.useCase.ApplyRequestEvent(ctx, event); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "applying request event")
+		log.Ctx(ctx).Error().Err(err).
+			Str("request_id", event.RequestID).
+			Str("event", string(event.Type)).
+			Msg("Failed to apply request event")
+		// Let Pub/Sub redeliver the event, the state store may be back by then.
+		msg.Nack()
+		return
+	}
+
+	msg.Ack()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/domain/service"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/gateway/sonarclient"
+)
+
+// exchangeConfig is the YAML configuration of the token exchange: the CI identity
+// providers whose tokens are accepted and the rules mapping them to projects.
+type exchangeConfig struct {
+	Issuers              []exchangeIssuer `yaml:"issuers"`
+	model.ExchangePolicy `yaml:",inline"`
+}
+
+type exchangeIssuer struct {
+	Issuer       string        `yaml:"issuer"`
+	Audience     string        `yaml:"audience"`
+	JWKSURL      string        `yaml:"jwks_url"`
+	JWKSFile     string        `yaml:"jwks_file"`
+	JWKSCacheTTL time.Duration `yaml:"jwks_cache_ttl"`
+}
+
+// tokenExchangeOptions enables the token exchange when an exchange configuration is
+// set. Tokens are issued synchronously, with the Sonar client of the HTTP service.
+func tokenExchangeOptions(cfg config, client *sonarclient.HTTPClient, audit service.AuditRecorder) ([]api.Option, error) {
+	if cfg.ExchangeConfigFile == "" {
+		return nil, nil
+	}
+	if client == nil {
+		return nil, errors.New("SONAR_AUTH_TOKEN or SONAR_AUTH_TOKEN_FILE is required with EXCHANGE_CONFIG_FILE")
+	}
+
+	exchange, err := loadExchangeConfig(cfg.ExchangeConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticators := make([]authx.Authenticator, 0, len(exchange.Issuers))
+	for _, issuer := range exchange.Issuers {
+		authenticator, err := newExchangeAuthenticator(issuer, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("issuer %s: %w", issuer.Issuer, err)
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+
+	permissionPolicy, err := model.ParsePermissionPolicy(cfg.SonarPermissionPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenService := service.NewTokenGenerationService(client, permissionPolicy)
+	exchangeService := service.NewTokenExchangeService(&exchange.ExchangePolicy, tokenService, audit)
+
+	return []api.Option{api.WithTokenExchange(exchangeService, authenticators...)}, nil
+}
+
+func loadExchangeConfig(path string) (*exchangeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading exchange configuration: %w", err)
+	}
+
+	var exchange exchangeConfig
+	if err := yaml.Unmarshal(data, &exchange); err != nil {
+		return nil, fmt.Errorf("decoding exchange configuration: %w", err)
+	}
+	if len(exchange.Issuers) == 0 {
+		return nil, errors.New("invalid exchange configuration: no issuer")
+	}
+	if err := exchange.Compile(); err != nil {
+		return nil, fmt.Errorf("invalid exchange configuration: %w", err)
+	}
+
+	return &exchange, nil
+}
+
+func newExchangeAuthenticator(issuer exchangeIssuer, cfg config) (*authx.JWT, error) {
+	var keys *authx.JWKS
+	switch {
+	case issuer.JWKSFile != "":
+		var err error
+		keys, err = authx.NewFileJWKS(issuer.JWKSFile, cfg.AuthJWTJWKSReloadInterval)
+		if err != nil {
+			return nil, fmt.Errorf("loading jwks file: %w", err)
+		}
+	case issuer.JWKSURL != "":
+		ttl := issuer.JWKSCacheTTL
+		if ttl == 0 {
+			ttl = cfg.AuthJWTJWKSCacheTTL
+		}
+		keys = authx.NewRemoteJWKS(issuer.JWKSURL, ttl, &http.Client{Timeout: 10 * time.Second})
+	default:
+		return nil, errors.New("jwks_url or jwks_file is required")
+	}
+
+	return authx.NewJWT(authx.JWTConfig{
+		Issuer:   issuer.Issuer,
+		Audience: issuer.Audience,
+		Leeway:   cfg.AuthJWTLeeway,
+		Claims: authx.ClaimMapping{
+			Attributes: []string{"*"},
+		},
+	}, keys)
+}
@@ -0,0 +1,383 @@
+package grpcapi
+
+//go:generate protoc -I proto --go_out=../../.. --go_opt=module=github.com/werbersondev/token-generator-test --go-grpc_out=../../.. --go-grpc_opt=module=github.com/werbersondev/token-generator-test tokengen/v1/token_generator.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/grpcapi/tokengenv1"
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+)
+
+//go:generate moq -stub -pkg mocks -out mocks/token_revocation_uc.go . TokenRevocationUseCase
+type TokenRevocationUseCase interface {
+	RevokeToken(ctx context.Context, id string) (model.RequestStatus, error)
+}
+
+// Server implements the TokenGenerator gRPC service with the use cases of the HTTP API.
+type Server struct {
+	tokengenv1.UnimplementedTokenGeneratorServer
+
+	generation api.RequestTokenGenerationUseCase
+	statuses   api.RequestStatusUseCase
+	maxWait    time.Duration
+	revocation TokenRevocationUseCase
+
+	authenticators []authx.Authenticator
+}
+
+type Option func(*Server)
+
+// WithAuthentication requires the calls to carry credentials recognized by one of the
+// authenticators.
+func WithAuthentication(authenticators ...authx.Authenticator) Option {
+	return func(s *Server) {
+		s.authenticators = append(s.authenticators, authenticators...)
+	}
+}
+
+// WithRequestStatus implements GetRequest and WatchRequest, and lets RequestToken wait
+// up to maxWait for the token when the call asks to, see RequestToken.
+func WithRequestStatus(uc api.RequestStatusUseCase, maxWait time.Duration) Option {
+	return func(s *Server) {
+		s.statuses = uc
+		s.maxWait = maxWait
+	}
+}
+
+// WithTokenRevocation implements RevokeToken.
+func WithTokenRevocation(uc TokenRevocationUseCase) Option {
+	return func(s *Server) {
+		s.revocation = uc
+	}
+}
+
+func New(generation api.RequestTokenGenerationUseCase, opts ...Option) *Server {
+	server := Server{generation: generation}
+	for _, opt := range opts {
+		opt(&server)
+	}
+
+	return &server
+}
+
+// Register registers the service on registrar. Methods whose use case is not
+// configured fail with codes.Unimplemented.
+func (s *Server) Register(registrar grpc.ServiceRegistrar) {
+	tokengenv1.RegisterTokenGeneratorServer(registrar, s)
+}
+
+// ServerOptions returns the interceptors authenticating the calls to the service. The
+// other services of the server, such as health checking, are left unauthenticated.
+func (s *Server) ServerOptions() []grpc.ServerOption {
+	if len(s.authenticators) == 0 {
+		return nil
+	}
+
+	unary := authx.UnaryServerInterceptor(s.authenticators...)
+	stream := authx.StreamServerInterceptor(s.authenticators...)
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if !isServiceMethod(info.FullMethod) {
+				return handler(ctx, req)
+			}
+			return unary(ctx, req, info, handler)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if !isServiceMethod(info.FullMethod) {
+				return handler(srv, ss)
+			}
+			return stream(srv, ss, info, handler)
+		}),
+	}
+}
+
+func isServiceMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+tokengenv1.TokenGenerator_ServiceDesc.ServiceName+"/")
+}
+
+// RequestToken queues the token generation request. When the call carries a
+// prefer: wait=<seconds> metadata, like the Prefer header of the HTTP API, it waits up
+// to maxWait for the request to complete, and returns the token issued in time, which
+// is then taken.
+func (s *Server) RequestToken(ctx context.Context, in *tokengenv1.RequestTokenRequest) (*tokengenv1.RequestStatus, error) {
+	if err := s.requireScope(ctx, model.ScopeRequestTokens); err != nil {
+		return nil, err
+	}
+
+	request, err := tokenGenerationRequest(in)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	requestStatus, err := s.generation.RequestTokenGeneration(ctx, request)
+	var deniedErr *model.AccessDeniedError
+	if errors.As(err, &deniedErr) {
+		log.Ctx(ctx).Warn().Str("project_id", request.ProjectID).Str("reason", deniedErr.Decision.Reason).Msg("Token generation request denied")
+		return nil, status.Error(codes.PermissionDenied, deniedErr.Decision.Reason)
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("project_id", request.ProjectID).Msg("Failed to queue the token generation request")
+		return nil, status.Error(codes.Internal, "failed to queue the request")
+	}
+
+	log.Ctx(ctx).Info().Str("project_id", request.ProjectID).Str("request_id", requestStatus.ID).Msg("Token generation request sent")
+
+	if wait := preferredWait(ctx); wait > 0 && s.statuses != nil && s.maxWait > 0 {
+		wait = min(wait, s.maxWait)
+		if err := grpc.SetHeader(ctx, metadata.Pairs("preference-applied", "wait="+strconv.Itoa(int(wait.Seconds())))); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("Failed to set the preference-applied header")
+		}
+
+		requestStatus, err = s.statuses.WaitForRequest(ctx, requestStatus.ID, wait)
+		if err != nil && ctx.Err() == nil {
+			log.Ctx(ctx).Error().Err(err).Str("request_id", requestStatus.ID).Msg("Failed to wait for the token generation request")
+		}
+
+		if requestStatus.State == model.RequestStateIssued {
+			// The status is returned without the token when it cannot be taken, the
+			// caller may still retrieve it.
+			if delivered, err := s.statuses.DeliverToken(ctx, requestStatus); err != nil {
+				log.Ctx(ctx).Error().Err(err).Str("request_id", requestStatus.ID).Msg("Failed to take the issued token")
+			} else {
+				requestStatus = delivered
+			}
+		}
+	}
+
+	out := newRequestStatus(requestStatus)
+	out.Token = requestStatus.Token
+	return out, nil
+}
+
+// preferredWait returns the wait preference of the call, in seconds. Preferences that
+// cannot be parsed are ignored, as per RFC 7240.
+func preferredWait(ctx context.Context) time.Duration {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("prefer") {
+		for _, preference := range strings.Split(value, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(preference), "=")
+			if !strings.EqualFold(name, "wait") {
+				continue
+			}
+
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil || seconds < 0 {
+				return 0
+			}
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	return 0
+}
+
+func (s *Server) GetRequest(ctx context.Context, in *tokengenv1.GetRequestRequest) (*tokengenv1.RequestStatus, error) {
+	if s.statuses == nil {
+		return s.UnimplementedTokenGeneratorServer.GetRequest(ctx, in)
+	}
+	if err := s.requireScope(ctx, model.ScopeRequestTokens); err != nil {
+		return nil, err
+	}
+
+	requestStatus, err := s.ownedRequestStatus(ctx, in.GetRequestId())
+	if err != nil {
+		return nil, err
+	}
+
+	return newRequestStatus(requestStatus), nil
+}
+
+// WatchRequest streams the events of the request, numbered from 1, until it completes
+// or the client cancels the call.
+func (s *Server) WatchRequest(in *tokengenv1.WatchRequestRequest, stream grpc.ServerStreamingServer[tokengenv1.RequestEvent]) error {
+	if s.statuses == nil {
+		return s.UnimplementedTokenGeneratorServer.WatchRequest(in, stream)
+	}
+
+	ctx := stream.Context()
+	if err := s.requireScope(ctx, model.ScopeRequestTokens); err != nil {
+		return err
+	}
+
+	id := in.GetRequestId()
+	if _, err := s.ownedRequestStatus(ctx, id); err != nil {
+		return err
+	}
+
+	statuses, err := s.statuses.WatchRequest(ctx, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("Failed to watch request status")
+		return status.Error(codes.Internal, "failed to watch the request status")
+	}
+
+	sent := int(in.GetAfterSequence())
+	for requestStatus := range statuses {
+		for ; sent < len(requestStatus.Events); sent++ {
+			if err := stream.Send(newRequestEvent(sent+1, requestStatus.Events[sent])); err != nil {
+				return err
+			}
+		}
+	}
+
+	return status.FromContextError(ctx.Err()).Err()
+}
+
+// RevokeToken revokes the token of a request. Like its status, a request is only
+// visible to its owner and administrators.
+func (s *Server) RevokeToken(ctx context.Context, in *tokengenv1.RevokeTokenRequest) (*tokengenv1.RequestStatus, error) {
+	if s.revocation == nil || s.statuses == nil {
+		return s.UnimplementedTokenGeneratorServer.RevokeToken(ctx, in)
+	}
+	if err := s.requireScope(ctx, model.ScopeRequestTokens); err != nil {
+		return nil, err
+	}
+
+	id := in.GetRequestId()
+	if _, err := s.ownedRequestStatus(ctx, id); err != nil {
+		return nil, err
+	}
+
+	requestStatus, err := s.revocation.RevokeToken(ctx, id)
+	if errors.Is(err, model.ErrTokenNotRevocable) {
+		return nil, status.Error(codes.FailedPrecondition, "request "+id+" has no token to revoke")
+	}
+	if errors.Is(err, model.ErrRequestNotFound) {
+		return nil, status.Error(codes.NotFound, "request "+id+" not found")
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("Failed to revoke token")
+		return nil, status.Error(codes.Internal, "failed to revoke the token")
+	}
+
+	log.Ctx(ctx).Info().Str("project_id", requestStatus.ProjectID).Str("request_id", id).Msg("Token revoked")
+
+	return newRequestStatus(requestStatus), nil
+}
+
+// requireScope enforces scope only when authentication is enabled.
+func (s *Server) requireScope(ctx context.Context, scope string) error {
+	if len(s.authenticators) == 0 {
+		return nil
+	}
+
+	return authx.CheckScope(ctx, scope)
+}
+
+// ownedRequestStatus returns the status of a request the caller may read. Requests of
+// other principals are reported as not found, so that IDs cannot be probed.
+func (s *Server) ownedRequestStatus(ctx context.Context, id string) (model.RequestStatus, error) {
+	requestStatus, err := s.statuses.GetRequestStatus(ctx, id)
+	if errors.Is(err, model.ErrRequestNotFound) || (err == nil && !canReadOwned(ctx, requestStatus.Owner)) {
+		return model.RequestStatus{}, status.Error(codes.NotFound, "request "+id+" not found")
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("Failed to get request status")
+		return model.RequestStatus{}, status.Error(codes.Internal, "failed to get the request status")
+	}
+
+	return requestStatus, nil
+}
+
+func canReadOwned(ctx context.Context, owner string) bool {
+	principal, ok := model.PrincipalFromContext(ctx)
+	if !ok {
+		return true
+	}
+
+	return principal.HasScope(model.ScopeAdmin) || owner == principal.Owner()
+}
+
+// tokenGenerationRequest validates the input and converts it to a domain request.
+func tokenGenerationRequest(in *tokengenv1.RequestTokenRequest) (model.TokenGenerationRequest, error) {
+	if in.GetProjectId() == "" {
+		return model.TokenGenerationRequest{}, errors.New("missing required field: project_id")
+	}
+	if !model.ValidProjectKey(in.GetProjectId()) {
+		return model.TokenGenerationRequest{}, fmt.Errorf("invalid project_id: %q is not a valid project key", in.GetProjectId())
+	}
+
+	request := model.TokenGenerationRequest{ProjectID: in.GetProjectId()}
+
+	switch in.GetTokenType() {
+	case tokengenv1.TokenType_TOKEN_TYPE_UNSPECIFIED, tokengenv1.TokenType_TOKEN_TYPE_PROJECT_ANALYSIS:
+		request.TokenType = model.TokenTypeProjectAnalysis
+	case tokengenv1.TokenType_TOKEN_TYPE_GLOBAL_ANALYSIS:
+		request.TokenType = model.TokenTypeGlobalAnalysis
+	default:
+		return model.TokenGenerationRequest{}, fmt.Errorf("invalid token_type: unknown token type %d", in.GetTokenType())
+	}
+
+	if in.GetTtl() != nil {
+		if err := in.GetTtl().CheckValid(); err != nil || in.GetTtl().AsDuration() <= 0 {
+			return model.TokenGenerationRequest{}, errors.New("invalid ttl: not a positive duration")
+		}
+		request.TTL = in.GetTtl().AsDuration()
+	}
+
+	return request, nil
+}
+
+var (
+	tokenTypes = map[model.TokenType]tokengenv1.TokenType{
+		model.TokenTypeProjectAnalysis: tokengenv1.TokenType_TOKEN_TYPE_PROJECT_ANALYSIS,
+		model.TokenTypeGlobalAnalysis:  tokengenv1.TokenType_TOKEN_TYPE_GLOBAL_ANALYSIS,
+	}
+	requestStates = map[model.RequestState]tokengenv1.RequestState{
+		model.RequestStateQueued:     tokengenv1.RequestState_REQUEST_STATE_QUEUED,
+		model.RequestStateProcessing: tokengenv1.RequestState_REQUEST_STATE_PROCESSING,
+		model.RequestStateIssued:     tokengenv1.RequestState_REQUEST_STATE_ISSUED,
+		model.RequestStateFailed:     tokengenv1.RequestState_REQUEST_STATE_FAILED,
+		model.RequestStateCanceled:   tokengenv1.RequestState_REQUEST_STATE_CANCELED,
+	}
+)
+
+func newRequestStatus(s model.RequestStatus) *tokengenv1.RequestStatus {
+	return &tokengenv1.RequestStatus{
+		RequestId:     s.ID,
+		State:         requestStates[s.State],
+		ProjectId:     s.ProjectID,
+		TokenType:     tokenTypes[s.TokenType],
+		CreateTime:    timestamp(&s.CreatedAt),
+		UpdateTime:    timestamp(&s.UpdatedAt),
+		ExpireTime:    timestamp(s.ExpiresAt),
+		FailureReason: string(s.FailureReason),
+		RevokeTime:    timestamp(s.RevokedAt),
+	}
+}
+
+func newRequestEvent(sequence int, event model.RequestEvent) *tokengenv1.RequestEvent {
+	return &tokengenv1.RequestEvent{
+		Sequence:      uint32(sequence),
+		RequestId:     event.RequestID,
+		Type:          string(event.Type),
+		Time:          timestamp(&event.Time),
+		Attempt:       int32(event.Attempt),
+		ExpireTime:    timestamp(event.ExpiresAt),
+		FailureReason: string(event.FailureReason),
+	}
+}
+
+// timestamp converts t, leaving unset times unset.
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,501 @@
+package grpcapi_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	apimocks "github.com/werbersondev/token-generator-test/cmd/httpservice/api/mocks"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/grpcapi"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/grpcapi/mocks"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/grpcapi/tokengenv1"
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/grpcx"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+type grpcTest struct {
+	client       tokengenv1.TokenGeneratorClient
+	adminKey     string
+	requesterKey string
+	requester    model.Principal
+}
+
+// setupGRPCTest serves the service over an in-memory connection, authenticating the
+// calls with API keys.
+func setupGRPCTest(t *testing.T, generation *apimocks.RequestTokenGenerationUseCaseMock, opts ...grpcapi.Option) *grpcTest {
+	apiKeys := authx.NewAPIKeys(authx.NewStateAPIKeyStore(statestore.NewMemory()))
+	adminKey, _, err := apiKeys.CreateAPIKey(context.Background(), "admin", []string{model.ScopeAdmin})
+	require.NoError(t, err)
+	requesterKey, requester, err := apiKeys.CreateAPIKey(context.Background(), "ci", []string{model.ScopeRequestTokens})
+	require.NoError(t, err)
+
+	service := grpcapi.New(generation, append(opts, grpcapi.WithAuthentication(apiKeys))...)
+	server := grpcx.NewServer("bufconn", service.ServerOptions()...)
+	service.Register(server.Server)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return &grpcTest{
+		client:       tokengenv1.NewTokenGeneratorClient(conn),
+		adminKey:     adminKey,
+		requesterKey: requesterKey,
+		requester:    model.Principal{Subject: "apikey:" + requester.ID, Method: model.AuthMethodAPIKey},
+	}
+}
+
+func withAPIKey(apiKey string) context.Context {
+	if apiKey == "" {
+		return context.Background()
+	}
+
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", apiKey)
+}
+
+func TestServer_RequestToken(t *testing.T) {
+	createdAt := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		apiKey          func(*grpcTest) string
+		input           *tokengenv1.RequestTokenRequest
+		useCaseErr      error
+		expectedCode    codes.Code
+		expectedRequest model.TokenGenerationRequest
+	}{
+		{
+			name:            "Queued",
+			apiKey:          func(g *grpcTest) string { return g.requesterKey },
+			input:           &tokengenv1.RequestTokenRequest{ProjectId: "app"},
+			expectedCode:    codes.OK,
+			expectedRequest: model.TokenGenerationRequest{ProjectID: "app", TokenType: model.TokenTypeProjectAnalysis},
+		},
+		{
+			name:   "Global Token With TTL",
+			apiKey: func(g *grpcTest) string { return g.requesterKey },
+			input: &tokengenv1.RequestTokenRequest{
+				ProjectId: "app",
+				TokenType: tokengenv1.TokenType_TOKEN_TYPE_GLOBAL_ANALYSIS,
+				Ttl:       durationpb.New(48 * time.Hour),
+			},
+			expectedCode:    codes.OK,
+			expectedRequest: model.TokenGenerationRequest{ProjectID: "app", TokenType: model.TokenTypeGlobalAnalysis, TTL: 48 * time.Hour},
+		},
+		{
+			name:         "Missing Credentials",
+			apiKey:       func(g *grpcTest) string { return "" },
+			input:        &tokengenv1.RequestTokenRequest{ProjectId: "app"},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "Invalid Credentials",
+			apiKey:       func(g *grpcTest) string { return "tg_invalid" },
+			input:        &tokengenv1.RequestTokenRequest{ProjectId: "app"},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "Insufficient Scope",
+			apiKey:       func(g *grpcTest) string { return g.adminKey },
+			input:        &tokengenv1.RequestTokenRequest{ProjectId: "app"},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "Missing Project",
+			apiKey:       func(g *grpcTest) string { return g.requesterKey },
+			input:        &tokengenv1.RequestTokenRequest{},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "Invalid Project Key",
+			apiKey:       func(g *grpcTest) string { return g.requesterKey },
+			input:        &tokengenv1.RequestTokenRequest{ProjectId: "my project"},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "Negative TTL",
+			apiKey:       func(g *grpcTest) string { return g.requesterKey },
+			input:        &tokengenv1.RequestTokenRequest{ProjectId: "app", Ttl: durationpb.New(-time.Hour)},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "Access Denied",
+			apiKey:       func(g *grpcTest) string { return g.requesterKey },
+			input:        &tokengenv1.RequestTokenRequest{ProjectId: "app"},
+			useCaseErr:   &model.AccessDeniedError{Decision: model.AccessDecision{Reason: "no rule allows project app"}},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "Publish Error",
+			apiKey:       func(g *grpcTest) string { return g.requesterKey },
+			input:        &tokengenv1.RequestTokenRequest{ProjectId: "app"},
+			useCaseErr:   errors.New("publish failed"),
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &apimocks.RequestTokenGenerationUseCaseMock{
+				RequestTokenGenerationFunc: func(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
+					if tt.useCaseErr != nil {
+						return model.RequestStatus{}, tt.useCaseErr
+					}
+					return model.RequestStatus{ID: "req-1", ProjectID: request.ProjectID, TokenType: request.TokenType, State: model.RequestStateQueued, CreatedAt: createdAt, UpdatedAt: createdAt}, nil
+				},
+			}
+			g := setupGRPCTest(t, useCase)
+
+			output, err := g.client.RequestToken(withAPIKey(tt.apiKey(g)), tt.input)
+
+			require.Equal(t, tt.expectedCode, status.Code(err), err)
+			if tt.expectedCode != codes.OK {
+				return
+			}
+
+			assert.Equal(t, "req-1", output.GetRequestId())
+			assert.Equal(t, tokengenv1.RequestState_REQUEST_STATE_QUEUED, output.GetState())
+			assert.Equal(t, createdAt, output.GetCreateTime().AsTime())
+			assert.Nil(t, output.GetExpireTime())
+
+			calls := useCase.RequestTokenGenerationCalls()
+			require.Len(t, calls, 1)
+			assert.Equal(t, tt.expectedRequest, calls[0].Request)
+			principal, ok := model.PrincipalFromContext(calls[0].Ctx)
+			require.True(t, ok)
+			assert.Equal(t, g.requester.Owner(), principal.Owner())
+		})
+	}
+}
+
+func TestServer_RequestToken_Wait(t *testing.T) {
+	queued := model.RequestStatus{ID: "req-1", ProjectID: "app", State: model.RequestStateQueued}
+	issued := model.RequestStatus{ID: "req-1", ProjectID: "app", State: model.RequestStateIssued}
+
+	tests := []struct {
+		name                      string
+		prefer                    string
+		status                    model.RequestStatus
+		expectedWait              time.Duration
+		expectedState             tokengenv1.RequestState
+		expectedToken             string
+		expectedPreferenceApplied []string
+	}{
+		{
+			name:          "Without Wait",
+			expectedState: tokengenv1.RequestState_REQUEST_STATE_QUEUED,
+		},
+		{
+			name:                      "Issued Within Wait",
+			prefer:                    "wait=5",
+			status:                    issued,
+			expectedWait:              5 * time.Second,
+			expectedState:             tokengenv1.RequestState_REQUEST_STATE_ISSUED,
+			expectedToken:             "sqp_token",
+			expectedPreferenceApplied: []string{"wait=5"},
+		},
+		{
+			name:                      "Still Queued After Capped Wait",
+			prefer:                    "respond-async, wait=30",
+			status:                    queued,
+			expectedWait:              10 * time.Second,
+			expectedState:             tokengenv1.RequestState_REQUEST_STATE_QUEUED,
+			expectedPreferenceApplied: []string{"wait=10"},
+		},
+		{
+			name:          "Invalid Wait Ignored",
+			prefer:        "wait=soon",
+			expectedState: tokengenv1.RequestState_REQUEST_STATE_QUEUED,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &apimocks.RequestTokenGenerationUseCaseMock{
+				RequestTokenGenerationFunc: func(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
+					return queued, nil
+				},
+			}
+			statuses := &apimocks.RequestStatusUseCaseMock{
+				WaitForRequestFunc: func(ctx context.Context, id string, timeout time.Duration) (model.RequestStatus, error) {
+					assert.Equal(t, queued.ID, id)
+					assert.Equal(t, tt.expectedWait, timeout)
+					return tt.status, nil
+				},
+				DeliverTokenFunc: func(ctx context.Context, status model.RequestStatus) (model.RequestStatus, error) {
+					status.Token = "sqp_token"
+					return status, nil
+				},
+			}
+			g := setupGRPCTest(t, useCase, grpcapi.WithRequestStatus(statuses, 10*time.Second))
+
+			ctx := withAPIKey(g.requesterKey)
+			if tt.prefer != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "prefer", tt.prefer)
+			}
+			var header metadata.MD
+			output, err := g.client.RequestToken(ctx, &tokengenv1.RequestTokenRequest{ProjectId: "app"}, grpc.Header(&header))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedState, output.GetState())
+			assert.Equal(t, tt.expectedToken, output.GetToken())
+			assert.Equal(t, tt.expectedPreferenceApplied, header.Get("preference-applied"))
+			if tt.expectedWait == 0 {
+				assert.Empty(t, statuses.WaitForRequestCalls())
+			}
+			if tt.expectedToken == "" {
+				assert.Empty(t, statuses.DeliverTokenCalls())
+			}
+		})
+	}
+}
+
+func TestServer_GetRequest(t *testing.T) {
+	expiresAt := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		apiKey        func(*grpcTest) string
+		owner         func(*grpcTest) string
+		statusErr     error
+		withoutStatus bool
+		expectedCode  codes.Code
+	}{
+		{
+			name:         "Owner",
+			apiKey:       func(g *grpcTest) string { return g.requesterKey },
+			owner:        func(g *grpcTest) string { return g.requester.Owner() },
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Insufficient Scope",
+			apiKey:       func(g *grpcTest) string { return g.adminKey },
+			owner:        func(g *grpcTest) string { return g.requester.Owner() },
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "Other Principal",
+			apiKey:       func(g *grpcTest) string { return g.requesterKey },
+			owner:        func(g *grpcTest) string { return "apikey:other" },
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "Unknown Request",
+			apiKey:       func(g *grpcTest) string { return g.requesterKey },
+			owner:        func(g *grpcTest) string { return g.requester.Owner() },
+			statusErr:    model.ErrRequestNotFound,
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "Store Error",
+			apiKey:       func(g *grpcTest) string { return g.requesterKey },
+			owner:        func(g *grpcTest) string { return g.requester.Owner() },
+			statusErr:    errors.New("connection refused"),
+			expectedCode: codes.Internal,
+		},
+		{
+			name:          "Not Configured",
+			apiKey:        func(g *grpcTest) string { return g.requesterKey },
+			owner:         func(g *grpcTest) string { return g.requester.Owner() },
+			withoutStatus: true,
+			expectedCode:  codes.Unimplemented,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g *grpcTest
+			statuses := &apimocks.RequestStatusUseCaseMock{
+				GetRequestStatusFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					if tt.statusErr != nil {
+						return model.RequestStatus{}, tt.statusErr
+					}
+					return model.RequestStatus{ID: id, ProjectID: "app", State: model.RequestStateIssued, Owner: tt.owner(g), Token: "squ_token", ExpiresAt: &expiresAt}, nil
+				},
+			}
+
+			var opts []grpcapi.Option
+			if !tt.withoutStatus {
+				opts = append(opts, grpcapi.WithRequestStatus(statuses, 10*time.Second))
+			}
+			g = setupGRPCTest(t, &apimocks.RequestTokenGenerationUseCaseMock{}, opts...)
+
+			output, err := g.client.GetRequest(withAPIKey(tt.apiKey(g)), &tokengenv1.GetRequestRequest{RequestId: "req-1"})
+
+			require.Equal(t, tt.expectedCode, status.Code(err), err)
+			if tt.expectedCode != codes.OK {
+				return
+			}
+
+			assert.Equal(t, "req-1", output.GetRequestId())
+			assert.Equal(t, tokengenv1.RequestState_REQUEST_STATE_ISSUED, output.GetState())
+			assert.Empty(t, output.GetToken(), "the token is only returned once retrieved")
+			assert.Equal(t, expiresAt, output.GetExpireTime().AsTime())
+		})
+	}
+}
+
+func TestServer_WatchRequest(t *testing.T) {
+	start := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	events := []model.RequestEvent{
+		{RequestID: "req-1", Type: model.RequestEventQueued, Time: start},
+		{RequestID: "req-1", Type: model.RequestEventPickedUp, Time: start.Add(time.Second)},
+		{RequestID: "req-1", Type: model.RequestEventRetrying, Time: start.Add(2 * time.Second), Attempt: 1},
+		{RequestID: "req-1", Type: model.RequestEventFailed, Time: start.Add(3 * time.Second), FailureReason: model.FailureReasonProviderError},
+	}
+
+	tests := []struct {
+		name              string
+		afterSequence     uint32
+		expectedSequences []uint32
+	}{
+		{name: "From Start", expectedSequences: []uint32{1, 2, 3, 4}},
+		{name: "Resumed", afterSequence: 2, expectedSequences: []uint32{3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g *grpcTest
+			statuses := &apimocks.RequestStatusUseCaseMock{
+				GetRequestStatusFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					return model.RequestStatus{ID: id, Owner: g.requester.Owner()}, nil
+				},
+				WatchRequestFunc: func(ctx context.Context, id string) (<-chan model.RequestStatus, error) {
+					updates := make(chan model.RequestStatus, 2)
+					updates <- model.RequestStatus{ID: id, State: model.RequestStateProcessing, Events: events[:2]}
+					updates <- model.RequestStatus{ID: id, State: model.RequestStateFailed, Events: events}
+					close(updates)
+					return updates, nil
+				},
+			}
+			g = setupGRPCTest(t, &apimocks.RequestTokenGenerationUseCaseMock{}, grpcapi.WithRequestStatus(statuses, 10*time.Second))
+
+			stream, err := g.client.WatchRequest(withAPIKey(g.requesterKey), &tokengenv1.WatchRequestRequest{RequestId: "req-1", AfterSequence: tt.afterSequence})
+			require.NoError(t, err)
+
+			var received []*tokengenv1.RequestEvent
+			for {
+				event, err := stream.Recv()
+				if err != nil {
+					require.ErrorIs(t, err, io.EOF)
+					break
+				}
+				received = append(received, event)
+			}
+
+			require.Len(t, received, len(tt.expectedSequences))
+			for i, event := range received {
+				assert.Equal(t, tt.expectedSequences[i], event.GetSequence())
+				assert.Equal(t, string(events[event.GetSequence()-1].Type), event.GetType())
+			}
+			last := received[len(received)-1]
+			assert.Equal(t, string(model.FailureReasonProviderError), last.GetFailureReason())
+		})
+	}
+}
+
+func TestServer_RevokeToken(t *testing.T) {
+	revokedAt := time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		owner           func(*grpcTest) string
+		revokeErr       error
+		withoutRevoking bool
+		expectedCode    codes.Code
+		expectedRevokes int
+	}{
+		{
+			name:            "Revoked",
+			owner:           func(g *grpcTest) string { return g.requester.Owner() },
+			expectedCode:    codes.OK,
+			expectedRevokes: 1,
+		},
+		{
+			name:         "Other Principal",
+			owner:        func(g *grpcTest) string { return "apikey:other" },
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:            "Not Revocable",
+			owner:           func(g *grpcTest) string { return g.requester.Owner() },
+			revokeErr:       model.ErrTokenNotRevocable,
+			expectedCode:    codes.FailedPrecondition,
+			expectedRevokes: 1,
+		},
+		{
+			name:            "Provider Error",
+			owner:           func(g *grpcTest) string { return g.requester.Owner() },
+			revokeErr:       errors.New("connection refused"),
+			expectedCode:    codes.Internal,
+			expectedRevokes: 1,
+		},
+		{
+			name:            "Not Configured",
+			owner:           func(g *grpcTest) string { return g.requester.Owner() },
+			withoutRevoking: true,
+			expectedCode:    codes.Unimplemented,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var g *grpcTest
+			statuses := &apimocks.RequestStatusUseCaseMock{
+				GetRequestStatusFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					return model.RequestStatus{ID: id, State: model.RequestStateIssued, Owner: tt.owner(g)}, nil
+				},
+			}
+			revocation := &mocks.TokenRevocationUseCaseMock{
+				RevokeTokenFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					if tt.revokeErr != nil {
+						return model.RequestStatus{}, tt.revokeErr
+					}
+					return model.RequestStatus{ID: id, State: model.RequestStateIssued, RevokedAt: &revokedAt}, nil
+				},
+			}
+
+			opts := []grpcapi.Option{grpcapi.WithRequestStatus(statuses, 10*time.Second)}
+			if !tt.withoutRevoking {
+				opts = append(opts, grpcapi.WithTokenRevocation(revocation))
+			}
+			g = setupGRPCTest(t, &apimocks.RequestTokenGenerationUseCaseMock{}, opts...)
+
+			output, err := g.client.RevokeToken(withAPIKey(g.requesterKey), &tokengenv1.RevokeTokenRequest{RequestId: "req-1"})
+
+			require.Equal(t, tt.expectedCode, status.Code(err), err)
+			require.Len(t, revocation.RevokeTokenCalls(), tt.expectedRevokes)
+			if tt.expectedCode != codes.OK {
+				return
+			}
+
+			assert.Equal(t, "req-1", revocation.RevokeTokenCalls()[0].Id)
+			assert.Equal(t, revokedAt, output.GetRevokeTime().AsTime())
+			assert.Empty(t, output.GetToken())
+		})
+	}
+}
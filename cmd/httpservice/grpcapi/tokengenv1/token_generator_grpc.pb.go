@@ -0,0 +1,269 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v4.25.2
+// source: tokengen/v1/token_generator.proto
+
+package tokengenv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TokenGenerator_RequestToken_FullMethodName = "/tokengen.v1.TokenGenerator/RequestToken"
+	TokenGenerator_GetRequest_FullMethodName   = "/tokengen.v1.TokenGenerator/GetRequest"
+	TokenGenerator_WatchRequest_FullMethodName = "/tokengen.v1.TokenGenerator/WatchRequest"
+	TokenGenerator_RevokeToken_FullMethodName  = "/tokengen.v1.TokenGenerator/RevokeToken"
+)
+
+// TokenGeneratorClient is the client API for TokenGenerator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// TokenGenerator issues Sonar analysis tokens, mirroring the /v1 HTTP API.
+//
+// Calls are authenticated with the credentials accepted by the HTTP API, sent as
+// authorization or x-api-key metadata, and require the same scopes.
+type TokenGeneratorClient interface {
+	// RequestToken queues the generation of a token and returns the status of the
+	// request, to be followed with GetRequest or WatchRequest. Calls with a
+	// prefer: wait=<seconds> metadata wait for the token, like over HTTP.
+	RequestToken(ctx context.Context, in *RequestTokenRequest, opts ...grpc.CallOption) (*RequestStatus, error)
+	// GetRequest returns the status of a token generation request, without the token,
+	// which is retrieved once with POST /v1/requests/{id}/token. Requests are only
+	// visible to their owner and administrators.
+	GetRequest(ctx context.Context, in *GetRequestRequest, opts ...grpc.CallOption) (*RequestStatus, error)
+	// WatchRequest streams the events of a token generation request until it completes.
+	// The token itself is not streamed, it is retrieved once with
+	// POST /v1/requests/{id}/token.
+	WatchRequest(ctx context.Context, in *WatchRequestRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RequestEvent], error)
+	// RevokeToken revokes the token issued for a request on Sonar.
+	RevokeToken(ctx context.Context, in *RevokeTokenRequest, opts ...grpc.CallOption) (*RequestStatus, error)
+}
+
+type tokenGeneratorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTokenGeneratorClient(cc grpc.ClientConnInterface) TokenGeneratorClient {
+	return &tokenGeneratorClient{cc}
+}
+
+func (c *tokenGeneratorClient) RequestToken(ctx context.Context, in *RequestTokenRequest, opts ...grpc.CallOption) (*RequestStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RequestStatus)
+	err := c.cc.Invoke(ctx, TokenGenerator_RequestToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenGeneratorClient) GetRequest(ctx context.Context, in *GetRequestRequest, opts ...grpc.CallOption) (*RequestStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RequestStatus)
+	err := c.cc.Invoke(ctx, TokenGenerator_GetRequest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenGeneratorClient) WatchRequest(ctx context.Context, in *WatchRequestRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RequestEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TokenGenerator_ServiceDesc.Streams[0], TokenGenerator_WatchRequest_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequestRequest, RequestEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TokenGenerator_WatchRequestClient = grpc.ServerStreamingClient[RequestEvent]
+
+func (c *tokenGeneratorClient) RevokeToken(ctx context.Context, in *RevokeTokenRequest, opts ...grpc.CallOption) (*RequestStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RequestStatus)
+	err := c.cc.Invoke(ctx, TokenGenerator_RevokeToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TokenGeneratorServer is the server API for TokenGenerator service.
+// All implementations must embed UnimplementedTokenGeneratorServer
+// for forward compatibility.
+//
+// TokenGenerator issues Sonar analysis tokens, mirroring the /v1 HTTP API.
+//
+// Calls are authenticated with the credentials accepted by the HTTP API, sent as
+// authorization or x-api-key metadata, and require the same scopes.
+type TokenGeneratorServer interface {
+	// RequestToken queues the generation of a token and returns the status of the
+	// request, to be followed with GetRequest or WatchRequest. Calls with a
+	// prefer: wait=<seconds> metadata wait for the token, like over HTTP.
+	RequestToken(context.Context, *RequestTokenRequest) (*RequestStatus, error)
+	// GetRequest returns the status of a token generation request, without the token,
+	// which is retrieved once with POST /v1/requests/{id}/token. Requests are only
+	// visible to their owner and administrators.
+	GetRequest(context.Context, *GetRequestRequest) (*RequestStatus, error)
+	// WatchRequest streams the events of a token generation request until it completes.
+	// The token itself is not streamed, it is retrieved once with
+	// POST /v1/requests/{id}/token.
+	WatchRequest(*WatchRequestRequest, grpc.ServerStreamingServer[RequestEvent]) error
+	// RevokeToken revokes the token issued for a request on Sonar.
+	RevokeToken(context.Context, *RevokeTokenRequest) (*RequestStatus, error)
+	mustEmbedUnimplementedTokenGeneratorServer()
+}
+
+// UnimplementedTokenGeneratorServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTokenGeneratorServer struct{}
+
+func (UnimplementedTokenGeneratorServer) RequestToken(context.Context, *RequestTokenRequest) (*RequestStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestToken not implemented")
+}
+func (UnimplementedTokenGeneratorServer) GetRequest(context.Context, *GetRequestRequest) (*RequestStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRequest not implemented")
+}
+func (UnimplementedTokenGeneratorServer) WatchRequest(*WatchRequestRequest, grpc.ServerStreamingServer[RequestEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchRequest not implemented")
+}
+func (UnimplementedTokenGeneratorServer) RevokeToken(context.Context, *RevokeTokenRequest) (*RequestStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeToken not implemented")
+}
+func (UnimplementedTokenGeneratorServer) mustEmbedUnimplementedTokenGeneratorServer() {}
+func (UnimplementedTokenGeneratorServer) testEmbeddedByValue()                        {}
+
+// UnsafeTokenGeneratorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TokenGeneratorServer will
+// result in compilation errors.
+type UnsafeTokenGeneratorServer interface {
+	mustEmbedUnimplementedTokenGeneratorServer()
+}
+
+func RegisterTokenGeneratorServer(s grpc.ServiceRegistrar, srv TokenGeneratorServer) {
+	// If the following call pancis, it indicates UnimplementedTokenGeneratorServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TokenGenerator_ServiceDesc, srv)
+}
+
+func _TokenGenerator_RequestToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenGeneratorServer).RequestToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TokenGenerator_RequestToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenGeneratorServer).RequestToken(ctx, req.(*RequestTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TokenGenerator_GetRequest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenGeneratorServer).GetRequest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TokenGenerator_GetRequest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenGeneratorServer).GetRequest(ctx, req.(*GetRequestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TokenGenerator_WatchRequest_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequestRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TokenGeneratorServer).WatchRequest(m, &grpc.GenericServerStream[WatchRequestRequest, RequestEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TokenGenerator_WatchRequestServer = grpc.ServerStreamingServer[RequestEvent]
+
+func _TokenGenerator_RevokeToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenGeneratorServer).RevokeToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TokenGenerator_RevokeToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenGeneratorServer).RevokeToken(ctx, req.(*RevokeTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TokenGenerator_ServiceDesc is the grpc.ServiceDesc for TokenGenerator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TokenGenerator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tokengen.v1.TokenGenerator",
+	HandlerType: (*TokenGeneratorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RequestToken",
+			Handler:    _TokenGenerator_RequestToken_Handler,
+		},
+		{
+			MethodName: "GetRequest",
+			Handler:    _TokenGenerator_GetRequest_Handler,
+		},
+		{
+			MethodName: "RevokeToken",
+			Handler:    _TokenGenerator_RevokeToken_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchRequest",
+			Handler:       _TokenGenerator_WatchRequest_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "tokengen/v1/token_generator.proto",
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"slices"
 	"time"
 
 	"cloud.google.com/go/pubsub"
@@ -13,23 +16,122 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gopkg.in/yaml.v3"
 
 	"github.com/werbersondev/token-generator-test/cmd/httpservice/api"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/consumer"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/grpcapi"
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/webui"
+	"github.com/werbersondev/token-generator-test/domain/model"
 	"github.com/werbersondev/token-generator-test/domain/service"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/correlation"
+	"github.com/werbersondev/token-generator-test/extensions/grpcx"
 	"github.com/werbersondev/token-generator-test/extensions/httpx"
 	"github.com/werbersondev/token-generator-test/extensions/loggerx"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
+	"github.com/werbersondev/token-generator-test/extensions/openapix"
 	"github.com/werbersondev/token-generator-test/extensions/pubsubx"
+	"github.com/werbersondev/token-generator-test/extensions/ratelimit"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+	"github.com/werbersondev/token-generator-test/extensions/tlsx"
+	"github.com/werbersondev/token-generator-test/extensions/tracex"
+	"github.com/werbersondev/token-generator-test/gateway/auditlog"
 	pubsubgw "github.com/werbersondev/token-generator-test/gateway/pubsub"
+	"github.com/werbersondev/token-generator-test/gateway/requeststore"
 )
 
 type config struct {
 	ServerAddr         string        `conf:"env:SERVER_ADDR,default:0.0.0.0:3000"`
 	ServerReadTimeout  time.Duration `conf:"env:SERVER_READ_TIMEOUT,default:30s"`
 	ServerWriteTimeout time.Duration `conf:"env:SERVER_WRITE_TIMEOUT,default:30s"`
+	ServerMaxBodyBytes int64         `conf:"env:SERVER_MAX_BODY_BYTES,default:65536"`
+	ServerTrustProxy   bool          `conf:"env:SERVER_TRUST_PROXY,default:false"`
+	ServerDrainDelay   time.Duration `conf:"env:SERVER_DRAIN_DELAY,default:5s"`
+
+	ServerShutdownTimeout time.Duration `conf:"env:SERVER_SHUTDOWN_TIMEOUT,default:5s"`
+	ServerHTTP2           bool          `conf:"env:SERVER_HTTP2,default:false"`
+	WebUIEnabled          bool          `conf:"env:WEB_UI_ENABLED,default:true"`
+
+	ServerTLSCertFile          string        `conf:"env:SERVER_TLS_CERT_FILE"`
+	ServerTLSKeyFile           string        `conf:"env:SERVER_TLS_KEY_FILE"`
+	ServerTLSMinVersion        string        `conf:"env:SERVER_TLS_MIN_VERSION,default:1.2"`
+	ServerTLSClientCAFile      string        `conf:"env:SERVER_TLS_CLIENT_CA_FILE"`
+	ServerTLSRequireClientCert bool          `conf:"env:SERVER_TLS_REQUIRE_CLIENT_CERT,default:false"`
+	ServerTLSReloadInterval    time.Duration `conf:"env:SERVER_TLS_RELOAD_INTERVAL,default:30s"`
+
+	GRPCAddr string `conf:"env:GRPC_ADDR,default:0.0.0.0:9090"`
+
+	ReadinessCheckTimeout time.Duration `conf:"env:READINESS_CHECK_TIMEOUT,default:2s"`
+	ReadinessCacheTTL     time.Duration `conf:"env:READINESS_CACHE_TTL,default:2s"`
+
+	RateLimitClient    string `conf:"env:RATE_LIMIT_CLIENT"`
+	RateLimitPrincipal string `conf:"env:RATE_LIMIT_PRINCIPAL"`
+	RateLimitProject   string `conf:"env:RATE_LIMIT_PROJECT"`
 
 	PubSubHost             string `conf:"env:PUBSUB_EMULATOR_HOST,required"`
 	ProjectID              string `conf:"env:GCP_PROJECT_ID,default:my_project_key"`
 	TokenGenerationTopicID string `conf:"env:GCP_TOKEN_GENERATOR_TOPIC,default:token_generation_topic"`
+	RequestEventsTopicID   string `conf:"env:GCP_TOKEN_EVENTS_TOPIC,default:token_generation_events_topic"`
+	RequestEventsSubPrefix string `conf:"env:GCP_TOKEN_EVENTS_SUBSCRIPTION_PREFIX,default:token_generation_events"`
+
+	StateStoreURL    string        `conf:"env:STATE_STORE_URL,default:memory://,mask"`
+	RequestStatusTTL time.Duration `conf:"env:REQUEST_STATUS_TTL,default:15m"`
+	SyncMaxWait      time.Duration `conf:"env:SYNC_MAX_WAIT,default:25s"`
+
+	AccessPolicyFile string `conf:"env:ACCESS_POLICY_FILE"`
+
+	ExchangeConfigFile           string        `conf:"env:EXCHANGE_CONFIG_FILE"`
+	SonarAPIAddress              string        `conf:"env:SONAR_API_ADDRESS,default:http://localhost:9000"`
+	SonarAPITimeout              time.Duration `conf:"env:SONAR_API_TIMEOUT,default:30s"`
+	SonarTLSCAFile               string        `conf:"env:SONAR_TLS_CA_FILE"`
+	SonarTLSCertFile             string        `conf:"env:SONAR_TLS_CERT_FILE"`
+	SonarTLSKeyFile              string        `conf:"env:SONAR_TLS_KEY_FILE"`
+	SonarTLSMinVersion           string        `conf:"env:SONAR_TLS_MIN_VERSION,default:1.2"`
+	SonarTLSReloadInterval       time.Duration `conf:"env:SONAR_TLS_RELOAD_INTERVAL,default:30s"`
+	SonarProxyURL                string        `conf:"env:SONAR_PROXY_URL"`
+	SonarNoProxy                 string        `conf:"env:SONAR_NO_PROXY"`
+	SonarAuthToken               string        `conf:"env:SONAR_AUTH_TOKEN,mask"`
+	SonarAuthTokenFile           string        `conf:"env:SONAR_AUTH_TOKEN_FILE"`
+	SonarAuthTokenReloadInterval time.Duration `conf:"env:SONAR_AUTH_TOKEN_RELOAD_INTERVAL,default:10s"`
+	SonarPermissionPolicy        string        `conf:"env:SONAR_PERMISSION_POLICY,default:check"`
+
+	AuthEnabled      bool   `conf:"env:AUTH_ENABLED,default:true"`
+	AuthAPIKeysStore string `conf:"env:AUTH_API_KEYS_STORE,default:file"`
+	AuthAPIKeysFile  string `conf:"env:AUTH_API_KEYS_FILE,default:api_keys.json"`
+
+	AuthJWTIssuer             string        `conf:"env:AUTH_JWT_ISSUER"`
+	AuthJWTAudience           string        `conf:"env:AUTH_JWT_AUDIENCE"`
+	AuthJWTJWKSURL            string        `conf:"env:AUTH_JWT_JWKS_URL"`
+	AuthJWTJWKSFile           string        `conf:"env:AUTH_JWT_JWKS_FILE"`
+	AuthJWTJWKSCacheTTL       time.Duration `conf:"env:AUTH_JWT_JWKS_CACHE_TTL,default:10m"`
+	AuthJWTJWKSReloadInterval time.Duration `conf:"env:AUTH_JWT_JWKS_RELOAD_INTERVAL,default:10s"`
+	AuthJWTLeeway             time.Duration `conf:"env:AUTH_JWT_LEEWAY,default:30s"`
+	AuthJWTSubjectClaim       string        `conf:"env:AUTH_JWT_SUBJECT_CLAIM,default:sub"`
+	AuthJWTNameClaim          string        `conf:"env:AUTH_JWT_NAME_CLAIM,default:name"`
+	AuthJWTGroupsClaim        string        `conf:"env:AUTH_JWT_GROUPS_CLAIM,default:groups"`
+	AuthJWTScopesClaim        string        `conf:"env:AUTH_JWT_SCOPES_CLAIM,default:scope"`
+	AuthJWTAttributeClaims    []string      `conf:"env:AUTH_JWT_ATTRIBUTE_CLAIMS"`
+	AuthJWTDefaultScopes      []string      `conf:"env:AUTH_JWT_DEFAULT_SCOPES,default:tokens:request"`
+
+	AuthClientCertIdentitiesFile string `conf:"env:AUTH_CLIENT_CERT_IDENTITIES_FILE"`
+
+	MetricsEnabled      bool `conf:"env:METRICS_ENABLED,default:true"`
+	MetricsProjectLabel bool `conf:"env:METRICS_PROJECT_LABEL,default:false"`
+
+	AuditSinks   []string `conf:"env:AUDIT_SINKS,default:log"`
+	AuditFile    string   `conf:"env:AUDIT_FILE,default:audit.jsonl"`
+	AuditSource  string   `conf:"env:AUDIT_SOURCE"`
+	AuditTopicID string   `conf:"env:GCP_TOKEN_AUDIT_TOPIC,default:token_audit_topic"`
+
+	TracingExporter     string  `conf:"env:TRACING_EXPORTER,default:none"`
+	TracingOTLPEndpoint string  `conf:"env:TRACING_OTLP_ENDPOINT"`
+	TracingOTLPProtocol string  `conf:"env:TRACING_OTLP_PROTOCOL,default:grpc"`
+	TracingOTLPInsecure bool    `conf:"env:TRACING_OTLP_INSECURE,default:false"`
+	TracingFile         string  `conf:"env:TRACING_FILE"`
+	TracingSampleRatio  float64 `conf:"env:TRACING_SAMPLE_RATIO,default:1"`
 }
 
 func main() {
@@ -55,6 +157,30 @@ func runServer(ctx context.Context) error {
 		return fmt.Errorf("error parsing the configuration: %w", err)
 	}
 
+	serverTLS, err := newServerTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	shutdownTracing, err := tracex.Setup(ctx, "httpservice", tracex.Config{
+		Exporter:     cfg.TracingExporter,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		OTLPProtocol: cfg.TracingOTLPProtocol,
+		OTLPInsecure: cfg.TracingOTLPInsecure,
+		File:         cfg.TracingFile,
+		SampleRatio:  cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("configuring tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("flush traces")
+		}
+	}()
+
 	client, err := pubsub.NewClient(ctx, cfg.ProjectID)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
@@ -70,23 +196,340 @@ func runServer(ctx context.Context) error {
 		return fmt.Errorf("creating topic %s: %w", cfg.TokenGenerationTopicID, err)
 	}
 
-	publisher := pubsubgw.NewRequestTokenGenerationPublisher(topic)
+	stateStore, err := statestore.Open(cfg.StateStoreURL)
+	if err != nil {
+		return fmt.Errorf("opening state store: %w", err)
+	}
+	defer func() {
+		if err := stateStore.Close(); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("close state store")
+		}
+	}()
 
-	tokenService := service.NewRequestTokenGenerationService(publisher)
+	eventsTopic, err := pubsubx.CreateTopicIfNotExists(ctx, client, cfg.RequestEventsTopicID)
+	if err != nil {
+		return fmt.Errorf("creating topic %s: %w", cfg.RequestEventsTopicID, err)
+	}
 
-	server := createServer(tokenService, cfg)
+	// Every replica applies every event, so that callers waiting on any of them are
+	// notified.
+	eventsSubs, err := pubsubx.CreateInstanceSubscription(ctx, client, eventsTopic, cfg.RequestEventsSubPrefix)
+	if err != nil {
+		return fmt.Errorf("creating subscription to %s: %w", cfg.RequestEventsTopicID, err)
+	}
+	defer func() {
+		if err := eventsSubs.Delete(context.WithoutCancel(ctx)); err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("subscription", eventsSubs.ID()).Msg("delete events subscription")
+		}
+	}()
 
-	httpx.Run(ctx, &server)
+	metrics := newMetrics(cfg.MetricsEnabled, cfg.MetricsProjectLabel)
+	publisher := pubsubgw.NewRequestTokenGenerationPublisher(topic, pubsubgw.WithMetrics(metrics))
+
+	accessPolicy, err := loadAccessPolicy(cfg.AccessPolicyFile)
+	if err != nil {
+		return err
+	}
+
+	audit, closeAudit, err := openAuditLog(ctx, cfg, client)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := closeAudit(); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("close audit log")
+		}
+	}()
+
+	statusStore := requeststore.New(stateStore, cfg.RequestStatusTTL)
+	statusService := service.NewRequestStatusService(statusStore, audit)
+	tokenService := service.NewRequestTokenGenerationService(publisher, statusStore, accessPolicy, audit)
+
+	apiOptions, authenticators, err := authenticationOptions(cfg, stateStore)
+	if err != nil {
+		return err
+	}
+	if cfg.AuthEnabled {
+		eventPublisher := pubsubgw.NewRequestEventPublisher(eventsTopic, pubsubgw.WithMetrics(metrics))
+		adminService := service.NewRequestAdminService(statusStore, publisher, eventPublisher, audit)
+		apiOptions = append(apiOptions, api.WithPolicyDryRun(tokenService), api.WithRequestAdministration(adminService))
+	}
+	grpcOptions := []grpcapi.Option{
+		grpcapi.WithAuthentication(authenticators...),
+		grpcapi.WithRequestStatus(statusService, cfg.SyncMaxWait),
+	}
+
+	sonarClient, err := newSonarClient(ctx, cfg, metrics)
+	if err != nil {
+		return err
+	}
+	var sonarChecks []httpx.Check
+	// Left nil without a Sonar client, the self-service then reports revocation as
+	// unavailable.
+	var revoker service.TokenRevoker
+	if sonarClient != nil {
+		sonarChecks = append(sonarChecks, sonarCheck(sonarClient))
+		revocationService := service.NewTokenRevocationService(sonarClient, statusStore, audit)
+		grpcOptions = append(grpcOptions, grpcapi.WithTokenRevocation(revocationService))
+		revoker = revocationService
+	}
+	selfService := service.NewSelfService(statusStore, accessPolicy, revoker, audit)
+
+	exchangeOptions, err := tokenExchangeOptions(cfg, sonarClient, audit)
+	if err != nil {
+		return fmt.Errorf("configuring token exchange: %w", err)
+	}
+	apiOptions = append(apiOptions, exchangeOptions...)
+	apiOptions = append(apiOptions, api.WithRequestStatus(statusService, cfg.SyncMaxWait))
+	apiOptions = append(apiOptions, api.WithSelfService(selfService))
+	apiOptions = append(apiOptions, api.WithValidationOptions(openapix.WithMaxBodyBytes(cfg.ServerMaxBodyBytes)))
+
+	rateLimits, err := parseRateLimits(cfg)
+	if err != nil {
+		return fmt.Errorf("error parsing the configuration: %w", err)
+	}
+	apiOptions = append(apiOptions, api.WithRateLimits(ratelimit.NewLimiter(stateStore), rateLimits))
+
+	readinessChecks := append([]httpx.Check{
+		{
+			Name: "pubsub",
+			Check: func(ctx context.Context) error {
+				exists, err := topic.Exists(ctx)
+				if err != nil {
+					return err
+				}
+				if !exists {
+					return fmt.Errorf("topic %s does not exist", cfg.TokenGenerationTopicID)
+				}
+				return nil
+			},
+		},
+		{Name: "state_store", Check: stateStore.Ping},
+	}, sonarChecks...)
+	readiness := httpx.NewReadiness(cfg.ReadinessCheckTimeout, cfg.ReadinessCacheTTL, readinessChecks...)
+	apiOptions = append(apiOptions, api.WithReadiness(readiness))
+	if metrics != nil {
+		apiOptions = append(apiOptions, api.WithMetrics(metrics))
+	}
+
+	eventConsumer := consumer.NewRequestEventConsumer(eventsSubs, statusService, consumer.WithMetrics(metrics))
+	go func() {
+		log.Ctx(ctx).Info().Str("topic", cfg.RequestEventsTopicID).
+			Str("subscription", eventsSubs.ID()).
+			Msg("request events consumer started")
+		if err := eventConsumer.Start(ctx); err != nil {
+			return
+		}
+	}()
+
+	server := createServer(tokenService, cfg, apiOptions...)
+
+	runOptions := createRunOptions(cfg, serverTLS)
+	runOptions = append(runOptions, httpx.WithReadiness(readiness, cfg.ServerDrainDelay))
+	if cfg.GRPCAddr != "" {
+		runOptions = append(runOptions, httpx.WithServer("grpc", createGRPCServer(tokenService, cfg, serverTLS, grpcOptions...)))
+	}
+
+	httpx.Run(ctx, &server, runOptions...)
+
+	ctxStop, cancelFunc := context.WithTimeout(ctx, cfg.ServerShutdownTimeout)
+	defer cancelFunc()
+
+	if err := eventConsumer.Stop(ctxStop); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("error stopping request events consumer")
+	}
 
 	return nil
 }
 
-func createServer(tokenService *service.RequestTokenGenerationService, cfg config) http.Server {
+// newMetrics creates the metrics of the service, nil when they are disabled.
+func newMetrics(enabled, projectLabel bool) *metricsx.Metrics {
+	if !enabled {
+		return nil
+	}
+
+	var opts []metricsx.Option
+	if projectLabel {
+		opts = append(opts, metricsx.WithProjectLabel())
+	}
+
+	return metricsx.New(opts...)
+}
+
+// loadAccessPolicy reads the YAML access policy at path. Without a policy, when path
+// is empty, every request but those of global analysis tokens is allowed.
+func loadAccessPolicy(path string) (*model.AccessPolicy, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading access policy: %w", err)
+	}
+
+	var policy model.AccessPolicy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("decoding access policy: %w", err)
+	}
+	if err := policy.Compile(); err != nil {
+		return nil, fmt.Errorf("invalid access policy: %w", err)
+	}
+
+	return &policy, nil
+}
+
+// authenticationOptions returns the options authenticating the API, along with the
+// authenticators the gRPC service shares with it.
+func authenticationOptions(cfg config, stateStore statestore.Store) ([]api.Option, []authx.Authenticator, error) {
+	if !cfg.AuthEnabled {
+		return nil, nil, nil
+	}
+
+	apiKeyStore, err := openAPIKeyStore(cfg, stateStore)
+	if err != nil {
+		return nil, nil, err
+	}
+	apiKeys := authx.NewAPIKeys(apiKeyStore)
+	authenticators := []authx.Authenticator{apiKeys}
+
+	if cfg.AuthJWTIssuer != "" {
+		jwtAuthenticator, err := newJWTAuthenticator(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		authenticators = append(authenticators, jwtAuthenticator)
+	}
+
+	if cfg.AuthClientCertIdentitiesFile != "" {
+		clientCerts, err := newClientCertAuthenticator(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Last, so that callers presenting a certificate may still authenticate with
+		// other credentials.
+		authenticators = append(authenticators, clientCerts)
+	}
+
+	options := []api.Option{
+		api.WithAuthentication(authenticators...),
+		api.WithAPIKeyAdministration(apiKeys),
+	}
+
+	return options, authenticators, nil
+}
+
+func newJWTAuthenticator(cfg config) (*authx.JWT, error) {
+	var keys *authx.JWKS
+	switch {
+	case cfg.AuthJWTJWKSFile != "":
+		var err error
+		keys, err = authx.NewFileJWKS(cfg.AuthJWTJWKSFile, cfg.AuthJWTJWKSReloadInterval)
+		if err != nil {
+			return nil, fmt.Errorf("loading jwks file: %w", err)
+		}
+	case cfg.AuthJWTJWKSURL != "":
+		keys = authx.NewRemoteJWKS(cfg.AuthJWTJWKSURL, cfg.AuthJWTJWKSCacheTTL, &http.Client{Timeout: 10 * time.Second})
+	default:
+		return nil, errors.New("AUTH_JWT_JWKS_URL or AUTH_JWT_JWKS_FILE is required with AUTH_JWT_ISSUER")
+	}
+
+	jwtAuthenticator, err := authx.NewJWT(authx.JWTConfig{
+		Issuer:   cfg.AuthJWTIssuer,
+		Audience: cfg.AuthJWTAudience,
+		Leeway:   cfg.AuthJWTLeeway,
+		Claims: authx.ClaimMapping{
+			Subject:    cfg.AuthJWTSubjectClaim,
+			Name:       cfg.AuthJWTNameClaim,
+			Groups:     cfg.AuthJWTGroupsClaim,
+			Scopes:     cfg.AuthJWTScopesClaim,
+			Attributes: cfg.AuthJWTAttributeClaims,
+		},
+		DefaultScopes: cfg.AuthJWTDefaultScopes,
+	}, keys)
+	if err != nil {
+		return nil, fmt.Errorf("creating jwt authenticator: %w", err)
+	}
+
+	return jwtAuthenticator, nil
+}
+
+// clientIdentitiesConfig is the YAML configuration mapping client certificates to
+// principals.
+type clientIdentitiesConfig struct {
+	Identities []authx.ClientIdentity `yaml:"identities"`
+}
+
+func newClientCertAuthenticator(cfg config) (*authx.ClientCertificates, error) {
+	// The authenticator trusts the certificates verified by the servers.
+	if cfg.ServerTLSClientCAFile == "" {
+		return nil, errors.New("SERVER_TLS_CLIENT_CA_FILE is required with AUTH_CLIENT_CERT_IDENTITIES_FILE")
+	}
+
+	data, err := os.ReadFile(cfg.AuthClientCertIdentitiesFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading client certificate identities: %w", err)
+	}
+
+	var identities clientIdentitiesConfig
+	if err := yaml.Unmarshal(data, &identities); err != nil {
+		return nil, fmt.Errorf("decoding client certificate identities: %w", err)
+	}
+
+	clientCerts, err := authx.NewClientCertificates(identities.Identities)
+	if err != nil {
+		return nil, fmt.Errorf("creating client certificate authenticator: %w", err)
+	}
+
+	return clientCerts, nil
+}
+
+func openAPIKeyStore(cfg config, stateStore statestore.Store) (authx.APIKeyStore, error) {
+	switch cfg.AuthAPIKeysStore {
+	case "file":
+		store, err := authx.NewFileAPIKeyStore(cfg.AuthAPIKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("opening api keys file: %w", err)
+		}
+		return store, nil
+	case "state":
+		return authx.NewStateAPIKeyStore(stateStore), nil
+	default:
+		return nil, fmt.Errorf("unknown api keys store %q", cfg.AuthAPIKeysStore)
+	}
+}
+
+func parseRateLimits(cfg config) (api.RateLimits, error) {
+	var limits api.RateLimits
+	var err error
+	if limits.Client, err = ratelimit.ParseLimit(cfg.RateLimitClient); err != nil {
+		return limits, fmt.Errorf("RATE_LIMIT_CLIENT: %w", err)
+	}
+	if limits.Principal, err = ratelimit.ParseLimit(cfg.RateLimitPrincipal); err != nil {
+		return limits, fmt.Errorf("RATE_LIMIT_PRINCIPAL: %w", err)
+	}
+	if limits.Project, err = ratelimit.ParseLimit(cfg.RateLimitProject); err != nil {
+		return limits, fmt.Errorf("RATE_LIMIT_PROJECT: %w", err)
+	}
+
+	return limits, nil
+}
+
+func createServer(tokenService *service.RequestTokenGenerationService, cfg config, opts ...api.Option) http.Server {
 	router := chi.NewRouter()
+	if cfg.ServerTrustProxy {
+		// The client address is taken from the forwarding headers set by the proxy.
+		router.Use(middleware.RealIP)
+	}
+	router.Use(tracex.Middleware("/liveness", "/readiness"))
+	router.Use(correlation.Middleware)
 	router.Use(middleware.Recoverer)
 
-	apiV1 := api.New(tokenService)
+	apiV1 := api.New(tokenService, opts...)
 	apiV1.Routes(router)
+	if cfg.WebUIEnabled {
+		webui.Routes(router)
+	}
 
 	return http.Server{
 		Addr:         cfg.ServerAddr,
@@ -95,3 +538,81 @@ func createServer(tokenService *service.RequestTokenGenerationService, cfg confi
 		WriteTimeout: cfg.ServerWriteTimeout,
 	}
 }
+
+// newServerTLSConfig creates the TLS configuration the servers are served with, nil
+// when no certificate is configured.
+func newServerTLSConfig(cfg config) (*tls.Config, error) {
+	if cfg.ServerTLSCertFile == "" && cfg.ServerTLSKeyFile == "" {
+		if cfg.ServerTLSClientCAFile != "" {
+			return nil, errors.New("SERVER_TLS_CLIENT_CA_FILE requires SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+
+	tlsConfig, err := tlsx.NewServerConfig(tlsx.ServerConfig{
+		CertFile:          cfg.ServerTLSCertFile,
+		KeyFile:           cfg.ServerTLSKeyFile,
+		MinVersion:        cfg.ServerTLSMinVersion,
+		ClientCAFile:      cfg.ServerTLSClientCAFile,
+		RequireClientCert: cfg.ServerTLSRequireClientCert,
+		ReloadInterval:    cfg.ServerTLSReloadInterval,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating server TLS configuration: %w", err)
+	}
+
+	return tlsConfig, nil
+}
+
+// createRunOptions configures how the HTTP server is served: over TLS when tlsConfig is
+// set, with HTTP/2, and how long shutdown waits for pending calls.
+func createRunOptions(cfg config, tlsConfig *tls.Config) []httpx.RunOption {
+	opts := []httpx.RunOption{httpx.WithShutdownTimeout(cfg.ServerShutdownTimeout)}
+	if cfg.ServerHTTP2 {
+		opts = append(opts, httpx.WithHTTP2())
+	}
+	if tlsConfig != nil {
+		opts = append(opts, httpx.WithTLS(tlsConfig))
+	}
+
+	return opts
+}
+
+// createGRPCServer creates the gRPC server, served over TLS when tlsConfig is set.
+func createGRPCServer(tokenService *service.RequestTokenGenerationService, cfg config, tlsConfig *tls.Config, opts ...grpcapi.Option) *grpcx.Server {
+	tokenGenerator := grpcapi.New(tokenService, opts...)
+
+	serverOptions := tokenGenerator.ServerOptions()
+	if tlsConfig != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := grpcx.NewServer(cfg.GRPCAddr, serverOptions...)
+	tokenGenerator.Register(server.Server)
+
+	return server
+}
+
+// openAuditLog opens the audit log of the service, creating the audit topic when it is
+// one of the sinks.
+func openAuditLog(ctx context.Context, cfg config, client *pubsub.Client) (*auditlog.Chain, func() error, error) {
+	auditCfg := auditlog.Config{
+		Source: cfg.AuditSource,
+		Sinks:  cfg.AuditSinks,
+		File:   cfg.AuditFile,
+	}
+	if slices.Contains(cfg.AuditSinks, auditlog.SinkTopic) {
+		topic, err := pubsubx.CreateTopicIfNotExists(ctx, client, cfg.AuditTopicID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating topic %s: %w", cfg.AuditTopicID, err)
+		}
+		auditCfg.Topic = topic
+	}
+
+	chain, closeAudit, err := auditlog.Open("httpservice", auditCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening audit log: %w", err)
+	}
+
+	return chain, closeAudit, nil
+}
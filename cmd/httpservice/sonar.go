@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
+	"github.com/werbersondev/token-generator-test/gateway/sonarclient"
+)
+
+// newSonarClient creates the client the HTTP service calls Sonar with, to exchange and
+// revoke tokens. Sonar is only called by the worker when no authentication token is
+// configured, and the client is nil then.
+func newSonarClient(ctx context.Context, cfg config, metrics *metricsx.Metrics) (*sonarclient.HTTPClient, error) {
+	if cfg.SonarAuthToken == "" && cfg.SonarAuthTokenFile == "" {
+		return nil, nil
+	}
+
+	client, err := sonarclient.New(sonarclient.Config{
+		Timeout:       cfg.SonarAPITimeout,
+		BaseURL:       cfg.SonarAPIAddress,
+		AuthToken:     cfg.SonarAuthToken,
+		AuthTokenFile: cfg.SonarAuthTokenFile,
+		TLS: sonarclient.TLSConfig{
+			CAFile:         cfg.SonarTLSCAFile,
+			CertFile:       cfg.SonarTLSCertFile,
+			KeyFile:        cfg.SonarTLSKeyFile,
+			MinVersion:     cfg.SonarTLSMinVersion,
+			ReloadInterval: cfg.SonarTLSReloadInterval,
+		},
+		Proxy: sonarclient.ProxyConfig{
+			URL:     cfg.SonarProxyURL,
+			NoProxy: cfg.SonarNoProxy,
+		},
+		Metrics: metrics,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating sonar client: %w", err)
+	}
+
+	go client.Credentials().Watch(ctx, cfg.SonarAuthTokenReloadInterval)
+
+	return client, nil
+}
+
+// sonarCheck reports the service unready while Sonar is unavailable.
+func sonarCheck(client *sonarclient.HTTPClient) httpx.Check {
+	return httpx.Check{
+		Name: "sonar",
+		Check: func(ctx context.Context) error {
+			status, err := client.SystemStatus(ctx)
+			if err != nil {
+				return err
+			}
+			if status != sonarclient.SystemStatusUp {
+				return fmt.Errorf("sonar is %s", status)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,440 @@
+"use strict";
+
+// The credentials are kept in the session storage, so they are forgotten with the tab.
+const credentialsKey = "token-generator.credentials";
+const terminalStates = ["issued", "failed", "canceled"];
+const pollInterval = 2000;
+
+const $ = (id) => document.getElementById(id);
+
+let caller = null;
+let watching = null;
+
+class APIError extends Error {
+  constructor(status, problem) {
+    super(problem.detail || problem.title || "request failed with status " + status);
+    this.status = status;
+    this.code = problem.code;
+  }
+}
+
+function authorization() {
+  const credentials = sessionStorage.getItem(credentialsKey);
+  return credentials ? { Authorization: "Bearer " + credentials } : {};
+}
+
+async function api(method, path, body) {
+  const headers = { Accept: "application/json", ...authorization() };
+  if (body !== undefined) {
+    headers["Content-Type"] = "application/json";
+  }
+
+  const resp = await fetch(path, {
+    method,
+    headers,
+    body: body === undefined ? undefined : JSON.stringify(body),
+    cache: "no-store",
+    credentials: "same-origin",
+  });
+  const payload = await resp.json().catch(() => ({}));
+  if (!resp.ok) {
+    throw new APIError(resp.status, payload);
+  }
+  return payload;
+}
+
+function showError(err) {
+  const message = err instanceof APIError && err.status === 401
+    ? "Sign in with an API key or a bearer token allowed to request tokens."
+    : err.message;
+  $("error").textContent = message;
+  $("error").hidden = false;
+}
+
+function clearError() {
+  $("error").hidden = true;
+  $("error").textContent = "";
+}
+
+function formatTime(value) {
+  return value ? new Date(value).toLocaleString() : "";
+}
+
+// Navigation
+
+function showPage() {
+  const page = location.hash === "#tokens" ? "tokens" : "request";
+  for (const section of document.querySelectorAll("section[data-page]")) {
+    section.hidden = section.dataset.page !== page;
+  }
+  for (const link of document.querySelectorAll("nav a")) {
+    link.classList.toggle("active", link.dataset.page === page);
+  }
+  clearError();
+}
+
+function navigate() {
+  showPage();
+  if (location.hash === "#tokens") {
+    loadTokens();
+  }
+}
+
+// Caller
+
+async function loadCaller() {
+  caller = null;
+  $("caller").textContent = "";
+  try {
+    caller = await api("GET", "/v1/me");
+  } catch (err) {
+    showError(err);
+    return;
+  }
+
+  const principal = caller.principal;
+  $("caller").textContent = principal
+    ? "Signed in as " + (principal.name || principal.subject)
+    : "Authentication is disabled";
+  renderGrants();
+}
+
+function renderGrants() {
+  const projects = $("projects");
+  projects.replaceChildren();
+
+  if (!caller.restricted) {
+    // Without an access policy, global analysis tokens are denied.
+    $("project-hint").textContent = "Any project may be requested, with a project analysis token.";
+    $("ttl-hint").textContent = "A duration such as 24h or 720h. Leave empty for a token that never expires.";
+    for (const option of $("token-type").options) {
+      option.disabled = option.value !== "project_analysis";
+    }
+    $("token-type").value = "project_analysis";
+    return;
+  }
+
+  if (caller.grants.length === 0) {
+    $("project-hint").textContent = "The access policy does not allow you to request any token.";
+    return;
+  }
+
+  const patterns = new Set();
+  const tokenTypes = new Set();
+  for (const grant of caller.grants) {
+    grant.projects.forEach((project) => patterns.add(project));
+    grant.token_types.forEach((tokenType) => tokenTypes.add(tokenType));
+  }
+  for (const pattern of patterns) {
+    if (!pattern.includes("*")) {
+      projects.append(new Option(pattern, pattern));
+    }
+  }
+  $("project-hint").textContent = "Allowed projects: " + [...patterns].join(", ");
+
+  for (const option of $("token-type").options) {
+    option.disabled = !tokenTypes.has(option.value);
+  }
+  const selected = $("token-type").selectedOptions[0];
+  if (selected && selected.disabled) {
+    const allowed = [...$("token-type").options].find((option) => !option.disabled);
+    if (allowed) {
+      $("token-type").value = allowed.value;
+    }
+  }
+
+  // Each rule caps the lifetime of the tokens it allows, the hint only lists the caps.
+  const limits = caller.grants.filter((grant) => grant.max_ttl).map((grant) => grant.rule + ": " + grant.max_ttl);
+  $("ttl-hint").textContent = limits.length === caller.grants.length
+    ? "A duration such as 24h. At most " + limits.join(", ") + "."
+    : "A duration such as 24h or 720h. Leave empty for a token that never expires, where allowed.";
+}
+
+// Requesting
+
+async function requestToken(event) {
+  event.preventDefault();
+  clearError();
+  stopWatching();
+  hideRevealed();
+
+  const request = { project_id: $("project").value.trim(), token_type: $("token-type").value };
+  if ($("ttl").value.trim() !== "") {
+    request.ttl = $("ttl").value.trim();
+  }
+
+  let status;
+  try {
+    status = await api("POST", "/v1/generate-token", request);
+  } catch (err) {
+    showError(err);
+    return;
+  }
+
+  $("progress-id").textContent = status.request_id;
+  $("progress-events").replaceChildren();
+  $("progress-state").textContent = "Queued";
+  $("reveal").hidden = true;
+  $("reveal").dataset.requestId = status.request_id;
+  $("progress").hidden = false;
+
+  watch(status.request_id);
+}
+
+function stopWatching() {
+  if (watching) {
+    watching.abort();
+    watching = null;
+  }
+}
+
+// watch follows the events of a request until it completes, falling back to polling its
+// status when the event stream is unavailable.
+async function watch(id) {
+  const controller = new AbortController();
+  watching = controller;
+
+  let state = null;
+  try {
+    state = await streamEvents(id, controller.signal);
+  } catch (err) {
+    if (controller.signal.aborted) {
+      return;
+    }
+  }
+  while (!terminalStates.includes(state) && !controller.signal.aborted) {
+    try {
+      const status = await api("GET", "/v1/requests/" + encodeURIComponent(id));
+      state = status.state;
+      if (terminalStates.includes(state)) {
+        complete(state, status.failure_reason);
+        break;
+      }
+      $("progress-state").textContent = capitalize(state);
+    } catch (err) {
+      showError(err);
+      return;
+    }
+    await new Promise((resolve) => setTimeout(resolve, pollInterval));
+  }
+}
+
+// streamEvents reads the Server-Sent Events of a request, returning its final state. The
+// stream is read through fetch rather than EventSource, which cannot send credentials.
+async function streamEvents(id, signal) {
+  const resp = await fetch("/v1/requests/" + encodeURIComponent(id) + "/events", {
+    headers: { Accept: "text/event-stream", ...authorization() },
+    cache: "no-store",
+    signal,
+  });
+  if (!resp.ok || !resp.body) {
+    throw new Error("event stream unavailable");
+  }
+
+  const reader = resp.body.pipeThrough(new TextDecoderStream()).getReader();
+  let buffered = "";
+  for (;;) {
+    const { value, done } = await reader.read();
+    if (done) {
+      return null;
+    }
+    buffered += value;
+
+    let end;
+    while ((end = buffered.indexOf("\n\n")) >= 0) {
+      const block = buffered.slice(0, end);
+      buffered = buffered.slice(end + 2);
+
+      const data = block.split("\n").filter((line) => line.startsWith("data:")).map((line) => line.slice(5).trim()).join("\n");
+      if (data === "") {
+        continue;
+      }
+      const event = JSON.parse(data);
+      addEvent(event);
+      if (terminalStates.includes(event.type)) {
+        complete(event.type, event.failure_reason);
+        reader.cancel();
+        return event.type;
+      }
+    }
+  }
+}
+
+function addEvent(event) {
+  const item = document.createElement("li");
+  let text = formatTime(event.time) + " " + event.type.replaceAll("_", " ");
+  if (event.attempt) {
+    text += " (attempt " + event.attempt + ")";
+  }
+  item.textContent = text;
+  $("progress-events").append(item);
+  $("progress-state").textContent = capitalize(event.type.replaceAll("_", " "));
+}
+
+function complete(state, failureReason) {
+  if (state === "issued") {
+    $("progress-state").textContent = "Issued: reveal the token to copy it, it can only be shown once.";
+    $("reveal").hidden = false;
+    return;
+  }
+  $("progress-state").textContent = capitalize(state) + (failureReason ? ": " + failureReason.replaceAll("_", " ") : "");
+}
+
+function capitalize(text) {
+  return text.charAt(0).toUpperCase() + text.slice(1);
+}
+
+// Revealing
+
+async function revealToken(id) {
+  clearError();
+
+  let token;
+  try {
+    token = await api("POST", "/v1/requests/" + encodeURIComponent(id) + "/token");
+  } catch (err) {
+    showError(err);
+    if (err instanceof APIError && err.status === 410) {
+      $("reveal").hidden = true;
+    }
+    return;
+  }
+
+  $("reveal").hidden = true;
+  $("revealed-token").value = token.token;
+  $("revealed-expiry").textContent = token.expires_at ? "Expires " + formatTime(token.expires_at) + "." : "Never expires.";
+  $("revealed").hidden = false;
+  $("revealed-token").select();
+}
+
+async function copyToken() {
+  try {
+    await navigator.clipboard.writeText($("revealed-token").value);
+    $("copy").textContent = "Copied";
+  } catch (err) {
+    $("revealed-token").select();
+    showError(new Error("Copying failed, copy the selected token instead."));
+  }
+}
+
+function hideRevealed() {
+  $("revealed-token").value = "";
+  $("revealed").hidden = true;
+  $("copy").textContent = "Copy";
+}
+
+// Tokens
+
+async function loadTokens() {
+  let page;
+  try {
+    page = await api("GET", "/v1/tokens");
+  } catch (err) {
+    showError(err);
+    return;
+  }
+
+  const rows = page.tokens.map((token) => {
+    const row = document.createElement("tr");
+    for (const value of [
+      token.project_id,
+      (token.token_type || "").replaceAll("_", " "),
+      token.token_name,
+      formatTime(token.created_at),
+      token.expires_at ? formatTime(token.expires_at) : "Never",
+      token.retrieved_at ? formatTime(token.retrieved_at) : "No",
+    ]) {
+      const cell = document.createElement("td");
+      cell.textContent = value || "";
+      row.append(cell);
+    }
+
+    const actions = document.createElement("td");
+    if (!token.retrieved_at) {
+      const reveal = document.createElement("button");
+      reveal.type = "button";
+      reveal.textContent = "Reveal";
+      reveal.addEventListener("click", async () => {
+        await revealToken(token.request_id);
+        loadTokens();
+      });
+      actions.append(reveal);
+    }
+    if (caller && caller.token_revocation) {
+      const revoke = document.createElement("button");
+      revoke.type = "button";
+      revoke.className = "danger";
+      revoke.textContent = "Revoke";
+      revoke.addEventListener("click", () => revokeToken(token, revoke));
+      actions.append(revoke);
+    }
+    row.append(actions);
+    return row;
+  });
+
+  $("tokens").replaceChildren(...rows);
+  $("no-tokens").hidden = rows.length > 0;
+}
+
+async function revokeToken(token, button) {
+  if (!confirm("Revoke the token of " + token.project_id + "? Anything using it will stop working.")) {
+    return;
+  }
+
+  clearError();
+  button.disabled = true;
+  try {
+    await api("POST", "/v1/requests/" + encodeURIComponent(token.request_id) + "/revoke");
+  } catch (err) {
+    button.disabled = false;
+    showError(err);
+    return;
+  }
+  loadTokens();
+}
+
+// Credentials
+
+function signIn(event) {
+  event.preventDefault();
+  const credentials = $("credentials-input").value.trim();
+  if (credentials === "") {
+    sessionStorage.removeItem(credentialsKey);
+  } else {
+    sessionStorage.setItem(credentialsKey, credentials);
+  }
+  $("credentials-input").value = "";
+  reload();
+}
+
+function signOut() {
+  sessionStorage.removeItem(credentialsKey);
+  stopWatching();
+  hideRevealed();
+  $("progress").hidden = true;
+  $("tokens").replaceChildren();
+  reload();
+}
+
+async function reload() {
+  clearError();
+  $("sign-out").hidden = sessionStorage.getItem(credentialsKey) === null;
+  await loadCaller();
+  if (location.hash === "#tokens") {
+    loadTokens();
+  }
+}
+
+document.addEventListener("DOMContentLoaded", () => {
+  $("credentials-form").addEventListener("submit", signIn);
+  $("sign-out").addEventListener("click", signOut);
+  $("request-form").addEventListener("submit", requestToken);
+  $("reveal").addEventListener("click", () => revealToken($("reveal").dataset.requestId));
+  $("copy").addEventListener("click", copyToken);
+  $("dismiss").addEventListener("click", hideRevealed);
+  $("refresh").addEventListener("click", loadTokens);
+  window.addEventListener("hashchange", navigate);
+
+  showPage();
+  reload();
+});
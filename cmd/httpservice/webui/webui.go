@@ -0,0 +1,49 @@
+// Package webui serves the self-service web UI of the HTTP service, a static page
+// calling the /v1 API from the browser with the credentials of its user.
+package webui
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Path is where the UI is served.
+const Path = "/ui"
+
+// contentSecurityPolicy only lets the page load its own scripts and styles and call the
+// API of its origin, so that a token revealed in the page cannot be sent elsewhere.
+const contentSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; img-src 'self'; connect-src 'self'; " +
+	"base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+//go:embed static
+var static embed.FS
+
+// Routes registers the routes of the UI, redirecting the path without its trailing
+// slash to it.
+func Routes(router chi.Router) {
+	router.Get(Path, http.RedirectHandler(Path+"/", http.StatusMovedPermanently).ServeHTTP)
+	router.Get(Path+"/*", http.StripPrefix(Path, Handler()).ServeHTTP)
+}
+
+// Handler serves the files of the UI, relative to the root of the requests.
+func Handler() http.Handler {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	fileServer := http.FileServerFS(files)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		// Revalidated on every load, so that the page and its script never mismatch
+		// across releases.
+		w.Header().Set("Cache-Control", "no-cache")
+		fileServer.ServeHTTP(w, r)
+	})
+}
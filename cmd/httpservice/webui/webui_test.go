@@ -0,0 +1,94 @@
+package webui_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/cmd/httpservice/webui"
+)
+
+func TestRoutes(t *testing.T) {
+	router := chi.NewRouter()
+	webui.Routes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	tests := []struct {
+		name                string
+		path                string
+		expectedStatus      int
+		expectedContentType string
+		expectedLocation    string
+		expectedBody        string
+	}{
+		{
+			name:             "Redirect",
+			path:             "/ui",
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "/ui/",
+		},
+		{
+			name:                "Page",
+			path:                "/ui/",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/html; charset=utf-8",
+			expectedBody:        `<script src="app.js" defer></script>`,
+		},
+		{
+			name:                "Script",
+			path:                "/ui/app.js",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/javascript; charset=utf-8",
+			expectedBody:        `"/v1/me"`,
+		},
+		{
+			name:                "Stylesheet",
+			path:                "/ui/app.css",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/css; charset=utf-8",
+		},
+		{
+			name:           "Unknown File",
+			path:           "/ui/unknown.js",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Get(server.URL + tt.path)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedLocation != "" {
+				assert.Equal(t, tt.expectedLocation, resp.Header.Get("Location"))
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, tt.expectedContentType, resp.Header.Get("Content-Type"))
+			assert.Contains(t, resp.Header.Get("Content-Security-Policy"), "frame-ancestors 'none'")
+			assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+			assert.Equal(t, "no-referrer", resp.Header.Get("Referrer-Policy"))
+			assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type API struct {
+	LivenessHandler http.HandlerFunc
+	StatusHandler   http.HandlerFunc
+}
+
+func New(credentials CredentialSource) *API {
+	api := API{
+		LivenessHandler: LivenessHandler(),
+		StatusHandler:   StatusHandler(credentials),
+	}
+
+	return &api
+}
+
+func (a *API) Routes(router *chi.Mux) {
+	router.Get("/liveness", a.LivenessHandler)
+	router.Get("/status", a.StatusHandler)
+}
+
+func LivenessHandler() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+}
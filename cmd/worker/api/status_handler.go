@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/gateway/sonarclient"
+)
+
+type CredentialSource interface {
+	Current() sonarclient.Credential
+}
+
+type StatusOutput struct {
+	Credential CredentialStatus `json:"credential"`
+}
+
+type CredentialStatus struct {
+	Source      string    `json:"source"`
+	Fingerprint string    `json:"fingerprint"`
+	ActivatedAt time.Time `json:"activated_at"`
+	AgeSeconds  float64   `json:"age_seconds"`
+}
+
+// StatusHandler reports the credential the worker currently authenticates to Sonar
+// with, identified by its fingerprint so it can be compared with the rotated secret.
+func StatusHandler(credentials CredentialSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		credential := credentials.Current()
+
+		output := StatusOutput{
+			Credential: CredentialStatus{
+				Source:      credential.Source,
+				Fingerprint: credential.Fingerprint(),
+				ActivatedAt: credential.ActivatedAt,
+				AgeSeconds:  credential.Age().Seconds(),
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(output); err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("encoding status response")
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/werbersondev/token-generator-test/cmd/worker/api"
+	"github.com/werbersondev/token-generator-test/gateway/sonarclient"
+)
+
+type staticCredentials sonarclient.Credential
+
+func (c staticCredentials) Current() sonarclient.Credential {
+	return sonarclient.Credential(c)
+}
+
+func TestStatusHandler(t *testing.T) {
+	credential := sonarclient.Credential{
+		Token:       "secret-token",
+		Source:      sonarclient.CredentialSourceFile,
+		ActivatedAt: time.Now().Add(-time.Hour),
+	}
+
+	router := chi.NewRouter()
+	api.New(staticCredentials(credential)).Routes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/status")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var output api.StatusOutput
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&output))
+	assert.Equal(t, credential.Fingerprint(), output.Credential.Fingerprint)
+	assert.Equal(t, sonarclient.CredentialSourceFile, output.Credential.Source)
+	assert.InDelta(t, time.Hour.Seconds(), output.Credential.AgeSeconds, 60)
+}
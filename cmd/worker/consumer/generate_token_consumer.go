@@ -8,25 +8,76 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
 )
 
 type GenerateTokenUseCase interface {
-	GenerateToken(ctx context.Context, request model.TokenGenerationRequest) (string, error)
+	IssueToken(ctx context.Context, request model.TokenGenerationRequest) (model.IssuedToken, error)
+}
+
+// RequestEventPublisher reports the progress of the requests to the HTTP service.
+type RequestEventPublisher interface {
+	PublishRequestEvent(ctx context.Context, event model.RequestEvent) error
+}
+
+// AuditRecorder records the tokens issued or failed to be issued by the worker.
+type AuditRecorder interface {
+	Record(ctx context.Context, entry model.AuditEntry) error
+}
+
+// CancellationChecker tells whether a request was canceled by an administrator.
+type CancellationChecker interface {
+	RequestCanceled(ctx context.Context, id string) (bool, error)
 }
 
 type GenerateTokenConsumer struct {
 	topicSubscription *pubsub.Subscription
 	useCase           GenerateTokenUseCase
+	events            RequestEventPublisher
+	metrics           *metricsx.Metrics
+	audit             AuditRecorder
+	cancellations     CancellationChecker
 	startCh, stopCh   chan struct{}
 }
 
-func NewGenerateTokenConsumer(topicSubscription *pubsub.Subscription, uc GenerateTokenUseCase) *GenerateTokenConsumer {
-	return &GenerateTokenConsumer{
+type Option func(*GenerateTokenConsumer)
+
+// WithMetrics records the age of the messages at receipt and those being processed,
+// and counts the tokens issued and failed.
+func WithMetrics(metrics *metricsx.Metrics) Option {
+	return func(c *GenerateTokenConsumer) {
+		c.metrics = metrics
+	}
+}
+
+// WithAuditRecorder audits the tokens issued and failed to be issued.
+func WithAuditRecorder(audit AuditRecorder) Option {
+	return func(c *GenerateTokenConsumer) {
+		c.audit = audit
+	}
+}
+
+// WithCancellations skips the requests canceled by an administrator, checked right
+// before calling Sonar.
+func WithCancellations(checker CancellationChecker) Option {
+	return func(c *GenerateTokenConsumer) {
+		c.cancellations = checker
+	}
+}
+
+func NewGenerateTokenConsumer(topicSubscription *pubsub.Subscription, uc GenerateTokenUseCase, events RequestEventPublisher, opts ...Option) *GenerateTokenConsumer {
+	c := &GenerateTokenConsumer{
 		topicSubscription: topicSubscription,
 		useCase:           uc,
+		events:            events,
 		startCh:           make(chan struct{}),
 		stopCh:            make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 // Start begins consuming messages from the Pub/Sub subscription and processing them.
@@ -39,7 +90,8 @@ func (c *GenerateTokenConsumer) Start(ctx context.Context) error {
 		cancel()
 	}()
 
-	if err := c.topicSubscription.Receive(ctx, c.GenerateTokenHandler); err != nil && !errors.Is(err, context.Canceled) {
+	handler := c.metrics.ObserveMessages(c.topicSubscription.ID(), c.GenerateTokenHandler)
+	if err := c.topicSubscription.Receive(ctx, handler); err != nil && !errors.Is(err, context.Canceled) {
 		log.Ctx(ctx).Error().Err(err).Msg("Error receiving messages")
 		return err
 	}
@@ -3,27 +3,129 @@ package consumer
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/codes"
 
 	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/correlation"
+	"github.com/werbersondev/token-generator-test/extensions/tracex"
+	"github.com/werbersondev/token-generator-test/gateway/sonarclient"
 )
 
 func (c *GenerateTokenConsumer) GenerateTokenHandler(ctx context.Context, msg *pubsub.Message) {
 	defer msg.Ack()
 
+	ctx = correlation.Restore(ctx, msg.Attributes[correlation.Attribute])
+	ctx, span := tracex.StartConsumerSpan(ctx, c.topicSubscription.ID(), msg.Attributes, msg.PublishTime)
+	defer span.End()
+
 	var request model.TokenGenerationRequest
 	if err := json.Unmarshal(msg.Data, &request); err != nil {
 		log.Ctx(ctx).Error().Err(err).Msg("failed to unmarshal message")
 		return
 	}
 
-	token, err := c.useCase.GenerateToken(ctx, request)
+	c.publishEvent(ctx, request, model.RequestEvent{Type: model.RequestEventPickedUp})
+
+	if c.canceled(ctx, request) {
+		c.record(ctx, request, "", model.AuditOutcomeCanceled, "canceled before calling sonar")
+		log.Ctx(ctx).Info().Str("request_id", request.ID).Msg("Skipping canceled request")
+		c.publishEvent(ctx, request, model.RequestEvent{Type: model.RequestEventCanceled})
+		return
+	}
+
+	ctx = sonarclient.ContextWithRetryObserver(ctx, func(ctx context.Context, attempt int) {
+		c.publishEvent(ctx, request, model.RequestEvent{Type: model.RequestEventRetrying, Attempt: attempt})
+	})
+	c.publishEvent(ctx, request, model.RequestEvent{Type: model.RequestEventCallingSonar})
+
+	issued, err := c.useCase.IssueToken(ctx, request)
+	if err != nil {
+		reason := model.FailureReasonOf(err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, string(reason))
+		c.metrics.CountToken(request.ProjectID, string(request.TokenType), string(reason))
+		c.record(ctx, request, "", model.AuditOutcomeFailed, string(reason))
+		log.Ctx(ctx).Error().Err(err).
+			Str("project_id", request.ProjectID).
+			Str("failure_reason", string(reason)).
+			Msg("Failed to generate token")
+		c.publishEvent(ctx, request, model.RequestEvent{Type: model.RequestEventFailed, FailureReason: reason, Error: err.Error()})
+		return
+	}
+
+	c.metrics.CountToken(request.ProjectID, string(request.TokenType), "")
+	c.record(ctx, request, issued.Name, model.AuditOutcomeIssued, "")
+
+	logEvent := log.Ctx(ctx).Info()
+	if request.Principal != nil {
+		logEvent = logEvent.Str("requested_by", request.Principal.Subject)
+	}
+	logEvent.Str("project_id", request.ProjectID).Str("token_name", issued.Name).Msg("Token generated")
+
+	event := model.RequestEvent{Type: model.RequestEventIssued, Token: issued.Token, TokenName: issued.Name}
+	if !issued.ExpiresAt.IsZero() {
+		event.ExpiresAt = &issued.ExpiresAt
+	}
+	c.publishEvent(ctx, request, event)
+}
+
+// publishEvent reports the progress of requests tracked by the HTTP service. A failure
+// is only logged, the token generation itself is not affected.
+func (c *GenerateTokenConsumer) publishEvent(ctx context.Context, request model.TokenGenerationRequest, event model.RequestEvent) {
+	if request.ID == "" {
+		return
+	}
+
+	event.RequestID = request.ID
+	event.Time = time.Now().UTC()
+	if err := c.events.PublishRequestEvent(ctx, event); err != nil {
+		log.Ctx(ctx).Error().Err(err).
+			Str("request_id", request.ID).
+			Str("event", string(event.Type)).
+			Msg("Failed to publish request event")
+	}
+}
+
+// canceled reports whether the request was canceled. Failing to check is only logged,
+// the request being processed rather than dropped.
+func (c *GenerateTokenConsumer) canceled(ctx context.Context, request model.TokenGenerationRequest) bool {
+	if c.cancellations == nil || request.ID == "" {
+		return false
+	}
+
+	canceled, err := c.cancellations.RequestCanceled(ctx, request.ID)
 	if err != nil {
-		log.Ctx(ctx).Error().Err(err).Msg("Failed to generate token")
+		log.Ctx(ctx).Error().Err(err).Str("request_id", request.ID).Msg("Failed to check request cancellation")
+		return false
+	}
+
+	return canceled
+}
+
+// record audits the issuance of the token requested by request, named tokenName once
+// issued, on behalf of the principal having requested it.
+func (c *GenerateTokenConsumer) record(ctx context.Context, request model.TokenGenerationRequest, tokenName, outcome, reason string) {
+	if c.audit == nil {
 		return
 	}
 
-	log.Ctx(ctx).Info().Msgf("Token generated for project: %s token: %s", request.ProjectID, token)
+	err := c.audit.Record(ctx, model.AuditEntry{
+		Time:      time.Now().UTC(),
+		Action:    model.AuditActionIssueToken,
+		Outcome:   outcome,
+		Principal: request.Principal,
+		RequestID: request.ID,
+		ProjectID: request.ProjectID,
+		TokenType: request.TokenType,
+		TokenName: tokenName,
+		TTL:       request.TTL,
+		Reason:    reason,
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("request_id", request.ID).Msg("recording audit entry")
+	}
 }
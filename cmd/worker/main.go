@@ -4,31 +4,77 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/ardanlabs/conf/v3"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/werbersondev/token-generator-test/cmd/worker/api"
 	"github.com/werbersondev/token-generator-test/cmd/worker/consumer"
+	"github.com/werbersondev/token-generator-test/domain/model"
 	"github.com/werbersondev/token-generator-test/domain/service"
 	"github.com/werbersondev/token-generator-test/extensions/loggerx"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
 	"github.com/werbersondev/token-generator-test/extensions/pubsubx"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+	"github.com/werbersondev/token-generator-test/extensions/tracex"
+	"github.com/werbersondev/token-generator-test/gateway/auditlog"
+	pubsubgw "github.com/werbersondev/token-generator-test/gateway/pubsub"
+	"github.com/werbersondev/token-generator-test/gateway/requeststore"
 	"github.com/werbersondev/token-generator-test/gateway/sonarclient"
 )
 
 type config struct {
-	PubSubHost                    string        `conf:"env:PUBSUB_EMULATOR_HOST,default:localhost:8085"`
-	ProjectID                     string        `conf:"env:GCP_PROJECT_ID,default:my_project_key"`
-	TokenGenerationTopicID        string        `conf:"env:GCP_TOKEN_GENERATOR_TOPIC,default:token_generation_topic"`
-	TokenGenerationSubscriptionID string        `conf:"env:GCP_TOKEN_GENERATOR_SUBSCRIPTION,default:token_generation_subscription"`
-	SonarAPIAddress               string        `conf:"env:SONAR_API_ADDRESS,default:http://localhost:9000"`
-	SonarAPITimeout               time.Duration `conf:"env:SONAR_API_TIMEOUT,default:30s"`
-	SonarAuthToken                string        `conf:"env:SONAR_AUTH_TOKEN,required"`
+	PubSubHost                     string        `conf:"env:PUBSUB_EMULATOR_HOST,default:localhost:8085"`
+	ProjectID                      string        `conf:"env:GCP_PROJECT_ID,default:my_project_key"`
+	TokenGenerationTopicID         string        `conf:"env:GCP_TOKEN_GENERATOR_TOPIC,default:token_generation_topic"`
+	TokenGenerationSubscriptionID  string        `conf:"env:GCP_TOKEN_GENERATOR_SUBSCRIPTION,default:token_generation_subscription"`
+	RequestEventsTopicID           string        `conf:"env:GCP_TOKEN_EVENTS_TOPIC,default:token_generation_events_topic"`
+	SonarAPIAddress                string        `conf:"env:SONAR_API_ADDRESS,default:http://localhost:9000"`
+	SonarAPITimeout                time.Duration `conf:"env:SONAR_API_TIMEOUT,default:30s"`
+	SonarTLSCAFile                 string        `conf:"env:SONAR_TLS_CA_FILE"`
+	SonarTLSCertFile               string        `conf:"env:SONAR_TLS_CERT_FILE"`
+	SonarTLSKeyFile                string        `conf:"env:SONAR_TLS_KEY_FILE"`
+	SonarTLSMinVersion             string        `conf:"env:SONAR_TLS_MIN_VERSION,default:1.2"`
+	SonarTLSReloadInterval         time.Duration `conf:"env:SONAR_TLS_RELOAD_INTERVAL,default:30s"`
+	SonarProxyURL                  string        `conf:"env:SONAR_PROXY_URL"`
+	SonarNoProxy                   string        `conf:"env:SONAR_NO_PROXY"`
+	SonarAuthToken                 string        `conf:"env:SONAR_AUTH_TOKEN,mask"`
+	SonarAuthTokenFile             string        `conf:"env:SONAR_AUTH_TOKEN_FILE"`
+	SonarAuthTokenReloadInterval   time.Duration `conf:"env:SONAR_AUTH_TOKEN_RELOAD_INTERVAL,default:10s"`
+	SonarAuthTokenRotationInterval time.Duration `conf:"env:SONAR_AUTH_TOKEN_ROTATION_INTERVAL"`
+	SonarAuthTokenRevokeDelay      time.Duration `conf:"env:SONAR_AUTH_TOKEN_REVOKE_DELAY,default:10m"`
+	SonarPermissionPolicy          string        `conf:"env:SONAR_PERMISSION_POLICY,default:check"`
+
+	// StateStoreURL is the state store shared with the HTTP service, to skip the
+	// requests canceled by an administrator. Cancellations are ignored when empty.
+	StateStoreURL string `conf:"env:STATE_STORE_URL,mask"`
+
+	StatusServerAddr string `conf:"env:WORKER_STATUS_ADDR,default:0.0.0.0:3001"`
+
+	MetricsServerAddr   string `conf:"env:WORKER_METRICS_ADDR,default:0.0.0.0:3002"`
+	MetricsProjectLabel bool   `conf:"env:METRICS_PROJECT_LABEL,default:false"`
+
+	AuditSinks   []string `conf:"env:AUDIT_SINKS,default:log"`
+	AuditFile    string   `conf:"env:AUDIT_FILE,default:audit.jsonl"`
+	AuditSource  string   `conf:"env:AUDIT_SOURCE"`
+	AuditTopicID string   `conf:"env:GCP_TOKEN_AUDIT_TOPIC,default:token_audit_topic"`
+
+	TracingExporter     string  `conf:"env:TRACING_EXPORTER,default:none"`
+	TracingOTLPEndpoint string  `conf:"env:TRACING_OTLP_ENDPOINT"`
+	TracingOTLPProtocol string  `conf:"env:TRACING_OTLP_PROTOCOL,default:grpc"`
+	TracingOTLPInsecure bool    `conf:"env:TRACING_OTLP_INSECURE,default:false"`
+	TracingFile         string  `conf:"env:TRACING_FILE"`
+	TracingSampleRatio  float64 `conf:"env:TRACING_SAMPLE_RATIO,default:1"`
 }
 
 func main() {
@@ -54,6 +100,34 @@ func runConsumer(ctx context.Context) error {
 		return fmt.Errorf("error parsing the configuration: %w", err)
 	}
 
+	if cfg.SonarAuthToken == "" && cfg.SonarAuthTokenFile == "" {
+		return errors.New("error parsing the configuration: SONAR_AUTH_TOKEN or SONAR_AUTH_TOKEN_FILE is required")
+	}
+
+	permissionPolicy, err := model.ParsePermissionPolicy(cfg.SonarPermissionPolicy)
+	if err != nil {
+		return fmt.Errorf("error parsing the configuration: %w", err)
+	}
+
+	shutdownTracing, err := tracex.Setup(ctx, "worker", tracex.Config{
+		Exporter:     cfg.TracingExporter,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		OTLPProtocol: cfg.TracingOTLPProtocol,
+		OTLPInsecure: cfg.TracingOTLPInsecure,
+		File:         cfg.TracingFile,
+		SampleRatio:  cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("configuring tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("flush traces")
+		}
+	}()
+
 	client, err := pubsub.NewClient(ctx, cfg.ProjectID)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
@@ -74,19 +148,124 @@ func runConsumer(ctx context.Context) error {
 		return fmt.Errorf("creating subscription %s: %w", cfg.TokenGenerationSubscriptionID, err)
 	}
 
-	httpClient := sonarclient.New(sonarclient.Config{
-		Timeout:   cfg.SonarAPITimeout,
-		BaseURL:   cfg.SonarAPIAddress,
-		AuthToken: cfg.SonarAuthToken,
+	eventsTopic, err := pubsubx.CreateTopicIfNotExists(ctx, client, cfg.RequestEventsTopicID)
+	if err != nil {
+		return fmt.Errorf("creating topic %s: %w", cfg.RequestEventsTopicID, err)
+	}
+
+	audit, closeAudit, err := openAuditLog(ctx, cfg, client)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := closeAudit(); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("close audit log")
+		}
+	}()
+
+	// The metrics are only collected while they are served.
+	var metrics *metricsx.Metrics
+	if cfg.MetricsServerAddr != "" {
+		var opts []metricsx.Option
+		if cfg.MetricsProjectLabel {
+			opts = append(opts, metricsx.WithProjectLabel())
+		}
+		metrics = metricsx.New(opts...)
+	}
+
+	httpClient, err := sonarclient.New(sonarclient.Config{
+		Timeout:       cfg.SonarAPITimeout,
+		BaseURL:       cfg.SonarAPIAddress,
+		AuthToken:     cfg.SonarAuthToken,
+		AuthTokenFile: cfg.SonarAuthTokenFile,
+		TLS: sonarclient.TLSConfig{
+			CAFile:         cfg.SonarTLSCAFile,
+			CertFile:       cfg.SonarTLSCertFile,
+			KeyFile:        cfg.SonarTLSKeyFile,
+			MinVersion:     cfg.SonarTLSMinVersion,
+			ReloadInterval: cfg.SonarTLSReloadInterval,
+		},
+		Proxy: sonarclient.ProxyConfig{
+			URL:     cfg.SonarProxyURL,
+			NoProxy: cfg.SonarNoProxy,
+		},
+		Metrics: metrics,
 	})
+	if err != nil {
+		return fmt.Errorf("creating sonar client: %w", err)
+	}
 
 	tokenService := service.NewTokenGenerationService(struct {
 		*sonarclient.HTTPClient
 	}{
 		httpClient,
-	})
+	}, permissionPolicy)
 
-	tokenGeneratorConsumer := consumer.NewGenerateTokenConsumer(subs, tokenService)
+	credentials := httpClient.Credentials()
+	go credentials.Watch(ctx, cfg.SonarAuthTokenReloadInterval)
+
+	if cfg.SonarAuthTokenRotationInterval > 0 {
+		rotator, err := sonarclient.NewCredentialRotator(httpClient, sonarclient.RotationConfig{
+			Interval:    cfg.SonarAuthTokenRotationInterval,
+			RevokeDelay: cfg.SonarAuthTokenRevokeDelay,
+			OnRotate: func(ctx context.Context, name string) {
+				err := audit.Record(ctx, model.AuditEntry{
+					Time:      time.Now().UTC(),
+					Action:    model.AuditActionRotateCredential,
+					Outcome:   model.AuditOutcomeRotated,
+					TokenName: name,
+				})
+				if err != nil {
+					log.Ctx(ctx).Error().Err(err).Msg("recording audit entry")
+				}
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("creating credential rotator: %w", err)
+		}
+		go rotator.Run(ctx)
+	}
+
+	eventPublisher := pubsubgw.NewRequestEventPublisher(eventsTopic, pubsubgw.WithMetrics(metrics))
+
+	consumerOptions := []consumer.Option{
+		consumer.WithMetrics(metrics),
+		consumer.WithAuditRecorder(audit),
+	}
+	if cfg.StateStoreURL != "" {
+		stateStore, err := statestore.Open(cfg.StateStoreURL)
+		if err != nil {
+			return fmt.Errorf("opening state store: %w", err)
+		}
+		defer func() {
+			if err := stateStore.Close(); err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("close state store")
+			}
+		}()
+		// The worker only reads the cancellations, the TTL applies to writes.
+		consumerOptions = append(consumerOptions, consumer.WithCancellations(requeststore.New(stateStore, 0)))
+	}
+
+	tokenGeneratorConsumer := consumer.NewGenerateTokenConsumer(subs, tokenService, eventPublisher, consumerOptions...)
+
+	statusServer := createStatusServer(credentials, cfg)
+	go func() {
+		log.Ctx(ctx).Info().Str("address", statusServer.Addr).Msg("status server started")
+		if err := statusServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Ctx(ctx).Error().Err(err).Msg("status server failed")
+		}
+	}()
+
+	var metricsServer *http.Server
+	if metrics != nil {
+		metricsServer = createMetricsServer(metrics, cfg)
+		go func() {
+			log.Ctx(ctx).Info().Str("address", metricsServer.Addr).Msg("metrics server started")
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Ctx(ctx).Error().Err(err).Msg("metrics server failed")
+			}
+		}()
+	}
 
 	go func() {
 		log.Ctx(ctx).Info().Str("project_id", cfg.ProjectID).
@@ -115,5 +294,66 @@ func runConsumer(ctx context.Context) error {
 	}
 	log.Ctx(ctx).Info().Msg("Consumer stopped gracefully")
 
+	if err := statusServer.Shutdown(ctxStop); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("error shutting down status server")
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctxStop); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("error shutting down metrics server")
+		}
+	}
+
 	return nil
 }
+
+func createStatusServer(credentials *sonarclient.Credentials, cfg config) *http.Server {
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer)
+
+	statusAPI := api.New(credentials)
+	statusAPI.Routes(router)
+
+	return &http.Server{
+		Addr:              cfg.StatusServerAddr,
+		Handler:           router,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// createMetricsServer serves the metrics of the worker on a port of their own, so that
+// they can be scraped without exposing the status server.
+func createMetricsServer(metrics *metricsx.Metrics, cfg config) *http.Server {
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer)
+	router.Handle("/metrics", metrics.Handler())
+
+	return &http.Server{
+		Addr:              cfg.MetricsServerAddr,
+		Handler:           router,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// openAuditLog opens the audit log of the service, creating the audit topic when it is
+// one of the sinks.
+func openAuditLog(ctx context.Context, cfg config, client *pubsub.Client) (*auditlog.Chain, func() error, error) {
+	auditCfg := auditlog.Config{
+		Source: cfg.AuditSource,
+		Sinks:  cfg.AuditSinks,
+		File:   cfg.AuditFile,
+	}
+	if slices.Contains(cfg.AuditSinks, auditlog.SinkTopic) {
+		topic, err := pubsubx.CreateTopicIfNotExists(ctx, client, cfg.AuditTopicID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating topic %s: %w", cfg.AuditTopicID, err)
+		}
+		auditCfg.Topic = topic
+	}
+
+	chain, closeAudit, err := auditlog.Open("worker", auditCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening audit log: %w", err)
+	}
+
+	return chain, closeAudit, nil
+}
@@ -0,0 +1,267 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AccessRule allows the principals it matches, by subject or group, to request tokens
+// of the allowed types and TTLs for the projects it matches. Global analysis tokens
+// analyze every project, so the rules allowing them list no project.
+//
+// Subjects, groups and projects are glob patterns, where * matches any sequence of
+// characters and ? a single one, or regular expressions when enclosed in slashes
+// such as /^team-[a-z]+$/.
+type AccessRule struct {
+	Name     string   `yaml:"name" json:"name"`
+	Subjects []string `yaml:"subjects" json:"subjects,omitempty"`
+	Groups   []string `yaml:"groups" json:"groups,omitempty"`
+	Projects []string `yaml:"projects" json:"projects"`
+	// TokenTypes allowed by the rule, only project analysis tokens when empty.
+	TokenTypes []TokenType `yaml:"token_types" json:"token_types,omitempty"`
+	// MaxTTL caps the TTL of the tokens, which may then not be everlasting. Requests
+	// without a TTL are given MaxTTL.
+	MaxTTL time.Duration `yaml:"max_ttl" json:"max_ttl,omitempty"`
+
+	subjects []*regexp.Regexp
+	groups   []*regexp.Regexp
+	projects []*regexp.Regexp
+}
+
+// AccessPolicy decides which principals may request tokens for which projects. Rules
+// are evaluated in order and the first one matching the principal, the project,
+// the token type and the TTL allows the request. Requests matching no rule are
+// denied.
+//
+// Without a policy, that is with a nil one, project analysis tokens are allowed to
+// any principal, while global analysis tokens are denied since no rule allows them.
+type AccessPolicy struct {
+	Rules []AccessRule `yaml:"rules" json:"rules"`
+}
+
+// AccessDecision is the outcome of the evaluation of an AccessPolicy.
+type AccessDecision struct {
+	Allowed bool `json:"allowed"`
+	// Rule is the name of the rule allowing the request.
+	Rule string `json:"rule,omitempty"`
+	// Reason explains a denial.
+	Reason string `json:"reason,omitempty"`
+	// TTL is the TTL the token is issued with, once capped by the rule.
+	TTL time.Duration `json:"ttl,omitempty"`
+}
+
+// AccessGrant is what a rule of an AccessPolicy allows a principal to request.
+type AccessGrant struct {
+	Rule string `json:"rule"`
+	// Projects are the patterns of the projects allowed, with the syntax of the rules,
+	// none for the rules of global analysis tokens.
+	Projects   []string      `json:"projects"`
+	TokenTypes []TokenType   `json:"token_types"`
+	MaxTTL     time.Duration `json:"max_ttl,omitempty"`
+}
+
+// AccessDeniedError is returned when the access policy denies a request.
+type AccessDeniedError struct {
+	Decision AccessDecision
+}
+
+func (e *AccessDeniedError) Error() string {
+	return "access denied: " + e.Decision.Reason
+}
+
+// Compile validates the rules and compiles their patterns. It must be called before
+// the policy is evaluated.
+func (p *AccessPolicy) Compile() error {
+	if len(p.Rules) == 0 {
+		return errors.New("access policy has no rule")
+	}
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if len(rule.Subjects) == 0 && len(rule.Groups) == 0 {
+			return fmt.Errorf("rule %s: subjects or groups are required", rule.Name)
+		}
+		if slices.Contains(rule.TokenTypes, TokenTypeGlobalAnalysis) {
+			if len(rule.Projects) > 0 {
+				return fmt.Errorf("rule %s: global_analysis tokens analyze every project, allow them in a rule without projects", rule.Name)
+			}
+		} else if len(rule.Projects) == 0 {
+			return fmt.Errorf("rule %s: projects are required", rule.Name)
+		}
+		if rule.MaxTTL < 0 {
+			return fmt.Errorf("rule %s: negative max_ttl", rule.Name)
+		}
+		for _, tokenType := range rule.TokenTypes {
+			if _, err := ParseTokenType(string(tokenType)); err != nil || tokenType == "" {
+				return fmt.Errorf("rule %s: unknown token type %q", rule.Name, tokenType)
+			}
+		}
+
+		var err error
+		if rule.subjects, err = compilePatterns(rule.Subjects); err != nil {
+			return fmt.Errorf("rule %s: subjects: %w", rule.Name, err)
+		}
+		if rule.groups, err = compilePatterns(rule.Groups); err != nil {
+			return fmt.Errorf("rule %s: groups: %w", rule.Name, err)
+		}
+		if rule.projects, err = compilePatterns(rule.Projects); err != nil {
+			return fmt.Errorf("rule %s: projects: %w", rule.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Evaluate decides whether principal may issue request. The most specific reason is
+// reported on denial, favoring the rules matching the principal and the project. The
+// project of global analysis tokens is not evaluated, since they analyze every
+// project. p may be nil, see AccessPolicy.
+func (p *AccessPolicy) Evaluate(principal Principal, request TokenGenerationRequest) AccessDecision {
+	tokenType := request.TokenType
+	if tokenType == "" {
+		tokenType = TokenTypeProjectAnalysis
+	}
+
+	if p == nil {
+		if tokenType == TokenTypeGlobalAnalysis {
+			return AccessDecision{Reason: "global_analysis tokens require an access policy rule allowing them"}
+		}
+		return AccessDecision{Allowed: true, TTL: request.TTL}
+	}
+
+	reason := fmt.Sprintf("no rule allows %s to request tokens", principal.Subject)
+	if principal.Subject == "" {
+		reason = "unauthenticated requests are not allowed"
+	}
+
+	for _, rule := range p.Rules {
+		if !rule.matchesPrincipal(principal) {
+			continue
+		}
+		if tokenType != TokenTypeGlobalAnalysis && !matchesAny(rule.projects, request.ProjectID) {
+			reason = fmt.Sprintf("no rule allows %s to request tokens for project %s", principal.Subject, request.ProjectID)
+			continue
+		}
+
+		allowedTypes := rule.TokenTypes
+		if len(allowedTypes) == 0 {
+			allowedTypes = []TokenType{TokenTypeProjectAnalysis}
+		}
+		if !slices.Contains(allowedTypes, tokenType) {
+			reason = fmt.Sprintf("rule %s does not allow %s tokens", rule.Name, tokenType)
+			continue
+		}
+
+		ttl := request.TTL
+		if rule.MaxTTL > 0 {
+			if ttl == 0 {
+				ttl = rule.MaxTTL
+			}
+			if ttl > rule.MaxTTL {
+				reason = fmt.Sprintf("rule %s allows a TTL of at most %s", rule.Name, rule.MaxTTL)
+				continue
+			}
+		}
+
+		return AccessDecision{Allowed: true, Rule: rule.Name, TTL: ttl}
+	}
+
+	return AccessDecision{Reason: reason}
+}
+
+// Grants returns what the rules matching principal allow, in the order of the rules.
+func (p *AccessPolicy) Grants(principal Principal) []AccessGrant {
+	var grants []AccessGrant
+	for _, rule := range p.Rules {
+		if !rule.matchesPrincipal(principal) {
+			continue
+		}
+
+		tokenTypes := rule.TokenTypes
+		if len(tokenTypes) == 0 {
+			tokenTypes = []TokenType{TokenTypeProjectAnalysis}
+		}
+		grants = append(grants, AccessGrant{
+			Rule:       rule.Name,
+			Projects:   append([]string{}, rule.Projects...),
+			TokenTypes: slices.Clone(tokenTypes),
+			MaxTTL:     rule.MaxTTL,
+		})
+	}
+
+	return grants
+}
+
+func (r AccessRule) matchesPrincipal(principal Principal) bool {
+	if principal.Subject == "" {
+		return false
+	}
+	if matchesAny(r.subjects, principal.Subject) {
+		return true
+	}
+	for _, group := range principal.Groups {
+		if matchesAny(r.groups, group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesAny(patterns []*regexp.Regexp, value string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+
+	return compiled, nil
+}
+
+// compilePattern compiles a /regular expression/ or a glob pattern into an anchored
+// regular expression.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile("^(?:" + pattern[1:len(pattern)-1] + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		return re, nil
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, c := range pattern {
+		switch c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+const (
+	AuditActionRequestToken     = "token.request"
+	AuditActionIssueToken       = "token.issue"
+	AuditActionDeliverToken     = "token.deliver"
+	AuditActionExchangeToken    = "token.exchange"
+	AuditActionRevokeToken      = "token.revoke"
+	AuditActionRotateCredential = "credential.rotate"
+	AuditActionCancelRequest    = "request.cancel"
+	AuditActionRequeueRequest   = "request.requeue"
+
+	AuditOutcomeAccepted  = "accepted"
+	AuditOutcomeDenied    = "denied"
+	AuditOutcomeIssued    = "issued"
+	AuditOutcomeDelivered = "delivered"
+	AuditOutcomeFailed    = "failed"
+	AuditOutcomeRevoked   = "revoked"
+	AuditOutcomeRotated   = "rotated"
+	AuditOutcomeCanceled  = "canceled"
+	AuditOutcomeRequeued  = "requeued"
+)
+
+// AuditEntry records a security relevant decision of the services, or a step of the
+// lifecycle of a token. Which service or worker recorded it is kept by the audit log.
+type AuditEntry struct {
+	Time      time.Time  `json:"time"`
+	Action    string     `json:"action"`
+	Outcome   string     `json:"outcome"`
+	Principal *Principal `json:"principal,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+	ProjectID string     `json:"project_id,omitempty"`
+	TokenType TokenType  `json:"token_type,omitempty"`
+	// TokenName is the name of the token on the provider.
+	TokenName string        `json:"token_name,omitempty"`
+	TTL       time.Duration `json:"ttl,omitempty"`
+	Reason    string        `json:"reason,omitempty"`
+}
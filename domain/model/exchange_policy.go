@@ -0,0 +1,147 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultExchangeTTL is the TTL of exchanged tokens when the rule sets none, the
+// shortest Sonar supports.
+const DefaultExchangeTTL = 24 * time.Hour
+
+// ExchangeRule maps the identity tokens of CI jobs to the project they may analyze.
+//
+// Claims holds the patterns the claims of the identity token must all match, with
+// the same syntax as the access policy, "sub" being the subject. ProjectKey is a
+// text/template rendered with the claims, such as
+//
+//	{{ .repository | replace "/" "_" }}
+//
+// where the replace, lower and trimPrefix functions are available.
+type ExchangeRule struct {
+	Name       string            `yaml:"name"`
+	Issuer     string            `yaml:"issuer"`
+	Claims     map[string]string `yaml:"claims"`
+	ProjectKey string            `yaml:"project_key"`
+	// TTL of the exchanged tokens, DefaultExchangeTTL when zero.
+	TTL time.Duration `yaml:"ttl"`
+
+	claims     map[string]*regexp.Regexp
+	projectKey *template.Template
+}
+
+// ExchangePolicy holds the exchange rules. Rules are evaluated in order and the first
+// one matching the issuer and the claims of the identity token is used.
+type ExchangePolicy struct {
+	Rules []ExchangeRule `yaml:"rules"`
+}
+
+// ExchangeDecision is the outcome of the evaluation of an ExchangePolicy.
+type ExchangeDecision struct {
+	Allowed   bool
+	Rule      string
+	ProjectID string
+	TTL       time.Duration
+	Reason    string
+}
+
+var projectKeyFuncs = template.FuncMap{
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"lower":      strings.ToLower,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+}
+
+// Compile validates the rules and compiles their patterns and templates. It must be
+// called before the policy is evaluated.
+func (p *ExchangePolicy) Compile() error {
+	if len(p.Rules) == 0 {
+		return errors.New("exchange policy has no rule")
+	}
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if rule.Issuer == "" {
+			return fmt.Errorf("rule %s: issuer is required", rule.Name)
+		}
+		if len(rule.Claims) == 0 {
+			return fmt.Errorf("rule %s: claims are required", rule.Name)
+		}
+		if rule.ProjectKey == "" {
+			return fmt.Errorf("rule %s: project_key is required", rule.Name)
+		}
+		if rule.TTL < 0 {
+			return fmt.Errorf("rule %s: negative ttl", rule.Name)
+		}
+		if rule.TTL == 0 {
+			rule.TTL = DefaultExchangeTTL
+		}
+
+		rule.claims = make(map[string]*regexp.Regexp, len(rule.Claims))
+		for claim, pattern := range rule.Claims {
+			re, err := compilePattern(pattern)
+			if err != nil {
+				return fmt.Errorf("rule %s: claim %s: %w", rule.Name, claim, err)
+			}
+			rule.claims[claim] = re
+		}
+
+		tmpl, err := template.New(rule.Name).Funcs(projectKeyFuncs).Option("missingkey=error").Parse(rule.ProjectKey)
+		if err != nil {
+			return fmt.Errorf("rule %s: project_key: %w", rule.Name, err)
+		}
+		rule.projectKey = tmpl
+	}
+
+	return nil
+}
+
+// Evaluate resolves the project the principal authenticated with an identity token
+// may get a token for.
+func (p *ExchangePolicy) Evaluate(principal Principal) ExchangeDecision {
+	claims := make(map[string]string, len(principal.Attributes)+1)
+	for name, value := range principal.Attributes {
+		claims[name] = value
+	}
+	claims["sub"] = principal.Subject
+
+	for _, rule := range p.Rules {
+		if rule.Issuer != principal.Issuer || !rule.matches(claims) {
+			continue
+		}
+
+		var projectKey strings.Builder
+		if err := rule.projectKey.Execute(&projectKey, claims); err != nil {
+			return ExchangeDecision{Rule: rule.Name, Reason: fmt.Sprintf("rule %s: rendering project key: %s", rule.Name, err)}
+		}
+		if !ValidProjectKey(projectKey.String()) {
+			return ExchangeDecision{Rule: rule.Name, Reason: fmt.Sprintf("rule %s: invalid project key %q", rule.Name, projectKey.String())}
+		}
+
+		return ExchangeDecision{
+			Allowed:   true,
+			Rule:      rule.Name,
+			ProjectID: projectKey.String(),
+			TTL:       rule.TTL,
+		}
+	}
+
+	return ExchangeDecision{Reason: fmt.Sprintf("no exchange rule matches %s from %s", principal.Subject, principal.Issuer)}
+}
+
+func (r ExchangeRule) matches(claims map[string]string) bool {
+	for claim, pattern := range r.claims {
+		value, ok := claims[claim]
+		if !ok || !pattern.MatchString(value) {
+			return false
+		}
+	}
+
+	return true
+}
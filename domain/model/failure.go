@@ -0,0 +1,43 @@
+package model
+
+import "errors"
+
+// FailureReason classifies why a token generation request could not be fulfilled.
+type FailureReason string
+
+const (
+	FailureReasonInvalidRequest    FailureReason = "invalid_request"
+	FailureReasonMissingPermission FailureReason = "missing_permission"
+	FailureReasonProviderError     FailureReason = "provider_error"
+	FailureReasonPublishFailed     FailureReason = "publish_failed"
+)
+
+// ErrPermissionDenied is wrapped by the errors of the provider refusing to generate a
+// token for lack of permission.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// TokenGenerationError is returned by the domain services whenever a token generation
+// request fails, carrying the reason reported back in the request status.
+type TokenGenerationError struct {
+	Reason FailureReason
+	Err    error
+}
+
+func (e *TokenGenerationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TokenGenerationError) Unwrap() error {
+	return e.Err
+}
+
+// FailureReasonOf extracts the failure reason from err, defaulting to a provider error
+// when err does not carry one.
+func FailureReasonOf(err error) FailureReason {
+	var tokenErr *TokenGenerationError
+	if errors.As(err, &tokenErr) {
+		return tokenErr.Reason
+	}
+
+	return FailureReasonProviderError
+}
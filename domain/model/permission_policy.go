@@ -0,0 +1,25 @@
+package model
+
+import "fmt"
+
+// PermissionPolicy controls how the worker handles the "Execute Analysis" permission
+// of the Sonar user issuing project analysis tokens.
+type PermissionPolicy string
+
+const (
+	// PermissionPolicySkip generates tokens without checking permissions.
+	PermissionPolicySkip PermissionPolicy = "skip"
+	// PermissionPolicyCheck refuses to generate tokens when the permission is missing.
+	PermissionPolicyCheck PermissionPolicy = "check"
+	// PermissionPolicyGrant grants the permission when it is missing before generating tokens.
+	PermissionPolicyGrant PermissionPolicy = "grant"
+)
+
+func ParsePermissionPolicy(value string) (PermissionPolicy, error) {
+	switch policy := PermissionPolicy(value); policy {
+	case PermissionPolicySkip, PermissionPolicyCheck, PermissionPolicyGrant:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown permission policy %q", value)
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"context"
+	"net/url"
+	"slices"
+)
+
+const (
+	AuthMethodAPIKey     = "api_key"
+	AuthMethodJWT        = "jwt"
+	AuthMethodClientCert = "client_cert"
+
+	// ScopeRequestTokens allows requesting token generations.
+	ScopeRequestTokens = "tokens:request"
+	// ScopeAdmin allows managing the service, such as its API keys.
+	ScopeAdmin = "admin"
+)
+
+// Principal is the authenticated caller of the services.
+type Principal struct {
+	Subject string `json:"subject"`
+	Method  string `json:"method"`
+	// Issuer of the credentials, for principals authenticated by an identity provider.
+	Issuer string   `json:"issuer,omitempty"`
+	Name   string   `json:"name,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+	// Attributes are additional claims describing the caller, such as the repository
+	// a CI job runs for.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Owner identifies the principal as the owner of requests, as method:issuer:subject,
+// since equal subjects authenticated differently or by different issuers are
+// different principals. The issuer is escaped so that the owner cannot be ambiguous.
+// The owner of unauthenticated requests is empty.
+func (p Principal) Owner() string {
+	if p.Subject == "" {
+		return ""
+	}
+
+	return p.Method + ":" + url.QueryEscape(p.Issuer) + ":" + p.Subject
+}
+
+type principalContextKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying the authenticated principal.
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal carried by ctx, if any.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,17 @@
+package model
+
+import "regexp"
+
+// MaxProjectKeyLength is the longest project key Sonar accepts.
+const MaxProjectKeyLength = 400
+
+// ProjectKeyPattern matches the keys Sonar accepts: letters, digits, '-', '_', '.' and
+// ':', with at least one non-digit.
+const ProjectKeyPattern = `^[A-Za-z0-9_.:-]*[A-Za-z_.:-][A-Za-z0-9_.:-]*$`
+
+var projectKeyPattern = regexp.MustCompile(ProjectKeyPattern)
+
+// ValidProjectKey reports whether key is a valid Sonar project key.
+func ValidProjectKey(key string) bool {
+	return len(key) <= MaxProjectKeyLength && projectKeyPattern.MatchString(key)
+}
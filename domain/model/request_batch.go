@@ -0,0 +1,68 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrBatchNotFound is returned when a batch of requests is unknown or expired.
+var ErrBatchNotFound = errors.New("batch not found")
+
+// RequestBatch groups the token generation requests submitted together.
+type RequestBatch struct {
+	ID string `json:"id"`
+	// Owner is the owner of the principal who submitted the batch, if any, see
+	// Principal.Owner.
+	Owner     string    `json:"owner,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// RequestIDs lists the requests of the batch that were queued.
+	RequestIDs []string `json:"request_ids"`
+	// Rejected is the number of requests of the batch that could not be queued.
+	Rejected int `json:"rejected"`
+}
+
+// BatchItemResult is the outcome of queueing one request of a batch: its status once
+// queued, or why it could not be.
+type BatchItemResult struct {
+	Status RequestStatus
+	Err    error
+}
+
+// BatchState is the aggregate state of a batch of requests.
+type BatchState string
+
+const (
+	// BatchStatePending is the state of a batch with requests still being processed.
+	BatchStatePending BatchState = "pending"
+	// BatchStateCompleted is the state of a batch whose requests all completed, either
+	// issued or failed.
+	BatchStateCompleted BatchState = "completed"
+)
+
+// BatchStatus is the aggregate status of a batch of requests.
+type BatchStatus struct {
+	Batch RequestBatch
+	State BatchState
+	// Counts holds the number of requests of the batch in each state.
+	Counts   map[RequestState]int
+	Requests []RequestStatus
+}
+
+// NewBatchStatus aggregates the statuses of the requests of batch.
+func NewBatchStatus(batch RequestBatch, statuses []RequestStatus) BatchStatus {
+	status := BatchStatus{
+		Batch:    batch,
+		State:    BatchStateCompleted,
+		Counts:   make(map[RequestState]int),
+		Requests: statuses,
+	}
+
+	for _, request := range statuses {
+		status.Counts[request.State]++
+		if !request.State.Terminal() {
+			status.State = BatchStatePending
+		}
+	}
+
+	return status
+}
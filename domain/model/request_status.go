@@ -0,0 +1,207 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRequestNotFound is returned when a token generation request is unknown or its
+// status expired.
+var ErrRequestNotFound = errors.New("request not found")
+
+// ErrTokenNotRevocable is returned when revoking the token of a request that did not
+// issue one, or whose token cannot be identified on the provider.
+var ErrTokenNotRevocable = errors.New("token not revocable")
+
+// ErrRequestNotCancelable is returned when canceling a request a worker may already
+// have picked up.
+var ErrRequestNotCancelable = errors.New("request not cancelable")
+
+// ErrRequestNotRequeueable is returned when requeueing a request that did not fail.
+var ErrRequestNotRequeueable = errors.New("request not requeueable")
+
+// ErrTokenNotIssued is returned when retrieving the token of a request that did not
+// issue one yet, or never will.
+var ErrTokenNotIssued = errors.New("token not issued")
+
+// ErrTokenAlreadyRetrieved is returned when retrieving a token that was already
+// retrieved once, or revoked.
+var ErrTokenAlreadyRetrieved = errors.New("token already retrieved")
+
+// ErrTokenRevocationUnavailable is returned when revoking a token while no provider
+// client is configured to revoke it.
+var ErrTokenRevocationUnavailable = errors.New("token revocation unavailable")
+
+// ErrStatusConflict is returned when updating a request status that was updated since it
+// was read.
+var ErrStatusConflict = errors.New("request status updated concurrently")
+
+// RequestState is the state of an asynchronous token generation request.
+type RequestState string
+
+const (
+	RequestStateQueued     RequestState = "queued"
+	RequestStateProcessing RequestState = "processing"
+	RequestStateIssued     RequestState = "issued"
+	RequestStateFailed     RequestState = "failed"
+	// RequestStateCanceled is the state of requests canceled by an administrator before
+	// being processed.
+	RequestStateCanceled RequestState = "canceled"
+)
+
+// Terminal reports whether the request reached its final state.
+func (s RequestState) Terminal() bool {
+	return s == RequestStateIssued || s == RequestStateFailed || s == RequestStateCanceled
+}
+
+// RequestEventType is the type of the events reported while a request is processed.
+type RequestEventType string
+
+const (
+	RequestEventQueued RequestEventType = "queued"
+	// RequestEventPickedUp is reported when a worker starts processing the request.
+	RequestEventPickedUp RequestEventType = "picked_up"
+	// RequestEventCallingSonar is reported before the worker calls Sonar.
+	RequestEventCallingSonar RequestEventType = "calling_sonar"
+	// RequestEventRetrying is reported when a call to Sonar is retried.
+	RequestEventRetrying RequestEventType = "retrying"
+	RequestEventIssued   RequestEventType = "issued"
+	RequestEventFailed   RequestEventType = "failed"
+	// RequestEventCanceled is reported when the request is canceled, and by the worker
+	// when it skips a canceled request.
+	RequestEventCanceled RequestEventType = "canceled"
+	// RequestEventRequeued is reported when a failed request is queued again.
+	RequestEventRequeued RequestEventType = "requeued"
+)
+
+// RequestEvent reports the progress of a token generation request.
+type RequestEvent struct {
+	RequestID string           `json:"request_id"`
+	Type      RequestEventType `json:"type"`
+	Time      time.Time        `json:"time"`
+	// Attempt is the number of the retried attempt on retrying events.
+	Attempt int `json:"attempt,omitempty"`
+	// Token, TokenName and ExpiresAt are set on issued events.
+	Token     string     `json:"token,omitempty"`
+	TokenName string     `json:"token_name,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// FailureReason and Error are set on failed events, Error describing the failure
+	// for administrators.
+	FailureReason FailureReason `json:"failure_reason,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// RequestStatus is the status of a token generation request, as built from its events.
+type RequestStatus struct {
+	ID        string       `json:"id"`
+	ProjectID string       `json:"project_id,omitempty"`
+	TokenType TokenType    `json:"token_type,omitempty"`
+	State     RequestState `json:"state"`
+	// Owner is the owner of the principal who requested the token, if any, see
+	// Principal.Owner.
+	Owner     string    `json:"owner,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// TTL and Principal are those of the queued request, to queue it again.
+	TTL       time.Duration `json:"ttl,omitempty"`
+	Principal *Principal    `json:"principal,omitempty"`
+
+	// Token is kept apart from the status, and only set on the status returned with it
+	// once retrieved.
+	Token         string        `json:"-"`
+	TokenName     string        `json:"token_name,omitempty"`
+	ExpiresAt     *time.Time    `json:"expires_at,omitempty"`
+	FailureReason FailureReason `json:"failure_reason,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	// RevokedAt is set once the issued token was revoked.
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// RetrievedAt is set once the token was retrieved, after which it is no longer kept.
+	RetrievedAt *time.Time `json:"retrieved_at,omitempty"`
+
+	// Events lists the events applied to the status, without the token.
+	Events []RequestEvent `json:"events,omitempty"`
+	// Version counts the saves of the status, to detect concurrent updates.
+	Version int64 `json:"version,omitempty"`
+}
+
+// Apply updates the status with event and reports whether it changed. Events already
+// applied are ignored, as are events received once the request reached a terminal
+// state, so that redelivered events cannot regress it, except for requeued events
+// restarting failed requests and for tokens issued by a worker that called Sonar before
+// the request was canceled.
+func (s *RequestStatus) Apply(event RequestEvent) bool {
+	switch {
+	case s.applied(event):
+		return false
+	case event.Type == RequestEventRequeued:
+		if s.State != RequestStateFailed {
+			return false
+		}
+	case event.Type == RequestEventIssued && s.State == RequestStateCanceled:
+	case s.State.Terminal():
+		return false
+	}
+
+	s.UpdatedAt = event.Time
+	history := event
+	history.Token = ""
+	s.Events = append(s.Events, history)
+
+	switch event.Type {
+	case RequestEventQueued:
+		s.State = RequestStateQueued
+	case RequestEventPickedUp, RequestEventCallingSonar, RequestEventRetrying:
+		s.State = RequestStateProcessing
+	case RequestEventIssued:
+		s.State = RequestStateIssued
+		s.TokenName = event.TokenName
+		s.ExpiresAt = event.ExpiresAt
+	case RequestEventFailed:
+		s.State = RequestStateFailed
+		s.FailureReason = event.FailureReason
+		s.Error = event.Error
+	case RequestEventCanceled:
+		s.State = RequestStateCanceled
+	case RequestEventRequeued:
+		s.State = RequestStateQueued
+		s.FailureReason = ""
+		s.Error = ""
+	}
+
+	return true
+}
+
+// applied reports whether event is one of the events already applied to the status.
+func (s *RequestStatus) applied(event RequestEvent) bool {
+	for _, applied := range s.Events {
+		if applied.Type == event.Type && applied.Time.Equal(event.Time) && applied.Attempt == event.Attempt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ErrInvalidPageToken is returned when listing requests with a page token that was not
+// returned by a previous listing.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// RequestFilter selects the requests to list. Empty fields match every request.
+type RequestFilter struct {
+	State     RequestState
+	ProjectID string
+	// Owner is the owner of the principal who requested the tokens, see
+	// Principal.Owner.
+	Owner string
+	// PageToken continues a previous listing after its last request.
+	PageToken string
+	// Limit is the largest number of requests returned.
+	Limit int
+}
+
+// RequestPage is a page of requests, the most recent first.
+type RequestPage struct {
+	Requests []RequestStatus
+	// NextPageToken lists the following requests, it is empty on the last page.
+	NextPageToken string
+}
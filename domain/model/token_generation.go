@@ -1,5 +1,26 @@
 package model
 
+import "time"
+
 type TokenGenerationRequest struct {
-	ProjectID string `json:"project_id"`
+	// ID identifies the request to track its status, it is empty for requests
+	// processed synchronously.
+	ID        string    `json:"id,omitempty"`
+	ProjectID string    `json:"project_id"`
+	TokenType TokenType `json:"token_type,omitempty"`
+	// TTL is how long the token remains valid, it never expires when zero.
+	TTL time.Duration `json:"ttl,omitempty"`
+	// Principal is the authenticated caller who requested the token, if any.
+	Principal *Principal `json:"principal,omitempty"`
+}
+
+// IssuedToken is a token generated on the provider.
+type IssuedToken struct {
+	Token string `json:"token"`
+	// Name identifies the token on the provider, to revoke it.
+	Name      string    `json:"name,omitempty"`
+	ProjectID string    `json:"project_id"`
+	TokenType TokenType `json:"token_type"`
+	// ExpiresAt is zero for tokens that never expire.
+	ExpiresAt time.Time `json:"expires_at"`
 }
@@ -0,0 +1,26 @@
+package model
+
+import "fmt"
+
+// TokenType is the kind of Sonar token a caller requests.
+type TokenType string
+
+const (
+	// TokenTypeProjectAnalysis tokens can only analyze the requested project.
+	TokenTypeProjectAnalysis TokenType = "project_analysis"
+	// TokenTypeGlobalAnalysis tokens can analyze every project the issuing user may analyze.
+	TokenTypeGlobalAnalysis TokenType = "global_analysis"
+)
+
+// ParseTokenType parses a token type, defaulting to a project analysis token when
+// value is empty.
+func ParseTokenType(value string) (TokenType, error) {
+	switch tokenType := TokenType(value); tokenType {
+	case "":
+		return TokenTypeProjectAnalysis, nil
+	case TokenTypeProjectAnalysis, TokenTypeGlobalAnalysis:
+		return tokenType, nil
+	default:
+		return "", fmt.Errorf("unknown token type %q", value)
+	}
+}
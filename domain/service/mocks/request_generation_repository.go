@@ -23,6 +23,9 @@ var _ service.RequestTokenGenerationRepository = &RequestTokenGenerationReposito
 //			PublishRequestTokenGenerationFunc: func(ctx context.Context, request model.TokenGenerationRequest) error {
 //				panic("mock out the PublishRequestTokenGeneration method")
 //			},
+//			PublishRequestTokenGenerationsFunc: func(ctx context.Context, requests []model.TokenGenerationRequest) []error {
+//				panic("mock out the PublishRequestTokenGenerations method")
+//			},
 //		}
 //
 //		// use mockedRequestTokenGenerationRepository in code that requires service.RequestTokenGenerationRepository
@@ -33,6 +36,9 @@ type RequestTokenGenerationRepositoryMock struct {
 	// PublishRequestTokenGenerationFunc mocks the PublishRequestTokenGeneration method.
 	PublishRequestTokenGenerationFunc func(ctx context.Context, request model.TokenGenerationRequest) error
 
+	// PublishRequestTokenGenerationsFunc mocks the PublishRequestTokenGenerations method.
+	PublishRequestTokenGenerationsFunc func(ctx context.Context, requests []model.TokenGenerationRequest) []error
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// PublishRequestTokenGeneration holds details about calls to the PublishRequestTokenGeneration method.
@@ -42,8 +48,16 @@ type RequestTokenGenerationRepositoryMock struct {
 			// Request is the request argument value.
 			Request model.TokenGenerationRequest
 		}
+		// PublishRequestTokenGenerations holds details about calls to the PublishRequestTokenGenerations method.
+		PublishRequestTokenGenerations []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Requests is the requests argument value.
+			Requests []model.TokenGenerationRequest
+		}
 	}
-	lockPublishRequestTokenGeneration sync.RWMutex
+	lockPublishRequestTokenGeneration  sync.RWMutex
+	lockPublishRequestTokenGenerations sync.RWMutex
 }
 
 // PublishRequestTokenGeneration calls PublishRequestTokenGenerationFunc.
@@ -84,3 +98,42 @@ func (mock *RequestTokenGenerationRepositoryMock) PublishRequestTokenGenerationC
 	mock.lockPublishRequestTokenGeneration.RUnlock()
 	return calls
 }
+
+// PublishRequestTokenGenerations calls PublishRequestTokenGenerationsFunc.
+func (mock *RequestTokenGenerationRepositoryMock) PublishRequestTokenGenerations(ctx context.Context, requests []model.TokenGenerationRequest) []error {
+	callInfo := struct {
+		Ctx      context.Context
+		Requests []model.TokenGenerationRequest
+	}{
+		Ctx:      ctx,
+		Requests: requests,
+	}
+	mock.lockPublishRequestTokenGenerations.Lock()
+	mock.calls.PublishRequestTokenGenerations = append(mock.calls.PublishRequestTokenGenerations, callInfo)
+	mock.lockPublishRequestTokenGenerations.Unlock()
+	if mock.PublishRequestTokenGenerationsFunc == nil {
+		var (
+			errorsOut []error
+		)
+		return errorsOut
+	}
+	return mock.PublishRequestTokenGenerationsFunc(ctx, requests)
+}
+
+// PublishRequestTokenGenerationsCalls gets all the calls that were made to PublishRequestTokenGenerations.
+// Check the length with:
+//
+//	len(mockedRequestTokenGenerationRepository.PublishRequestTokenGenerationsCalls())
+func (mock *RequestTokenGenerationRepositoryMock) PublishRequestTokenGenerationsCalls() []struct {
+	Ctx      context.Context
+	Requests []model.TokenGenerationRequest
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Requests []model.TokenGenerationRequest
+	}
+	mock.lockPublishRequestTokenGenerations.RLock()
+	calls = mock.calls.PublishRequestTokenGenerations
+	mock.lockPublishRequestTokenGenerations.RUnlock()
+	return calls
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"github.com/werbersondev/token-generator-test/domain/service"
 	"sync"
+	"time"
 )
 
 // Ensure, that TokenGenerationRepositoryMock does implement service.TokenGenerationRepository.
@@ -19,9 +20,18 @@ var _ service.TokenGenerationRepository = &TokenGenerationRepositoryMock{}
 //
 //		// make and configure a mocked service.TokenGenerationRepository
 //		mockedTokenGenerationRepository := &TokenGenerationRepositoryMock{
-//			GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string) (string, error) {
+//			GenerateGlobalAnalysisTokenFunc: func(ctx context.Context, tokenName string, expiresAt time.Time) (string, error) {
+//				panic("mock out the GenerateGlobalAnalysisToken method")
+//			},
+//			GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string, expiresAt time.Time) (string, error) {
 //				panic("mock out the GenerateProjectAnalysisToken method")
 //			},
+//			GrantProjectAnalysisPermissionFunc: func(ctx context.Context, projectID string) error {
+//				panic("mock out the GrantProjectAnalysisPermission method")
+//			},
+//			HasProjectAnalysisPermissionFunc: func(ctx context.Context, projectID string) (bool, error) {
+//				panic("mock out the HasProjectAnalysisPermission method")
+//			},
 //		}
 //
 //		// use mockedTokenGenerationRepository in code that requires service.TokenGenerationRepository
@@ -29,11 +39,29 @@ var _ service.TokenGenerationRepository = &TokenGenerationRepositoryMock{}
 //
 //	}
 type TokenGenerationRepositoryMock struct {
+	// GenerateGlobalAnalysisTokenFunc mocks the GenerateGlobalAnalysisToken method.
+	GenerateGlobalAnalysisTokenFunc func(ctx context.Context, tokenName string, expiresAt time.Time) (string, error)
+
 	// GenerateProjectAnalysisTokenFunc mocks the GenerateProjectAnalysisToken method.
-	GenerateProjectAnalysisTokenFunc func(ctx context.Context, projectID string, tokenName string) (string, error)
+	GenerateProjectAnalysisTokenFunc func(ctx context.Context, projectID string, tokenName string, expiresAt time.Time) (string, error)
+
+	// GrantProjectAnalysisPermissionFunc mocks the GrantProjectAnalysisPermission method.
+	GrantProjectAnalysisPermissionFunc func(ctx context.Context, projectID string) error
+
+	// HasProjectAnalysisPermissionFunc mocks the HasProjectAnalysisPermission method.
+	HasProjectAnalysisPermissionFunc func(ctx context.Context, projectID string) (bool, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// GenerateGlobalAnalysisToken holds details about calls to the GenerateGlobalAnalysisToken method.
+		GenerateGlobalAnalysisToken []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// TokenName is the tokenName argument value.
+			TokenName string
+			// ExpiresAt is the expiresAt argument value.
+			ExpiresAt time.Time
+		}
 		// GenerateProjectAnalysisToken holds details about calls to the GenerateProjectAnalysisToken method.
 		GenerateProjectAnalysisToken []struct {
 			// Ctx is the ctx argument value.
@@ -42,21 +70,86 @@ type TokenGenerationRepositoryMock struct {
 			ProjectID string
 			// TokenName is the tokenName argument value.
 			TokenName string
+			// ExpiresAt is the expiresAt argument value.
+			ExpiresAt time.Time
+		}
+		// GrantProjectAnalysisPermission holds details about calls to the GrantProjectAnalysisPermission method.
+		GrantProjectAnalysisPermission []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProjectID is the projectID argument value.
+			ProjectID string
+		}
+		// HasProjectAnalysisPermission holds details about calls to the HasProjectAnalysisPermission method.
+		HasProjectAnalysisPermission []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProjectID is the projectID argument value.
+			ProjectID string
 		}
 	}
-	lockGenerateProjectAnalysisToken sync.RWMutex
+	lockGenerateGlobalAnalysisToken    sync.RWMutex
+	lockGenerateProjectAnalysisToken   sync.RWMutex
+	lockGrantProjectAnalysisPermission sync.RWMutex
+	lockHasProjectAnalysisPermission   sync.RWMutex
+}
+
+// GenerateGlobalAnalysisToken calls GenerateGlobalAnalysisTokenFunc.
+func (mock *TokenGenerationRepositoryMock) GenerateGlobalAnalysisToken(ctx context.Context, tokenName string, expiresAt time.Time) (string, error) {
+	callInfo := struct {
+		Ctx       context.Context
+		TokenName string
+		ExpiresAt time.Time
+	}{
+		Ctx:       ctx,
+		TokenName: tokenName,
+		ExpiresAt: expiresAt,
+	}
+	mock.lockGenerateGlobalAnalysisToken.Lock()
+	mock.calls.GenerateGlobalAnalysisToken = append(mock.calls.GenerateGlobalAnalysisToken, callInfo)
+	mock.lockGenerateGlobalAnalysisToken.Unlock()
+	if mock.GenerateGlobalAnalysisTokenFunc == nil {
+		var (
+			sOut   string
+			errOut error
+		)
+		return sOut, errOut
+	}
+	return mock.GenerateGlobalAnalysisTokenFunc(ctx, tokenName, expiresAt)
+}
+
+// GenerateGlobalAnalysisTokenCalls gets all the calls that were made to GenerateGlobalAnalysisToken.
+// Check the length with:
+//
+//	len(mockedTokenGenerationRepository.GenerateGlobalAnalysisTokenCalls())
+func (mock *TokenGenerationRepositoryMock) GenerateGlobalAnalysisTokenCalls() []struct {
+	Ctx       context.Context
+	TokenName string
+	ExpiresAt time.Time
+} {
+	var calls []struct {
+		Ctx       context.Context
+		TokenName string
+		ExpiresAt time.Time
+	}
+	mock.lockGenerateGlobalAnalysisToken.RLock()
+	calls = mock.calls.GenerateGlobalAnalysisToken
+	mock.lockGenerateGlobalAnalysisToken.RUnlock()
+	return calls
 }
 
 // GenerateProjectAnalysisToken calls GenerateProjectAnalysisTokenFunc.
-func (mock *TokenGenerationRepositoryMock) GenerateProjectAnalysisToken(ctx context.Context, projectID string, tokenName string) (string, error) {
+func (mock *TokenGenerationRepositoryMock) GenerateProjectAnalysisToken(ctx context.Context, projectID string, tokenName string, expiresAt time.Time) (string, error) {
 	callInfo := struct {
 		Ctx       context.Context
 		ProjectID string
 		TokenName string
+		ExpiresAt time.Time
 	}{
 		Ctx:       ctx,
 		ProjectID: projectID,
 		TokenName: tokenName,
+		ExpiresAt: expiresAt,
 	}
 	mock.lockGenerateProjectAnalysisToken.Lock()
 	mock.calls.GenerateProjectAnalysisToken = append(mock.calls.GenerateProjectAnalysisToken, callInfo)
@@ -68,7 +161,7 @@ func (mock *TokenGenerationRepositoryMock) GenerateProjectAnalysisToken(ctx cont
 		)
 		return sOut, errOut
 	}
-	return mock.GenerateProjectAnalysisTokenFunc(ctx, projectID, tokenName)
+	return mock.GenerateProjectAnalysisTokenFunc(ctx, projectID, tokenName, expiresAt)
 }
 
 // GenerateProjectAnalysisTokenCalls gets all the calls that were made to GenerateProjectAnalysisToken.
@@ -79,14 +172,95 @@ func (mock *TokenGenerationRepositoryMock) GenerateProjectAnalysisTokenCalls() [
 	Ctx       context.Context
 	ProjectID string
 	TokenName string
+	ExpiresAt time.Time
 } {
 	var calls []struct {
 		Ctx       context.Context
 		ProjectID string
 		TokenName string
+		ExpiresAt time.Time
 	}
 	mock.lockGenerateProjectAnalysisToken.RLock()
 	calls = mock.calls.GenerateProjectAnalysisToken
 	mock.lockGenerateProjectAnalysisToken.RUnlock()
 	return calls
 }
+
+// GrantProjectAnalysisPermission calls GrantProjectAnalysisPermissionFunc.
+func (mock *TokenGenerationRepositoryMock) GrantProjectAnalysisPermission(ctx context.Context, projectID string) error {
+	callInfo := struct {
+		Ctx       context.Context
+		ProjectID string
+	}{
+		Ctx:       ctx,
+		ProjectID: projectID,
+	}
+	mock.lockGrantProjectAnalysisPermission.Lock()
+	mock.calls.GrantProjectAnalysisPermission = append(mock.calls.GrantProjectAnalysisPermission, callInfo)
+	mock.lockGrantProjectAnalysisPermission.Unlock()
+	if mock.GrantProjectAnalysisPermissionFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.GrantProjectAnalysisPermissionFunc(ctx, projectID)
+}
+
+// GrantProjectAnalysisPermissionCalls gets all the calls that were made to GrantProjectAnalysisPermission.
+// Check the length with:
+//
+//	len(mockedTokenGenerationRepository.GrantProjectAnalysisPermissionCalls())
+func (mock *TokenGenerationRepositoryMock) GrantProjectAnalysisPermissionCalls() []struct {
+	Ctx       context.Context
+	ProjectID string
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ProjectID string
+	}
+	mock.lockGrantProjectAnalysisPermission.RLock()
+	calls = mock.calls.GrantProjectAnalysisPermission
+	mock.lockGrantProjectAnalysisPermission.RUnlock()
+	return calls
+}
+
+// HasProjectAnalysisPermission calls HasProjectAnalysisPermissionFunc.
+func (mock *TokenGenerationRepositoryMock) HasProjectAnalysisPermission(ctx context.Context, projectID string) (bool, error) {
+	callInfo := struct {
+		Ctx       context.Context
+		ProjectID string
+	}{
+		Ctx:       ctx,
+		ProjectID: projectID,
+	}
+	mock.lockHasProjectAnalysisPermission.Lock()
+	mock.calls.HasProjectAnalysisPermission = append(mock.calls.HasProjectAnalysisPermission, callInfo)
+	mock.lockHasProjectAnalysisPermission.Unlock()
+	if mock.HasProjectAnalysisPermissionFunc == nil {
+		var (
+			bOut   bool
+			errOut error
+		)
+		return bOut, errOut
+	}
+	return mock.HasProjectAnalysisPermissionFunc(ctx, projectID)
+}
+
+// HasProjectAnalysisPermissionCalls gets all the calls that were made to HasProjectAnalysisPermission.
+// Check the length with:
+//
+//	len(mockedTokenGenerationRepository.HasProjectAnalysisPermissionCalls())
+func (mock *TokenGenerationRepositoryMock) HasProjectAnalysisPermissionCalls() []struct {
+	Ctx       context.Context
+	ProjectID string
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ProjectID string
+	}
+	mock.lockHasProjectAnalysisPermission.RLock()
+	calls = mock.calls.HasProjectAnalysisPermission
+	mock.lockHasProjectAnalysisPermission.RUnlock()
+	return calls
+}
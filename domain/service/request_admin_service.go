@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+const (
+	defaultRequestPageSize = 50
+	maxRequestPageSize     = 500
+)
+
+// errStatusChanged is returned when restoring a status changed concurrently.
+var errStatusChanged = errors.New("request status changed since read")
+
+//go:generate moq -stub -pkg mocks -out mocks/request_admin_repository.go . RequestAdminRepository
+type RequestAdminRepository interface {
+	RequestStatusRepository
+	// ListRequestStatuses returns the status of every request that did not expire.
+	ListRequestStatuses(ctx context.Context) ([]model.RequestStatus, error)
+	// CancelRequest flags the request for the worker to skip it.
+	CancelRequest(ctx context.Context, id string) error
+}
+
+//go:generate moq -stub -pkg mocks -out mocks/request_event_repository.go . RequestEventRepository
+type RequestEventRepository interface {
+	PublishRequestEvent(ctx context.Context, event model.RequestEvent) error
+}
+
+// RequestAdminService lets administrators inspect the token generation requests,
+// cancel those not processed yet and queue failed ones again.
+type RequestAdminService struct {
+	repository RequestAdminRepository
+	requests   RequestTokenGenerationRepository
+	events     RequestEventRepository
+	audit      AuditRecorder
+}
+
+func NewRequestAdminService(repo RequestAdminRepository, requests RequestTokenGenerationRepository, events RequestEventRepository, audit AuditRecorder) *RequestAdminService {
+	return &RequestAdminService{
+		repository: repo,
+		requests:   requests,
+		events:     events,
+		audit:      audit,
+	}
+}
+
+// ListRequests returns a page of the requests matching filter, the most recent first.
+// It fails with model.ErrInvalidPageToken when the page token cannot be decoded.
+func (s *RequestAdminService) ListRequests(ctx context.Context, filter model.RequestFilter) (model.RequestPage, error) {
+	var after *pagePosition
+	if filter.PageToken != "" {
+		position, err := decodePageToken(filter.PageToken)
+		if err != nil {
+			return model.RequestPage{}, err
+		}
+		after = &position
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultRequestPageSize
+	}
+	limit = min(limit, maxRequestPageSize)
+
+	statuses, err := s.repository.ListRequestStatuses(ctx)
+	if err != nil {
+		return model.RequestPage{}, err
+	}
+
+	matching := make([]model.RequestStatus, 0, len(statuses))
+	for _, status := range statuses {
+		if matchesFilter(status, filter) && (after == nil || after.before(status)) {
+			matching = append(matching, status)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return positionOf(matching[i]).before(matching[j])
+	})
+
+	var page model.RequestPage
+	if len(matching) > limit {
+		matching = matching[:limit]
+		page.NextPageToken = positionOf(matching[limit-1]).encode()
+	}
+	page.Requests = matching
+
+	return page, nil
+}
+
+// GetRequest returns the status of the request with the given ID, with its events and
+// errors.
+func (s *RequestAdminService) GetRequest(ctx context.Context, id string) (model.RequestStatus, error) {
+	return s.repository.GetRequestStatus(ctx, id)
+}
+
+// CancelRequest cancels a queued request and returns its updated status. Requests a
+// worker may have picked up fail with model.ErrRequestNotCancelable. The worker checks
+// the cancellation right before calling Sonar, a worker already past that point still
+// issues the token, which then replaces the canceled state.
+func (s *RequestAdminService) CancelRequest(ctx context.Context, id string) (model.RequestStatus, error) {
+	status, err := s.repository.GetRequestStatus(ctx, id)
+	if err != nil {
+		return model.RequestStatus{}, err
+	}
+	if status.State != model.RequestStateQueued {
+		return model.RequestStatus{}, model.ErrRequestNotCancelable
+	}
+
+	// Flag the request first, so that the worker skips it even if what follows fails.
+	if err := s.repository.CancelRequest(ctx, id); err != nil {
+		return model.RequestStatus{}, fmt.Errorf("canceling request: %w", err)
+	}
+
+	event := model.RequestEvent{RequestID: id, Type: model.RequestEventCanceled, Time: time.Now().UTC()}
+	// The status is only saved if unchanged since read, a worker may pick it up
+	// meanwhile.
+	status, err = updateRequestStatus(ctx, s.repository, id, func(status *model.RequestStatus) error {
+		if status.State != model.RequestStateQueued {
+			return model.ErrRequestNotCancelable
+		}
+		status.Apply(event)
+		return nil
+	})
+	if err != nil {
+		return model.RequestStatus{}, err
+	}
+	s.record(ctx, model.AuditActionCancelRequest, status, model.AuditOutcomeCanceled)
+
+	// Callers waiting for the request on other replicas are notified through the events.
+	if err := s.events.PublishRequestEvent(ctx, event); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("publishing request event")
+	}
+
+	return status, nil
+}
+
+// RequeueRequest queues a failed request again, on behalf of the principal who
+// requested it, and returns its updated status. Other requests fail with
+// model.ErrRequestNotRequeueable.
+func (s *RequestAdminService) RequeueRequest(ctx context.Context, id string) (model.RequestStatus, error) {
+	// The status is saved first, the worker may report progress right away, and only if
+	// unchanged since read, another administrator may requeue it meanwhile.
+	var failed model.RequestStatus
+	requeued := model.RequestEvent{RequestID: id, Type: model.RequestEventRequeued, Time: time.Now().UTC()}
+	status, err := updateRequestStatus(ctx, s.repository, id, func(status *model.RequestStatus) error {
+		if status.State != model.RequestStateFailed {
+			return model.ErrRequestNotRequeueable
+		}
+		failed = *status
+		status.Events = append([]model.RequestEvent(nil), status.Events...)
+		status.Apply(requeued)
+		return nil
+	})
+	if err != nil {
+		return model.RequestStatus{}, err
+	}
+
+	err = s.requests.PublishRequestTokenGeneration(ctx, model.TokenGenerationRequest{
+		ID:        status.ID,
+		ProjectID: status.ProjectID,
+		TokenType: status.TokenType,
+		TTL:       status.TTL,
+		Principal: status.Principal,
+	})
+	if err != nil {
+		// The failed status is restored unless the request changed since requeued.
+		_, restoreErr := updateRequestStatus(ctx, s.repository, id, func(status *model.RequestStatus) error {
+			if status.State != model.RequestStateQueued || !status.UpdatedAt.Equal(requeued.Time) {
+				return errStatusChanged
+			}
+			version := status.Version
+			*status = failed
+			status.Version = version
+			return nil
+		})
+		if restoreErr != nil {
+			log.Ctx(ctx).Error().Err(restoreErr).Str("request_id", id).Msg("restoring failed request status")
+		}
+		return model.RequestStatus{}, fmt.Errorf("publishing request token generation: %w", err)
+	}
+	s.record(ctx, model.AuditActionRequeueRequest, status, model.AuditOutcomeRequeued)
+
+	return status, nil
+}
+
+func (s *RequestAdminService) record(ctx context.Context, action string, status model.RequestStatus, outcome string) {
+	entry := model.AuditEntry{
+		Time:      time.Now().UTC(),
+		Action:    action,
+		Outcome:   outcome,
+		RequestID: status.ID,
+		ProjectID: status.ProjectID,
+		TokenType: status.TokenType,
+	}
+	if principal, ok := model.PrincipalFromContext(ctx); ok {
+		entry.Principal = &principal
+	}
+
+	if err := s.audit.Record(ctx, entry); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("recording audit entry")
+	}
+}
+
+func matchesFilter(status model.RequestStatus, filter model.RequestFilter) bool {
+	return (filter.State == "" || status.State == filter.State) &&
+		(filter.ProjectID == "" || status.ProjectID == filter.ProjectID) &&
+		(filter.Owner == "" || status.Owner == filter.Owner)
+}
+
+// pagePosition is the position of a request in the listing, ordered by creation time
+// then ID, so that requests created at once keep a stable order across pages.
+type pagePosition struct {
+	createdAt time.Time
+	id        string
+}
+
+func positionOf(status model.RequestStatus) pagePosition {
+	return pagePosition{createdAt: status.CreatedAt, id: status.ID}
+}
+
+// before reports whether the position comes before status, the most recent first.
+func (p pagePosition) before(status model.RequestStatus) bool {
+	if !p.createdAt.Equal(status.CreatedAt) {
+		return p.createdAt.After(status.CreatedAt)
+	}
+
+	return p.id < status.ID
+}
+
+func (p pagePosition) encode() string {
+	raw := strconv.FormatInt(p.createdAt.UnixNano(), 10) + ":" + p.id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodePageToken(token string) (pagePosition, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return pagePosition{}, model.ErrInvalidPageToken
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return pagePosition{}, model.ErrInvalidPageToken
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return pagePosition{}, model.ErrInvalidPageToken
+	}
+
+	return pagePosition{createdAt: time.Unix(0, unixNano).UTC(), id: id}, nil
+}
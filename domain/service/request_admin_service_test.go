@@ -0,0 +1,299 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/domain/service"
+	"github.com/werbersondev/token-generator-test/domain/service/mocks"
+)
+
+func TestRequestAdminService_ListRequests(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	statuses := []model.RequestStatus{
+		{ID: "a", ProjectID: "app", Owner: "ci", State: model.RequestStateIssued, CreatedAt: now.Add(-3 * time.Minute)},
+		{ID: "b", ProjectID: "app", Owner: "ci", State: model.RequestStateFailed, CreatedAt: now},
+		{ID: "c", ProjectID: "lib", Owner: "dev", State: model.RequestStateQueued, CreatedAt: now.Add(-time.Minute)},
+		// Created at once with b, ordered after it by ID.
+		{ID: "d", ProjectID: "lib", Owner: "ci", State: model.RequestStateFailed, CreatedAt: now},
+	}
+
+	repository := &mocks.RequestAdminRepositoryMock{
+		ListRequestStatusesFunc: func(ctx context.Context) ([]model.RequestStatus, error) {
+			return statuses, nil
+		},
+	}
+	admin := service.NewRequestAdminService(repository, &mocks.RequestTokenGenerationRepositoryMock{}, &mocks.RequestEventRepositoryMock{}, &mocks.AuditRecorderMock{})
+
+	ids := func(page model.RequestPage) []string {
+		var ids []string
+		for _, status := range page.Requests {
+			ids = append(ids, status.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name        string
+		filter      model.RequestFilter
+		expectedIDs []string
+	}{
+		{name: "All", expectedIDs: []string{"b", "d", "c", "a"}},
+		{name: "By State", filter: model.RequestFilter{State: model.RequestStateFailed}, expectedIDs: []string{"b", "d"}},
+		{name: "By Project", filter: model.RequestFilter{ProjectID: "lib"}, expectedIDs: []string{"d", "c"}},
+		{name: "By Owner", filter: model.RequestFilter{Owner: "ci", ProjectID: "app"}, expectedIDs: []string{"b", "a"}},
+		{name: "No Match", filter: model.RequestFilter{Owner: "nobody"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := admin.ListRequests(context.Background(), tt.filter)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedIDs, ids(page))
+			assert.Empty(t, page.NextPageToken)
+		})
+	}
+
+	t.Run("Pages", func(t *testing.T) {
+		var listed []string
+		filter := model.RequestFilter{Limit: 3}
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 3, "the listing does not end")
+
+			page, err := admin.ListRequests(context.Background(), filter)
+			require.NoError(t, err)
+			listed = append(listed, ids(page)...)
+			if page.NextPageToken == "" {
+				break
+			}
+			filter.PageToken = page.NextPageToken
+		}
+
+		assert.Equal(t, []string{"b", "d", "c", "a"}, listed)
+	})
+
+	t.Run("Invalid Page Token", func(t *testing.T) {
+		for _, token := range []string{"not base64!", "bm9jb2xvbg", "eDpk"} {
+			_, err := admin.ListRequests(context.Background(), model.RequestFilter{PageToken: token})
+			assert.ErrorIs(t, err, model.ErrInvalidPageToken, token)
+		}
+	})
+}
+
+func TestRequestAdminService_CancelRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          model.RequestStatus
+		statusErr       error
+		cancelErr       error
+		concurrent      *model.RequestStatus
+		expectedErr     error
+		expectedCancels int
+		expectedEvents  int
+		expectedUpdates int
+	}{
+		{
+			name:            "Canceled",
+			status:          model.RequestStatus{ID: "req-1", ProjectID: "app", State: model.RequestStateQueued},
+			expectedCancels: 1,
+			expectedEvents:  1,
+			expectedUpdates: 1,
+		},
+		{
+			name:            "Canceled After Concurrent Update",
+			status:          model.RequestStatus{ID: "req-1", ProjectID: "app", State: model.RequestStateQueued},
+			concurrent:      &model.RequestStatus{ID: "req-1", ProjectID: "app", State: model.RequestStateQueued, Version: 1},
+			expectedCancels: 1,
+			expectedEvents:  1,
+			expectedUpdates: 2,
+		},
+		{
+			name:            "Picked Up Concurrently",
+			status:          model.RequestStatus{ID: "req-1", State: model.RequestStateQueued},
+			concurrent:      &model.RequestStatus{ID: "req-1", State: model.RequestStateProcessing, Version: 1},
+			expectedErr:     model.ErrRequestNotCancelable,
+			expectedCancels: 1,
+			expectedUpdates: 1,
+		},
+		{
+			name:        "Unknown Request",
+			statusErr:   model.ErrRequestNotFound,
+			expectedErr: model.ErrRequestNotFound,
+		},
+		{
+			name:        "Processing",
+			status:      model.RequestStatus{ID: "req-1", State: model.RequestStateProcessing},
+			expectedErr: model.ErrRequestNotCancelable,
+		},
+		{
+			name:        "Issued",
+			status:      model.RequestStatus{ID: "req-1", State: model.RequestStateIssued},
+			expectedErr: model.ErrRequestNotCancelable,
+		},
+		{
+			name:            "Flag Failure",
+			status:          model.RequestStatus{ID: "req-1", State: model.RequestStateQueued},
+			cancelErr:       errors.New("state store unavailable"),
+			expectedCancels: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := tt.status
+			repository := &mocks.RequestAdminRepositoryMock{
+				GetRequestStatusFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					return current, tt.statusErr
+				},
+				UpdateRequestStatusFunc: func(ctx context.Context, status model.RequestStatus) error {
+					if tt.concurrent != nil && current.Version != tt.concurrent.Version {
+						current = *tt.concurrent
+						return model.ErrStatusConflict
+					}
+					current = status
+					return nil
+				},
+				CancelRequestFunc: func(ctx context.Context, id string) error {
+					return tt.cancelErr
+				},
+			}
+			events := &mocks.RequestEventRepositoryMock{}
+			audit := &mocks.AuditRecorderMock{}
+			admin := service.NewRequestAdminService(repository, &mocks.RequestTokenGenerationRepositoryMock{}, events, audit)
+
+			status, err := admin.CancelRequest(context.Background(), "req-1")
+
+			assert.Len(t, repository.CancelRequestCalls(), tt.expectedCancels)
+			assert.Len(t, events.PublishRequestEventCalls(), tt.expectedEvents)
+			assert.Len(t, repository.UpdateRequestStatusCalls(), tt.expectedUpdates)
+			assert.Empty(t, repository.SaveRequestStatusCalls(), "the status is saved without version check")
+			if tt.expectedErr != nil || tt.cancelErr != nil {
+				if tt.expectedErr != nil {
+					assert.ErrorIs(t, err, tt.expectedErr)
+				} else {
+					assert.Error(t, err)
+				}
+				assert.Empty(t, audit.RecordCalls())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, model.RequestStateCanceled, status.State)
+			assert.Equal(t, model.RequestStateCanceled, current.State)
+			assert.Equal(t, model.RequestEventCanceled, events.PublishRequestEventCalls()[0].Event.Type)
+			if assert.Len(t, audit.RecordCalls(), 1) {
+				entry := audit.RecordCalls()[0].Entry
+				assert.Equal(t, model.AuditActionCancelRequest, entry.Action)
+				assert.Equal(t, model.AuditOutcomeCanceled, entry.Outcome)
+				assert.Equal(t, "req-1", entry.RequestID)
+			}
+		})
+	}
+}
+
+func TestRequestAdminService_RequeueRequest(t *testing.T) {
+	principal := &model.Principal{Subject: "ci", Method: model.AuthMethodAPIKey}
+	failed := model.RequestStatus{
+		ID:            "req-1",
+		ProjectID:     "app",
+		TokenType:     model.TokenTypeGlobalAnalysis,
+		State:         model.RequestStateFailed,
+		TTL:           time.Hour,
+		Principal:     principal,
+		FailureReason: model.FailureReasonProviderError,
+		Error:         "sonar responded 503",
+		Events: []model.RequestEvent{
+			{RequestID: "req-1", Type: model.RequestEventQueued},
+			{RequestID: "req-1", Type: model.RequestEventFailed, FailureReason: model.FailureReasonProviderError},
+		},
+	}
+
+	requeuedConcurrently := model.RequestStatus{ID: "req-1", State: model.RequestStateQueued, Version: 1}
+
+	tests := []struct {
+		name            string
+		status          model.RequestStatus
+		concurrent      *model.RequestStatus
+		publishErr      error
+		expectedErr     error
+		expectedUpdates int
+	}{
+		{name: "Requeued", status: failed, expectedUpdates: 1},
+		{name: "Not Failed", status: model.RequestStatus{ID: "req-1", State: model.RequestStateIssued}, expectedErr: model.ErrRequestNotRequeueable},
+		{name: "Canceled", status: model.RequestStatus{ID: "req-1", State: model.RequestStateCanceled}, expectedErr: model.ErrRequestNotRequeueable},
+		{name: "Requeued Concurrently", status: failed, concurrent: &requeuedConcurrently, expectedErr: model.ErrRequestNotRequeueable, expectedUpdates: 1},
+		// The failed status is restored.
+		{name: "Publish Failure", status: failed, publishErr: errors.New("publish failed"), expectedUpdates: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := tt.status
+			repository := &mocks.RequestAdminRepositoryMock{
+				GetRequestStatusFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					return current, nil
+				},
+				UpdateRequestStatusFunc: func(ctx context.Context, status model.RequestStatus) error {
+					if tt.concurrent != nil && current.Version != tt.concurrent.Version {
+						current = *tt.concurrent
+						return model.ErrStatusConflict
+					}
+					current = status
+					return nil
+				},
+			}
+			requests := &mocks.RequestTokenGenerationRepositoryMock{
+				PublishRequestTokenGenerationFunc: func(ctx context.Context, request model.TokenGenerationRequest) error {
+					return tt.publishErr
+				},
+			}
+			audit := &mocks.AuditRecorderMock{}
+			admin := service.NewRequestAdminService(repository, requests, &mocks.RequestEventRepositoryMock{}, audit)
+
+			status, err := admin.RequeueRequest(context.Background(), "req-1")
+
+			updates := repository.UpdateRequestStatusCalls()
+			assert.Len(t, updates, tt.expectedUpdates)
+			assert.Empty(t, repository.SaveRequestStatusCalls(), "the status is saved without version check")
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Empty(t, requests.PublishRequestTokenGenerationCalls())
+				return
+			}
+			if tt.publishErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, failed, updates[1].Status)
+				assert.Empty(t, audit.RecordCalls())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, model.RequestStateQueued, status.State)
+			assert.Empty(t, status.FailureReason)
+			assert.Empty(t, status.Error)
+			assert.Len(t, status.Events, 3)
+			assert.Equal(t, status, updates[0].Status)
+
+			if assert.Len(t, requests.PublishRequestTokenGenerationCalls(), 1) {
+				assert.Equal(t, model.TokenGenerationRequest{
+					ID:        "req-1",
+					ProjectID: "app",
+					TokenType: model.TokenTypeGlobalAnalysis,
+					TTL:       time.Hour,
+					Principal: principal,
+				}, requests.PublishRequestTokenGenerationCalls()[0].Request)
+			}
+			if assert.Len(t, audit.RecordCalls(), 1) {
+				assert.Equal(t, model.AuditActionRequeueRequest, audit.RecordCalls()[0].Entry.Action)
+				assert.Equal(t, model.AuditOutcomeRequeued, audit.RecordCalls()[0].Entry.Outcome)
+			}
+		})
+	}
+}
@@ -2,34 +2,220 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
 
 	"github.com/werbersondev/token-generator-test/domain/model"
 )
 
 type RequestTokenGenerationService struct {
-	repository RequestTokenGenerationRepository
+	repository   RequestTokenGenerationRepository
+	statuses     RequestStatusRepository
+	accessPolicy *model.AccessPolicy
+	audit        AuditRecorder
 }
 
 //go:generate moq -stub -pkg mocks -out mocks/request_generation_repository.go . RequestTokenGenerationRepository
 type RequestTokenGenerationRepository interface {
 	PublishRequestTokenGeneration(ctx context.Context, request model.TokenGenerationRequest) error
+	// PublishRequestTokenGenerations publishes several requests at once, returning the
+	// error of each in the order of requests.
+	PublishRequestTokenGenerations(ctx context.Context, requests []model.TokenGenerationRequest) []error
+}
+
+//go:generate moq -stub -pkg mocks -out mocks/audit_recorder.go . AuditRecorder
+type AuditRecorder interface {
+	Record(ctx context.Context, entry model.AuditEntry) error
+}
+
+// NewRequestTokenGenerationService creates the service. When accessPolicy is nil,
+// every request but those of global analysis tokens is allowed.
+func NewRequestTokenGenerationService(repo RequestTokenGenerationRepository, statuses RequestStatusRepository, accessPolicy *model.AccessPolicy, audit AuditRecorder) *RequestTokenGenerationService {
+	return &RequestTokenGenerationService{
+		repository:   repo,
+		statuses:     statuses,
+		accessPolicy: accessPolicy,
+		audit:        audit,
+	}
+}
+
+// RequestTokenGeneration queues the generation of a token and returns the status of
+// the request, whose progress is reported by the worker.
+func (r *RequestTokenGenerationService) RequestTokenGeneration(ctx context.Context, request model.TokenGenerationRequest) (model.RequestStatus, error) {
+	request, status, err := r.prepareRequest(ctx, request)
+	if err != nil {
+		return model.RequestStatus{}, err
+	}
+
+	err = r.repository.PublishRequestTokenGeneration(ctx, request)
+	if err != nil {
+		r.failUnpublished(ctx, status, err)
+		return model.RequestStatus{}, fmt.Errorf("publishing request token generation: %w", err)
+	}
+	r.record(ctx, request, model.AuditOutcomeAccepted, "")
+
+	return status, nil
+}
+
+// RequestTokenGenerations queues the generation of several tokens at once. Every
+// request is validated and authorized on its own, the result of each being returned in
+// the order of requests, and those queued are grouped in a batch whose aggregate
+// status can be queried.
+func (r *RequestTokenGenerationService) RequestTokenGenerations(ctx context.Context, requests []model.TokenGenerationRequest) (model.RequestBatch, []model.BatchItemResult, error) {
+	batchID, err := newRequestID()
+	if err != nil {
+		return model.RequestBatch{}, nil, err
+	}
+
+	batch := model.RequestBatch{
+		ID:         batchID,
+		CreatedAt:  time.Now().UTC(),
+		RequestIDs: make([]string, 0, len(requests)),
+	}
+	if principal, ok := model.PrincipalFromContext(ctx); ok {
+		batch.Owner = principal.Owner()
+	}
+
+	results := make([]model.BatchItemResult, len(requests))
+	prepared := make([]model.TokenGenerationRequest, 0, len(requests))
+	indexes := make([]int, 0, len(requests))
+	for i, request := range requests {
+		request, status, err := r.prepareRequest(ctx, request)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		results[i].Status = status
+		prepared = append(prepared, request)
+		indexes = append(indexes, i)
+	}
+
+	errs := r.repository.PublishRequestTokenGenerations(ctx, prepared)
+	for j, i := range indexes {
+		if errs[j] != nil {
+			r.failUnpublished(ctx, results[i].Status, errs[j])
+			results[i] = model.BatchItemResult{Err: fmt.Errorf("publishing request token generation: %w", errs[j])}
+			continue
+		}
+		batch.RequestIDs = append(batch.RequestIDs, results[i].Status.ID)
+		r.record(ctx, prepared[j], model.AuditOutcomeAccepted, "")
+	}
+	batch.Rejected = len(requests) - len(batch.RequestIDs)
+
+	if err := r.statuses.SaveRequestBatch(ctx, batch); err != nil {
+		return model.RequestBatch{}, nil, fmt.Errorf("saving request batch: %w", err)
+	}
+
+	return batch, results, nil
 }
 
-func NewRequestTokenGenerationService(repo RequestTokenGenerationRepository) *RequestTokenGenerationService {
-	return &RequestTokenGenerationService{repository: repo}
+// prepareRequest validates and authorizes request, assigns it an ID and saves its
+// queued status.
+func (r *RequestTokenGenerationService) prepareRequest(ctx context.Context, request model.TokenGenerationRequest) (model.TokenGenerationRequest, model.RequestStatus, error) {
+	if strings.TrimSpace(request.ProjectID) == "" {
+		return request, model.RequestStatus{}, errors.New("projectID cannot be blank")
+	}
+	if request.TTL < 0 {
+		return request, model.RequestStatus{}, errors.New("ttl cannot be negative")
+	}
+	if request.TokenType == "" {
+		request.TokenType = model.TokenTypeProjectAnalysis
+	}
+
+	var principal model.Principal
+	if p, ok := model.PrincipalFromContext(ctx); ok {
+		principal = p
+		request.Principal = &p
+	}
+
+	decision := r.accessPolicy.Evaluate(principal, request)
+	if !decision.Allowed {
+		r.record(ctx, request, model.AuditOutcomeDenied, decision.Reason)
+		return request, model.RequestStatus{}, &model.AccessDeniedError{Decision: decision}
+	}
+	request.TTL = decision.TTL
+
+	id, err := newRequestID()
+	if err != nil {
+		return request, model.RequestStatus{}, err
+	}
+	request.ID = id
+
+	now := time.Now().UTC()
+	status := model.RequestStatus{
+		ID:        request.ID,
+		ProjectID: request.ProjectID,
+		TokenType: request.TokenType,
+		Owner:     principal.Owner(),
+		CreatedAt: now,
+		TTL:       request.TTL,
+		Principal: request.Principal,
+	}
+	status.Apply(model.RequestEvent{RequestID: request.ID, Type: model.RequestEventQueued, Time: now})
+	// The status is saved first, the worker may report progress right away.
+	if err := r.statuses.SaveRequestStatus(ctx, status); err != nil {
+		return request, model.RequestStatus{}, fmt.Errorf("saving request status: %w", err)
+	}
+
+	return request, status, nil
 }
 
-func (r *RequestTokenGenerationService) RequestTokenGeneration(ctx context.Context, projectID string) error {
-	if strings.TrimSpace(projectID) == "" {
-		return errors.New("projectID cannot be blank")
+// failUnpublished marks the queued status of a request that could not be published as
+// failed, rather than leaving it queued forever. Like any failed request, it can be
+// requeued.
+func (r *RequestTokenGenerationService) failUnpublished(ctx context.Context, status model.RequestStatus, publishErr error) {
+	failed := model.RequestEvent{
+		RequestID:     status.ID,
+		Type:          model.RequestEventFailed,
+		Time:          time.Now().UTC(),
+		FailureReason: model.FailureReasonPublishFailed,
+		Error:         publishErr.Error(),
 	}
-	err := r.repository.PublishRequestTokenGeneration(ctx, model.TokenGenerationRequest{ProjectID: projectID})
+	_, err := updateRequestStatus(ctx, r.statuses, status.ID, func(status *model.RequestStatus) error {
+		status.Apply(failed)
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("publishing request token generation: %w", err)
+		log.Ctx(ctx).Error().Err(err).Str("request_id", status.ID).Msg("marking unpublished request failed")
+	}
+}
+
+// EvaluateAccess evaluates the access policy without requesting anything, to debug
+// the policy.
+func (r *RequestTokenGenerationService) EvaluateAccess(principal model.Principal, request model.TokenGenerationRequest) model.AccessDecision {
+	return r.accessPolicy.Evaluate(principal, request)
+}
+
+// record audits a request, accepted once queued or denied by the access policy.
+func (r *RequestTokenGenerationService) record(ctx context.Context, request model.TokenGenerationRequest, outcome, reason string) {
+	err := r.audit.Record(ctx, model.AuditEntry{
+		Time:      time.Now().UTC(),
+		Action:    model.AuditActionRequestToken,
+		Outcome:   outcome,
+		Principal: request.Principal,
+		RequestID: request.ID,
+		ProjectID: request.ProjectID,
+		TokenType: request.TokenType,
+		TTL:       request.TTL,
+		Reason:    reason,
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("recording audit entry")
+	}
+}
+
+func newRequestID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("generating request id: %w", err)
 	}
 
-	return nil
+	return hex.EncodeToString(id), nil
 }
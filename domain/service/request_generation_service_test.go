@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/werbersondev/token-generator-test/domain/model"
 	"github.com/werbersondev/token-generator-test/domain/service"
@@ -36,10 +38,23 @@ func TestRequestTokenGenerationService_RequestTokenGeneration_Success(t *testing
 		t.Run(tt.name, func(t *testing.T) {
 			repository := tt.repoSetup(t)
 
-			s := service.NewRequestTokenGenerationService(repository)
-			err := s.RequestTokenGeneration(context.Background(), tt.projectID)
+			statuses := &mocks.RequestStatusRepositoryMock{}
 
-			assert.NoError(t, err)
+			s := service.NewRequestTokenGenerationService(repository, statuses, nil, &mocks.AuditRecorderMock{})
+			status, err := s.RequestTokenGeneration(context.Background(), model.TokenGenerationRequest{ProjectID: tt.projectID})
+
+			require.NoError(t, err)
+			assert.NotEmpty(t, status.ID)
+			assert.Equal(t, model.RequestStateQueued, status.State)
+			assert.Equal(t, tt.projectID, status.ProjectID)
+
+			saved := statuses.SaveRequestStatusCalls()
+			require.Len(t, saved, 1)
+			assert.Equal(t, status, saved[0].Status)
+
+			published := repository.(*mocks.RequestTokenGenerationRepositoryMock).PublishRequestTokenGenerationCalls()
+			require.Len(t, published, 1)
+			assert.Equal(t, status.ID, published[0].Request.ID)
 		})
 	}
 }
@@ -77,10 +92,329 @@ func TestRequestTokenGenerationService_RequestTokenGeneration_Failure(t *testing
 		t.Run(tt.name, func(t *testing.T) {
 			repository := tt.repoSetup(t)
 
-			s := service.NewRequestTokenGenerationService(repository)
-			err := s.RequestTokenGeneration(context.Background(), tt.projectID)
+			s := service.NewRequestTokenGenerationService(repository, &mocks.RequestStatusRepositoryMock{}, nil, &mocks.AuditRecorderMock{})
+			_, err := s.RequestTokenGeneration(context.Background(), model.TokenGenerationRequest{ProjectID: tt.projectID})
 
 			assert.ErrorContains(t, err, tt.expectedErr.Error())
 		})
 	}
 }
+
+func TestRequestTokenGenerationService_RequestTokenGeneration_PublishFailure(t *testing.T) {
+	repository := &mocks.RequestTokenGenerationRepositoryMock{
+		PublishRequestTokenGenerationFunc: func(ctx context.Context, request model.TokenGenerationRequest) error {
+			return errors.New("failed to publish request token")
+		},
+	}
+	statuses := savingStatusRepository()
+
+	s := service.NewRequestTokenGenerationService(repository, statuses, nil, &mocks.AuditRecorderMock{})
+	_, err := s.RequestTokenGeneration(context.Background(), model.TokenGenerationRequest{ProjectID: "valid-project-id"})
+	require.Error(t, err)
+
+	// The queued status is saved before publishing, then marked failed unless updated
+	// meanwhile.
+	saved := statuses.SaveRequestStatusCalls()
+	require.Len(t, saved, 1)
+	assert.Equal(t, model.RequestStateQueued, saved[0].Status.State)
+	assert.Len(t, saved[0].Status.Events, 1)
+	updated := statuses.UpdateRequestStatusCalls()
+	require.Len(t, updated, 1)
+	assert.Equal(t, saved[0].Status.ID, updated[0].Status.ID)
+	assert.Equal(t, model.RequestStateFailed, updated[0].Status.State)
+	assert.Equal(t, model.FailureReasonPublishFailed, updated[0].Status.FailureReason)
+	assert.Equal(t, "failed to publish request token", updated[0].Status.Error)
+}
+
+// savingStatusRepository returns a repository mock reading the statuses saved with it.
+func savingStatusRepository() *mocks.RequestStatusRepositoryMock {
+	saved := make(map[string]model.RequestStatus)
+	return &mocks.RequestStatusRepositoryMock{
+		SaveRequestStatusFunc: func(ctx context.Context, status model.RequestStatus) error {
+			saved[status.ID] = status
+			return nil
+		},
+		GetRequestStatusFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+			status, ok := saved[id]
+			if !ok {
+				return model.RequestStatus{}, model.ErrRequestNotFound
+			}
+			return status, nil
+		},
+	}
+}
+
+func TestRequestTokenGenerationService_AccessPolicy(t *testing.T) {
+	policy := &model.AccessPolicy{
+		Rules: []model.AccessRule{
+			{
+				Name:     "ci",
+				Subjects: []string{"repo:acme/*"},
+				Projects: []string{"acme-*"},
+				MaxTTL:   720 * time.Hour,
+			},
+			{
+				Name:     "platform",
+				Groups:   []string{"platform"},
+				Projects: []string{`/^(acme|infra)-[a-z]+$/`},
+			},
+			{
+				Name:       "platform-global",
+				Subjects:   []string{"apikey:0123"},
+				TokenTypes: []model.TokenType{model.TokenTypeGlobalAnalysis},
+			},
+		},
+	}
+	require.NoError(t, policy.Compile())
+
+	ciPrincipal := model.Principal{Subject: "repo:acme/app:ref:refs/heads/main", Method: model.AuthMethodJWT}
+	platformPrincipal := model.Principal{Subject: "apikey:0123", Method: model.AuthMethodAPIKey, Groups: []string{"platform"}}
+
+	tests := []struct {
+		name           string
+		principal      *model.Principal
+		request        model.TokenGenerationRequest
+		expectedTTL    time.Duration
+		expectedReason string
+	}{
+		{
+			name:        "Allowed By Subject With Default TTL",
+			principal:   &ciPrincipal,
+			request:     model.TokenGenerationRequest{ProjectID: "acme-app"},
+			expectedTTL: 720 * time.Hour,
+		},
+		{
+			name:        "Allowed By Subject With Shorter TTL",
+			principal:   &ciPrincipal,
+			request:     model.TokenGenerationRequest{ProjectID: "acme-app", TTL: 24 * time.Hour},
+			expectedTTL: 24 * time.Hour,
+		},
+		{
+			name:      "Allowed By Group And Regular Expression",
+			principal: &platformPrincipal,
+			request:   model.TokenGenerationRequest{ProjectID: "infra-dns"},
+		},
+		{
+			name:      "Global Token Allowed Whatever The Project",
+			principal: &platformPrincipal,
+			request:   model.TokenGenerationRequest{ProjectID: "billing-api", TokenType: model.TokenTypeGlobalAnalysis},
+		},
+		{
+			name:           "Global Token Denied Without Dedicated Rule",
+			principal:      &model.Principal{Subject: "apikey:4567", Groups: []string{"platform"}},
+			request:        model.TokenGenerationRequest{ProjectID: "infra-dns", TokenType: model.TokenTypeGlobalAnalysis},
+			expectedReason: "rule platform does not allow global_analysis tokens",
+		},
+		{
+			name:           "Denied Project",
+			principal:      &ciPrincipal,
+			request:        model.TokenGenerationRequest{ProjectID: "infra-dns"},
+			expectedReason: "no rule allows repo:acme/app:ref:refs/heads/main to request tokens for project infra-dns",
+		},
+		{
+			name:           "Denied TTL",
+			principal:      &ciPrincipal,
+			request:        model.TokenGenerationRequest{ProjectID: "acme-app", TTL: 1000 * time.Hour},
+			expectedReason: "rule ci allows a TTL of at most 720h0m0s",
+		},
+		{
+			name:           "Denied Token Type",
+			principal:      &ciPrincipal,
+			request:        model.TokenGenerationRequest{ProjectID: "acme-app", TokenType: model.TokenTypeGlobalAnalysis},
+			expectedReason: "rule ci does not allow global_analysis tokens",
+		},
+		{
+			name:           "Denied Unknown Principal",
+			principal:      &model.Principal{Subject: "apikey:4567", Groups: []string{"developers"}},
+			request:        model.TokenGenerationRequest{ProjectID: "acme-app"},
+			expectedReason: "no rule allows apikey:4567 to request tokens",
+		},
+		{
+			name:           "Denied Unauthenticated",
+			request:        model.TokenGenerationRequest{ProjectID: "acme-app"},
+			expectedReason: "unauthenticated requests are not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &mocks.RequestTokenGenerationRepositoryMock{}
+			audit := &mocks.AuditRecorderMock{}
+
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = model.ContextWithPrincipal(ctx, *tt.principal)
+			}
+
+			s := service.NewRequestTokenGenerationService(repository, &mocks.RequestStatusRepositoryMock{}, policy, audit)
+			_, err := s.RequestTokenGeneration(ctx, tt.request)
+
+			if tt.expectedReason != "" {
+				var deniedErr *model.AccessDeniedError
+				require.ErrorAs(t, err, &deniedErr)
+				assert.Equal(t, tt.expectedReason, deniedErr.Decision.Reason)
+				assert.Empty(t, repository.PublishRequestTokenGenerationCalls())
+
+				entries := audit.RecordCalls()
+				require.Len(t, entries, 1)
+				assert.Equal(t, model.AuditActionRequestToken, entries[0].Entry.Action)
+				assert.Equal(t, model.AuditOutcomeDenied, entries[0].Entry.Outcome)
+				assert.Equal(t, tt.request.ProjectID, entries[0].Entry.ProjectID)
+				assert.Equal(t, tt.expectedReason, entries[0].Entry.Reason)
+				return
+			}
+
+			require.NoError(t, err)
+
+			calls := repository.PublishRequestTokenGenerationCalls()
+			require.Len(t, calls, 1)
+
+			entries := audit.RecordCalls()
+			require.Len(t, entries, 1)
+			assert.Equal(t, model.AuditOutcomeAccepted, entries[0].Entry.Outcome)
+			assert.Equal(t, calls[0].Request.ID, entries[0].Entry.RequestID)
+			assert.Equal(t, tt.expectedTTL, entries[0].Entry.TTL)
+			assert.Equal(t, tt.expectedTTL, calls[0].Request.TTL)
+			assert.Equal(t, tt.principal, calls[0].Request.Principal)
+		})
+	}
+}
+
+func TestRequestTokenGenerationService_NoAccessPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		request        model.TokenGenerationRequest
+		expectedReason string
+	}{
+		{
+			name:    "Project Token Allowed",
+			request: model.TokenGenerationRequest{ProjectID: "acme-app", TTL: time.Hour},
+		},
+		{
+			name:           "Global Token Denied",
+			request:        model.TokenGenerationRequest{ProjectID: "acme-app", TokenType: model.TokenTypeGlobalAnalysis},
+			expectedReason: "global_analysis tokens require an access policy rule allowing them",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &mocks.RequestTokenGenerationRepositoryMock{}
+			ctx := model.ContextWithPrincipal(context.Background(), model.Principal{Subject: "apikey:0123", Method: model.AuthMethodAPIKey})
+
+			s := service.NewRequestTokenGenerationService(repository, &mocks.RequestStatusRepositoryMock{}, nil, &mocks.AuditRecorderMock{})
+			_, err := s.RequestTokenGeneration(ctx, tt.request)
+
+			principal, _ := model.PrincipalFromContext(ctx)
+			decision := s.EvaluateAccess(principal, tt.request)
+			assert.Equal(t, tt.expectedReason, decision.Reason)
+
+			if tt.expectedReason != "" {
+				var deniedErr *model.AccessDeniedError
+				require.ErrorAs(t, err, &deniedErr)
+				assert.Equal(t, tt.expectedReason, deniedErr.Decision.Reason)
+				assert.False(t, decision.Allowed)
+				assert.Empty(t, repository.PublishRequestTokenGenerationCalls())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, decision.Allowed)
+			assert.Equal(t, tt.request.TTL, decision.TTL)
+			require.Len(t, repository.PublishRequestTokenGenerationCalls(), 1)
+		})
+	}
+}
+
+func TestAccessPolicy_Compile(t *testing.T) {
+	tests := []struct {
+		name string
+		rule model.AccessRule
+	}{
+		{name: "Without Principal", rule: model.AccessRule{Projects: []string{"*"}}},
+		{name: "Without Projects", rule: model.AccessRule{Subjects: []string{"*"}}},
+		{name: "Invalid Regular Expression", rule: model.AccessRule{Subjects: []string{"*"}, Projects: []string{"/[/"}}},
+		{name: "Unknown Token Type", rule: model.AccessRule{Subjects: []string{"*"}, Projects: []string{"*"}, TokenTypes: []model.TokenType{"user"}}},
+		{name: "Global Token With Projects", rule: model.AccessRule{Subjects: []string{"*"}, Projects: []string{"acme-*"}, TokenTypes: []model.TokenType{model.TokenTypeGlobalAnalysis}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := model.AccessPolicy{Rules: []model.AccessRule{tt.rule}}
+
+			assert.Error(t, policy.Compile())
+		})
+	}
+}
+
+func TestRequestTokenGenerationService_RequestTokenGenerations(t *testing.T) {
+	policy := &model.AccessPolicy{
+		Rules: []model.AccessRule{{Name: "ci", Subjects: []string{"repo:acme/*"}, Projects: []string{"acme-*"}}},
+	}
+	require.NoError(t, policy.Compile())
+
+	repository := &mocks.RequestTokenGenerationRepositoryMock{
+		PublishRequestTokenGenerationsFunc: func(ctx context.Context, requests []model.TokenGenerationRequest) []error {
+			errs := make([]error, len(requests))
+			for i, request := range requests {
+				if request.ProjectID == "acme-broken" {
+					errs[i] = errors.New("failed to publish request token")
+				}
+			}
+			return errs
+		},
+	}
+	statuses := savingStatusRepository()
+	audit := &mocks.AuditRecorderMock{}
+	principal := model.Principal{Subject: "repo:acme/app", Method: model.AuthMethodJWT, Issuer: "https://token.actions.githubusercontent.com"}
+	ctx := model.ContextWithPrincipal(context.Background(), principal)
+
+	s := service.NewRequestTokenGenerationService(repository, statuses, policy, audit)
+	batch, results, err := s.RequestTokenGenerations(ctx, []model.TokenGenerationRequest{
+		{ProjectID: "acme-app"},
+		{ProjectID: ""},
+		{ProjectID: "infra-dns"},
+		{ProjectID: "acme-broken"},
+		{ProjectID: "acme-lib"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	assert.NoError(t, results[0].Err)
+	assert.ErrorContains(t, results[1].Err, "projectID cannot be blank")
+	var deniedErr *model.AccessDeniedError
+	assert.ErrorAs(t, results[2].Err, &deniedErr)
+	assert.ErrorContains(t, results[3].Err, "failed to publish request token")
+	assert.NoError(t, results[4].Err)
+
+	assert.NotEmpty(t, batch.ID)
+	assert.Equal(t, "jwt:https%3A%2F%2Ftoken.actions.githubusercontent.com:repo:acme/app", batch.Owner)
+	assert.Equal(t, []string{results[0].Status.ID, results[4].Status.ID}, batch.RequestIDs)
+	assert.Equal(t, 3, batch.Rejected)
+
+	// The valid requests are published at once.
+	published := repository.PublishRequestTokenGenerationsCalls()
+	require.Len(t, published, 1)
+	assert.Len(t, published[0].Requests, 3)
+
+	saved := statuses.SaveRequestBatchCalls()
+	require.Len(t, saved, 1)
+	assert.Equal(t, batch, saved[0].Batch)
+
+	// The status of the request whose publication failed does not stay queued.
+	var states []string
+	for _, call := range statuses.SaveRequestStatusCalls() {
+		states = append(states, call.Status.ProjectID+" "+string(call.Status.State))
+	}
+	for _, call := range statuses.UpdateRequestStatusCalls() {
+		states = append(states, call.Status.ProjectID+" "+string(call.Status.State))
+	}
+	assert.Equal(t, []string{"acme-app queued", "acme-broken queued", "acme-lib queued", "acme-broken failed"}, states)
+
+	// Only the requests denied or queued are audited.
+	var audited []string
+	for _, call := range audit.RecordCalls() {
+		audited = append(audited, call.Entry.ProjectID+" "+call.Entry.Outcome)
+	}
+	assert.Equal(t, []string{"infra-dns denied", "acme-app accepted", "acme-lib accepted"}, audited)
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+//go:generate moq -stub -pkg mocks -out mocks/request_status_repository.go . RequestStatusRepository
+type RequestStatusRepository interface {
+	SaveRequestStatus(ctx context.Context, status model.RequestStatus) error
+	// UpdateRequestStatus saves status unless the stored one was saved since status was
+	// read, returning model.ErrStatusConflict then. A status never saved is only saved
+	// when none exists.
+	UpdateRequestStatus(ctx context.Context, status model.RequestStatus) error
+	// GetRequestStatus returns model.ErrRequestNotFound for unknown requests.
+	GetRequestStatus(ctx context.Context, id string) (model.RequestStatus, error)
+	// SaveToken keeps the token issued for a request apart from its status, which never
+	// holds it.
+	SaveToken(ctx context.Context, id, token string) error
+	// TakeToken returns the token issued for a request and deletes it, atomically so
+	// that a single caller gets it. It returns model.ErrTokenAlreadyRetrieved once the
+	// token was taken, deleted or expired.
+	TakeToken(ctx context.Context, id string) (string, error)
+	// DeleteToken deletes the token issued for a request, if any.
+	DeleteToken(ctx context.Context, id string) error
+	// SaveIssuedToken records the token issued for status, without the token itself,
+	// until the token expires rather than the status.
+	SaveIssuedToken(ctx context.Context, status model.RequestStatus) error
+	// GetIssuedToken returns model.ErrRequestNotFound once the token expired or its
+	// record was deleted.
+	GetIssuedToken(ctx context.Context, id string) (model.RequestStatus, error)
+	// DeleteIssuedToken deletes the record of the token issued for status, if any.
+	DeleteIssuedToken(ctx context.Context, status model.RequestStatus) error
+	SaveRequestBatch(ctx context.Context, batch model.RequestBatch) error
+	// GetRequestBatch returns model.ErrBatchNotFound for unknown batches.
+	GetRequestBatch(ctx context.Context, id string) (model.RequestBatch, error)
+}
+
+// RequestStatusService tracks the status of token generation requests from the events
+// reported by the worker, and lets callers wait for their outcome.
+type RequestStatusService struct {
+	repository RequestStatusRepository
+	audit      AuditRecorder
+
+	mu      sync.Mutex
+	waiters map[string][]chan model.RequestStatus
+}
+
+func NewRequestStatusService(repo RequestStatusRepository, audit AuditRecorder) *RequestStatusService {
+	return &RequestStatusService{
+		repository: repo,
+		audit:      audit,
+		waiters:    make(map[string][]chan model.RequestStatus),
+	}
+}
+
+// maxStatusUpdateAttempts bounds the attempts to apply an event to a status updated
+// concurrently, by another replica applying the same events or by a caller.
+const maxStatusUpdateAttempts = 5
+
+// ApplyRequestEvent updates the status of the request reported by event and notifies
+// the callers waiting for it. Every replica receives every event: the status is only
+// saved by the first one applying it, and only if it did not change since read, so that
+// a stale status cannot overwrite a newer one. The token of issued events is saved apart,
+// with a record of it, by the replica saving the status, so that a token already
+// retrieved is never saved again.
+func (s *RequestStatusService) ApplyRequestEvent(ctx context.Context, event model.RequestEvent) error {
+	for attempt := 1; ; attempt++ {
+		status, err := s.repository.GetRequestStatus(ctx, event.RequestID)
+		if errors.Is(err, model.ErrRequestNotFound) {
+			// The request expired or was queued by a service this one does not share its
+			// state with. Track it anyway for the callers waiting on this replica.
+			status = model.RequestStatus{ID: event.RequestID, CreatedAt: event.Time}
+		} else if err != nil {
+			return fmt.Errorf("getting request status: %w", err)
+		}
+
+		if status.Apply(event) {
+			err = s.repository.UpdateRequestStatus(ctx, status)
+			if errors.Is(err, model.ErrStatusConflict) && attempt < maxStatusUpdateAttempts {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("saving request status: %w", err)
+			}
+
+			if event.Type == model.RequestEventIssued {
+				if err := s.saveIssuedToken(ctx, status, event.Token); err != nil {
+					return err
+				}
+			}
+		}
+
+		s.notify(status)
+
+		return nil
+	}
+}
+
+// updateRequestStatus changes the status of the request with the given ID with update
+// and saves it, unless it was saved since read: it is then read and changed again, as
+// ApplyRequestEvent does. The status is left unchanged when update fails.
+func updateRequestStatus(ctx context.Context, repo RequestStatusRepository, id string, update func(status *model.RequestStatus) error) (model.RequestStatus, error) {
+	for attempt := 1; ; attempt++ {
+		status, err := repo.GetRequestStatus(ctx, id)
+		if err != nil {
+			return model.RequestStatus{}, err
+		}
+		if err := update(&status); err != nil {
+			return model.RequestStatus{}, err
+		}
+
+		err = repo.UpdateRequestStatus(ctx, status)
+		if errors.Is(err, model.ErrStatusConflict) && attempt < maxStatusUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return model.RequestStatus{}, fmt.Errorf("saving request status: %w", err)
+		}
+
+		return status, nil
+	}
+}
+
+// saveIssuedToken saves the token issued for status, to be retrieved once, and its
+// record, listed until the token expires.
+func (s *RequestStatusService) saveIssuedToken(ctx context.Context, status model.RequestStatus, token string) error {
+	if token != "" {
+		if err := s.repository.SaveToken(ctx, status.ID, token); err != nil {
+			return fmt.Errorf("saving token: %w", err)
+		}
+	}
+	if err := s.repository.SaveIssuedToken(ctx, status); err != nil {
+		return fmt.Errorf("saving issued token: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RequestStatusService) GetRequestStatus(ctx context.Context, id string) (model.RequestStatus, error) {
+	return s.repository.GetRequestStatus(ctx, id)
+}
+
+// GetBatchStatus aggregates the status of the requests of a batch. Requests whose
+// status expired are left out.
+func (s *RequestStatusService) GetBatchStatus(ctx context.Context, id string) (model.BatchStatus, error) {
+	batch, err := s.repository.GetRequestBatch(ctx, id)
+	if err != nil {
+		return model.BatchStatus{}, err
+	}
+
+	statuses := make([]model.RequestStatus, 0, len(batch.RequestIDs))
+	for _, requestID := range batch.RequestIDs {
+		status, err := s.repository.GetRequestStatus(ctx, requestID)
+		if errors.Is(err, model.ErrRequestNotFound) {
+			continue
+		}
+		if err != nil {
+			return model.BatchStatus{}, fmt.Errorf("getting request status: %w", err)
+		}
+		statuses = append(statuses, status)
+	}
+
+	return model.NewBatchStatus(batch, statuses), nil
+}
+
+// WaitForRequest waits up to timeout for the request to reach a terminal state, and
+// returns its latest status either way.
+func (s *RequestStatusService) WaitForRequest(ctx context.Context, id string, timeout time.Duration) (model.RequestStatus, error) {
+	// Register before reading the status, so that an event applied in between is
+	// not missed.
+	updates := s.subscribe(id)
+	defer s.unsubscribe(id, updates)
+
+	status, err := s.repository.GetRequestStatus(ctx, id)
+	if err != nil {
+		return model.RequestStatus{}, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for !status.State.Terminal() {
+		select {
+		case status = <-updates:
+		case <-timer.C:
+			return status, nil
+		case <-ctx.Done():
+			return status, ctx.Err()
+		}
+	}
+
+	return status, nil
+}
+
+// DeliverToken returns status, as returned by WaitForRequest to the caller who made the
+// request, with its token, which is then no longer kept, see SelfService.RetrieveToken.
+func (s *RequestStatusService) DeliverToken(ctx context.Context, status model.RequestStatus) (model.RequestStatus, error) {
+	return deliverToken(ctx, s.repository, s.audit, status)
+}
+
+// WatchRequest streams the status of the request, starting with its current status and
+// followed by every update. The channel is closed once the request reached a terminal
+// state or ctx is done. Intermediate updates may be skipped by slow readers, the
+// events of the last status received list them all.
+func (s *RequestStatusService) WatchRequest(ctx context.Context, id string) (<-chan model.RequestStatus, error) {
+	updates := s.subscribe(id)
+
+	status, err := s.repository.GetRequestStatus(ctx, id)
+	if err != nil {
+		s.unsubscribe(id, updates)
+		return nil, err
+	}
+
+	statuses := make(chan model.RequestStatus)
+	go func() {
+		defer close(statuses)
+		defer s.unsubscribe(id, updates)
+
+		for {
+			select {
+			case statuses <- status:
+			case <-ctx.Done():
+				return
+			}
+
+			if status.State.Terminal() {
+				return
+			}
+
+			select {
+			case status = <-updates:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return statuses, nil
+}
+
+func (s *RequestStatusService) subscribe(id string) chan model.RequestStatus {
+	updates := make(chan model.RequestStatus, 1)
+
+	s.mu.Lock()
+	s.waiters[id] = append(s.waiters[id], updates)
+	s.mu.Unlock()
+
+	return updates
+}
+
+func (s *RequestStatusService) unsubscribe(id string, updates chan model.RequestStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	waiters := s.waiters[id]
+	for i, waiter := range waiters {
+		if waiter == updates {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+
+	if len(waiters) == 0 {
+		delete(s.waiters, id)
+		return
+	}
+	s.waiters[id] = waiters
+}
+
+func (s *RequestStatusService) notify(status model.RequestStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, updates := range s.waiters[status.ID] {
+		// Only the latest status matters, replace a pending one.
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- status
+	}
+}
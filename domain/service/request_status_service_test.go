@@ -0,0 +1,292 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/domain/service"
+	"github.com/werbersondev/token-generator-test/domain/service/mocks"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+	"github.com/werbersondev/token-generator-test/gateway/requeststore"
+)
+
+func TestRequestStatusService_ApplyRequestEvent(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(24 * time.Hour)
+
+	tests := []struct {
+		name          string
+		events        []model.RequestEvent
+		expectedState model.RequestState
+		expectedToken string
+	}{
+		{
+			name:          "Issued",
+			events:        []model.RequestEvent{{Type: model.RequestEventIssued, Token: "sqp_token", ExpiresAt: &expiresAt}},
+			expectedState: model.RequestStateIssued,
+			expectedToken: "sqp_token",
+		},
+		{
+			name:          "Failed",
+			events:        []model.RequestEvent{{Type: model.RequestEventFailed, FailureReason: model.FailureReasonProviderError}},
+			expectedState: model.RequestStateFailed,
+		},
+		{
+			name: "Redelivered Event After Terminal State",
+			events: []model.RequestEvent{
+				{Type: model.RequestEventIssued, Token: "sqp_token"},
+				{Type: model.RequestEventFailed, FailureReason: model.FailureReasonProviderError},
+			},
+			expectedState: model.RequestStateIssued,
+			expectedToken: "sqp_token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repository := requeststore.New(statestore.NewMemory(), time.Minute)
+			require.NoError(t, repository.SaveRequestStatus(ctx, model.RequestStatus{ID: "0123", State: model.RequestStateQueued, CreatedAt: now}))
+
+			s := service.NewRequestStatusService(repository, &mocks.AuditRecorderMock{})
+			for _, event := range tt.events {
+				event.RequestID = "0123"
+				event.Time = now.Add(time.Second)
+				require.NoError(t, s.ApplyRequestEvent(ctx, event))
+			}
+
+			status, err := s.GetRequestStatus(ctx, "0123")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedState, status.State)
+			assert.Empty(t, status.Token, "the status holds the token")
+			assert.Equal(t, now, status.CreatedAt)
+
+			token, err := repository.TakeToken(ctx, "0123")
+			if tt.expectedToken == "" {
+				assert.ErrorIs(t, err, model.ErrTokenAlreadyRetrieved)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedToken, token)
+		})
+	}
+}
+
+// racingRepository runs interleave once, between the first read of a status and its
+// update, as another replica would.
+type racingRepository struct {
+	*requeststore.Store
+	interleave func()
+}
+
+func (r *racingRepository) GetRequestStatus(ctx context.Context, id string) (model.RequestStatus, error) {
+	status, err := r.Store.GetRequestStatus(ctx, id)
+	if r.interleave != nil {
+		r.interleave()
+		r.interleave = nil
+	}
+	return status, err
+}
+
+func TestRequestStatusService_ApplyRequestEvent_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := requeststore.New(statestore.NewMemory(), time.Minute)
+	require.NoError(t, store.SaveRequestStatus(ctx, model.RequestStatus{ID: "0123", State: model.RequestStateProcessing, CreatedAt: now}))
+
+	issued := model.RequestEvent{RequestID: "0123", Type: model.RequestEventIssued, Time: now.Add(time.Second), Token: "sqp_token", TokenName: "app-analysis"}
+
+	// Another replica applies the same event, then the caller retrieves the token,
+	// while this replica still holds the status read before.
+	other := service.NewRequestStatusService(store, &mocks.AuditRecorderMock{})
+	repository := &racingRepository{Store: store, interleave: func() {
+		require.NoError(t, other.ApplyRequestEvent(ctx, issued))
+		_, err := store.TakeToken(ctx, "0123")
+		require.NoError(t, err)
+		status, err := store.GetRequestStatus(ctx, "0123")
+		require.NoError(t, err)
+		retrievedAt := now.Add(2 * time.Second)
+		status.RetrievedAt = &retrievedAt
+		require.NoError(t, store.SaveRequestStatus(ctx, status))
+	}}
+
+	s := service.NewRequestStatusService(repository, &mocks.AuditRecorderMock{})
+	require.NoError(t, s.ApplyRequestEvent(ctx, issued))
+
+	status, err := store.GetRequestStatus(ctx, "0123")
+	require.NoError(t, err)
+	assert.Equal(t, model.RequestStateIssued, status.State)
+	assert.NotNil(t, status.RetrievedAt)
+	assert.Len(t, status.Events, 1)
+	_, err = store.TakeToken(ctx, "0123")
+	assert.ErrorIs(t, err, model.ErrTokenAlreadyRetrieved, "the retrieved token is saved again")
+
+	// A redelivered event changes nothing, the status is not saved again.
+	version := status.Version
+	require.NoError(t, s.ApplyRequestEvent(ctx, issued))
+	status, err = store.GetRequestStatus(ctx, "0123")
+	require.NoError(t, err)
+	assert.Equal(t, version, status.Version)
+}
+
+func TestRequestStatusService_WaitForRequest(t *testing.T) {
+	ctx := context.Background()
+	repository := requeststore.New(statestore.NewMemory(), time.Minute)
+	require.NoError(t, repository.SaveRequestStatus(ctx, model.RequestStatus{ID: "0123", State: model.RequestStateQueued}))
+
+	s := service.NewRequestStatusService(repository, &mocks.AuditRecorderMock{})
+
+	t.Run("Times Out While Queued", func(t *testing.T) {
+		status, err := s.WaitForRequest(ctx, "0123", 10*time.Millisecond)
+
+		require.NoError(t, err)
+		assert.Equal(t, model.RequestStateQueued, status.State)
+	})
+
+	t.Run("Unknown Request", func(t *testing.T) {
+		_, err := s.WaitForRequest(ctx, "4567", 10*time.Millisecond)
+
+		assert.ErrorIs(t, err, model.ErrRequestNotFound)
+	})
+
+	t.Run("Returns Once Issued", func(t *testing.T) {
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			assert.NoError(t, s.ApplyRequestEvent(ctx, model.RequestEvent{RequestID: "0123", Type: model.RequestEventIssued, Token: "sqp_token"}))
+		}()
+
+		status, err := s.WaitForRequest(ctx, "0123", 5*time.Second)
+
+		require.NoError(t, err)
+		assert.Equal(t, model.RequestStateIssued, status.State)
+		assert.Empty(t, status.Token, "the token is only retrieved once")
+	})
+}
+
+func TestRequestStatusService_WatchRequest(t *testing.T) {
+	ctx := context.Background()
+	repository := requeststore.New(statestore.NewMemory(), time.Minute)
+
+	queued := model.RequestStatus{ID: "0123"}
+	queued.Apply(model.RequestEvent{RequestID: "0123", Type: model.RequestEventQueued})
+	require.NoError(t, repository.SaveRequestStatus(ctx, queued))
+
+	s := service.NewRequestStatusService(repository, &mocks.AuditRecorderMock{})
+
+	statuses, err := s.WatchRequest(ctx, "0123")
+	require.NoError(t, err)
+
+	status := <-statuses
+	assert.Equal(t, model.RequestStateQueued, status.State)
+
+	go func() {
+		for _, eventType := range []model.RequestEventType{model.RequestEventPickedUp, model.RequestEventCallingSonar, model.RequestEventIssued} {
+			assert.NoError(t, s.ApplyRequestEvent(ctx, model.RequestEvent{RequestID: "0123", Type: eventType, Token: "sqp_token"}))
+		}
+	}()
+
+	for status = range statuses {
+	}
+
+	// Updates may be coalesced, the last status lists every event, never the token.
+	assert.Equal(t, model.RequestStateIssued, status.State)
+	assert.Empty(t, status.Token)
+	require.Len(t, status.Events, 4)
+	for i, eventType := range []model.RequestEventType{model.RequestEventQueued, model.RequestEventPickedUp, model.RequestEventCallingSonar, model.RequestEventIssued} {
+		assert.Equal(t, eventType, status.Events[i].Type)
+		assert.Empty(t, status.Events[i].Token)
+	}
+
+	_, err = s.WatchRequest(ctx, "4567")
+	assert.ErrorIs(t, err, model.ErrRequestNotFound)
+}
+
+func TestRequestStatusService_DeliverToken(t *testing.T) {
+	ctx := context.Background()
+	repository := requeststore.New(statestore.NewMemory(), time.Minute)
+	require.NoError(t, repository.SaveRequestStatus(ctx, model.RequestStatus{ID: "0123", State: model.RequestStateProcessing}))
+	require.NoError(t, repository.SaveRequestStatus(ctx, model.RequestStatus{ID: "4567", State: model.RequestStateFailed}))
+
+	audit := &mocks.AuditRecorderMock{}
+	s := service.NewRequestStatusService(repository, audit)
+	require.NoError(t, s.ApplyRequestEvent(ctx, model.RequestEvent{RequestID: "0123", Type: model.RequestEventIssued, Time: time.Now(), Token: "sqp_token"}))
+
+	status, err := s.WaitForRequest(ctx, "0123", time.Second)
+	require.NoError(t, err)
+	delivered, err := s.DeliverToken(ctx, status)
+	require.NoError(t, err)
+	assert.Equal(t, "sqp_token", delivered.Token)
+	assert.NotNil(t, delivered.RetrievedAt)
+	require.Len(t, audit.RecordCalls(), 1)
+	assert.Equal(t, model.AuditActionDeliverToken, audit.RecordCalls()[0].Entry.Action)
+
+	_, err = s.DeliverToken(ctx, status)
+	assert.ErrorIs(t, err, model.ErrTokenAlreadyRetrieved, "the token is delivered twice")
+	status, err = s.GetRequestStatus(ctx, "0123")
+	require.NoError(t, err)
+	assert.NotNil(t, status.RetrievedAt)
+
+	status, err = s.GetRequestStatus(ctx, "4567")
+	require.NoError(t, err)
+	_, err = s.DeliverToken(ctx, status)
+	assert.ErrorIs(t, err, model.ErrTokenNotIssued)
+}
+
+func TestRequestStatusService_GetBatchStatus(t *testing.T) {
+	ctx := context.Background()
+	repository := requeststore.New(statestore.NewMemory(), time.Minute)
+
+	require.NoError(t, repository.SaveRequestStatus(ctx, model.RequestStatus{ID: "0123", State: model.RequestStateIssued}))
+	require.NoError(t, repository.SaveRequestStatus(ctx, model.RequestStatus{ID: "4567", State: model.RequestStateFailed}))
+	require.NoError(t, repository.SaveRequestStatus(ctx, model.RequestStatus{ID: "89ab", State: model.RequestStateProcessing}))
+	require.NoError(t, repository.SaveRequestBatch(ctx, model.RequestBatch{ID: "pending", RequestIDs: []string{"0123", "4567", "89ab"}}))
+	require.NoError(t, repository.SaveRequestBatch(ctx, model.RequestBatch{ID: "completed", RequestIDs: []string{"0123", "4567", "expired"}}))
+
+	s := service.NewRequestStatusService(repository, &mocks.AuditRecorderMock{})
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedState  model.BatchState
+		expectedCounts map[model.RequestState]int
+		expectedErr    error
+	}{
+		{
+			name:           "Pending",
+			id:             "pending",
+			expectedState:  model.BatchStatePending,
+			expectedCounts: map[model.RequestState]int{model.RequestStateIssued: 1, model.RequestStateFailed: 1, model.RequestStateProcessing: 1},
+		},
+		{
+			name:           "Completed Without Expired Requests",
+			id:             "completed",
+			expectedState:  model.BatchStateCompleted,
+			expectedCounts: map[model.RequestState]int{model.RequestStateIssued: 1, model.RequestStateFailed: 1},
+		},
+		{
+			name:        "Unknown Batch",
+			id:          "unknown",
+			expectedErr: model.ErrBatchNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := s.GetBatchStatus(ctx, tt.id)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedState, status.State)
+			assert.Equal(t, tt.expectedCounts, status.Counts)
+		})
+	}
+}
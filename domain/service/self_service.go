@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+//go:generate moq -stub -pkg mocks -out mocks/owned_request_repository.go . OwnedRequestRepository
+type OwnedRequestRepository interface {
+	RequestStatusRepository
+	// ListIssuedTokens returns the records of the tokens issued for the requests of
+	// owner that did not expire.
+	ListIssuedTokens(ctx context.Context, owner string) ([]model.RequestStatus, error)
+}
+
+//go:generate moq -stub -pkg mocks -out mocks/token_revoker.go . TokenRevoker
+type TokenRevoker interface {
+	RevokeToken(ctx context.Context, id string) (model.RequestStatus, error)
+}
+
+// SelfService lets callers manage their own tokens: see what the access policy allows
+// them to request, list their active tokens, retrieve each token once and revoke them.
+// Requests of other principals are reported as not found, so that IDs cannot be probed.
+type SelfService struct {
+	repository   OwnedRequestRepository
+	accessPolicy *model.AccessPolicy
+	revoker      TokenRevoker
+	audit        AuditRecorder
+}
+
+// NewSelfService creates a SelfService. Every request but those of global analysis
+// tokens is allowed when accessPolicy is nil, and tokens cannot be revoked when
+// revoker is nil.
+func NewSelfService(repo OwnedRequestRepository, accessPolicy *model.AccessPolicy, revoker TokenRevoker, audit AuditRecorder) *SelfService {
+	return &SelfService{
+		repository:   repo,
+		accessPolicy: accessPolicy,
+		revoker:      revoker,
+		audit:        audit,
+	}
+}
+
+// AccessGrants returns what the access policy allows the caller to request, and
+// whether there is an access policy at all; without one every request but those of
+// global analysis tokens is allowed.
+func (s *SelfService) AccessGrants(ctx context.Context) ([]model.AccessGrant, bool) {
+	if s.accessPolicy == nil {
+		return nil, false
+	}
+
+	principal, _ := model.PrincipalFromContext(ctx)
+	return s.accessPolicy.Grants(principal), true
+}
+
+// TokenRevocationEnabled reports whether RevokeToken may succeed.
+func (s *SelfService) TokenRevocationEnabled() bool {
+	return s.revoker != nil
+}
+
+// ListActiveTokens returns the records of the tokens issued for the caller that are
+// neither revoked nor expired, the most recent first. The records are kept until the
+// tokens expire, whatever the expiration of the request statuses.
+func (s *SelfService) ListActiveTokens(ctx context.Context) ([]model.RequestStatus, error) {
+	principal, _ := model.PrincipalFromContext(ctx)
+	tokens, err := s.repository.ListIssuedTokens(ctx, principal.Owner())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]model.RequestStatus, 0, len(tokens))
+	for _, token := range tokens {
+		if token.RevokedAt != nil || !ownedByCaller(ctx, token) {
+			continue
+		}
+		if token.ExpiresAt != nil && !token.ExpiresAt.After(now) {
+			continue
+		}
+		active = append(active, token)
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return positionOf(active[i]).before(active[j])
+	})
+
+	return active, nil
+}
+
+// RetrieveToken returns the status of a request of the caller with its token, which is
+// then no longer kept, see deliverToken.
+func (s *SelfService) RetrieveToken(ctx context.Context, id string) (model.RequestStatus, error) {
+	status, err := s.ownedRequestStatus(ctx, id)
+	if err != nil {
+		return model.RequestStatus{}, err
+	}
+
+	return deliverToken(ctx, s.repository, s.audit, status)
+}
+
+// deliverToken returns status with its token, which is then no longer kept: later calls
+// fail with model.ErrTokenAlreadyRetrieved, as do those for revoked tokens. Requests
+// without an issued token fail with model.ErrTokenNotIssued. The token is taken
+// atomically from the repository, so that concurrent calls cannot all retrieve it, and
+// its delivery is audited.
+func deliverToken(ctx context.Context, repo RequestStatusRepository, audit AuditRecorder, status model.RequestStatus) (model.RequestStatus, error) {
+	if status.RetrievedAt != nil || status.RevokedAt != nil {
+		return model.RequestStatus{}, model.ErrTokenAlreadyRetrieved
+	}
+	if status.State != model.RequestStateIssued {
+		return model.RequestStatus{}, model.ErrTokenNotIssued
+	}
+
+	token, err := repo.TakeToken(ctx, status.ID)
+	if errors.Is(err, model.ErrTokenAlreadyRetrieved) {
+		return model.RequestStatus{}, err
+	}
+	if err != nil {
+		return model.RequestStatus{}, fmt.Errorf("taking token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	status.Token = token
+	recordDelivery(ctx, audit, status)
+	// The token is no longer kept whatever the outcome, so it is returned even when
+	// the status cannot be updated.
+	if err := markRetrieved(ctx, repo, status, now); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("request_id", status.ID).Msg("saving request status")
+	}
+
+	status.RetrievedAt = &now
+	status.UpdatedAt = now
+	if err := repo.SaveIssuedToken(ctx, status); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("request_id", status.ID).Msg("saving issued token")
+	}
+
+	return status, nil
+}
+
+// markRetrieved records that the token of status was retrieved at the given time,
+// reading the status again when it was updated concurrently.
+func markRetrieved(ctx context.Context, repo RequestStatusRepository, status model.RequestStatus, at time.Time) error {
+	for attempt := 1; ; attempt++ {
+		status.RetrievedAt = &at
+		status.UpdatedAt = at
+		err := repo.UpdateRequestStatus(ctx, status)
+		if !errors.Is(err, model.ErrStatusConflict) || attempt == maxStatusUpdateAttempts {
+			return err
+		}
+
+		if status, err = repo.GetRequestStatus(ctx, status.ID); err != nil {
+			return err
+		}
+	}
+}
+
+// RevokeToken revokes the token of a request of the caller, see
+// TokenRevocationService.RevokeToken, including the active tokens whose request status
+// expired. It fails with model.ErrTokenRevocationUnavailable without a revoker.
+func (s *SelfService) RevokeToken(ctx context.Context, id string) (model.RequestStatus, error) {
+	if s.revoker == nil {
+		return model.RequestStatus{}, model.ErrTokenRevocationUnavailable
+	}
+
+	_, err := s.ownedRequestStatus(ctx, id)
+	if errors.Is(err, model.ErrRequestNotFound) {
+		var token model.RequestStatus
+		if token, err = s.repository.GetIssuedToken(ctx, id); err == nil && !ownedByCaller(ctx, token) {
+			err = model.ErrRequestNotFound
+		}
+	}
+	if err != nil {
+		return model.RequestStatus{}, err
+	}
+
+	return s.revoker.RevokeToken(ctx, id)
+}
+
+func (s *SelfService) ownedRequestStatus(ctx context.Context, id string) (model.RequestStatus, error) {
+	status, err := s.repository.GetRequestStatus(ctx, id)
+	if err != nil {
+		return model.RequestStatus{}, err
+	}
+	if !ownedByCaller(ctx, status) {
+		return model.RequestStatus{}, model.ErrRequestNotFound
+	}
+
+	return status, nil
+}
+
+func recordDelivery(ctx context.Context, audit AuditRecorder, status model.RequestStatus) {
+	entry := model.AuditEntry{
+		Time:      time.Now().UTC(),
+		Action:    model.AuditActionDeliverToken,
+		Outcome:   model.AuditOutcomeDelivered,
+		RequestID: status.ID,
+		ProjectID: status.ProjectID,
+		TokenType: status.TokenType,
+		TokenName: status.TokenName,
+	}
+	if principal, ok := model.PrincipalFromContext(ctx); ok {
+		entry.Principal = &principal
+	}
+
+	if err := audit.Record(ctx, entry); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("recording audit entry")
+	}
+}
+
+// ownedByCaller reports whether status was requested by the caller. Without an
+// authenticated caller, only the requests of unauthenticated callers are theirs.
+func ownedByCaller(ctx context.Context, status model.RequestStatus) bool {
+	principal, _ := model.PrincipalFromContext(ctx)
+	return status.Owner == principal.Owner()
+}
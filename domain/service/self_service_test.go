@@ -0,0 +1,287 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/domain/service"
+	"github.com/werbersondev/token-generator-test/domain/service/mocks"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+	"github.com/werbersondev/token-generator-test/gateway/requeststore"
+)
+
+// caller is the principal owning the requests of the tests.
+var caller = model.Principal{Subject: "ci", Method: model.AuthMethodAPIKey}
+
+func TestSelfService_AccessGrants(t *testing.T) {
+	policy := &model.AccessPolicy{Rules: []model.AccessRule{
+		{Name: "ci", Subjects: []string{"ci"}, Projects: []string{"acme-*"}, MaxTTL: time.Hour},
+		{Name: "platform", Groups: []string{"platform"}, TokenTypes: []model.TokenType{model.TokenTypeGlobalAnalysis}},
+		{Name: "other", Subjects: []string{"other"}, Projects: []string{"other"}},
+	}}
+	require.NoError(t, policy.Compile())
+
+	ctx := model.ContextWithPrincipal(context.Background(), model.Principal{Subject: "ci", Groups: []string{"platform"}})
+
+	t.Run("Policy", func(t *testing.T) {
+		selfService := service.NewSelfService(&mocks.OwnedRequestRepositoryMock{}, policy, nil, &mocks.AuditRecorderMock{})
+
+		grants, restricted := selfService.AccessGrants(ctx)
+
+		assert.True(t, restricted)
+		assert.Equal(t, []model.AccessGrant{
+			{Rule: "ci", Projects: []string{"acme-*"}, TokenTypes: []model.TokenType{model.TokenTypeProjectAnalysis}, MaxTTL: time.Hour},
+			{Rule: "platform", Projects: []string{}, TokenTypes: []model.TokenType{model.TokenTypeGlobalAnalysis}},
+		}, grants)
+	})
+
+	t.Run("No Policy", func(t *testing.T) {
+		selfService := service.NewSelfService(&mocks.OwnedRequestRepositoryMock{}, nil, nil, &mocks.AuditRecorderMock{})
+
+		grants, restricted := selfService.AccessGrants(ctx)
+
+		assert.False(t, restricted)
+		assert.Empty(t, grants)
+	})
+}
+
+func TestSelfService_ListActiveTokens(t *testing.T) {
+	now := time.Now().UTC()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	repository := &mocks.OwnedRequestRepositoryMock{
+		ListIssuedTokensFunc: func(ctx context.Context, owner string) ([]model.RequestStatus, error) {
+			assert.Equal(t, caller.Owner(), owner)
+			return []model.RequestStatus{
+				{ID: "old", Owner: caller.Owner(), State: model.RequestStateIssued, CreatedAt: earlier},
+				{ID: "new", Owner: caller.Owner(), State: model.RequestStateIssued, CreatedAt: now, ExpiresAt: &later},
+				{ID: "expired", Owner: caller.Owner(), State: model.RequestStateIssued, CreatedAt: now, ExpiresAt: &earlier},
+				{ID: "revoked", Owner: caller.Owner(), State: model.RequestStateIssued, CreatedAt: now, RevokedAt: &earlier},
+			}, nil
+		},
+	}
+	selfService := service.NewSelfService(repository, nil, nil, &mocks.AuditRecorderMock{})
+	ctx := model.ContextWithPrincipal(context.Background(), caller)
+
+	tokens, err := selfService.ListActiveTokens(ctx)
+	require.NoError(t, err)
+
+	var ids []string
+	for _, status := range tokens {
+		ids = append(ids, status.ID)
+	}
+	assert.Equal(t, []string{"new", "old"}, ids)
+}
+
+func TestSelfService_ListActiveTokens_ExpiredStatus(t *testing.T) {
+	ctx := model.ContextWithPrincipal(context.Background(), caller)
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Hour)
+	repository := requeststore.New(statestore.NewMemory(), 10*time.Millisecond)
+
+	queued := model.RequestStatus{ID: "req-1", Owner: caller.Owner(), ProjectID: "app", CreatedAt: now}
+	queued.Apply(model.RequestEvent{RequestID: "req-1", Type: model.RequestEventQueued, Time: now})
+	require.NoError(t, repository.SaveRequestStatus(ctx, queued))
+	require.NoError(t, repository.SaveRequestStatus(ctx, model.RequestStatus{ID: "req-2", Owner: "api_key::dev", State: model.RequestStateQueued}))
+	statuses := service.NewRequestStatusService(repository, &mocks.AuditRecorderMock{})
+	for _, id := range []string{"req-1", "req-2"} {
+		require.NoError(t, statuses.ApplyRequestEvent(ctx, model.RequestEvent{RequestID: id, Type: model.RequestEventIssued, Time: now, Token: "squ_token", TokenName: "app-analysis", ExpiresAt: &expiresAt}))
+	}
+
+	revoker := service.NewTokenRevocationService(&mocks.TokenRevocationRepositoryMock{}, repository, &mocks.AuditRecorderMock{})
+	selfService := service.NewSelfService(repository, nil, revoker, &mocks.AuditRecorderMock{})
+
+	// The token outlives the status of its request.
+	time.Sleep(20 * time.Millisecond)
+	_, err := repository.GetRequestStatus(ctx, "req-1")
+	require.ErrorIs(t, err, model.ErrRequestNotFound)
+
+	tokens, err := selfService.ListActiveTokens(ctx)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, "req-1", tokens[0].ID)
+	assert.Equal(t, "app", tokens[0].ProjectID)
+	assert.Equal(t, "app-analysis", tokens[0].TokenName)
+	assert.Empty(t, tokens[0].Events)
+
+	_, err = selfService.RevokeToken(ctx, "req-2")
+	assert.ErrorIs(t, err, model.ErrRequestNotFound, "the token of another owner is revoked")
+
+	revoked, err := selfService.RevokeToken(ctx, "req-1")
+	require.NoError(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+
+	tokens, err = selfService.ListActiveTokens(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+}
+
+func TestSelfService_RetrieveToken(t *testing.T) {
+	retrievedAt := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	issued := model.RequestStatus{ID: "req-1", Owner: caller.Owner(), ProjectID: "app", State: model.RequestStateIssued, TokenName: "app-token"}
+
+	tests := []struct {
+		name            string
+		status          model.RequestStatus
+		statusErr       error
+		takeErr         error
+		updateErr       error
+		expectedErr     error
+		expectedTakes   int
+		expectedUpdates int
+	}{
+		{name: "Retrieved", status: issued, expectedTakes: 1, expectedUpdates: 1},
+		{name: "Unknown Request", statusErr: model.ErrRequestNotFound, expectedErr: model.ErrRequestNotFound},
+		{name: "Other Owner", status: model.RequestStatus{ID: "req-1", Owner: "api_key::dev", State: model.RequestStateIssued}, expectedErr: model.ErrRequestNotFound},
+		{name: "Not Issued", status: model.RequestStatus{ID: "req-1", Owner: caller.Owner(), State: model.RequestStateProcessing}, expectedErr: model.ErrTokenNotIssued},
+		{name: "Already Retrieved", status: model.RequestStatus{ID: "req-1", Owner: caller.Owner(), State: model.RequestStateIssued, RetrievedAt: &retrievedAt}, expectedErr: model.ErrTokenAlreadyRetrieved},
+		{name: "Revoked", status: model.RequestStatus{ID: "req-1", Owner: caller.Owner(), State: model.RequestStateIssued, RevokedAt: &retrievedAt}, expectedErr: model.ErrTokenAlreadyRetrieved},
+		{name: "Taken Concurrently", status: issued, takeErr: model.ErrTokenAlreadyRetrieved, expectedErr: model.ErrTokenAlreadyRetrieved, expectedTakes: 1},
+		{name: "Take Failure", status: issued, takeErr: errors.New("state store unavailable"), expectedTakes: 1},
+		{name: "Save Failure", status: issued, updateErr: errors.New("state store unavailable"), expectedTakes: 1, expectedUpdates: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &mocks.OwnedRequestRepositoryMock{
+				GetRequestStatusFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					return tt.status, tt.statusErr
+				},
+				TakeTokenFunc: func(ctx context.Context, id string) (string, error) {
+					if tt.takeErr != nil {
+						return "", tt.takeErr
+					}
+					return "squ_token", nil
+				},
+				UpdateRequestStatusFunc: func(ctx context.Context, status model.RequestStatus) error {
+					return tt.updateErr
+				},
+			}
+			audit := &mocks.AuditRecorderMock{}
+			selfService := service.NewSelfService(repository, nil, nil, audit)
+			ctx := model.ContextWithPrincipal(context.Background(), caller)
+
+			status, err := selfService.RetrieveToken(ctx, "req-1")
+
+			assert.Len(t, repository.TakeTokenCalls(), tt.expectedTakes)
+			assert.Len(t, repository.UpdateRequestStatusCalls(), tt.expectedUpdates)
+			if tt.expectedErr != nil || tt.takeErr != nil {
+				if tt.expectedErr != nil {
+					assert.ErrorIs(t, err, tt.expectedErr)
+				} else {
+					assert.Error(t, err)
+				}
+				assert.Empty(t, audit.RecordCalls())
+				return
+			}
+
+			// The token is no longer kept once taken, so it is returned even when the
+			// status cannot be saved.
+			require.NoError(t, err)
+			assert.Equal(t, "squ_token", status.Token)
+			assert.NotNil(t, status.RetrievedAt)
+
+			saved := repository.UpdateRequestStatusCalls()[0].Status
+			assert.Equal(t, "app-token", saved.TokenName)
+			assert.Equal(t, status.RetrievedAt, saved.RetrievedAt)
+
+			if assert.Len(t, audit.RecordCalls(), 1) {
+				assert.Equal(t, model.AuditActionDeliverToken, audit.RecordCalls()[0].Entry.Action)
+				assert.Equal(t, "req-1", audit.RecordCalls()[0].Entry.RequestID)
+			}
+		})
+	}
+}
+
+// racingStore runs interleave once, right after a token is first read, as a concurrent
+// caller would.
+type racingStore struct {
+	statestore.Store
+	interleave func()
+}
+
+func (r *racingStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.Store.Get(ctx, key)
+	if r.interleave != nil && strings.HasPrefix(key, "tokens/") {
+		interleave := r.interleave
+		r.interleave = nil
+		interleave()
+	}
+	return value, err
+}
+
+func TestSelfService_RetrieveToken_Concurrent(t *testing.T) {
+	ctx := model.ContextWithPrincipal(context.Background(), caller)
+	store := &racingStore{Store: statestore.NewMemory()}
+	repository := requeststore.New(store, time.Minute)
+	require.NoError(t, repository.SaveRequestStatus(ctx, model.RequestStatus{ID: "req-1", Owner: caller.Owner(), State: model.RequestStateIssued}))
+	require.NoError(t, repository.SaveToken(ctx, "req-1", "squ_token"))
+	selfService := service.NewSelfService(repository, nil, nil, &mocks.AuditRecorderMock{})
+
+	// Another caller retrieves the token between the moment this one reads it and
+	// deletes it.
+	var retrieved []string
+	retrieve := func() {
+		status, err := selfService.RetrieveToken(ctx, "req-1")
+		if err != nil {
+			assert.ErrorIs(t, err, model.ErrTokenAlreadyRetrieved)
+			return
+		}
+		retrieved = append(retrieved, status.Token)
+	}
+	store.interleave = retrieve
+	retrieve()
+
+	assert.Equal(t, []string{"squ_token"}, retrieved, "the token must be retrieved once")
+	status, err := repository.GetRequestStatus(ctx, "req-1")
+	require.NoError(t, err)
+	assert.NotNil(t, status.RetrievedAt)
+}
+
+func TestSelfService_RevokeToken(t *testing.T) {
+	repository := &mocks.OwnedRequestRepositoryMock{
+		GetRequestStatusFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+			return model.RequestStatus{ID: id, Owner: caller.Owner(), State: model.RequestStateIssued}, nil
+		},
+	}
+	revoker := &mocks.TokenRevokerMock{
+		RevokeTokenFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+			return model.RequestStatus{ID: id, State: model.RequestStateIssued}, nil
+		},
+	}
+
+	tests := []struct {
+		name            string
+		revoker         service.TokenRevoker
+		principal       model.Principal
+		expectedErr     error
+		expectedRevokes int
+	}{
+		{name: "Revoked", revoker: revoker, principal: caller, expectedRevokes: 1},
+		{name: "Other Owner", revoker: revoker, principal: model.Principal{Subject: "dev", Method: model.AuthMethodAPIKey}, expectedErr: model.ErrRequestNotFound},
+		{name: "Same Subject Other Issuer", revoker: revoker, principal: model.Principal{Subject: "ci", Method: model.AuthMethodJWT, Issuer: "https://issuer.example.com"}, expectedErr: model.ErrRequestNotFound},
+		{name: "Unavailable", principal: caller, expectedErr: model.ErrTokenRevocationUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revokes := len(revoker.RevokeTokenCalls())
+			selfService := service.NewSelfService(repository, nil, tt.revoker, &mocks.AuditRecorderMock{})
+			ctx := model.ContextWithPrincipal(context.Background(), tt.principal)
+
+			_, err := selfService.RevokeToken(ctx, "req-1")
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.revoker != nil, selfService.TokenRevocationEnabled())
+			assert.Len(t, revoker.RevokeTokenCalls(), revokes+tt.expectedRevokes)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+//go:generate moq -stub -pkg mocks -out mocks/token_issuer.go . TokenIssuer
+type TokenIssuer interface {
+	IssueToken(ctx context.Context, request model.TokenGenerationRequest) (model.IssuedToken, error)
+}
+
+// TokenExchangeService exchanges the identity tokens of CI jobs for short-lived project
+// analysis tokens, issued synchronously.
+type TokenExchangeService struct {
+	policy *model.ExchangePolicy
+	issuer TokenIssuer
+	audit  AuditRecorder
+}
+
+func NewTokenExchangeService(policy *model.ExchangePolicy, issuer TokenIssuer, audit AuditRecorder) *TokenExchangeService {
+	return &TokenExchangeService{
+		policy: policy,
+		issuer: issuer,
+		audit:  audit,
+	}
+}
+
+// ExchangeToken issues a project analysis token for the project the exchange policy
+// maps the principal to. The principal is the one authenticated by its identity token.
+func (s *TokenExchangeService) ExchangeToken(ctx context.Context, principal model.Principal) (model.IssuedToken, error) {
+	decision := s.policy.Evaluate(principal)
+	if !decision.Allowed {
+		s.record(ctx, principal, decision, model.AuditOutcomeDenied, decision.Reason)
+		return model.IssuedToken{}, &model.AccessDeniedError{Decision: model.AccessDecision{
+			Rule:   decision.Rule,
+			Reason: decision.Reason,
+		}}
+	}
+
+	issued, err := s.issuer.IssueToken(ctx, model.TokenGenerationRequest{
+		ProjectID: decision.ProjectID,
+		TokenType: model.TokenTypeProjectAnalysis,
+		TTL:       decision.TTL,
+		Principal: &principal,
+	})
+	if err != nil {
+		s.record(ctx, principal, decision, model.AuditOutcomeFailed, string(model.FailureReasonOf(err)))
+		return model.IssuedToken{}, err
+	}
+
+	s.record(ctx, principal, decision, model.AuditOutcomeIssued, "")
+
+	return issued, nil
+}
+
+func (s *TokenExchangeService) record(ctx context.Context, principal model.Principal, decision model.ExchangeDecision, outcome, reason string) {
+	err := s.audit.Record(ctx, model.AuditEntry{
+		Time:      time.Now().UTC(),
+		Action:    model.AuditActionExchangeToken,
+		Outcome:   outcome,
+		Principal: &principal,
+		ProjectID: decision.ProjectID,
+		TokenType: model.TokenTypeProjectAnalysis,
+		TTL:       decision.TTL,
+		Reason:    reason,
+	})
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("recording audit entry")
+	}
+}
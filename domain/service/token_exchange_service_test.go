@@ -0,0 +1,177 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/domain/service"
+	"github.com/werbersondev/token-generator-test/domain/service/mocks"
+)
+
+const (
+	githubIssuer = "https://token.actions.githubusercontent.com"
+	gitlabIssuer = "https://gitlab.example.com"
+)
+
+func newExchangePolicy(t *testing.T) *model.ExchangePolicy {
+	policy := &model.ExchangePolicy{
+		Rules: []model.ExchangeRule{
+			{
+				Name:   "github-main",
+				Issuer: githubIssuer,
+				Claims: map[string]string{
+					"repository": "acme/*",
+					"ref":        "refs/heads/main",
+				},
+				ProjectKey: `{{ .repository | replace "/" "_" }}`,
+				TTL:        48 * time.Hour,
+			},
+			{
+				Name:   "gitlab",
+				Issuer: gitlabIssuer,
+				Claims: map[string]string{
+					"project_path": "/^platform/[a-z-]+$/",
+				},
+				ProjectKey: `{{ .project_path | trimPrefix "platform/" | lower }}`,
+			},
+			{
+				Name:       "github-bad-key",
+				Issuer:     githubIssuer,
+				Claims:     map[string]string{"repository": "invalid/*"},
+				ProjectKey: `{{ .repository }} key`,
+			},
+		},
+	}
+	require.NoError(t, policy.Compile())
+
+	return policy
+}
+
+func TestTokenExchangeService_ExchangeToken(t *testing.T) {
+	tests := []struct {
+		name            string
+		principal       model.Principal
+		issuerErr       error
+		expectedProject string
+		expectedTTL     time.Duration
+		expectedOutcome string
+		expectDenied    bool
+	}{
+		{
+			name: "GitHub Main Branch",
+			principal: model.Principal{
+				Subject:    "repo:acme/app:ref:refs/heads/main",
+				Issuer:     githubIssuer,
+				Attributes: map[string]string{"repository": "acme/app", "ref": "refs/heads/main"},
+			},
+			expectedProject: "acme_app",
+			expectedTTL:     48 * time.Hour,
+			expectedOutcome: model.AuditOutcomeIssued,
+		},
+		{
+			name: "GitLab Project With Default TTL",
+			principal: model.Principal{
+				Subject:    "project_path:platform/DNS:ref_type:branch:ref:main",
+				Issuer:     gitlabIssuer,
+				Attributes: map[string]string{"project_path": "platform/dns"},
+			},
+			expectedProject: "dns",
+			expectedTTL:     model.DefaultExchangeTTL,
+			expectedOutcome: model.AuditOutcomeIssued,
+		},
+		{
+			name: "GitHub Feature Branch",
+			principal: model.Principal{
+				Subject:    "repo:acme/app:ref:refs/heads/feature",
+				Issuer:     githubIssuer,
+				Attributes: map[string]string{"repository": "acme/app", "ref": "refs/heads/feature"},
+			},
+			expectedOutcome: model.AuditOutcomeDenied,
+			expectDenied:    true,
+		},
+		{
+			name: "Claims Of Another Issuer",
+			principal: model.Principal{
+				Subject:    "project_path:platform/dns",
+				Issuer:     githubIssuer,
+				Attributes: map[string]string{"project_path": "platform/dns"},
+			},
+			expectedOutcome: model.AuditOutcomeDenied,
+			expectDenied:    true,
+		},
+		{
+			name: "Invalid Project Key",
+			principal: model.Principal{
+				Subject:    "repo:invalid/app",
+				Issuer:     githubIssuer,
+				Attributes: map[string]string{"repository": "invalid/app"},
+			},
+			expectedOutcome: model.AuditOutcomeDenied,
+			expectDenied:    true,
+		},
+		{
+			name: "Provider Error",
+			principal: model.Principal{
+				Subject:    "repo:acme/app:ref:refs/heads/main",
+				Issuer:     githubIssuer,
+				Attributes: map[string]string{"repository": "acme/app", "ref": "refs/heads/main"},
+			},
+			issuerErr:       &model.TokenGenerationError{Reason: model.FailureReasonMissingPermission, Err: errors.New("missing permission")},
+			expectedProject: "acme_app",
+			expectedTTL:     48 * time.Hour,
+			expectedOutcome: model.AuditOutcomeFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := &mocks.TokenIssuerMock{
+				IssueTokenFunc: func(ctx context.Context, request model.TokenGenerationRequest) (model.IssuedToken, error) {
+					if tt.issuerErr != nil {
+						return model.IssuedToken{}, tt.issuerErr
+					}
+					return model.IssuedToken{Token: "squ_token", ProjectID: request.ProjectID, TokenType: request.TokenType}, nil
+				},
+			}
+			audit := &mocks.AuditRecorderMock{}
+
+			s := service.NewTokenExchangeService(newExchangePolicy(t), issuer, audit)
+			issued, err := s.ExchangeToken(context.Background(), tt.principal)
+
+			entries := audit.RecordCalls()
+			require.Len(t, entries, 1)
+			assert.Equal(t, model.AuditActionExchangeToken, entries[0].Entry.Action)
+			assert.Equal(t, tt.expectedOutcome, entries[0].Entry.Outcome)
+
+			if tt.expectDenied {
+				var deniedErr *model.AccessDeniedError
+				assert.ErrorAs(t, err, &deniedErr)
+				assert.Empty(t, issuer.IssueTokenCalls())
+				return
+			}
+
+			calls := issuer.IssueTokenCalls()
+			require.Len(t, calls, 1)
+			assert.Equal(t, tt.expectedProject, calls[0].Request.ProjectID)
+			assert.Equal(t, model.TokenTypeProjectAnalysis, calls[0].Request.TokenType)
+			assert.Equal(t, tt.expectedTTL, calls[0].Request.TTL)
+			assert.Equal(t, tt.principal.Subject, calls[0].Request.Principal.Subject)
+
+			if tt.issuerErr != nil {
+				assert.ErrorIs(t, err, tt.issuerErr)
+				assert.Equal(t, string(model.FailureReasonMissingPermission), entries[0].Entry.Reason)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "squ_token", issued.Token)
+			assert.Equal(t, tt.expectedProject, issued.ProjectID)
+		})
+	}
+}
@@ -11,29 +11,142 @@ import (
 )
 
 type TokenGenerationService struct {
-	repository TokenGenerationRepository
+	repository       TokenGenerationRepository
+	permissionPolicy model.PermissionPolicy
 }
 
 //go:generate moq -stub -pkg mocks -out mocks/token_generation_repository.go . TokenGenerationRepository
 type TokenGenerationRepository interface {
-	GenerateProjectAnalysisToken(ctx context.Context, projectID, tokenName string) (string, error)
+	GenerateProjectAnalysisToken(ctx context.Context, projectID, tokenName string, expiresAt time.Time) (string, error)
+	GenerateGlobalAnalysisToken(ctx context.Context, tokenName string, expiresAt time.Time) (string, error)
+	HasProjectAnalysisPermission(ctx context.Context, projectID string) (bool, error)
+	GrantProjectAnalysisPermission(ctx context.Context, projectID string) error
 }
 
-func NewTokenGenerationService(repo TokenGenerationRepository) *TokenGenerationService {
-	return &TokenGenerationService{repository: repo}
+func NewTokenGenerationService(repo TokenGenerationRepository, permissionPolicy model.PermissionPolicy) *TokenGenerationService {
+	return &TokenGenerationService{
+		repository:       repo,
+		permissionPolicy: permissionPolicy,
+	}
 }
 
+// GenerateToken generates the token requested by request.
 func (r *TokenGenerationService) GenerateToken(ctx context.Context, request model.TokenGenerationRequest) (string, error) {
-	if strings.TrimSpace(request.ProjectID) == "" {
-		return "", errors.New("projectID cannot be blank")
+	issued, err := r.IssueToken(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	return issued.Token, nil
+}
+
+// IssueToken generates the token requested by request, along with its expiration.
+// Global analysis tokens are not bound to the project of the request, which may be
+// blank, and are issued without checking the permissions of any project.
+func (r *TokenGenerationService) IssueToken(ctx context.Context, request model.TokenGenerationRequest) (model.IssuedToken, error) {
+	if request.TokenType == "" {
+		request.TokenType = model.TokenTypeProjectAnalysis
+	}
+	if request.TokenType == model.TokenTypeProjectAnalysis && strings.TrimSpace(request.ProjectID) == "" {
+		return model.IssuedToken{}, &model.TokenGenerationError{
+			Reason: model.FailureReasonInvalidRequest,
+			Err:    errors.New("projectID cannot be blank"),
+		}
 	}
 
-	tokenName := fmt.Sprintf("%s-analysis-%s", request.ProjectID, time.Now().String())
+	now := time.Now()
+	expiresAt := expirationTime(now, request.TTL)
+
+	var (
+		tokenName string
+		token     string
+		err       error
+	)
+	switch request.TokenType {
+	case model.TokenTypeProjectAnalysis:
+		if err := r.ensureAnalysisPermission(ctx, request.ProjectID); err != nil {
+			return model.IssuedToken{}, err
+		}
+		tokenName = fmt.Sprintf("%s-analysis-%s", request.ProjectID, now.String())
+		token, err = r.repository.GenerateProjectAnalysisToken(ctx, request.ProjectID, tokenName, expiresAt)
+	case model.TokenTypeGlobalAnalysis:
+		tokenName = fmt.Sprintf("global-analysis-%s", now.String())
+		token, err = r.repository.GenerateGlobalAnalysisToken(ctx, tokenName, expiresAt)
+	default:
+		return model.IssuedToken{}, &model.TokenGenerationError{
+			Reason: model.FailureReasonInvalidRequest,
+			Err:    fmt.Errorf("unknown token type %q", request.TokenType),
+		}
+	}
+	if err != nil {
+		reason := model.FailureReasonProviderError
+		if errors.Is(err, model.ErrPermissionDenied) {
+			reason = model.FailureReasonMissingPermission
+		}
+		return model.IssuedToken{}, &model.TokenGenerationError{
+			Reason: reason,
+			Err:    fmt.Errorf("generating token on provider: %w", err),
+		}
+	}
+
+	return model.IssuedToken{
+		Token:     token,
+		Name:      tokenName,
+		ProjectID: request.ProjectID,
+		TokenType: request.TokenType,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// ensureAnalysisPermission makes sure the issuing user may execute analysis on the
+// project according to the configured permission policy, since a project analysis
+// token is useless otherwise.
+func (r *TokenGenerationService) ensureAnalysisPermission(ctx context.Context, projectID string) error {
+	if r.permissionPolicy == model.PermissionPolicySkip {
+		return nil
+	}
 
-	token, err := r.repository.GenerateProjectAnalysisToken(ctx, request.ProjectID, tokenName)
+	allowed, err := r.repository.HasProjectAnalysisPermission(ctx, projectID)
 	if err != nil {
-		return "", fmt.Errorf("generating token on provider: %w", err)
+		return &model.TokenGenerationError{
+			Reason: model.FailureReasonProviderError,
+			Err:    fmt.Errorf("checking analysis permission on provider: %w", err),
+		}
+	}
+	if allowed {
+		return nil
+	}
+
+	if r.permissionPolicy != model.PermissionPolicyGrant {
+		return &model.TokenGenerationError{
+			Reason: model.FailureReasonMissingPermission,
+			Err:    fmt.Errorf("issuing user lacks analysis permission on project %s", projectID),
+		}
+	}
+
+	if err := r.repository.GrantProjectAnalysisPermission(ctx, projectID); err != nil {
+		return &model.TokenGenerationError{
+			Reason: model.FailureReasonMissingPermission,
+			Err:    fmt.Errorf("granting analysis permission on provider: %w", err),
+		}
+	}
+
+	return nil
+}
+
+// expirationTime converts a TTL into the expiration of a token. Sonar expires tokens at
+// the start of a day, so the TTL is truncated to whole days, with a minimum of one day.
+// Tokens without TTL never expire.
+func expirationTime(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+
+	day := 24 * time.Hour
+	expiresAt := now.UTC().Add(ttl).Truncate(day)
+	if !expiresAt.After(now) {
+		expiresAt = expiresAt.Add(day)
 	}
 
-	return token, nil
+	return expiresAt
 }
@@ -3,9 +3,13 @@ package service_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/werbersondev/token-generator-test/domain/model"
 	"github.com/werbersondev/token-generator-test/domain/service"
@@ -14,20 +18,68 @@ import (
 
 func TestTokenGenerationService_GenerateToken_Success(t *testing.T) {
 	tests := []struct {
-		name          string
-		projectID     string
-		repoSetup     func(*testing.T) service.TokenGenerationRepository
-		expectedToken string
+		name             string
+		projectID        string
+		permissionPolicy model.PermissionPolicy
+		repoSetup        func(*testing.T) service.TokenGenerationRepository
+		expectedToken    string
 	}{
 		{
-			name:      "Generate Token Success",
-			projectID: "valid-project-id",
+			name:             "Generate Token Success",
+			projectID:        "valid-project-id",
+			permissionPolicy: model.PermissionPolicySkip,
 			repoSetup: func(t *testing.T) service.TokenGenerationRepository {
 				return &mock.TokenGenerationRepositoryMock{
-					GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string) (string, error) {
+					GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string, expiresAt time.Time) (string, error) {
 						// Simulate successful token generation
 						return "generated-token", nil
 					},
+					HasProjectAnalysisPermissionFunc: func(ctx context.Context, projectID string) (bool, error) {
+						// it should not be called
+						t.FailNow()
+						return false, nil
+					},
+				}
+			},
+			expectedToken: "generated-token",
+		},
+		{
+			name:             "Generate Token Success With Analysis Permission",
+			projectID:        "valid-project-id",
+			permissionPolicy: model.PermissionPolicyCheck,
+			repoSetup: func(t *testing.T) service.TokenGenerationRepository {
+				return &mock.TokenGenerationRepositoryMock{
+					GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string, expiresAt time.Time) (string, error) {
+						return "generated-token", nil
+					},
+					HasProjectAnalysisPermissionFunc: func(ctx context.Context, projectID string) (bool, error) {
+						return true, nil
+					},
+					GrantProjectAnalysisPermissionFunc: func(ctx context.Context, projectID string) error {
+						// it should not be called
+						t.FailNow()
+						return nil
+					},
+				}
+			},
+			expectedToken: "generated-token",
+		},
+		{
+			name:             "Generate Token Success Granting Analysis Permission",
+			projectID:        "valid-project-id",
+			permissionPolicy: model.PermissionPolicyGrant,
+			repoSetup: func(t *testing.T) service.TokenGenerationRepository {
+				return &mock.TokenGenerationRepositoryMock{
+					GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string, expiresAt time.Time) (string, error) {
+						return "generated-token", nil
+					},
+					HasProjectAnalysisPermissionFunc: func(ctx context.Context, projectID string) (bool, error) {
+						return false, nil
+					},
+					GrantProjectAnalysisPermissionFunc: func(ctx context.Context, projectID string) error {
+						assert.Equal(t, "valid-project-id", projectID)
+						return nil
+					},
 				}
 			},
 			expectedToken: "generated-token",
@@ -42,7 +94,7 @@ func TestTokenGenerationService_GenerateToken_Success(t *testing.T) {
 				ProjectID: tt.projectID,
 			}
 
-			s := service.NewTokenGenerationService(repository)
+			s := service.NewTokenGenerationService(repository, tt.permissionPolicy)
 			token, err := s.GenerateToken(context.Background(), request)
 
 			assert.NoError(t, err)
@@ -53,36 +105,106 @@ func TestTokenGenerationService_GenerateToken_Success(t *testing.T) {
 
 func TestTokenGenerationService_GenerateToken_Failure(t *testing.T) {
 	tests := []struct {
-		name        string
-		projectID   string
-		repoSetup   func(*testing.T) service.TokenGenerationRepository
-		expectedErr error
+		name             string
+		projectID        string
+		permissionPolicy model.PermissionPolicy
+		repoSetup        func(*testing.T) service.TokenGenerationRepository
+		expectedErr      error
+		expectedReason   model.FailureReason
 	}{
 		{
-			name:      "Empty Project ID",
-			projectID: "",
+			name:             "Empty Project ID",
+			projectID:        "",
+			permissionPolicy: model.PermissionPolicySkip,
 			repoSetup: func(t *testing.T) service.TokenGenerationRepository {
 				return &mock.TokenGenerationRepositoryMock{
-					GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string) (string, error) {
+					GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string, expiresAt time.Time) (string, error) {
 						// it should not be called
 						t.FailNow()
 						return "", nil
 					},
 				}
 			},
-			expectedErr: errors.New("projectID cannot be blank"),
+			expectedErr:    errors.New("projectID cannot be blank"),
+			expectedReason: model.FailureReasonInvalidRequest,
 		},
 		{
-			name:      "Repository Error",
-			projectID: "valid-project-id",
+			name:             "Repository Error",
+			projectID:        "valid-project-id",
+			permissionPolicy: model.PermissionPolicySkip,
 			repoSetup: func(t *testing.T) service.TokenGenerationRepository {
 				return &mock.TokenGenerationRepositoryMock{
-					GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string) (string, error) {
+					GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string, expiresAt time.Time) (string, error) {
 						return "", errors.New("failed to generate analysis token")
 					},
 				}
 			},
-			expectedErr: errors.New("failed to generate analysis token"),
+			expectedErr:    errors.New("failed to generate analysis token"),
+			expectedReason: model.FailureReasonProviderError,
+		},
+		{
+			name:             "Generation Refused By Provider",
+			projectID:        "valid-project-id",
+			permissionPolicy: model.PermissionPolicySkip,
+			repoSetup: func(t *testing.T) service.TokenGenerationRepository {
+				return &mock.TokenGenerationRepositoryMock{
+					GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string, expiresAt time.Time) (string, error) {
+						return "", fmt.Errorf("%w: insufficient privileges", model.ErrPermissionDenied)
+					},
+				}
+			},
+			expectedErr:    errors.New("permission denied: insufficient privileges"),
+			expectedReason: model.FailureReasonMissingPermission,
+		},
+		{
+			name:             "Missing Analysis Permission",
+			projectID:        "valid-project-id",
+			permissionPolicy: model.PermissionPolicyCheck,
+			repoSetup: func(t *testing.T) service.TokenGenerationRepository {
+				return &mock.TokenGenerationRepositoryMock{
+					HasProjectAnalysisPermissionFunc: func(ctx context.Context, projectID string) (bool, error) {
+						return false, nil
+					},
+					GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string, expiresAt time.Time) (string, error) {
+						// it should not be called
+						t.FailNow()
+						return "", nil
+					},
+				}
+			},
+			expectedErr:    errors.New("issuing user lacks analysis permission on project valid-project-id"),
+			expectedReason: model.FailureReasonMissingPermission,
+		},
+		{
+			name:             "Granting Analysis Permission Error",
+			projectID:        "valid-project-id",
+			permissionPolicy: model.PermissionPolicyGrant,
+			repoSetup: func(t *testing.T) service.TokenGenerationRepository {
+				return &mock.TokenGenerationRepositoryMock{
+					HasProjectAnalysisPermissionFunc: func(ctx context.Context, projectID string) (bool, error) {
+						return false, nil
+					},
+					GrantProjectAnalysisPermissionFunc: func(ctx context.Context, projectID string) error {
+						return errors.New("insufficient privileges")
+					},
+				}
+			},
+			expectedErr:    errors.New("granting analysis permission on provider: insufficient privileges"),
+			expectedReason: model.FailureReasonMissingPermission,
+		},
+		{
+			name:             "Checking Analysis Permission Error",
+			projectID:        "valid-project-id",
+			permissionPolicy: model.PermissionPolicyCheck,
+			repoSetup: func(t *testing.T) service.TokenGenerationRepository {
+				return &mock.TokenGenerationRepositoryMock{
+					HasProjectAnalysisPermissionFunc: func(ctx context.Context, projectID string) (bool, error) {
+						return false, errors.New("connection refused")
+					},
+				}
+			},
+			expectedErr:    errors.New("checking analysis permission on provider: connection refused"),
+			expectedReason: model.FailureReasonProviderError,
 		},
 	}
 
@@ -94,11 +216,96 @@ func TestTokenGenerationService_GenerateToken_Failure(t *testing.T) {
 				ProjectID: tt.projectID,
 			}
 
-			s := service.NewTokenGenerationService(repository)
+			s := service.NewTokenGenerationService(repository, tt.permissionPolicy)
 			token, err := s.GenerateToken(context.Background(), request)
 
 			assert.ErrorContains(t, err, tt.expectedErr.Error())
+			assert.Equal(t, tt.expectedReason, model.FailureReasonOf(err))
 			assert.Empty(t, token)
 		})
 	}
 }
+
+func TestTokenGenerationService_GenerateToken_TokenTypes(t *testing.T) {
+	tests := []struct {
+		name             string
+		request          model.TokenGenerationRequest
+		expectProject    bool
+		expectExpiration bool
+	}{
+		{
+			name:          "Project Analysis Token Without Expiration",
+			request:       model.TokenGenerationRequest{ProjectID: "valid-project-id"},
+			expectProject: true,
+		},
+		{
+			name:             "Project Analysis Token With TTL",
+			request:          model.TokenGenerationRequest{ProjectID: "valid-project-id", TokenType: model.TokenTypeProjectAnalysis, TTL: 72 * time.Hour},
+			expectProject:    true,
+			expectExpiration: true,
+		},
+		{
+			name:             "Global Analysis Token With TTL",
+			request:          model.TokenGenerationRequest{ProjectID: "valid-project-id", TokenType: model.TokenTypeGlobalAnalysis, TTL: time.Hour},
+			expectExpiration: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var expiration time.Time
+			repository := &mock.TokenGenerationRepositoryMock{
+				GenerateProjectAnalysisTokenFunc: func(ctx context.Context, projectID string, tokenName string, expiresAt time.Time) (string, error) {
+					expiration = expiresAt
+					return "project-token", nil
+				},
+				GenerateGlobalAnalysisTokenFunc: func(ctx context.Context, tokenName string, expiresAt time.Time) (string, error) {
+					expiration = expiresAt
+					return "global-token", nil
+				},
+			}
+
+			s := service.NewTokenGenerationService(repository, model.PermissionPolicySkip)
+			token, err := s.GenerateToken(context.Background(), tt.request)
+
+			assert.NoError(t, err)
+			if tt.expectProject {
+				assert.Equal(t, "project-token", token)
+			} else {
+				assert.Equal(t, "global-token", token)
+			}
+
+			if !tt.expectExpiration {
+				assert.True(t, expiration.IsZero())
+				return
+			}
+
+			// Sonar expires tokens on a day boundary, no later than the TTL but at least
+			// one day ahead.
+			assert.Equal(t, expiration, expiration.Truncate(24*time.Hour))
+			assert.True(t, expiration.After(time.Now()))
+			if tt.request.TTL >= 24*time.Hour {
+				assert.False(t, expiration.After(time.Now().Add(tt.request.TTL)))
+			}
+		})
+	}
+}
+
+func TestTokenGenerationService_IssueToken_GlobalAnalysis(t *testing.T) {
+	repository := &mock.TokenGenerationRepositoryMock{
+		GenerateGlobalAnalysisTokenFunc: func(ctx context.Context, tokenName string, expiresAt time.Time) (string, error) {
+			return "global-token", nil
+		},
+	}
+
+	s := service.NewTokenGenerationService(repository, model.PermissionPolicyGrant)
+	issued, err := s.IssueToken(context.Background(), model.TokenGenerationRequest{TokenType: model.TokenTypeGlobalAnalysis})
+
+	require.NoError(t, err)
+	assert.Equal(t, "global-token", issued.Token)
+	assert.Equal(t, model.TokenTypeGlobalAnalysis, issued.TokenType)
+	assert.True(t, strings.HasPrefix(issued.Name, "global-analysis-"))
+	// Global tokens are bound to no project, whose permissions are not checked.
+	assert.Empty(t, repository.HasProjectAnalysisPermissionCalls())
+	assert.Empty(t, repository.GrantProjectAnalysisPermissionCalls())
+}
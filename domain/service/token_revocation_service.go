@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+//go:generate moq -stub -pkg mocks -out mocks/token_revocation_repository.go . TokenRevocationRepository
+type TokenRevocationRepository interface {
+	// RevokeToken revokes the token with the given name of the user login, or of the
+	// issuing user when login is empty.
+	RevokeToken(ctx context.Context, login, name string) error
+}
+
+// TokenRevocationService revokes the tokens issued for token generation requests.
+type TokenRevocationService struct {
+	repository TokenRevocationRepository
+	statuses   RequestStatusRepository
+	audit      AuditRecorder
+}
+
+func NewTokenRevocationService(repo TokenRevocationRepository, statuses RequestStatusRepository, audit AuditRecorder) *TokenRevocationService {
+	return &TokenRevocationService{
+		repository: repo,
+		statuses:   statuses,
+		audit:      audit,
+	}
+}
+
+// RevokeToken revokes the token issued for the request with the given ID, deleting it if
+// it was not retrieved along with its record, and returns the updated status. Tokens are
+// revoked from their record once the status expired. Revoking a revoked token succeeds
+// without calling the provider again, while requests without an issued token fail with
+// model.ErrTokenNotRevocable.
+func (s *TokenRevocationService) RevokeToken(ctx context.Context, id string) (model.RequestStatus, error) {
+	status, err := s.statuses.GetRequestStatus(ctx, id)
+	if errors.Is(err, model.ErrRequestNotFound) {
+		// The status expired, while the token may still be active.
+		status, err = s.statuses.GetIssuedToken(ctx, id)
+	}
+	if err != nil {
+		return model.RequestStatus{}, err
+	}
+	if status.RevokedAt != nil {
+		return status, nil
+	}
+	if status.State != model.RequestStateIssued || status.TokenName == "" {
+		return model.RequestStatus{}, model.ErrTokenNotRevocable
+	}
+
+	if err := s.repository.RevokeToken(ctx, "", status.TokenName); err != nil {
+		s.record(ctx, status, model.AuditOutcomeFailed, string(model.FailureReasonProviderError))
+		return model.RequestStatus{}, fmt.Errorf("revoking token on provider: %w", err)
+	}
+
+	if err := s.statuses.DeleteToken(ctx, id); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("deleting revoked token")
+	}
+	if err := s.statuses.DeleteIssuedToken(ctx, status); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("request_id", id).Msg("deleting issued token")
+	}
+
+	now := time.Now().UTC()
+	status.RevokedAt = &now
+	status.UpdatedAt = now
+
+	s.record(ctx, status, model.AuditOutcomeRevoked, "")
+
+	if err := s.statuses.SaveRequestStatus(ctx, status); err != nil {
+		return model.RequestStatus{}, fmt.Errorf("saving request status: %w", err)
+	}
+
+	return status, nil
+}
+
+func (s *TokenRevocationService) record(ctx context.Context, status model.RequestStatus, outcome, reason string) {
+	entry := model.AuditEntry{
+		Time:      time.Now().UTC(),
+		Action:    model.AuditActionRevokeToken,
+		Outcome:   outcome,
+		RequestID: status.ID,
+		ProjectID: status.ProjectID,
+		TokenType: status.TokenType,
+		TokenName: status.TokenName,
+		Reason:    reason,
+	}
+	if principal, ok := model.PrincipalFromContext(ctx); ok {
+		entry.Principal = &principal
+	}
+
+	if err := s.audit.Record(ctx, entry); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("recording audit entry")
+	}
+}
@@ -0,0 +1,144 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/domain/service"
+	"github.com/werbersondev/token-generator-test/domain/service/mocks"
+)
+
+func TestTokenRevocationService_RevokeToken(t *testing.T) {
+	revokedAt := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	issued := model.RequestStatus{
+		ID:        "req-1",
+		ProjectID: "app",
+		TokenType: model.TokenTypeProjectAnalysis,
+		State:     model.RequestStateIssued,
+		TokenName: "app-analysis-1",
+	}
+
+	tests := []struct {
+		name            string
+		status          model.RequestStatus
+		statusErr       error
+		revokeErr       error
+		expectedErr     error
+		expectedRevokes int
+		expectedOutcome string
+	}{
+		{
+			name:            "Revoked",
+			status:          issued,
+			expectedRevokes: 1,
+			expectedOutcome: model.AuditOutcomeRevoked,
+		},
+		{
+			name:        "Unknown Request",
+			statusErr:   model.ErrRequestNotFound,
+			expectedErr: model.ErrRequestNotFound,
+		},
+		{
+			name:        "Not Issued",
+			status:      model.RequestStatus{ID: "req-1", State: model.RequestStateQueued},
+			expectedErr: model.ErrTokenNotRevocable,
+		},
+		{
+			name: "Unnamed Token",
+			status: model.RequestStatus{
+				ID:    "req-1",
+				State: model.RequestStateIssued,
+			},
+			expectedErr: model.ErrTokenNotRevocable,
+		},
+		{
+			name: "Already Revoked",
+			status: model.RequestStatus{
+				ID:        "req-1",
+				State:     model.RequestStateIssued,
+				TokenName: "app-analysis-1",
+				RevokedAt: &revokedAt,
+			},
+		},
+		{
+			name:            "Provider Error",
+			status:          issued,
+			revokeErr:       errors.New("connection refused"),
+			expectedRevokes: 1,
+			expectedOutcome: model.AuditOutcomeFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &mocks.TokenRevocationRepositoryMock{
+				RevokeTokenFunc: func(ctx context.Context, login, name string) error {
+					return tt.revokeErr
+				},
+			}
+			statuses := &mocks.RequestStatusRepositoryMock{
+				GetRequestStatusFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					return tt.status, tt.statusErr
+				},
+				GetIssuedTokenFunc: func(ctx context.Context, id string) (model.RequestStatus, error) {
+					return model.RequestStatus{}, model.ErrRequestNotFound
+				},
+			}
+			audit := &mocks.AuditRecorderMock{}
+
+			s := service.NewTokenRevocationService(repository, statuses, audit)
+
+			ctx := model.ContextWithPrincipal(context.Background(), model.Principal{Subject: "apikey:ci"})
+			status, err := s.RevokeToken(ctx, "req-1")
+
+			require.Len(t, repository.RevokeTokenCalls(), tt.expectedRevokes)
+			if tt.expectedRevokes > 0 {
+				assert.Equal(t, "", repository.RevokeTokenCalls()[0].Login)
+				assert.Equal(t, "app-analysis-1", repository.RevokeTokenCalls()[0].Name)
+			}
+
+			if tt.expectedOutcome != "" {
+				require.Len(t, audit.RecordCalls(), 1)
+				entry := audit.RecordCalls()[0].Entry
+				assert.Equal(t, model.AuditActionRevokeToken, entry.Action)
+				assert.Equal(t, tt.expectedOutcome, entry.Outcome)
+				assert.Equal(t, "app", entry.ProjectID)
+				assert.Equal(t, "req-1", entry.RequestID)
+				require.NotNil(t, entry.Principal)
+				assert.Equal(t, "apikey:ci", entry.Principal.Subject)
+			} else {
+				assert.Empty(t, audit.RecordCalls())
+			}
+
+			if tt.revokeErr != nil {
+				require.ErrorIs(t, err, tt.revokeErr)
+				assert.Empty(t, statuses.SaveRequestStatusCalls())
+				return
+			}
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.Empty(t, statuses.SaveRequestStatusCalls())
+				return
+			}
+			require.NoError(t, err)
+
+			require.NotNil(t, status.RevokedAt)
+			if tt.expectedRevokes == 0 {
+				assert.Empty(t, statuses.SaveRequestStatusCalls())
+				return
+			}
+
+			require.Len(t, statuses.DeleteTokenCalls(), 1, "the revoked token must no longer be kept")
+			assert.Equal(t, "req-1", statuses.DeleteTokenCalls()[0].Id)
+
+			require.Len(t, statuses.SaveRequestStatusCalls(), 1)
+			assert.Equal(t, status, statuses.SaveRequestStatusCalls()[0].Status)
+		})
+	}
+}
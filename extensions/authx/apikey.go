@@ -0,0 +1,156 @@
+package authx
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+const (
+	apiKeyPrefix  = "tgk"
+	apiKeyHeader  = "X-API-Key"
+	apiKeyIDBytes = 6
+	apiKeyBytes   = 32
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKey is a stored API key. Only the hash of its secret is kept, the key itself
+// is displayed once when created.
+type APIKey struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	SecretHash string    `json:"secret_hash"`
+	Scopes     []string  `json:"scopes"`
+	Disabled   bool      `json:"disabled"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// APIKeyStore persists API keys.
+type APIKeyStore interface {
+	GetAPIKey(ctx context.Context, id string) (APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	SaveAPIKey(ctx context.Context, key APIKey) error
+}
+
+// APIKeys authenticates requests carrying an API key, either as a bearer token or in
+// the X-API-Key header, and manages the keys of its store.
+type APIKeys struct {
+	store APIKeyStore
+}
+
+func NewAPIKeys(store APIKeyStore) *APIKeys {
+	return &APIKeys{store: store}
+}
+
+// NewAPIKey generates a key and its stored representation. Keys have the form
+// tgk_<id>_<secret>, the id locating the stored hash of the secret.
+func NewAPIKey(name string, scopes []string) (string, APIKey, error) {
+	id := make([]byte, apiKeyIDBytes)
+	secret := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(id); err != nil {
+		return "", APIKey{}, fmt.Errorf("generating api key id: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", APIKey{}, fmt.Errorf("generating api key secret: %w", err)
+	}
+
+	key := APIKey{
+		ID:         hex.EncodeToString(id),
+		Name:       name,
+		SecretHash: hashSecret(base64.RawURLEncoding.EncodeToString(secret)),
+		Scopes:     scopes,
+		CreatedAt:  time.Now().UTC(),
+	}
+	plaintext := strings.Join([]string{apiKeyPrefix, key.ID, base64.RawURLEncoding.EncodeToString(secret)}, "_")
+
+	return plaintext, key, nil
+}
+
+func (a *APIKeys) Authenticate(r *http.Request) (model.Principal, error) {
+	plaintext := r.Header.Get(apiKeyHeader)
+	if plaintext == "" {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !strings.HasPrefix(bearer, apiKeyPrefix+"_") {
+			return model.Principal{}, ErrNoCredentials
+		}
+		plaintext = bearer
+	}
+
+	parts := strings.SplitN(plaintext, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return model.Principal{}, fmt.Errorf("%w: malformed api key", ErrInvalidCredentials)
+	}
+
+	key, err := a.store.GetAPIKey(r.Context(), parts[1])
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return model.Principal{}, fmt.Errorf("%w: unknown api key %s", ErrInvalidCredentials, parts[1])
+	}
+	if err != nil {
+		return model.Principal{}, fmt.Errorf("getting api key: %w", err)
+	}
+
+	hash := hashSecret(parts[2])
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(key.SecretHash)) != 1 {
+		return model.Principal{}, fmt.Errorf("%w: api key %s secret mismatch", ErrInvalidCredentials, key.ID)
+	}
+
+	if key.Disabled {
+		return model.Principal{}, fmt.Errorf("%w: api key %s is disabled", ErrInvalidCredentials, key.ID)
+	}
+
+	return model.Principal{
+		Subject: "apikey:" + key.ID,
+		Method:  model.AuthMethodAPIKey,
+		Name:    key.Name,
+		Scopes:  key.Scopes,
+	}, nil
+}
+
+// CreateAPIKey generates and stores a new key, returning it in plaintext.
+func (a *APIKeys) CreateAPIKey(ctx context.Context, name string, scopes []string) (string, APIKey, error) {
+	plaintext, key, err := NewAPIKey(name, scopes)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+
+	if err := a.store.SaveAPIKey(ctx, key); err != nil {
+		return "", APIKey{}, fmt.Errorf("saving api key: %w", err)
+	}
+
+	return plaintext, key, nil
+}
+
+// DisableAPIKey prevents the key from authenticating any further request.
+func (a *APIKeys) DisableAPIKey(ctx context.Context, id string) error {
+	key, err := a.store.GetAPIKey(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	key.Disabled = true
+	if err := a.store.SaveAPIKey(ctx, key); err != nil {
+		return fmt.Errorf("saving api key: %w", err)
+	}
+
+	return nil
+}
+
+func (a *APIKeys) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	return a.store.ListAPIKeys(ctx)
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,214 @@
+package authx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/werbersondev/token-generator-test/extensions/filex"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+const (
+	apiKeysFileMode       = 0o600
+	apiKeysReloadInterval = 5 * time.Second
+	apiKeyStatePrefix     = "apikeys/"
+)
+
+type apiKeysFile struct {
+	Keys []APIKey `json:"keys"`
+}
+
+// FileAPIKeyStore keeps API keys in a JSON file, reloaded when it changes on disk.
+type FileAPIKeyStore struct {
+	path    string
+	watcher *filex.Watcher
+
+	mu   sync.RWMutex
+	keys map[string]APIKey
+}
+
+// NewFileAPIKeyStore loads the keys of the JSON file at path. A missing file is
+// treated as empty and created on the first save.
+func NewFileAPIKeyStore(path string) (*FileAPIKeyStore, error) {
+	s := &FileAPIKeyStore{
+		path:    path,
+		watcher: filex.NewWatcher(apiKeysReloadInterval, path),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileAPIKeyStore) GetAPIKey(_ context.Context, id string) (APIKey, error) {
+	s.reloadIfChanged()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+
+	return key, nil
+}
+
+func (s *FileAPIKeyStore) ListAPIKeys(_ context.Context) ([]APIKey, error) {
+	s.reloadIfChanged()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedAPIKeys(s.keys), nil
+}
+
+// SaveAPIKey adds or replaces key in the file. The file is read again first, so that
+// the keys saved since it was loaded, by the apikey command or another replica, are
+// kept, and replaced atomically.
+func (s *FileAPIKeyStore) SaveAPIKey(_ context.Context, key APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := readAPIKeysFile(s.path)
+	if err != nil {
+		return err
+	}
+	keys[key.ID] = key
+
+	data, err := json.MarshalIndent(apiKeysFile{Keys: sortedAPIKeys(keys)}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding api keys: %w", err)
+	}
+
+	if err := filex.WriteFileAtomic(s.path, data, apiKeysFileMode); err != nil {
+		return err
+	}
+
+	s.keys = keys
+
+	return nil
+}
+
+func (s *FileAPIKeyStore) reloadIfChanged() {
+	if !s.watcher.Changed() {
+		return
+	}
+
+	// A file that cannot be loaded keeps the previous keys, it may be mid-edit.
+	_ = s.load()
+}
+
+func (s *FileAPIKeyStore) load() error {
+	keys, err := readAPIKeysFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+
+	return nil
+}
+
+// readAPIKeysFile returns the keys of the JSON file at path, none when it is missing.
+func readAPIKeysFile(path string) (map[string]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]APIKey), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading api keys file: %w", err)
+	}
+
+	var file apiKeysFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decoding api keys file: %w", err)
+	}
+
+	keys := make(map[string]APIKey, len(file.Keys))
+	for _, key := range file.Keys {
+		keys[key.ID] = key
+	}
+
+	return keys, nil
+}
+
+// StateAPIKeyStore keeps API keys in the shared state store.
+type StateAPIKeyStore struct {
+	store statestore.Store
+}
+
+func NewStateAPIKeyStore(store statestore.Store) *StateAPIKeyStore {
+	return &StateAPIKeyStore{store: store}
+}
+
+func (s *StateAPIKeyStore) GetAPIKey(ctx context.Context, id string) (APIKey, error) {
+	data, err := s.store.Get(ctx, apiKeyStatePrefix+id)
+	if errors.Is(err, statestore.ErrNotFound) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	var key APIKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return APIKey{}, fmt.Errorf("decoding api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (s *StateAPIKeyStore) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	ids, err := s.store.Keys(ctx, apiKeyStatePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]APIKey, len(ids))
+	for _, id := range ids {
+		key, err := s.GetAPIKey(ctx, strings.TrimPrefix(id, apiKeyStatePrefix))
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys[key.ID] = key
+	}
+
+	return sortedAPIKeys(keys), nil
+}
+
+func (s *StateAPIKeyStore) SaveAPIKey(ctx context.Context, key APIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("encoding api key: %w", err)
+	}
+
+	return s.store.Set(ctx, apiKeyStatePrefix+key.ID, data, 0)
+}
+
+func sortedAPIKeys(keys map[string]APIKey) []APIKey {
+	sorted := make([]APIKey, 0, len(keys))
+	for _, key := range keys {
+		sorted = append(sorted, key)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	return sorted
+}
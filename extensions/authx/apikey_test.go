@@ -0,0 +1,133 @@
+package authx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+func TestAPIKeys_Authenticate(t *testing.T) {
+	stores := map[string]func(t *testing.T) authx.APIKeyStore{
+		"file": func(t *testing.T) authx.APIKeyStore {
+			store, err := authx.NewFileAPIKeyStore(filepath.Join(t.TempDir(), "api_keys.json"))
+			require.NoError(t, err)
+			return store
+		},
+		"state": func(t *testing.T) authx.APIKeyStore {
+			return authx.NewStateAPIKeyStore(statestore.NewMemory())
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			apiKeys := authx.NewAPIKeys(newStore(t))
+
+			plaintext, key, err := apiKeys.CreateAPIKey(ctx, "ci", []string{model.ScopeRequestTokens})
+			require.NoError(t, err)
+
+			tests := []struct {
+				name        string
+				header      string
+				value       string
+				expectedErr error
+			}{
+				{name: "bearer token", header: "Authorization", value: "Bearer " + plaintext},
+				{name: "api key header", header: "X-API-Key", value: plaintext},
+				{name: "no credentials", expectedErr: authx.ErrNoCredentials},
+				{name: "other bearer token", header: "Authorization", value: "Bearer eyJhbGciOi", expectedErr: authx.ErrNoCredentials},
+				{name: "wrong secret", header: "X-API-Key", value: "tgk_" + key.ID + "_wrong", expectedErr: authx.ErrInvalidCredentials},
+				{name: "unknown key", header: "X-API-Key", value: "tgk_000000000000_secret", expectedErr: authx.ErrInvalidCredentials},
+				{name: "malformed key", header: "X-API-Key", value: "not-a-key", expectedErr: authx.ErrInvalidCredentials},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					req := httptest.NewRequest(http.MethodPost, "/generate_token", nil)
+					if tt.header != "" {
+						req.Header.Set(tt.header, tt.value)
+					}
+
+					principal, err := apiKeys.Authenticate(req)
+
+					if tt.expectedErr != nil {
+						assert.ErrorIs(t, err, tt.expectedErr)
+						return
+					}
+
+					assert.NoError(t, err)
+					assert.Equal(t, "apikey:"+key.ID, principal.Subject)
+					assert.Equal(t, model.AuthMethodAPIKey, principal.Method)
+					assert.True(t, principal.HasScope(model.ScopeRequestTokens))
+				})
+			}
+
+			require.NoError(t, apiKeys.DisableAPIKey(ctx, key.ID))
+
+			req := httptest.NewRequest(http.MethodPost, "/generate_token", nil)
+			req.Header.Set("X-API-Key", plaintext)
+			_, err = apiKeys.Authenticate(req)
+			assert.ErrorIs(t, err, authx.ErrInvalidCredentials)
+
+			assert.ErrorIs(t, apiKeys.DisableAPIKey(ctx, "unknown"), authx.ErrAPIKeyNotFound)
+		})
+	}
+}
+
+func TestFileAPIKeyStore_Persistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+
+	store, err := authx.NewFileAPIKeyStore(path)
+	require.NoError(t, err)
+
+	_, key, err := authx.NewAPIKeys(store).CreateAPIKey(ctx, "ci", []string{model.ScopeRequestTokens})
+	require.NoError(t, err)
+
+	reopened, err := authx.NewFileAPIKeyStore(path)
+	require.NoError(t, err)
+
+	keys, err := reopened.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	if assert.Len(t, keys, 1) {
+		assert.Equal(t, key.ID, keys[0].ID)
+		assert.Equal(t, key.SecretHash, keys[0].SecretHash)
+	}
+}
+
+func TestFileAPIKeyStore_SaveKeepsKeysSavedElsewhere(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+
+	// Both stores load the file before the other saves, as the apikey command and the
+	// HTTP service would.
+	service, err := authx.NewFileAPIKeyStore(path)
+	require.NoError(t, err)
+	command, err := authx.NewFileAPIKeyStore(path)
+	require.NoError(t, err)
+
+	_, created, err := authx.NewAPIKeys(command).CreateAPIKey(ctx, "ci", []string{model.ScopeRequestTokens})
+	require.NoError(t, err)
+	_, other, err := authx.NewAPIKeys(service).CreateAPIKey(ctx, "deploy", []string{model.ScopeRequestTokens})
+	require.NoError(t, err)
+
+	reopened, err := authx.NewFileAPIKeyStore(path)
+	require.NoError(t, err)
+	keys, err := reopened.ListAPIKeys(ctx)
+	require.NoError(t, err)
+
+	var ids []string
+	for _, key := range keys {
+		ids = append(ids, key.ID)
+	}
+	assert.ElementsMatch(t, []string{created.ID, other.ID}, ids, "a key saved by another process is lost")
+}
@@ -0,0 +1,77 @@
+package authx
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+// Stable codes of the problems reported by the middlewares.
+const (
+	CodeUnauthenticated   = "unauthenticated"
+	CodeInsufficientScope = "insufficient_scope"
+)
+
+var (
+	// ErrNoCredentials is returned by an Authenticator when the request does not carry
+	// the kind of credentials it handles, letting the next one try.
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned when the credentials are present but rejected.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator resolves the principal behind the credentials of a request.
+type Authenticator interface {
+	Authenticate(r *http.Request) (model.Principal, error)
+}
+
+// Middleware authenticates requests with the first authenticator recognizing their
+// credentials and attaches the principal to the request context. Requests without
+// valid credentials are rejected with 401 Unauthorized.
+func Middleware(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, authenticator := range authenticators {
+				principal, err := authenticator.Authenticate(r)
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+				if err != nil {
+					log.Ctx(r.Context()).Warn().Err(err).Msg("authentication failed")
+					httpx.WriteProblem(w, r, http.StatusUnauthorized, CodeUnauthenticated, "invalid credentials")
+					return
+				}
+
+				ctx := model.ContextWithPrincipal(r.Context(), principal)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			httpx.WriteProblem(w, r, http.StatusUnauthorized, CodeUnauthenticated, "missing credentials")
+		})
+	}
+}
+
+// RequireScope rejects with 403 Forbidden the requests whose principal lacks scope.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := model.PrincipalFromContext(r.Context())
+			if !ok {
+				httpx.WriteProblem(w, r, http.StatusUnauthorized, CodeUnauthenticated, "missing credentials")
+				return
+			}
+
+			if !principal.HasScope(scope) {
+				httpx.WriteProblem(w, r, http.StatusForbidden, CodeInsufficientScope, "missing required scope: "+scope)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
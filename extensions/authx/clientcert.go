@@ -0,0 +1,131 @@
+package authx
+
+import (
+	"crypto/x509"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+// Prefixes of the certificate identities other than URI SANs.
+const (
+	identityDNSPrefix        = "dns:"
+	identityEmailPrefix      = "email:"
+	identityCommonNamePrefix = "cn:"
+)
+
+// ClientIdentity maps the client certificates presenting an identity to a principal.
+type ClientIdentity struct {
+	// Identity the certificate must present: a URI SAN such as the SPIFFE ID
+	// "spiffe://example.org/ns/ci/sa/deployer", or a DNS SAN, an email SAN or the
+	// subject common name, prefixed with "dns:", "email:" or "cn:".
+	Identity string `yaml:"identity"`
+	// Subject of the principal, the identity itself by default.
+	Subject    string            `yaml:"subject"`
+	Name       string            `yaml:"name"`
+	Groups     []string          `yaml:"groups"`
+	Scopes     []string          `yaml:"scopes"`
+	Attributes map[string]string `yaml:"attributes"`
+}
+
+// ClientCertificates authenticates requests by the client certificate of their TLS
+// connection, mapping its identity to a principal through the configured identities.
+//
+// The certificate is not verified here: it must only be used behind servers verifying
+// client certificates, such as those configured by tlsx.NewServerConfig with a client CA.
+type ClientCertificates struct {
+	identities map[string]ClientIdentity
+}
+
+func NewClientCertificates(identities []ClientIdentity) (*ClientCertificates, error) {
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("client certificate authentication requires an identity")
+	}
+
+	byIdentity := make(map[string]ClientIdentity, len(identities))
+	for _, identity := range identities {
+		if identity.Identity == "" {
+			return nil, fmt.Errorf("client certificate identity %q: identity is required", identity.Subject)
+		}
+		if _, ok := byIdentity[identity.Identity]; ok {
+			return nil, fmt.Errorf("client certificate identity %s: mapped twice", identity.Identity)
+		}
+		byIdentity[identity.Identity] = identity
+	}
+
+	return &ClientCertificates{identities: byIdentity}, nil
+}
+
+func (c *ClientCertificates) Authenticate(r *http.Request) (model.Principal, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return model.Principal{}, ErrNoCredentials
+	}
+
+	cert := r.TLS.PeerCertificates[0]
+	for _, presented := range CertificateIdentities(cert) {
+		if identity, ok := c.identities[presented]; ok {
+			return identity.principal(cert), nil
+		}
+	}
+
+	return model.Principal{}, fmt.Errorf("%w: client certificate %s is not mapped to a principal", ErrInvalidCredentials, cert.Subject)
+}
+
+func (i ClientIdentity) principal(cert *x509.Certificate) model.Principal {
+	principal := model.Principal{
+		Subject:    i.Subject,
+		Method:     model.AuthMethodClientCert,
+		Issuer:     cert.Issuer.String(),
+		Name:       i.Name,
+		Groups:     slices.Clone(i.Groups),
+		Scopes:     slices.Clone(i.Scopes),
+		Attributes: maps.Clone(i.Attributes),
+	}
+	if principal.Subject == "" {
+		principal.Subject = i.Identity
+	}
+	if spiffeID := SPIFFEID(cert); spiffeID != "" {
+		if principal.Attributes == nil {
+			principal.Attributes = make(map[string]string, 1)
+		}
+		principal.Attributes["spiffe_id"] = spiffeID
+	}
+
+	return principal
+}
+
+// CertificateIdentities returns the identities cert presents, in the order they are
+// matched: its URI SANs, DNS SANs, email SANs, then its subject common name.
+func CertificateIdentities(cert *x509.Certificate) []string {
+	identities := make([]string, 0, len(cert.URIs)+len(cert.DNSNames)+len(cert.EmailAddresses)+1)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	for _, name := range cert.DNSNames {
+		identities = append(identities, identityDNSPrefix+name)
+	}
+	for _, email := range cert.EmailAddresses {
+		identities = append(identities, identityEmailPrefix+email)
+	}
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, identityCommonNamePrefix+cert.Subject.CommonName)
+	}
+
+	return identities
+}
+
+// SPIFFEID returns the SPIFFE ID of cert, its spiffe URI SAN, or an empty string when it
+// has none. SPIFFE certificates carry exactly one.
+func SPIFFEID(cert *x509.Certificate) string {
+	for _, uri := range cert.URIs {
+		if strings.EqualFold(uri.Scheme, "spiffe") {
+			return uri.String()
+		}
+	}
+
+	return ""
+}
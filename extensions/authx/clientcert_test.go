@@ -0,0 +1,246 @@
+package authx_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+	"github.com/werbersondev/token-generator-test/extensions/tlsx"
+)
+
+type certificateAuthority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newCertificateAuthority(t *testing.T, commonName string) certificateAuthority {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return certificateAuthority{cert: cert, key: key}
+}
+
+// issue signs a certificate from template, valid for an hour.
+func (a certificateAuthority) issue(t *testing.T, template *x509.Certificate) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (a certificateAuthority) writePEM(t *testing.T, path string) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: a.cert.Raw})
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func writeKeyPair(t *testing.T, certFile, keyFile string, cert tls.Certificate) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func TestClientCertificates_Authenticate(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.pem")
+	keyFile := filepath.Join(dir, "server-key.pem")
+	clientCAFile := filepath.Join(dir, "client-ca.pem")
+
+	serverCA := newCertificateAuthority(t, "server-ca")
+	clientCA := newCertificateAuthority(t, "client-ca")
+	writeKeyPair(t, certFile, keyFile, serverCA.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "token-generator"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+	}))
+	clientCA.writePEM(t, clientCAFile)
+
+	tlsConfig, err := tlsx.NewServerConfig(tlsx.ServerConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: clientCAFile})
+	require.NoError(t, err)
+
+	clientCerts, err := authx.NewClientCertificates([]authx.ClientIdentity{
+		{
+			Identity:   "spiffe://example.org/ns/ci/sa/deployer",
+			Subject:    "deployer",
+			Groups:     []string{"platform"},
+			Scopes:     []string{model.ScopeRequestTokens},
+			Attributes: map[string]string{"team": "platform"},
+		},
+		{Identity: "dns:reporting.internal", Scopes: []string{model.ScopeRequestTokens}},
+		{Identity: "cn:auditor"},
+	})
+	require.NoError(t, err)
+
+	handler := authx.Middleware(clientCerts)(authx.RequireScope(model.ScopeRequestTokens)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := model.PrincipalFromContext(r.Context())
+			_ = json.NewEncoder(w).Encode(principal)
+		}),
+	))
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	require.NoError(t, err)
+	server := &http.Server{Handler: handler}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	spiffeID, err := url.Parse("spiffe://example.org/ns/ci/sa/deployer")
+	require.NoError(t, err)
+	clientUsage := []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+
+	tests := []struct {
+		name              string
+		certs             []tls.Certificate
+		expectedHandshake bool
+		expectedStatus    int
+		expectedPrincipal model.Principal
+	}{
+		{
+			name:           "SPIFFE ID",
+			certs:          []tls.Certificate{clientCA.issue(t, &x509.Certificate{URIs: []*url.URL{spiffeID}, ExtKeyUsage: clientUsage})},
+			expectedStatus: http.StatusOK,
+			expectedPrincipal: model.Principal{
+				Subject:    "deployer",
+				Method:     model.AuthMethodClientCert,
+				Issuer:     "CN=client-ca",
+				Groups:     []string{"platform"},
+				Scopes:     []string{model.ScopeRequestTokens},
+				Attributes: map[string]string{"team": "platform", "spiffe_id": "spiffe://example.org/ns/ci/sa/deployer"},
+			},
+		},
+		{
+			name: "DNS Name",
+			certs: []tls.Certificate{clientCA.issue(t, &x509.Certificate{
+				Subject:     pkix.Name{CommonName: "unmapped"},
+				DNSNames:    []string{"reporting.internal"},
+				ExtKeyUsage: clientUsage,
+			})},
+			expectedStatus: http.StatusOK,
+			expectedPrincipal: model.Principal{
+				Subject: "dns:reporting.internal",
+				Method:  model.AuthMethodClientCert,
+				Issuer:  "CN=client-ca",
+				Scopes:  []string{model.ScopeRequestTokens},
+			},
+		},
+		{
+			name:           "Missing Scope",
+			certs:          []tls.Certificate{clientCA.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "auditor"}, ExtKeyUsage: clientUsage})},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Unmapped Identity",
+			certs:          []tls.Certificate{clientCA.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "stranger"}, ExtKeyUsage: clientUsage})},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "No Certificate",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "Untrusted Authority",
+			certs: []tls.Certificate{newCertificateAuthority(t, "client-ca").issue(t, &x509.Certificate{
+				URIs:        []*url.URL{spiffeID},
+				ExtKeyUsage: clientUsage,
+			})},
+			expectedHandshake: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: tt.certs},
+			}}
+
+			resp, err := client.Get("https://" + listener.Addr().String())
+			if tt.expectedHandshake {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var principal model.Principal
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&principal))
+			assert.Equal(t, tt.expectedPrincipal, principal)
+		})
+	}
+}
+
+func TestNewClientCertificates_InvalidIdentities(t *testing.T) {
+	tests := []struct {
+		name        string
+		identities  []authx.ClientIdentity
+		expectedErr string
+	}{
+		{name: "No Identity", expectedErr: "requires an identity"},
+		{name: "Missing Identity", identities: []authx.ClientIdentity{{Subject: "deployer"}}, expectedErr: "identity is required"},
+		{
+			name:        "Duplicate Identity",
+			identities:  []authx.ClientIdentity{{Identity: "cn:deployer"}, {Identity: "cn:deployer"}},
+			expectedErr: "mapped twice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := authx.NewClientCertificates(tt.identities)
+			assert.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package authx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+// UnaryServerInterceptor is the gRPC counterpart of Middleware. Calls are authenticated
+// from their metadata, presented to the authenticators as the headers of a request,
+// and rejected with codes.Unauthenticated without valid credentials.
+func UnaryServerInterceptor(authenticators ...Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticateCall(ctx, info.FullMethod, authenticators)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor.
+func StreamServerInterceptor(authenticators ...Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateCall(ss.Context(), info.FullMethod, authenticators)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// CheckScope is the gRPC counterpart of RequireScope, returning the status to fail a
+// call with when the principal of ctx lacks scope.
+func CheckScope(ctx context.Context, scope string) error {
+	principal, ok := model.PrincipalFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing credentials")
+	}
+
+	if !principal.HasScope(scope) {
+		return status.Error(codes.PermissionDenied, "missing required scope: "+scope)
+	}
+
+	return nil
+}
+
+func authenticateCall(ctx context.Context, method string, authenticators []Authenticator) (context.Context, error) {
+	r := callRequest(ctx, method)
+	for _, authenticator := range authenticators {
+		principal, err := authenticator.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			log.Ctx(ctx).Warn().Err(err).Str("method", method).Msg("authentication failed")
+			return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+		}
+
+		return model.ContextWithPrincipal(ctx, principal), nil
+	}
+
+	return nil, status.Error(codes.Unauthenticated, "missing credentials")
+}
+
+// callRequest presents a gRPC call as an HTTP request: its metadata as headers, and its
+// peer as the remote address and TLS connection state.
+func callRequest(ctx context.Context, method string) *http.Request {
+	r := &http.Request{
+		Method:     http.MethodPost,
+		URL:        &url.URL{Path: method},
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+		Header:     make(http.Header),
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		if strings.HasPrefix(key, ":") {
+			continue
+		}
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if p.Addr != nil {
+			r.RemoteAddr = p.Addr.String()
+		}
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+	}
+
+	return r.WithContext(ctx)
+}
+
+// serverStream replaces the context of a stream with the authenticated one.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
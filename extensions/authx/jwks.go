@@ -0,0 +1,311 @@
+package authx
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/werbersondev/token-generator-test/extensions/filex"
+)
+
+// jwksRefreshCooldown bounds how often an unknown key id triggers a refresh, so
+// tokens signed with garbage key ids cannot be used to hammer the JWKS endpoint.
+const jwksRefreshCooldown = 30 * time.Second
+
+var errKeyNotFound = errors.New("signing key not found")
+
+// JWKS is a JSON Web Key Set fetched from a URL or read from a local file. Fetched
+// keys are cached for a TTL and refreshed earlier when a token references a key id
+// unknown to the cache, which happens right after the issuer rotates its keys.
+// Local files are reloaded when they change.
+type JWKS struct {
+	url     string
+	path    string
+	ttl     time.Duration
+	client  *http.Client
+	watcher *filex.Watcher
+
+	fetches singleflight.Group
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewRemoteJWKS creates a key set fetched from url and cached for ttl.
+func NewRemoteJWKS(url string, ttl time.Duration, client *http.Client) *JWKS {
+	return &JWKS{
+		url:    url,
+		ttl:    ttl,
+		client: client,
+	}
+}
+
+// NewFileJWKS creates a key set read from path, checked for changes every interval.
+func NewFileJWKS(path string, interval time.Duration) (*JWKS, error) {
+	keys, err := readJWKSFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWKS{
+		path:      path,
+		watcher:   filex.NewWatcher(interval, path),
+		keys:      keys,
+		fetchedAt: time.Now(),
+	}, nil
+}
+
+// Key returns the public key identified by kid. An empty kid matches the key set
+// when it holds a single key.
+func (j *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if err := j.refresh(ctx, false); err != nil {
+		return nil, err
+	}
+
+	key, err := j.lookup(kid)
+	if errors.Is(err, errKeyNotFound) && j.url != "" {
+		if err := j.refresh(ctx, true); err != nil {
+			return nil, err
+		}
+		key, err = j.lookup(kid)
+	}
+
+	return key, err
+}
+
+func (j *JWKS) lookup(kid string) (crypto.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, nil
+		}
+	}
+
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: kid %q", errKeyNotFound, kid)
+	}
+
+	return key, nil
+}
+
+// refresh reloads the keys when changed on disk, expired, or forced by an unknown
+// key id once the refresh cooldown elapsed. A failed refresh keeps the cached keys,
+// if any, so that an unavailable issuer does not reject tokens signed with known
+// keys.
+func (j *JWKS) refresh(ctx context.Context, force bool) error {
+	if j.path != "" {
+		return j.reload()
+	}
+
+	j.mu.Lock()
+	stale := j.keys == nil || time.Since(j.fetchedAt) > j.ttl ||
+		force && time.Since(j.fetchedAt) > jwksRefreshCooldown
+	j.mu.Unlock()
+	if !stale {
+		return nil
+	}
+
+	// The remote fetch runs without the lock, so that requests verified with cached
+	// keys are not held up by a slow issuer, and is shared by concurrent callers. It
+	// is detached from the caller's cancellation since other callers wait for it.
+	result := j.fetches.DoChan("", func() (any, error) {
+		keys, err := j.fetch(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		j.mu.Lock()
+		j.keys = keys
+		j.fetchedAt = time.Now()
+		j.mu.Unlock()
+
+		return nil, nil
+	})
+
+	var err error
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case res := <-result:
+		err = res.Err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err != nil && j.keys == nil {
+		return err
+	}
+
+	return nil
+}
+
+func (j *JWKS) reload() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.watcher.Changed() {
+		return nil
+	}
+
+	keys, err := readJWKSFile(j.path)
+	if err != nil {
+		return nil
+	}
+
+	j.keys = keys
+	j.fetchedAt = time.Now()
+
+	return nil
+}
+
+func (j *JWKS) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating jwks request: %w", err)
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("reading jwks: %w", err)
+	}
+
+	return ParseJWKS(body)
+}
+
+func readJWKSFile(path string) (map[string]crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading jwks file: %w", err)
+	}
+
+	return ParseJWKS(data)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses the signature keys of a JSON Web Key Set by key id. RSA, EC
+// (P-256, P-384, P-521) and Ed25519 keys are supported, others are skipped.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("decoding jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("parsing key %q: %w", jwk.Kid, err)
+		}
+		if key != nil {
+			keys[jwk.Kid] = key
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwks holds no signature key")
+	}
+
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,202 @@
+package authx
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+// signingMethods are the asymmetric algorithms accepted for bearer tokens. HMAC is
+// excluded, the services never share a secret with the issuer.
+var signingMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// ClaimMapping names the token claims the principal is built from.
+type ClaimMapping struct {
+	// Subject identifies the caller, "sub" by default.
+	Subject string
+	// Name is a display name of the caller.
+	Name string
+	// Groups holds the groups of the caller, as an array or a space separated string.
+	Groups string
+	// Scopes holds the scopes granted to the caller, as an array or a space separated
+	// string such as the OAuth 2.0 "scope" claim.
+	Scopes string
+	// Attributes are copied to the principal attributes, for instance the repository
+	// and ref claims of CI tokens. "*" copies every string, number and boolean claim.
+	Attributes []string
+}
+
+type JWTConfig struct {
+	Issuer   string
+	Audience string
+	// Leeway tolerated on the expiry and not before times for clock skew.
+	Leeway time.Duration
+	Claims ClaimMapping
+	// DefaultScopes are granted to every authenticated caller on top of the scopes
+	// found in the token.
+	DefaultScopes []string
+}
+
+// JWT authenticates requests carrying a JSON Web Token as bearer token, issued by the
+// configured issuer for the configured audience and signed with a key of the JWKS.
+// Tokens of another issuer are left to the next authenticator, so that one JWT per
+// trusted issuer can be chained.
+type JWT struct {
+	config JWTConfig
+	keys   *JWKS
+	parser *jwt.Parser
+}
+
+func NewJWT(config JWTConfig, keys *JWKS) (*JWT, error) {
+	if config.Issuer == "" {
+		return nil, fmt.Errorf("jwt authentication requires an issuer")
+	}
+	if config.Audience == "" {
+		return nil, fmt.Errorf("jwt authentication requires an audience")
+	}
+	if config.Claims.Subject == "" {
+		config.Claims.Subject = "sub"
+	}
+
+	return &JWT{
+		config: config,
+		keys:   keys,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(signingMethods),
+			jwt.WithIssuer(config.Issuer),
+			jwt.WithAudience(config.Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+			jwt.WithLeeway(config.Leeway),
+		),
+	}, nil
+}
+
+func (j *JWT) Authenticate(r *http.Request) (model.Principal, error) {
+	raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || strings.Count(raw, ".") != 2 {
+		return model.Principal{}, ErrNoCredentials
+	}
+	if issuer, ok := unverifiedIssuer(raw); ok && issuer != j.config.Issuer {
+		return model.Principal{}, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := j.parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return j.keys.Key(r.Context(), kid)
+	})
+	if err != nil {
+		return model.Principal{}, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	return j.principal(claims)
+}
+
+// unverifiedIssuer reads the issuer claim of raw before its signature is checked, only
+// to pick the authenticator of that issuer.
+func unverifiedIssuer(raw string) (string, bool) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(raw, claims); err != nil {
+		return "", false
+	}
+
+	issuer, err := claims.GetIssuer()
+	return issuer, err == nil && issuer != ""
+}
+
+func (j *JWT) principal(claims jwt.MapClaims) (model.Principal, error) {
+	mapping := j.config.Claims
+
+	subject, _ := claims[mapping.Subject].(string)
+	if subject == "" {
+		return model.Principal{}, fmt.Errorf("%w: missing %s claim", ErrInvalidCredentials, mapping.Subject)
+	}
+
+	principal := model.Principal{
+		Subject: subject,
+		Method:  model.AuthMethodJWT,
+		Issuer:  j.config.Issuer,
+		Groups:  stringsClaim(claims, mapping.Groups),
+		Scopes:  slices.Clone(j.config.DefaultScopes),
+	}
+	if mapping.Name != "" {
+		principal.Name, _ = claims[mapping.Name].(string)
+	}
+
+	for _, scope := range stringsClaim(claims, mapping.Scopes) {
+		if !slices.Contains(principal.Scopes, scope) {
+			principal.Scopes = append(principal.Scopes, scope)
+		}
+	}
+
+	principal.Attributes = attributeClaims(claims, mapping.Attributes)
+
+	return principal, nil
+}
+
+func attributeClaims(claims jwt.MapClaims, names []string) map[string]string {
+	if slices.Contains(names, "*") {
+		names = make([]string, 0, len(claims))
+		for name, value := range claims {
+			switch value.(type) {
+			case string, float64, bool:
+				names = append(names, name)
+			}
+		}
+	}
+
+	var attributes map[string]string
+	for _, name := range names {
+		value, ok := claims[name]
+		if !ok {
+			continue
+		}
+		if attributes == nil {
+			attributes = make(map[string]string, len(names))
+		}
+		if number, ok := value.(float64); ok {
+			// Avoid the exponent notation of large identifiers.
+			attributes[name] = strconv.FormatFloat(number, 'f', -1, 64)
+			continue
+		}
+		attributes[name] = fmt.Sprint(value)
+	}
+
+	return attributes
+}
+
+// stringsClaim reads a claim holding either an array of strings or a space
+// separated string.
+func stringsClaim(claims jwt.MapClaims, name string) []string {
+	if name == "" {
+		return nil
+	}
+
+	switch value := claims[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
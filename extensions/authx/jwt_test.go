@@ -0,0 +1,312 @@
+package authx_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/authx"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "token-generator"
+)
+
+type signingKey struct {
+	kid    string
+	method jwt.SigningMethod
+	key    crypto.Signer
+}
+
+func newRSAKey(t *testing.T, kid string) signingKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return signingKey{kid: kid, method: jwt.SigningMethodRS256, key: key}
+}
+
+func newECKey(t *testing.T, kid string) signingKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return signingKey{kid: kid, method: jwt.SigningMethodES256, key: key}
+}
+
+func (k signingKey) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.kid
+	signed, err := token.SignedString(k.key)
+	require.NoError(t, err)
+	return signed
+}
+
+func encodeJWKS(t *testing.T, keys ...signingKey) []byte {
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	jwks := make([]map[string]string, 0, len(keys))
+	for _, k := range keys {
+		switch public := k.key.Public().(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, map[string]string{
+				"kty": "RSA", "kid": k.kid, "use": "sig",
+				"n": b64(public.N.Bytes()), "e": b64(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			jwks = append(jwks, map[string]string{
+				"kty": "EC", "kid": k.kid, "crv": "P-256",
+				"x": b64(public.X.FillBytes(make([]byte, 32))), "y": b64(public.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+	}
+
+	data, err := json.Marshal(map[string]any{"keys": jwks})
+	require.NoError(t, err)
+	return data
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":        testIssuer,
+		"aud":        testAudience,
+		"sub":        "repo:acme/app:ref:refs/heads/main",
+		"exp":        time.Now().Add(time.Hour).Unix(),
+		"iat":        time.Now().Unix(),
+		"name":       "acme/app",
+		"groups":     []string{"ci"},
+		"scope":      "admin",
+		"repository": "acme/app",
+	}
+}
+
+func newJWTAuthenticator(t *testing.T, keys *authx.JWKS) *authx.JWT {
+	authenticator, err := authx.NewJWT(authx.JWTConfig{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		Claims: authx.ClaimMapping{
+			Name:       "name",
+			Groups:     "groups",
+			Scopes:     "scope",
+			Attributes: []string{"repository", "missing"},
+		},
+		DefaultScopes: []string{model.ScopeRequestTokens},
+	}, keys)
+	require.NoError(t, err)
+	return authenticator
+}
+
+func authenticateBearer(authenticator authx.Authenticator, token string) (model.Principal, error) {
+	req := httptest.NewRequest(http.MethodPost, "/generate_token", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return authenticator.Authenticate(req)
+}
+
+func TestJWT_Authenticate(t *testing.T) {
+	rsaKey := newRSAKey(t, "rsa")
+	ecKey := newECKey(t, "ec")
+	unknownKey := newRSAKey(t, "rsa")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(encodeJWKS(t, rsaKey, ecKey))
+	}))
+	defer server.Close()
+
+	authenticator := newJWTAuthenticator(t, authx.NewRemoteJWKS(server.URL, time.Minute, server.Client()))
+
+	withClaim := func(name string, value any) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		expectedErr error
+	}{
+		{name: "rsa signed", token: rsaKey.sign(t, validClaims())},
+		{name: "ec signed", token: ecKey.sign(t, validClaims())},
+		{name: "no credentials", token: "", expectedErr: authx.ErrNoCredentials},
+		{name: "api key", token: "tgk_abc_def", expectedErr: authx.ErrNoCredentials},
+		{name: "unknown signing key", token: unknownKey.sign(t, validClaims()), expectedErr: authx.ErrInvalidCredentials},
+		{name: "other issuer", token: rsaKey.sign(t, withClaim("iss", "https://other.example.com")), expectedErr: authx.ErrNoCredentials},
+		{name: "without issuer", token: rsaKey.sign(t, withClaim("iss", nil)), expectedErr: authx.ErrInvalidCredentials},
+		{name: "wrong audience", token: rsaKey.sign(t, withClaim("aud", "other")), expectedErr: authx.ErrInvalidCredentials},
+		{name: "expired", token: rsaKey.sign(t, withClaim("exp", time.Now().Add(-time.Hour).Unix())), expectedErr: authx.ErrInvalidCredentials},
+		{name: "without expiry", token: rsaKey.sign(t, withClaim("exp", nil)), expectedErr: authx.ErrInvalidCredentials},
+		{name: "without subject", token: rsaKey.sign(t, withClaim("sub", nil)), expectedErr: authx.ErrInvalidCredentials},
+		{name: "unsigned", token: unsignedToken(t), expectedErr: authx.ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticateBearer(authenticator, tt.token)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, model.Principal{
+				Subject:    "repo:acme/app:ref:refs/heads/main",
+				Method:     model.AuthMethodJWT,
+				Issuer:     testIssuer,
+				Name:       "acme/app",
+				Groups:     []string{"ci"},
+				Scopes:     []string{model.ScopeRequestTokens, model.ScopeAdmin},
+				Attributes: map[string]string{"repository": "acme/app"},
+			}, principal)
+		})
+	}
+}
+
+func unsignedToken(t *testing.T) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	return token
+}
+
+func TestRemoteJWKS_Caching(t *testing.T) {
+	oldKey := newRSAKey(t, "old")
+	newKey := newRSAKey(t, "new")
+
+	var (
+		fetches atomic.Int32
+		rotated atomic.Bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if rotated.Load() {
+			_, _ = w.Write(encodeJWKS(t, newKey))
+			return
+		}
+		_, _ = w.Write(encodeJWKS(t, oldKey))
+	}))
+	defer server.Close()
+
+	authenticator := newJWTAuthenticator(t, authx.NewRemoteJWKS(server.URL, time.Hour, server.Client()))
+
+	for i := 0; i < 3; i++ {
+		_, err := authenticateBearer(authenticator, oldKey.sign(t, validClaims()))
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, fetches.Load(), "keys are cached")
+
+	// A key rotated by the issuer is only picked up once the refresh cooldown elapsed,
+	// so unknown key ids do not trigger a fetch on every request.
+	rotated.Store(true)
+	_, err := authenticateBearer(authenticator, newKey.sign(t, validClaims()))
+	assert.ErrorIs(t, err, authx.ErrInvalidCredentials)
+	assert.EqualValues(t, 1, fetches.Load())
+}
+
+func TestRemoteJWKS_ConcurrentRefresh(t *testing.T) {
+	key := newRSAKey(t, "key")
+
+	var fetches atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			close(started)
+			select {
+			case <-release:
+			case <-time.After(time.Second):
+			}
+		}
+		_, _ = w.Write(encodeJWKS(t, key))
+	}))
+	defer server.Close()
+
+	// A zero TTL refreshes the keys on every lookup.
+	keys := authx.NewRemoteJWKS(server.URL, 0, server.Client())
+	_, err := keys.Key(context.Background(), "key")
+	require.NoError(t, err)
+
+	refreshed := make(chan error)
+	go func() {
+		_, err := keys.Key(context.Background(), "key")
+		refreshed <- err
+	}()
+	<-started
+
+	// A caller giving up on the slow refresh in flight is served the cached keys
+	// rather than waiting for it, and does not start another fetch.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	cached, err := keys.Key(ctx, "key")
+	require.NoError(t, err)
+	assert.NotNil(t, cached)
+	assert.Less(t, time.Since(begin), 500*time.Millisecond, "the lookup waited for the refresh")
+
+	close(release)
+	require.NoError(t, <-refreshed)
+	assert.EqualValues(t, 2, fetches.Load(), "the refresh is shared")
+}
+
+func TestFileJWKS_Reload(t *testing.T) {
+	oldKey := newECKey(t, "old")
+	newKey := newECKey(t, "new")
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, encodeJWKS(t, oldKey), 0o600))
+
+	keys, err := authx.NewFileJWKS(path, 0)
+	require.NoError(t, err)
+	authenticator := newJWTAuthenticator(t, keys)
+
+	_, err = authenticateBearer(authenticator, oldKey.sign(t, validClaims()))
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, encodeJWKS(t, oldKey, newKey), 0o600))
+	// Make sure the modification time changes on coarse grained file systems.
+	require.NoError(t, os.Chtimes(path, time.Now().Add(time.Second), time.Now().Add(time.Second)))
+
+	_, err = authenticateBearer(authenticator, newKey.sign(t, validClaims()))
+	assert.NoError(t, err)
+}
+
+func TestParseJWKS_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "not json", data: "keys"},
+		{name: "empty", data: `{"keys": []}`},
+		{name: "encryption keys only", data: `{"keys": [{"kty": "RSA", "use": "enc", "n": "AQAB", "e": "AQAB"}]}`},
+		{name: "invalid parameter", data: `{"keys": [{"kty": "RSA", "n": "!", "e": "AQAB"}]}`},
+		{name: "unsupported curve", data: `{"keys": [{"kty": "EC", "crv": "P-192", "x": "AQAB", "y": "AQAB"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := authx.ParseJWKS([]byte(tt.data))
+
+			assert.Error(t, err)
+		})
+	}
+}
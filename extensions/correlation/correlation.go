@@ -0,0 +1,99 @@
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Header is the HTTP header, and the gRPC metadata key in lower case, carrying the
+	// correlation ID of a request.
+	Header = "X-Request-ID"
+	// Attribute is the Pub/Sub message attribute carrying the correlation ID.
+	Attribute = "correlation_id"
+	// LogField is the field of the log lines written for a correlated request.
+	LogField = "correlation_id"
+
+	maxIDLength = 128
+)
+
+type contextKey struct{}
+
+// ContextWithID returns a copy of ctx carrying id, with a context logger adding it to
+// every line.
+func ContextWithID(ctx context.Context, id string) context.Context {
+	logger := log.Ctx(ctx).With().Str(LogField, id).Logger()
+	ctx = logger.WithContext(ctx)
+
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the correlation ID carried by ctx, if any.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Restore returns a copy of ctx carrying the id received from another service, or ctx
+// itself when id is missing or invalid.
+func Restore(ctx context.Context, id string) context.Context {
+	if !Valid(id) {
+		return ctx
+	}
+
+	return ContextWithID(ctx, id)
+}
+
+// Valid reports whether id can be used as a correlation ID: 1 to 128 letters, digits
+// and the characters "-._~:/+=@", so that it cannot forge log lines or headers.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+
+	for _, c := range []byte(id) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '.', c == '_', c == '~', c == ':', c == '/', c == '+', c == '=', c == '@':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewID generates a random correlation ID.
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// Resolve returns id when valid, or a new ID to use in place of a missing or invalid one.
+func Resolve(id string) string {
+	if Valid(id) {
+		return id
+	}
+
+	return NewID()
+}
+
+// Middleware correlates every request with the ID of its X-Request-ID header, or a
+// generated one when missing or invalid. The ID is echoed in the response header, and
+// added to the log lines written with the request context.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := Resolve(r.Header.Get(Header))
+
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(ContextWithID(r.Context(), id)))
+	})
+}
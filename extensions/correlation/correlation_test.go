@@ -0,0 +1,76 @@
+package correlation_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/extensions/correlation"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		expectedID string
+	}{
+		{name: "Accepted", header: "build-42/step:3", expectedID: "build-42/step:3"},
+		{name: "Generated"},
+		{name: "Forged Log Line", header: "abc\n{\"level\":\"error\"}"},
+		{name: "Too Long", header: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := zerolog.New(&logs)
+
+			var ctxID string
+			handler := correlation.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = correlation.FromContext(r.Context())
+				log.Ctx(r.Context()).Info().Msg("handled")
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set(correlation.Header, tt.header)
+			}
+			r = r.WithContext(logger.WithContext(r.Context()))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			id := w.Header().Get(correlation.Header)
+			if tt.expectedID != "" {
+				assert.Equal(t, tt.expectedID, id)
+			} else {
+				assert.Len(t, id, 32)
+				assert.True(t, correlation.Valid(id))
+			}
+			assert.Equal(t, id, ctxID)
+
+			var line map[string]any
+			require.NoError(t, json.Unmarshal(logs.Bytes(), &line))
+			assert.Equal(t, id, line[correlation.LogField])
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	ctx := correlation.Restore(context.Background(), "req-1")
+	assert.Equal(t, "req-1", correlation.FromContext(ctx))
+
+	ctx = correlation.Restore(context.Background(), "")
+	assert.Empty(t, correlation.FromContext(ctx))
+
+	ctx = correlation.Restore(context.Background(), "req 1")
+	assert.Empty(t, correlation.FromContext(ctx))
+}
@@ -0,0 +1,82 @@
+package filex
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// Watcher detects changes of a set of files by comparing their size and modification
+// time. Files are stat'ed lazily and at most once per interval, so Changed can be
+// called on hot paths. Symbolic links are followed, which makes it suitable for
+// secrets mounted by orchestrators that swap a link to publish a new version.
+type Watcher struct {
+	paths    []string
+	interval time.Duration
+
+	mu        sync.Mutex
+	lastCheck time.Time
+	states    []fileState
+}
+
+// NewWatcher creates a Watcher for paths, recording their current state.
+// Empty paths are ignored.
+func NewWatcher(interval time.Duration, paths ...string) *Watcher {
+	w := &Watcher{interval: interval}
+	for _, path := range paths {
+		if path != "" {
+			w.paths = append(w.paths, path)
+		}
+	}
+
+	w.lastCheck = time.Now()
+	w.states = w.stat()
+
+	return w
+}
+
+// Changed reports whether any watched file changed since the previous call that
+// reported a change, or since the Watcher was created.
+func (w *Watcher) Changed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(w.lastCheck) < w.interval {
+		return false
+	}
+	w.lastCheck = now
+
+	states := w.stat()
+	for i := range states {
+		if states[i] != w.states[i] {
+			w.states = states
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *Watcher) stat() []fileState {
+	states := make([]fileState, len(w.paths))
+	for i, path := range w.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		states[i] = fileState{
+			exists:  true,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		}
+	}
+
+	return states
+}
@@ -0,0 +1,54 @@
+package grpcx
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/werbersondev/token-generator-test/extensions/correlation"
+)
+
+// UnaryCorrelationInterceptor is the gRPC counterpart of correlation.Middleware, taking
+// the correlation ID of the calls from their x-request-id metadata and echoing it in
+// their header.
+func UnaryCorrelationInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	id := incomingCorrelationID(ctx)
+	if err := grpc.SetHeader(ctx, metadata.Pairs(correlation.Header, id)); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("Failed to set the correlation ID header")
+	}
+
+	return handler(correlation.ContextWithID(ctx, id), req)
+}
+
+// StreamCorrelationInterceptor is the streaming counterpart of
+// UnaryCorrelationInterceptor.
+func StreamCorrelationInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id := incomingCorrelationID(ss.Context())
+	if err := ss.SetHeader(metadata.Pairs(correlation.Header, id)); err != nil {
+		log.Ctx(ss.Context()).Warn().Err(err).Msg("Failed to set the correlation ID header")
+	}
+
+	return handler(srv, &serverStream{ServerStream: ss, ctx: correlation.ContextWithID(ss.Context(), id)})
+}
+
+func incomingCorrelationID(ctx context.Context) string {
+	var id string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(correlation.Header); len(values) > 0 {
+		id = values[0]
+	}
+
+	return correlation.Resolve(id)
+}
+
+// serverStream replaces the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,98 @@
+package grpcx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server is a gRPC server exposing the standard health checking and reflection
+// services, whose lifecycle is managed by httpx.Run along with the HTTP server.
+type Server struct {
+	Addr   string
+	Server *grpc.Server
+	Health *health.Server
+}
+
+// NewServer creates a gRPC server listening on addr. Its services are registered on
+// Server before running it, and reported as serving by the health service until the
+// server drains. Calls are correlated before running the interceptors of opts, and
+// traced, except the health checks.
+func NewServer(addr string, opts ...grpc.ServerOption) *Server {
+	opts = append([]grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))),
+		grpc.ChainUnaryInterceptor(UnaryCorrelationInterceptor),
+		grpc.ChainStreamInterceptor(StreamCorrelationInterceptor),
+	}, opts...)
+
+	server := grpc.NewServer(opts...)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+
+	return &Server{
+		Addr:   addr,
+		Server: server,
+		Health: healthServer,
+	}
+}
+
+// ListenAndServe listens on the address of the server and serves the calls until the
+// server is shut down, returning http.ErrServerClosed then, like an HTTP server.
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(listener)
+}
+
+// Serve serves the calls accepted by listener until the server is shut down.
+func (s *Server) Serve(listener net.Listener) error {
+	for service := range s.Server.GetServiceInfo() {
+		s.Health.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+
+	err := s.Server.Serve(listener)
+	if err == nil || errors.Is(err, grpc.ErrServerStopped) {
+		return http.ErrServerClosed
+	}
+
+	return err
+}
+
+// Drain reports every service as not serving, so that clients checking the health of
+// the server stop sending it calls before it shuts down.
+func (s *Server) Drain() {
+	s.Health.Shutdown()
+}
+
+// Shutdown drains the server and waits for the pending calls to complete, cancelling
+// those still running when ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.Drain()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.Server.Stop()
+		<-stopped
+		return ctx.Err()
+	}
+}
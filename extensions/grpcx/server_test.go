@@ -0,0 +1,121 @@
+package grpcx_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/werbersondev/token-generator-test/extensions/correlation"
+	"github.com/werbersondev/token-generator-test/extensions/grpcx"
+)
+
+// dial connects to server through an in-memory listener.
+func dial(t *testing.T, server *grpcx.Server) (*grpc.ClientConn, <-chan error) {
+	listener := bufconn.Listen(1 << 20)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, served
+}
+
+func TestServer_Lifecycle(t *testing.T) {
+	server := grpcx.NewServer("bufconn")
+	conn, served := dial(t, server)
+
+	health := healthpb.NewHealthClient(conn)
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return resp.GetStatus()
+	}
+
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(healthpb.Health_ServiceDesc.ServiceName))
+
+	reflection, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, reflection.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	resp, err := reflection.Recv()
+	require.NoError(t, err)
+	var services []string
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	assert.Contains(t, services, healthpb.Health_ServiceDesc.ServiceName)
+	require.NoError(t, reflection.CloseSend())
+
+	server.Drain()
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(""))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, server.Shutdown(ctx))
+
+	select {
+	case err := <-served:
+		assert.ErrorIs(t, err, http.ErrServerClosed)
+	case <-time.After(time.Second):
+		t.Fatal("server still serving after shutdown")
+	}
+}
+
+func TestServer_Correlation(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		expectedID string
+	}{
+		{name: "Accepted", id: "build-42", expectedID: "build-42"},
+		{name: "Generated"},
+		{name: "Invalid", id: "build 42"},
+	}
+
+	server := grpcx.NewServer("bufconn")
+	t.Cleanup(server.Server.Stop)
+	conn, _ := dial(t, server)
+	health := healthpb.NewHealthClient(conn)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.id != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", tt.id)
+			}
+
+			var header metadata.MD
+			_, err := health.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+			require.NoError(t, err)
+
+			ids := header.Get(correlation.Header)
+			require.Len(t, ids, 1)
+			if tt.expectedID != "" {
+				assert.Equal(t, tt.expectedID, ids[0])
+			} else {
+				assert.True(t, correlation.Valid(ids[0]))
+				assert.NotEqual(t, tt.id, ids[0])
+			}
+		})
+	}
+}
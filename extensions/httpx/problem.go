@@ -0,0 +1,85 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/extensions/correlation"
+)
+
+// Stable codes of the problems reported by the shared handlers.
+const (
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeInvalidBody          = "invalid_body"
+	CodeValidationFailed     = "validation_failed"
+	CodeBodyTooLarge         = "body_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+)
+
+// ProblemContentType is the media type of problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details object. Problems are told apart by their Code,
+// a stable identifier clients can rely on, while Title and Detail are meant for humans.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// WriteProblem responds to r with the problem identified by code. The problem type is
+// left to about:blank, the status text being its title.
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: correlation.FromContext(r.Context()),
+	}
+	if recorded, ok := r.Context().Value(problemRecorderKey{}).(*string); ok {
+		*recorded = code
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("encoding problem details")
+	}
+}
+
+type problemRecorderKey struct{}
+
+// RecordProblem returns a copy of ctx in which WriteProblem records the code of the
+// problem it writes, along with a function returning that code, empty while no problem
+// was written. It lets middlewares tell why the requests they pass on were rejected.
+func RecordProblem(ctx context.Context) (context.Context, func() string) {
+	var code string
+	ctx = context.WithValue(ctx, problemRecorderKey{}, &code)
+
+	return ctx, func() string { return code }
+}
+
+// NotFoundHandler reports unknown routes as problems.
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, http.StatusNotFound, CodeNotFound, "no route matches "+r.URL.Path)
+}
+
+// MethodNotAllowedHandler reports routes called with an unsupported method as
+// problems.
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+}
@@ -0,0 +1,171 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Readiness states.
+const (
+	ReadinessReady        = "ready"
+	ReadinessNotReady     = "not_ready"
+	ReadinessShuttingDown = "shutting_down"
+)
+
+// Check states.
+const (
+	CheckOK     = "ok"
+	CheckFailed = "failed"
+)
+
+// Reasons of failed checks. The readiness is served without authentication, so the
+// report only tells why a check failed in these terms, the error being logged.
+const (
+	CheckErrorUnavailable = "unavailable"
+	CheckErrorTimeout     = "timeout"
+)
+
+// Check verifies a dependency the server needs to serve requests.
+type Check struct {
+	Name string
+	// Timeout bounds the check, the default timeout of the readiness when zero.
+	Timeout time.Duration
+	Check   func(ctx context.Context) error
+}
+
+// ReadinessReport is the outcome of the checks of a Readiness.
+type ReadinessReport struct {
+	Status string        `json:"status"`
+	Checks []CheckReport `json:"checks"`
+}
+
+type CheckReport struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Readiness tells whether the server is ready to serve requests, from the checks of its
+// dependencies. Reports are cached, so that frequent probes do not load the
+// dependencies, and the server is no longer ready once it shuts down.
+type Readiness struct {
+	checks   []Check
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	shuttingDown atomic.Bool
+
+	mu        sync.Mutex
+	report    ReadinessReport
+	checkedAt time.Time
+}
+
+// NewReadiness creates a readiness running checks with timeout unless they have their
+// own, and caching their report for cacheTTL.
+func NewReadiness(timeout, cacheTTL time.Duration, checks ...Check) *Readiness {
+	return &Readiness{
+		checks:   checks,
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Shutdown marks the server as shutting down, failing the readiness from now on.
+func (r *Readiness) Shutdown() {
+	r.shuttingDown.Store(true)
+}
+
+// Report runs the checks concurrently, unless a report younger than the cache TTL is
+// available.
+func (r *Readiness) Report(ctx context.Context) ReadinessReport {
+	if r.shuttingDown.Load() {
+		return ReadinessReport{Status: ReadinessShuttingDown, Checks: []CheckReport{}}
+	}
+
+	// Concurrent probes wait for the report being computed rather than running the
+	// checks again.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.checkedAt.IsZero() && time.Since(r.checkedAt) < r.cacheTTL {
+		return r.report
+	}
+
+	report := ReadinessReport{Status: ReadinessReady, Checks: make([]CheckReport, len(r.checks))}
+	var wg sync.WaitGroup
+	for i, check := range r.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = r.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Status != CheckOK {
+			report.Status = ReadinessNotReady
+		}
+	}
+
+	r.report = report
+	r.checkedAt = time.Now()
+
+	return report
+}
+
+func (r *Readiness) run(ctx context.Context, check Check) CheckReport {
+	timeout := check.Timeout
+	if timeout == 0 {
+		timeout = r.timeout
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	report := CheckReport{
+		Name:       check.Name,
+		Status:     CheckOK,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("check", check.Name).Msg("readiness check failed")
+		report.Status = CheckFailed
+		report.Error = CheckErrorUnavailable
+		if errors.Is(err, context.DeadlineExceeded) {
+			report.Error = CheckErrorTimeout
+		}
+	}
+
+	return report
+}
+
+// Handler reports the readiness as JSON, with 200 OK when ready and 503 Service
+// Unavailable otherwise.
+func (r *Readiness) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		report := r.Report(req.Context())
+
+		status := http.StatusOK
+		if report.Status != ReadinessReady {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Ctx(req.Context()).Error().Err(err).Msg("encoding readiness report")
+		}
+	}
+}
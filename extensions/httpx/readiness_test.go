@@ -0,0 +1,109 @@
+package httpx_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+func TestReadiness_Handler(t *testing.T) {
+	ok := httpx.Check{Name: "pubsub", Check: func(ctx context.Context) error { return nil }}
+	failing := httpx.Check{Name: "sonar", Check: func(ctx context.Context) error { return errors.New("sonar is STARTING") }}
+	slow := httpx.Check{Name: "state_store", Timeout: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	tests := []struct {
+		name           string
+		checks         []httpx.Check
+		shuttingDown   bool
+		expectedStatus int
+		expectedReport httpx.ReadinessReport
+	}{
+		{
+			name:           "Ready",
+			checks:         []httpx.Check{ok},
+			expectedStatus: http.StatusOK,
+			expectedReport: httpx.ReadinessReport{Status: httpx.ReadinessReady, Checks: []httpx.CheckReport{{Name: "pubsub", Status: httpx.CheckOK}}},
+		},
+		{
+			name:           "Failing Check",
+			checks:         []httpx.Check{ok, failing},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReport: httpx.ReadinessReport{Status: httpx.ReadinessNotReady, Checks: []httpx.CheckReport{
+				{Name: "pubsub", Status: httpx.CheckOK},
+				{Name: "sonar", Status: httpx.CheckFailed, Error: httpx.CheckErrorUnavailable},
+			}},
+		},
+		{
+			name:           "Timed Out Check",
+			checks:         []httpx.Check{slow},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReport: httpx.ReadinessReport{Status: httpx.ReadinessNotReady, Checks: []httpx.CheckReport{
+				{Name: "state_store", Status: httpx.CheckFailed, Error: httpx.CheckErrorTimeout},
+			}},
+		},
+		{
+			name:           "Shutting Down",
+			checks:         []httpx.Check{ok},
+			shuttingDown:   true,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReport: httpx.ReadinessReport{Status: httpx.ReadinessShuttingDown, Checks: []httpx.CheckReport{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readiness := httpx.NewReadiness(time.Second, 0, tt.checks...)
+			if tt.shuttingDown {
+				readiness.Shutdown()
+			}
+
+			rec := httptest.NewRecorder()
+			readiness.Handler()(rec, httptest.NewRequest(http.MethodGet, "/readiness", nil))
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var report httpx.ReadinessReport
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+			for i := range report.Checks {
+				report.Checks[i].DurationMS = 0
+			}
+			assert.Equal(t, tt.expectedReport, report)
+		})
+	}
+}
+
+func TestReadiness_Cache(t *testing.T) {
+	var calls atomic.Int32
+	readiness := httpx.NewReadiness(time.Second, 50*time.Millisecond, httpx.Check{
+		Name: "pubsub",
+		Check: func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		},
+	})
+
+	readiness.Report(context.Background())
+	readiness.Report(context.Background())
+	assert.Equal(t, int32(1), calls.Load(), "the report must be cached")
+
+	time.Sleep(60 * time.Millisecond)
+	readiness.Report(context.Background())
+	assert.Equal(t, int32(2), calls.Load(), "the cached report must expire")
+
+	readiness.Shutdown()
+	assert.Equal(t, httpx.ReadinessShuttingDown, readiness.Report(context.Background()).Status, "shutdown must not wait for the cache to expire")
+}
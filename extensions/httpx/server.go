@@ -2,36 +2,171 @@ package httpx
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
+const defaultShutdownTimeout = 5 * time.Second
+
+// Server is a server whose lifecycle Run manages along with the HTTP server, such as
+// a gRPC server. ListenAndServe returns http.ErrServerClosed once shut down.
+type Server interface {
+	ListenAndServe() error
+	Shutdown(ctx context.Context) error
+}
+
+// drainer is implemented by the servers reporting their own health, to fail it as soon
+// as shutdown begins.
+type drainer interface {
+	Drain()
+}
+
+type namedServer struct {
+	name   string
+	server Server
+}
+
+type runConfig struct {
+	readiness       *Readiness
+	drainDelay      time.Duration
+	servers         []namedServer
+	tls             *tls.Config
+	http2           bool
+	shutdownTimeout time.Duration
+}
+
+type RunOption func(*runConfig)
+
+// WithReadiness fails readiness as soon as shutdown begins, and keeps serving for
+// drainDelay before shutting down, giving load balancers time to stop routing requests
+// to the server.
+func WithReadiness(readiness *Readiness, drainDelay time.Duration) RunOption {
+	return func(c *runConfig) {
+		c.readiness = readiness
+		c.drainDelay = drainDelay
+	}
+}
+
+// WithServer runs server alongside the HTTP server. A failure of either stops both, and
+// both are drained and shut down together.
+func WithServer(name string, server Server) RunOption {
+	return func(c *runConfig) {
+		c.servers = append(c.servers, namedServer{name: name, server: server})
+	}
+}
+
+// WithTLS serves HTTPS with config, which must provide the server certificate.
+func WithTLS(config *tls.Config) RunOption {
+	return func(c *runConfig) {
+		c.tls = config
+	}
+}
+
+// WithHTTP2 serves HTTP/2 along with HTTP/1.1, negotiated through ALPN over TLS, or
+// as cleartext HTTP/2 (h2c) without it. HTTP/2 is disabled otherwise.
+func WithHTTP2() RunOption {
+	return func(c *runConfig) {
+		c.http2 = true
+	}
+}
+
+// WithShutdownTimeout sets how long pending calls are given to complete once the
+// servers shut down, 5 seconds by default.
+func WithShutdownTimeout(timeout time.Duration) RunOption {
+	return func(c *runConfig) {
+		c.shutdownTimeout = timeout
+	}
+}
+
+// tlsServer serves an HTTP server over TLS, with the certificate of its TLS config.
+type tlsServer struct {
+	*http.Server
+}
+
+func (s tlsServer) ListenAndServe() error {
+	return s.ListenAndServeTLS("", "")
+}
+
+// configure prepares server for the TLS and HTTP/2 options of cfg, and returns the
+// server to run.
+func (cfg runConfig) configure(server *http.Server) (Server, error) {
+	if cfg.tls == nil {
+		if cfg.http2 {
+			server.Handler = h2c.NewHandler(server.Handler, &http2.Server{})
+		}
+		return server, nil
+	}
+
+	server.TLSConfig = cfg.tls.Clone()
+	if cfg.http2 {
+		if err := http2.ConfigureServer(server, nil); err != nil {
+			return nil, err
+		}
+	} else {
+		// A non-nil empty map disables the HTTP/2 support of net/http.
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+
+	return tlsServer{Server: server}, nil
+}
+
 // Run starts the HTTP server and listens for shutdown signals.
 // It gracefully shuts down the server when an interrupt or terminate signal is received.
 //
 // Parameters:
 //   - ctx: The context for managing the lifecycle of the server and logging.
 //   - server: The HTTP server instance to be started and managed.
-func Run(ctx context.Context, server *http.Server) {
+//   - opts: Options such as the readiness to fail on shutdown, TLS, or other servers to run.
+func Run(ctx context.Context, server *http.Server, opts ...RunOption) {
+	cfg := runConfig{shutdownTimeout: defaultShutdownTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	httpServer, err := cfg.configure(server)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("configuring server")
+		return
+	}
+
 	stopped := make(chan struct{})
+	var stopOnce sync.Once
+	serve := func(name string, server Server) {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Ctx(ctx).Error().Err(err).Str("server", name).Msg("server failed")
+			stopOnce.Do(func() { close(stopped) })
+		}
+	}
+
 	go func() {
 		log.Ctx(ctx).Info().Str("address", server.Addr).
 			Float64("read_timeout_sec", server.ReadTimeout.Seconds()).
 			Float64("write_timeout_sec", server.WriteTimeout.Seconds()).
+			Bool("tls", cfg.tls != nil).
+			Bool("http2", cfg.http2).
 			Msg("server started")
 
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Ctx(ctx).Error().Err(err).Msg("http server failed")
-			close(stopped)
-		}
+		serve("http", httpServer)
 	}()
 
+	servers := append([]namedServer{{name: "http", server: httpServer}}, cfg.servers...)
+	for _, s := range cfg.servers {
+		go func() {
+			log.Ctx(ctx).Info().Str("server", s.name).Msg("server started")
+			serve(s.name, s.server)
+		}()
+	}
+
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 
@@ -44,11 +179,36 @@ func Run(ctx context.Context, server *http.Server) {
 
 	signal.Stop(signals)
 
-	log.Ctx(ctx).Info().Msg("shutting down HTTP server")
-	c, cancel := context.WithDeadline(ctx, time.Now().Add(time.Second*5))
+	if cfg.readiness != nil {
+		cfg.readiness.Shutdown()
+	}
+	for _, s := range servers {
+		if d, ok := s.server.(drainer); ok {
+			d.Drain()
+		}
+	}
+	if cfg.readiness != nil && cfg.drainDelay > 0 {
+		log.Ctx(ctx).Info().Dur("drain_delay", cfg.drainDelay).Msg("draining traffic")
+		select {
+		case <-time.After(cfg.drainDelay):
+		case <-stopped:
+		}
+	}
+
+	c, cancel := context.WithTimeout(ctx, cfg.shutdownTimeout)
 	defer cancel()
 
-	if err := server.Shutdown(c); err != nil {
-		log.Ctx(ctx).Error().Err(err).Msg("error shutting down HTTP server")
+	var wg sync.WaitGroup
+	for _, s := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			log.Ctx(ctx).Info().Str("server", s.name).Msg("shutting down server")
+			if err := s.server.Shutdown(c); err != nil {
+				log.Ctx(ctx).Error().Err(err).Str("server", s.name).Msg("error shutting down server")
+			}
+		}()
 	}
+	wg.Wait()
 }
@@ -0,0 +1,188 @@
+package httpx_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+type fakeServer struct {
+	serveErr error
+	drained  atomic.Bool
+	shutdown atomic.Bool
+}
+
+func (s *fakeServer) ListenAndServe() error { return s.serveErr }
+
+func (s *fakeServer) Drain() { s.drained.Store(true) }
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	s.shutdown.Store(true)
+	return nil
+}
+
+func TestRun_StopsServersTogether(t *testing.T) {
+	readiness := httpx.NewReadiness(time.Second, 0)
+	other := &fakeServer{serveErr: errors.New("address already in use")}
+	server := &http.Server{Addr: "127.0.0.1:0"}
+
+	done := make(chan struct{})
+	go func() {
+		httpx.Run(context.Background(), server, httpx.WithReadiness(readiness, time.Minute), httpx.WithServer("grpc", other))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after a server failed")
+	}
+
+	assert.True(t, other.drained.Load())
+	assert.True(t, other.shutdown.Load())
+	require.ErrorIs(t, server.ListenAndServe(), http.ErrServerClosed)
+
+	report := readiness.Report(context.Background())
+	assert.Equal(t, httpx.ReadinessShuttingDown, report.Status)
+}
+
+// stoppingServer fails once stop is closed, which stops Run.
+type stoppingServer struct {
+	stop chan struct{}
+}
+
+func (s *stoppingServer) ListenAndServe() error {
+	<-s.stop
+	return errors.New("stopped")
+}
+
+func (s *stoppingServer) Shutdown(ctx context.Context) error { return nil }
+
+func TestRun_Protocols(t *testing.T) {
+	cert := selfSignedCertificate(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+
+	tests := []struct {
+		name             string
+		opts             []httpx.RunOption
+		transport        http.RoundTripper
+		scheme           string
+		expectedProtocol string
+	}{
+		{
+			name:             "HTTP/1.1",
+			transport:        &http.Transport{},
+			scheme:           "http",
+			expectedProtocol: "HTTP/1.1",
+		},
+		{
+			name:             "TLS",
+			opts:             []httpx.RunOption{httpx.WithTLS(&tls.Config{Certificates: []tls.Certificate{cert}})},
+			transport:        &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}, ForceAttemptHTTP2: true},
+			scheme:           "https",
+			expectedProtocol: "HTTP/1.1",
+		},
+		{
+			name:             "TLS With HTTP/2",
+			opts:             []httpx.RunOption{httpx.WithTLS(&tls.Config{Certificates: []tls.Certificate{cert}}), httpx.WithHTTP2()},
+			transport:        &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}, ForceAttemptHTTP2: true},
+			scheme:           "https",
+			expectedProtocol: "HTTP/2.0",
+		},
+		{
+			name: "Cleartext HTTP/2",
+			opts: []httpx.RunOption{httpx.WithHTTP2()},
+			transport: &http2.Transport{
+				AllowHTTP: true,
+				DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, network, addr)
+				},
+			},
+			scheme:           "http",
+			expectedProtocol: "HTTP/2.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := freeAddress(t)
+			server := &http.Server{Addr: addr, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(r.Proto))
+			})}
+			other := &stoppingServer{stop: make(chan struct{})}
+
+			done := make(chan struct{})
+			go func() {
+				opts := append([]httpx.RunOption{httpx.WithServer("other", other), httpx.WithShutdownTimeout(time.Second)}, tt.opts...)
+				httpx.Run(context.Background(), server, opts...)
+				close(done)
+			}()
+			defer func() {
+				close(other.stop)
+				<-done
+			}()
+
+			client := &http.Client{Transport: tt.transport, Timeout: time.Second}
+			var resp *http.Response
+			require.Eventually(t, func() bool {
+				var err error
+				resp, err = client.Get(tt.scheme + "://" + addr)
+				return err == nil
+			}, 5*time.Second, 10*time.Millisecond)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedProtocol, resp.Proto)
+		})
+	}
+}
+
+// freeAddress returns a loopback address with a port nothing listens on.
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
@@ -0,0 +1,230 @@
+package metricsx
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tokengen"
+
+// Outcomes of the token requests and of the tokens.
+const (
+	OutcomeAccepted = "accepted"
+	OutcomeRejected = "rejected"
+	OutcomeIssued   = "issued"
+	OutcomeFailed   = "failed"
+)
+
+type Option func(*Metrics)
+
+// WithProjectLabel labels the token metrics by project. It is off by default, as every
+// project adds its own series.
+func WithProjectLabel() Option {
+	return func(m *Metrics) {
+		m.projectLabel = true
+	}
+}
+
+// Metrics holds the Prometheus metrics of a service, in a registry of its own. Its
+// methods do nothing on a nil Metrics, so that the components can be used without.
+//
+// Labels only take values from bounded sets, such as routes, problem codes, topics or
+// failure reasons, so that the number of series stays bounded.
+type Metrics struct {
+	registry     *prometheus.Registry
+	projectLabel bool
+
+	tokenRequests   *prometheus.CounterVec
+	publishDuration *prometheus.HistogramVec
+	queueAge        *prometheus.HistogramVec
+	inFlight        *prometheus.GaugeVec
+	sonarDuration   *prometheus.HistogramVec
+	sonarRetries    *prometheus.CounterVec
+	tokens          *prometheus.CounterVec
+}
+
+func New(opts ...Option) *Metrics {
+	m := &Metrics{registry: prometheus.NewRegistry()}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	tokenLabels := []string{"outcome", "token_type", "reason"}
+	if m.projectLabel {
+		tokenLabels = append(tokenLabels, "project")
+	}
+
+	m.tokenRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_requests_total",
+		Help:      "Token requests received, by route, outcome and problem code when rejected.",
+	}, []string{"route", "outcome", "reason"})
+	m.publishDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "publish_duration_seconds",
+		Help:      "Time taken to publish messages to Pub/Sub, by topic and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"topic", "outcome"})
+	m.queueAge = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "consumer_queue_age_seconds",
+		Help:      "Time the messages spent in the queue, from their publication to their receipt.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900},
+	}, []string{"subscription"})
+	m.inFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "consumer_messages_in_flight",
+		Help:      "Messages being processed by the consumer.",
+	}, []string{"subscription"})
+	m.sonarDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sonar_request_duration_seconds",
+		Help:      "Duration of the attempts of the calls to the Sonar Web API, by endpoint and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "status"})
+	m.sonarRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sonar_retries_total",
+		Help:      "Calls to the Sonar Web API retried, by endpoint.",
+	}, []string{"endpoint"})
+	m.tokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_total",
+		Help:      "Tokens issued or failed to be issued, by token type and failure reason.",
+	}, tokenLabels)
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.tokenRequests,
+		m.publishDuration,
+		m.queueAge,
+		m.inFlight,
+		m.sonarDuration,
+		m.sonarRetries,
+		m.tokens,
+	)
+
+	return m
+}
+
+// Registry returns the registry of the metrics, to gather them.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// CountTokenRequest counts a token request received on route, rejected with the problem
+// code reason, or accepted when reason is empty.
+func (m *Metrics) CountTokenRequest(route, reason string) {
+	if m == nil {
+		return
+	}
+
+	outcome := OutcomeAccepted
+	if reason != "" {
+		outcome = OutcomeRejected
+	}
+	m.tokenRequests.WithLabelValues(route, outcome, reason).Inc()
+}
+
+// ObservePublish records the time taken to publish to topic since start, failed when err
+// is not nil.
+func (m *Metrics) ObservePublish(topic string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+
+	outcome := "published"
+	if err != nil {
+		outcome = OutcomeFailed
+	}
+	m.publishDuration.WithLabelValues(topic, outcome).Observe(time.Since(start).Seconds())
+}
+
+// ObserveMessages wraps the handler of the messages received from subscription, to
+// record their age at receipt and count those being processed.
+func (m *Metrics) ObserveMessages(subscription string, handler func(context.Context, *pubsub.Message)) func(context.Context, *pubsub.Message) {
+	if m == nil {
+		return handler
+	}
+
+	queueAge := m.queueAge.WithLabelValues(subscription)
+	inFlight := m.inFlight.WithLabelValues(subscription)
+	return func(ctx context.Context, msg *pubsub.Message) {
+		if !msg.PublishTime.IsZero() {
+			queueAge.Observe(time.Since(msg.PublishTime).Seconds())
+		}
+
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		handler(ctx, msg)
+	}
+}
+
+// Transport records the duration of the requests sent to the Sonar Web API through
+// base, by path and status code. The paths of the Web API embed no identifier.
+func (m *Metrics) Transport(base http.RoundTripper) http.RoundTripper {
+	if m == nil {
+		return base
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := base.RoundTrip(req)
+
+		status := "error"
+		if err == nil {
+			status = strconv.Itoa(resp.StatusCode)
+		}
+		m.sonarDuration.WithLabelValues(req.URL.Path, status).Observe(time.Since(start).Seconds())
+
+		return resp, err
+	})
+}
+
+// CountSonarRetry counts a retry of a call to the endpoint of the Sonar Web API.
+func (m *Metrics) CountSonarRetry(endpoint string) {
+	if m == nil {
+		return
+	}
+
+	m.sonarRetries.WithLabelValues(endpoint).Inc()
+}
+
+// CountToken counts a token of tokenType issued for projectID, or failed to be issued
+// with reason when it is not empty. The project is only recorded WithProjectLabel.
+func (m *Metrics) CountToken(projectID, tokenType, reason string) {
+	if m == nil {
+		return
+	}
+
+	outcome := OutcomeIssued
+	if reason != "" {
+		outcome = OutcomeFailed
+	}
+
+	labels := []string{outcome, tokenType, reason}
+	if m.projectLabel {
+		labels = append(labels, projectID)
+	}
+	m.tokens.WithLabelValues(labels...).Inc()
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
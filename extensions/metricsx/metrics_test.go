@@ -0,0 +1,140 @@
+package metricsx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
+)
+
+func TestMetrics_Nil(t *testing.T) {
+	var metrics *metricsx.Metrics
+
+	assert.NotPanics(t, func() {
+		metrics.CountTokenRequest("/v1/generate-token", "")
+		metrics.ObservePublish("topic", time.Now(), nil)
+		metrics.CountSonarRetry("/api/user_tokens/generate")
+		metrics.CountToken("app", "project_analysis", "")
+	})
+	assert.Equal(t, http.DefaultTransport, metrics.Transport(http.DefaultTransport))
+
+	handled := false
+	metrics.ObserveMessages("subscription", func(context.Context, *pubsub.Message) { handled = true })(context.Background(), &pubsub.Message{})
+	assert.True(t, handled)
+}
+
+func TestMetrics_CountToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []metricsx.Option
+		expected string
+	}{
+		{
+			name: "Without Project",
+			expected: `
+# HELP tokengen_tokens_total Tokens issued or failed to be issued, by token type and failure reason.
+# TYPE tokengen_tokens_total counter
+tokengen_tokens_total{outcome="failed",reason="missing_permission",token_type="project_analysis"} 1
+tokengen_tokens_total{outcome="issued",reason="",token_type="project_analysis"} 2
+`,
+		},
+		{
+			name: "With Project",
+			opts: []metricsx.Option{metricsx.WithProjectLabel()},
+			expected: `
+# HELP tokengen_tokens_total Tokens issued or failed to be issued, by token type and failure reason.
+# TYPE tokengen_tokens_total counter
+tokengen_tokens_total{outcome="failed",project="app",reason="missing_permission",token_type="project_analysis"} 1
+tokengen_tokens_total{outcome="issued",project="app",reason="",token_type="project_analysis"} 1
+tokengen_tokens_total{outcome="issued",project="other",reason="",token_type="project_analysis"} 1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := metricsx.New(tt.opts...)
+
+			metrics.CountToken("app", "project_analysis", "")
+			metrics.CountToken("other", "project_analysis", "")
+			metrics.CountToken("app", "project_analysis", "missing_permission")
+
+			assert.NoError(t, testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(tt.expected), "tokengen_tokens_total"))
+		})
+	}
+}
+
+func TestMetrics_Transport(t *testing.T) {
+	metrics := metricsx.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: metrics.Transport(http.DefaultTransport)}
+	resp, err := client.Get(server.URL + "/api/system/status")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	_, err = client.Get("http://127.0.0.1:0/api/system/status")
+	require.Error(t, err)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.Registry(), "tokengen_sonar_request_duration_seconds"))
+	expected := []string{
+		`tokengen_sonar_request_duration_seconds_count{endpoint="/api/system/status",status="503"} 1`,
+		`tokengen_sonar_request_duration_seconds_count{endpoint="/api/system/status",status="error"} 1`,
+	}
+	body := scrape(t, metrics)
+	for _, line := range expected {
+		assert.Contains(t, body, line)
+	}
+}
+
+func TestMetrics_ObserveMessages(t *testing.T) {
+	metrics := metricsx.New()
+
+	var processing string
+	handler := metrics.ObserveMessages("subscription", func(context.Context, *pubsub.Message) {
+		processing = scrape(t, metrics)
+	})
+	handler(context.Background(), &pubsub.Message{PublishTime: time.Now().Add(-2 * time.Second)})
+
+	assert.Contains(t, processing, `tokengen_consumer_messages_in_flight{subscription="subscription"} 1`)
+	body := scrape(t, metrics)
+	assert.Contains(t, body, `tokengen_consumer_messages_in_flight{subscription="subscription"} 0`)
+	assert.Contains(t, body, `tokengen_consumer_queue_age_seconds_bucket{subscription="subscription",le="1"} 0`)
+	assert.Contains(t, body, `tokengen_consumer_queue_age_seconds_bucket{subscription="subscription",le="5"} 1`)
+}
+
+func TestMetrics_ObservePublish(t *testing.T) {
+	metrics := metricsx.New()
+
+	metrics.ObservePublish("topic", time.Now(), nil)
+	metrics.ObservePublish("topic", time.Now(), errors.New("unavailable"))
+	metrics.ObservePublish("topic", time.Now(), nil)
+
+	body := scrape(t, metrics)
+	assert.Contains(t, body, `tokengen_publish_duration_seconds_count{outcome="published",topic="topic"} 2`)
+	assert.Contains(t, body, `tokengen_publish_duration_seconds_count{outcome="failed",topic="topic"} 1`)
+}
+
+// scrape returns the metrics as served to Prometheus.
+func scrape(t *testing.T, metrics *metricsx.Metrics) string {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	return recorder.Body.String()
+}
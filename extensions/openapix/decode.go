@@ -0,0 +1,101 @@
+package openapix
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrDuplicateKey is returned by Decode when an object repeats a key.
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// Decode strictly decodes a single JSON value: numbers are kept as json.Number, and
+// duplicate object keys or trailing data are rejected, so that the value validated is
+// the value the handler decodes.
+func Decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	value, err := decodeValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+
+	return value, nil
+}
+
+func decodeValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	switch delim := token.(type) {
+	case json.Delim:
+		switch delim {
+		case '{':
+			return decodeObject(decoder)
+		case '[':
+			return decodeArray(decoder)
+		default:
+			return nil, fmt.Errorf("unexpected %q", delim)
+		}
+	default:
+		return token, nil
+	}
+}
+
+func decodeObject(decoder *json.Decoder) (map[string]any, error) {
+	object := make(map[string]any)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected object key %v", token)
+		}
+		if _, ok := object[key]; ok {
+			return nil, fmt.Errorf("%w %q", ErrDuplicateKey, key)
+		}
+
+		value, err := decodeValue(decoder)
+		if err != nil {
+			return nil, err
+		}
+		object[key] = value
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+func decodeArray(decoder *json.Decoder) ([]any, error) {
+	array := make([]any, 0)
+	for decoder.More() {
+		value, err := decodeValue(decoder)
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	return array, nil
+}
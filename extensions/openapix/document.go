@@ -0,0 +1,275 @@
+// Package openapix validates HTTP requests and responses against an OpenAPI 3
+// document. It supports the subset of OpenAPI and JSON Schema the services use: local
+// references, objects, arrays, scalars, enumerations, patterns and length, item and
+// range bounds.
+package openapix
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Document is a parsed OpenAPI 3 document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	routes []route
+}
+
+type Components struct {
+	Schemas   map[string]*Schema   `json:"schemas"`
+	Responses map[string]*Response `json:"responses"`
+}
+
+type PathItem struct {
+	Get    *Operation `json:"get"`
+	Put    *Operation `json:"put"`
+	Post   *Operation `json:"post"`
+	Delete *Operation `json:"delete"`
+	Patch  *Operation `json:"patch"`
+}
+
+// Operations returns the operations of the path item by method.
+func (p *PathItem) Operations() map[string]*Operation {
+	operations := make(map[string]*Operation)
+	for method, operation := range map[string]*Operation{
+		http.MethodGet:    p.Get,
+		http.MethodPut:    p.Put,
+		http.MethodPost:   p.Post,
+		http.MethodDelete: p.Delete,
+		http.MethodPatch:  p.Patch,
+	} {
+		if operation != nil {
+			operations[method] = operation
+		}
+	}
+
+	return operations
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Deprecated  bool                 `json:"deprecated"`
+	Parameters  []Parameter          `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Load parses an OpenAPI document and checks its references resolve.
+func Load(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decoding openapi document: %w", err)
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %q", doc.OpenAPI)
+	}
+
+	for path, item := range doc.Paths {
+		for method, operation := range item.Operations() {
+			for status, response := range operation.Responses {
+				if _, err := doc.response(response); err != nil {
+					return nil, fmt.Errorf("%s %s response %s: %w", method, path, status, err)
+				}
+			}
+		}
+		doc.routes = append(doc.routes, newRoute(path, item))
+	}
+
+	for name, schema := range doc.Components.Schemas {
+		if err := schema.compile(&doc); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	for path, item := range doc.Paths {
+		for method, operation := range item.Operations() {
+			if err := operation.compile(&doc); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+		}
+	}
+
+	// Literal segments take precedence over parameters, as in the routers.
+	sort.Slice(doc.routes, func(i, j int) bool {
+		return doc.routes[i].literals > doc.routes[j].literals
+	})
+
+	return &doc, nil
+}
+
+// MustLoad is like Load but panics on error, for documents embedded in the binary.
+func MustLoad(data []byte) *Document {
+	doc, err := Load(data)
+	if err != nil {
+		panic(err)
+	}
+
+	return doc
+}
+
+// Find returns the operation matching the method and path of a request, along with
+// its path parameters. It returns a nil operation when none matches.
+func (d *Document) Find(method, path string) (*Operation, map[string]string) {
+	for _, route := range d.routes {
+		params, ok := route.match(path)
+		if !ok {
+			continue
+		}
+
+		return route.item.Operations()[method], params
+	}
+
+	return nil, nil
+}
+
+// Operations lists the "METHOD path" of every operation of the document.
+func (d *Document) Operations() []string {
+	var operations []string
+	for path, item := range d.Paths {
+		for method := range item.Operations() {
+			operations = append(operations, method+" "+path)
+		}
+	}
+	sort.Strings(operations)
+
+	return operations
+}
+
+func (d *Document) response(response *Response) (*Response, error) {
+	if response.Ref == "" {
+		return response, nil
+	}
+
+	name, ok := strings.CutPrefix(response.Ref, "#/components/responses/")
+	if !ok {
+		return nil, fmt.Errorf("unsupported reference %q", response.Ref)
+	}
+
+	resolved, ok := d.Components.Responses[name]
+	if !ok {
+		return nil, fmt.Errorf("unresolved reference %q", response.Ref)
+	}
+
+	return resolved, nil
+}
+
+func (o *Operation) compile(doc *Document) error {
+	for _, param := range o.Parameters {
+		if param.Schema != nil {
+			if err := param.Schema.compile(doc); err != nil {
+				return fmt.Errorf("parameter %s: %w", param.Name, err)
+			}
+		}
+	}
+
+	if o.RequestBody != nil {
+		for contentType, media := range o.RequestBody.Content {
+			if media.Schema != nil {
+				if err := media.Schema.compile(doc); err != nil {
+					return fmt.Errorf("request body %s: %w", contentType, err)
+				}
+			}
+		}
+	}
+
+	for status, response := range o.Responses {
+		response, _ = doc.response(response)
+		for contentType, media := range response.Content {
+			if media.Schema != nil {
+				if err := media.Schema.compile(doc); err != nil {
+					return fmt.Errorf("response %s %s: %w", status, contentType, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// responseFor returns the response declared for status: the exact status, then its
+// class such as 4XX, then the default response.
+func (o *Operation) responseFor(doc *Document, status int) (*Response, bool) {
+	code := fmt.Sprint(status)
+	for _, key := range []string{code, code[:1] + "XX", "default"} {
+		if response, ok := o.Responses[key]; ok {
+			response, err := doc.response(response)
+			return response, err == nil
+		}
+	}
+
+	return nil, false
+}
+
+type route struct {
+	segments []string
+	literals int
+	item     *PathItem
+}
+
+func newRoute(path string, item *PathItem) route {
+	r := route{
+		segments: strings.Split(strings.Trim(path, "/"), "/"),
+		item:     item,
+	}
+	for _, segment := range r.segments {
+		if !isParam(segment) {
+			r.literals++
+		}
+	}
+
+	return r
+}
+
+func (r route) match(path string) (map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, segment := range r.segments {
+		if isParam(segment) {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(segment, "{}")] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
@@ -0,0 +1,290 @@
+package openapix
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+// DefaultMaxBodyBytes is the largest request body accepted unless configured otherwise.
+const DefaultMaxBodyBytes = 64 << 10
+
+// maxRecordedResponseBytes caps the response bodies buffered for validation, larger
+// bodies are not validated.
+const maxRecordedResponseBytes = 1 << 20
+
+const jsonContentType = "application/json"
+
+type validator struct {
+	doc                 *Document
+	maxBodyBytes        int64
+	onResponseViolation func(r *http.Request, status int, err error)
+}
+
+type Option func(*validator)
+
+// WithMaxBodyBytes limits the size of the request bodies, DefaultMaxBodyBytes by
+// default.
+func WithMaxBodyBytes(n int64) Option {
+	return func(v *validator) {
+		v.maxBodyBytes = n
+	}
+}
+
+// WithResponseViolationHandler calls fn with the responses that do not match the
+// document, in addition to logging them.
+func WithResponseViolationHandler(fn func(r *http.Request, status int, err error)) Option {
+	return func(v *validator) {
+		v.onResponseViolation = fn
+	}
+}
+
+// Middleware validates the requests of the operations of doc, rejecting those that do
+// not match it with a problem, and checks the responses match it too. Responses are
+// validated after being sent, so that streaming ones are not held back: violations are
+// logged rather than rejected. Requests of routes unknown to doc are passed through.
+func Middleware(doc *Document, opts ...Option) func(http.Handler) http.Handler {
+	v := &validator{
+		doc:          doc,
+		maxBodyBytes: DefaultMaxBodyBytes,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			operation, params := v.doc.Find(r.Method, r.URL.Path)
+			if operation == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !v.validateRequest(w, r, operation, params) {
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+
+			if err := v.validateResponse(operation, recorder); err != nil {
+				log.Ctx(r.Context()).Warn().Err(err).
+					Str("method", r.Method).
+					Str("path", r.URL.Path).
+					Int("status", recorder.status).
+					Msg("response does not match the openapi document")
+				if v.onResponseViolation != nil {
+					v.onResponseViolation(r, recorder.status, err)
+				}
+			}
+		})
+	}
+}
+
+// validateRequest reports whether the request matches the operation, responding with
+// a problem when it does not.
+func (v *validator) validateRequest(w http.ResponseWriter, r *http.Request, operation *Operation, params map[string]string) bool {
+	query := r.URL.Query()
+	for _, param := range operation.Parameters {
+		var value string
+		var present bool
+		switch param.In {
+		case "path":
+			value, present = params[param.Name]
+		case "query":
+			present = query.Has(param.Name)
+			value = query.Get(param.Name)
+		default:
+			continue
+		}
+
+		if !present {
+			if param.Required {
+				httpx.WriteProblem(w, r, http.StatusUnprocessableEntity, httpx.CodeValidationFailed, fmt.Sprintf("missing required %s parameter %s", param.In, param.Name))
+				return false
+			}
+			continue
+		}
+
+		if param.Schema != nil {
+			if err := param.Schema.validate(param.Name, parameterValue(param.Schema, value)); err != nil {
+				httpx.WriteProblem(w, r, http.StatusUnprocessableEntity, httpx.CodeValidationFailed, "invalid "+param.In+" parameter "+err.Error())
+				return false
+			}
+		}
+	}
+
+	if operation.RequestBody == nil {
+		return true
+	}
+
+	// Clients omitting the content type are assumed to send JSON.
+	contentType := jsonContentType
+	if header := r.Header.Get("Content-Type"); header != "" {
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			httpx.WriteProblem(w, r, http.StatusUnsupportedMediaType, httpx.CodeUnsupportedMediaType, "invalid content type "+strconv.Quote(header))
+			return false
+		}
+		contentType = mediaType
+	}
+
+	media, ok := operation.RequestBody.Content[contentType]
+	if !ok {
+		httpx.WriteProblem(w, r, http.StatusUnsupportedMediaType, httpx.CodeUnsupportedMediaType, "the request body must be "+jsonContentType)
+		return false
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, v.maxBodyBytes))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		httpx.WriteProblem(w, r, http.StatusRequestEntityTooLarge, httpx.CodeBodyTooLarge, fmt.Sprintf("the request body must not exceed %d bytes", v.maxBodyBytes))
+		return false
+	}
+	if err != nil {
+		httpx.WriteProblem(w, r, http.StatusBadRequest, httpx.CodeInvalidBody, "failed to read the request body")
+		return false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 && !operation.RequestBody.Required {
+		return true
+	}
+
+	value, err := Decode(data)
+	if err != nil {
+		httpx.WriteProblem(w, r, http.StatusBadRequest, httpx.CodeInvalidBody, "the request body is not valid JSON: "+err.Error())
+		return false
+	}
+
+	if media.Schema != nil {
+		if err := media.Schema.Validate(value); err != nil {
+			httpx.WriteProblem(w, r, http.StatusUnprocessableEntity, httpx.CodeValidationFailed, err.Error())
+			return false
+		}
+	}
+
+	return true
+}
+
+// parameterValue converts a parameter to the type of its schema, leaving values that
+// cannot be converted as strings for the validation to reject.
+func parameterValue(schema *Schema, value string) any {
+	if schema.resolved != nil {
+		schema = schema.resolved
+	}
+
+	switch schema.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+
+	return value
+}
+
+func (v *validator) validateResponse(operation *Operation, recorder *responseRecorder) error {
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+
+	response, ok := operation.responseFor(v.doc, recorder.status)
+	if !ok {
+		return fmt.Errorf("undeclared status %d", recorder.status)
+	}
+
+	if recorder.size == 0 {
+		return nil
+	}
+
+	header := recorder.Header().Get("Content-Type")
+	contentType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q", header)
+	}
+
+	media, ok := response.Content[contentType]
+	if !ok {
+		return fmt.Errorf("undeclared content type %q for status %d", contentType, recorder.status)
+	}
+
+	if media.Schema == nil || !recorder.recording() {
+		return nil
+	}
+
+	value, err := Decode(recorder.body.Bytes())
+	if err != nil {
+		return fmt.Errorf("decoding response body: %w", err)
+	}
+
+	return media.Schema.Validate(value)
+}
+
+// responseRecorder keeps a copy of the JSON responses written through it.
+type responseRecorder struct {
+	http.ResponseWriter
+
+	status    int
+	size      int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	n, err := r.ResponseWriter.Write(p)
+	r.size += n
+	if r.recording() {
+		if r.body.Len()+n > maxRecordedResponseBytes {
+			r.truncated = true
+			r.body.Reset()
+		} else {
+			r.body.Write(p[:n])
+		}
+	}
+
+	return n, err
+}
+
+// recording tells whether the body is JSON and small enough to be validated.
+func (r *responseRecorder) recording() bool {
+	if r.truncated {
+		return false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header().Get("Content-Type"))
+	return mediaType == jsonContentType || mediaType == httpx.ProblemContentType
+}
+
+func (r *responseRecorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,244 @@
+package openapix_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+	"github.com/werbersondev/token-generator-test/extensions/openapix"
+)
+
+const testDocument = `{
+  "openapi": "3.0.3",
+  "components": {
+    "schemas": {
+      "Item": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "name": {"type": "string", "minLength": 1, "maxLength": 8, "pattern": "^[a-z]+$"},
+          "kind": {"type": "string", "enum": ["a", "b"]},
+          "count": {"type": "integer", "minimum": 1},
+          "at": {"type": "string", "format": "date-time"},
+          "tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+          "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+          "note": {"type": "string", "nullable": true}
+        },
+        "additionalProperties": false
+      }
+    },
+    "responses": {
+      "Problem": {"description": "Problem.", "content": {"application/problem+json": {"schema": {"type": "object"}}}}
+    }
+  },
+  "paths": {
+    "/items": {
+      "post": {
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}},
+        "responses": {
+          "201": {"description": "Created.", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}},
+          "4XX": {"$ref": "#/components/responses/Problem"}
+        }
+      }
+    },
+    "/items/{name}": {
+      "get": {
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string", "pattern": "^[a-z]+$"}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "maximum": 10}}
+        ],
+        "responses": {"204": {"description": "Found."}}
+      }
+    },
+    "/items/all": {
+      "get": {"responses": {"204": {"description": "Found."}}}
+    }
+  }
+}`
+
+func TestSchema_Validate(t *testing.T) {
+	doc := openapix.MustLoad([]byte(testDocument))
+	schema := doc.Components.Schemas["Item"]
+
+	tests := []struct {
+		name          string
+		body          string
+		expectedError string
+	}{
+		{name: "Valid", body: `{"name": "abc", "kind": "a", "count": 2, "at": "2024-07-01T00:00:00Z", "tags": ["x"], "labels": {"k": "v"}, "note": null}`},
+		{name: "Missing Required", body: `{}`, expectedError: "name: is required"},
+		{name: "Unknown Field", body: `{"name": "abc", "size": 1}`, expectedError: "size: is not a known field"},
+		{name: "Wrong Type", body: `{"name": 1}`, expectedError: "name: must be a string"},
+		{name: "Not An Object", body: `[]`, expectedError: "must be an object"},
+		{name: "Too Long", body: `{"name": "abcdefghi"}`, expectedError: "name: must be at most 8 characters long"},
+		{name: "Pattern", body: `{"name": "ABC"}`, expectedError: "name: must match ^[a-z]+$"},
+		{name: "Enum", body: `{"name": "abc", "kind": "c"}`, expectedError: "kind: must be one of a, b"},
+		{name: "Not An Integer", body: `{"name": "abc", "count": 1.5}`, expectedError: "count: must be an integer"},
+		{name: "Minimum", body: `{"name": "abc", "count": 0}`, expectedError: "count: must be at least 1"},
+		{name: "Date Time", body: `{"name": "abc", "at": "yesterday"}`, expectedError: "at: must be an RFC 3339 date-time"},
+		{name: "Max Items", body: `{"name": "abc", "tags": ["x", "y", "z"]}`, expectedError: "tags: must have at most 2 items"},
+		{name: "Array Item", body: `{"name": "abc", "tags": ["x", 1]}`, expectedError: "tags[1]: must be a string"},
+		{name: "Additional Property", body: `{"name": "abc", "labels": {"k": 1}}`, expectedError: "labels.k: must be a string"},
+		{name: "Null", body: `{"name": null}`, expectedError: "name: must not be null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := openapix.Decode([]byte(tt.body))
+			require.NoError(t, err)
+
+			err = schema.Validate(value)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedError string
+	}{
+		{name: "Valid", body: `{"a": [1, {"b": true}]}`},
+		{name: "Duplicate Key", body: `{"a": 1, "a": 2}`, expectedError: `duplicate key "a"`},
+		{name: "Nested Duplicate Key", body: `[{"a": 1, "a": 2}]`, expectedError: `duplicate key "a"`},
+		{name: "Trailing Data", body: `{} {}`, expectedError: "unexpected data after the JSON value"},
+		{name: "Truncated", body: `{"a": `, expectedError: "unexpected EOF"},
+		{name: "Empty", body: ``, expectedError: "unexpected EOF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := openapix.Decode([]byte(tt.body))
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestLoad_UnresolvedReference(t *testing.T) {
+	_, err := openapix.Load([]byte(strings.Replace(testDocument, "#/components/schemas/Item", "#/components/schemas/Other", 1)))
+	assert.ErrorContains(t, err, `unresolved reference "#/components/schemas/Other"`)
+}
+
+func TestMiddleware_Requests(t *testing.T) {
+	doc := openapix.MustLoad([]byte(testDocument))
+	var received []byte
+	handler := openapix.Middleware(doc, openapix.WithMaxBodyBytes(64))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = nil
+		if r.Method == http.MethodPost {
+			buf := new(bytes.Buffer)
+			_, _ = buf.ReadFrom(r.Body)
+			received = buf.Bytes()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(received)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "Valid Body", method: http.MethodPost, path: "/items", contentType: "application/json; charset=utf-8", body: `{"name": "abc"}`, expectedStatus: http.StatusCreated},
+		{name: "Missing Content Type", method: http.MethodPost, path: "/items", body: `{"name": "abc"}`, expectedStatus: http.StatusCreated},
+		{name: "Unsupported Media Type", method: http.MethodPost, path: "/items", contentType: "text/plain", body: `{"name": "abc"}`, expectedStatus: http.StatusUnsupportedMediaType, expectedCode: httpx.CodeUnsupportedMediaType},
+		{name: "Body Too Large", method: http.MethodPost, path: "/items", body: `{"name": "` + strings.Repeat("a", 64) + `"}`, expectedStatus: http.StatusRequestEntityTooLarge, expectedCode: httpx.CodeBodyTooLarge},
+		{name: "Empty Body", method: http.MethodPost, path: "/items", expectedStatus: http.StatusBadRequest, expectedCode: httpx.CodeInvalidBody},
+		{name: "Duplicate Key", method: http.MethodPost, path: "/items", body: `{"name": "a", "name": "b"}`, expectedStatus: http.StatusBadRequest, expectedCode: httpx.CodeInvalidBody},
+		{name: "Invalid Body", method: http.MethodPost, path: "/items", body: `{"name": "abc", "size": 1}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: httpx.CodeValidationFailed},
+		{name: "Valid Parameters", method: http.MethodGet, path: "/items/abc?limit=5", expectedStatus: http.StatusNoContent},
+		{name: "Invalid Path Parameter", method: http.MethodGet, path: "/items/ABC", expectedStatus: http.StatusUnprocessableEntity, expectedCode: httpx.CodeValidationFailed},
+		{name: "Invalid Query Parameter", method: http.MethodGet, path: "/items/abc?limit=11", expectedStatus: http.StatusUnprocessableEntity, expectedCode: httpx.CodeValidationFailed},
+		{name: "Literal Segment", method: http.MethodGet, path: "/items/all", expectedStatus: http.StatusNoContent},
+		{name: "Unknown Route", method: http.MethodGet, path: "/other", expectedStatus: http.StatusNoContent},
+		{name: "Unknown Method", method: http.MethodDelete, path: "/items/abc", expectedStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedCode != "" {
+				var problem httpx.Problem
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+				assert.Equal(t, tt.expectedCode, problem.Code)
+				return
+			}
+			if tt.method == http.MethodPost {
+				assert.Equal(t, tt.body, string(received), "the handler must receive the body")
+			}
+		})
+	}
+}
+
+func TestMiddleware_Responses(t *testing.T) {
+	doc := openapix.MustLoad([]byte(testDocument))
+
+	tests := []struct {
+		name          string
+		status        int
+		contentType   string
+		body          string
+		expectedError string
+	}{
+		{name: "Valid", status: http.StatusCreated, contentType: "application/json", body: `{"name": "abc"}`},
+		{name: "Status Class", status: http.StatusConflict, contentType: httpx.ProblemContentType, body: `{}`},
+		{name: "Undeclared Status", status: http.StatusOK, contentType: "application/json", body: `{"name": "abc"}`, expectedError: "undeclared status 200"},
+		{name: "Undeclared Content Type", status: http.StatusCreated, contentType: "text/plain", body: `abc`, expectedError: `undeclared content type "text/plain" for status 201`},
+		{name: "Invalid Body", status: http.StatusCreated, contentType: "application/json", body: `{"name": "abc", "size": 1}`, expectedError: "size: is not a known field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var violation error
+			handler := openapix.Middleware(doc, openapix.WithResponseViolationHandler(func(r *http.Request, status int, err error) {
+				assert.Equal(t, tt.status, status)
+				violation = err
+			}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{"name": "abc"}`)))
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.body, rec.Body.String(), "the response must be passed through")
+			if tt.expectedError != "" {
+				assert.EqualError(t, violation, tt.expectedError)
+				return
+			}
+			assert.NoError(t, violation)
+		})
+	}
+}
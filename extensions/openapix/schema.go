@@ -0,0 +1,306 @@
+package openapix
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema is the subset of the OpenAPI schema object the validator supports.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Pattern              string             `json:"pattern"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+
+	compiled   bool
+	resolved   *Schema
+	pattern    *regexp.Regexp
+	additional *Schema
+	// closed is set when additionalProperties is false.
+	closed bool
+}
+
+// ValidationError reports where a value violates its schema.
+type ValidationError struct {
+	// Field is the JSON path of the value, such as items[0].project_id.
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Reason
+	}
+
+	return e.Field + ": " + e.Reason
+}
+
+func (s *Schema) compile(doc *Document) error {
+	if s.compiled {
+		return nil
+	}
+	s.compiled = true
+
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if !ok {
+			return fmt.Errorf("unsupported reference %q", s.Ref)
+		}
+		resolved, ok := doc.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("unresolved reference %q", s.Ref)
+		}
+		s.resolved = resolved
+
+		return resolved.compile(doc)
+	}
+
+	switch s.Type {
+	case "", "object", "array", "string", "integer", "number", "boolean":
+	default:
+		return fmt.Errorf("unsupported type %q", s.Type)
+	}
+
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("compiling pattern: %w", err)
+		}
+		s.pattern = pattern
+	}
+
+	if len(s.AdditionalProperties) > 0 {
+		var allowed bool
+		if err := json.Unmarshal(s.AdditionalProperties, &allowed); err == nil {
+			s.closed = !allowed
+		} else {
+			var additional Schema
+			if err := json.Unmarshal(s.AdditionalProperties, &additional); err != nil {
+				return fmt.Errorf("decoding additionalProperties: %w", err)
+			}
+			s.additional = &additional
+		}
+	}
+
+	for name, property := range s.Properties {
+		if err := property.compile(doc); err != nil {
+			return fmt.Errorf("property %s: %w", name, err)
+		}
+	}
+	if s.additional != nil {
+		if err := s.additional.compile(doc); err != nil {
+			return fmt.Errorf("additionalProperties: %w", err)
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(doc); err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks a value decoded by Decode against the schema.
+func (s *Schema) Validate(value any) error {
+	return s.validate("", value)
+}
+
+func (s *Schema) validate(field string, value any) error {
+	if s.resolved != nil {
+		return s.resolved.validate(field, value)
+	}
+
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return &ValidationError{Field: field, Reason: "must not be null"}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("must be one of %s", formatEnum(s.Enum))}
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return typeError(field, s.Type)
+		}
+		return s.validateObject(field, object)
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return typeError(field, s.Type)
+		}
+		return s.validateArray(field, array)
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return typeError(field, s.Type)
+		}
+		return s.validateString(field, str)
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return typeError(field, s.Type)
+		}
+		return s.validateNumber(field, number)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeError(field, s.Type)
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateObject(field string, object map[string]any) error {
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			return &ValidationError{Field: join(field, name), Reason: "is required"}
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := s.Properties[name]
+		switch {
+		case ok:
+		case s.additional != nil:
+			property = s.additional
+		case s.closed:
+			return &ValidationError{Field: join(field, name), Reason: "is not a known field"}
+		default:
+			continue
+		}
+
+		if err := property.validate(join(field, name), object[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateArray(field string, array []any) error {
+	if s.MinItems != nil && len(array) < *s.MinItems {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("must have at least %d items", *s.MinItems)}
+	}
+	if s.MaxItems != nil && len(array) > *s.MaxItems {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("must have at most %d items", *s.MaxItems)}
+	}
+
+	if s.Items != nil {
+		for i, item := range array {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", field, i), item); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateString(field, str string) error {
+	length := utf8.RuneCountInString(str)
+	if s.MinLength != nil && length < *s.MinLength {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("must be at least %d characters long", *s.MinLength)}
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("must be at most %d characters long", *s.MaxLength)}
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("must match %s", s.Pattern)}
+	}
+	if s.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			return &ValidationError{Field: field, Reason: "must be an RFC 3339 date-time"}
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateNumber(field string, number json.Number) error {
+	if s.Type == "integer" {
+		if _, err := number.Int64(); err != nil {
+			return typeError(field, s.Type)
+		}
+	}
+
+	value, err := number.Float64()
+	if err != nil {
+		return typeError(field, s.Type)
+	}
+	if s.Minimum != nil && value < *s.Minimum {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("must be at least %v", *s.Minimum)}
+	}
+	if s.Maximum != nil && value > *s.Maximum {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("must be at most %v", *s.Maximum)}
+	}
+
+	return nil
+}
+
+func typeError(field, typ string) error {
+	article := "a"
+	if strings.ContainsRune("aeiou", rune(typ[0])) {
+		article = "an"
+	}
+
+	return &ValidationError{Field: field, Reason: fmt.Sprintf("must be %s %s", article, typ)}
+}
+
+func inEnum(enum []any, value any) bool {
+	if number, ok := value.(json.Number); ok {
+		value = number.String()
+	}
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatEnum(enum []any) string {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		values[i] = fmt.Sprint(value)
+	}
+
+	return strings.Join(values, ", ")
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+
+	return field + "." + name
+}
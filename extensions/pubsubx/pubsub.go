@@ -2,10 +2,20 @@ package pubsubx
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 )
 
+const maxSubscriptionNameLength = 255
+
+var invalidSubscriptionChars = regexp.MustCompile(`[^A-Za-z0-9._~+%-]`)
+
 // CreateTopicIfNotExists checks if a Pub/Sub topic exists and creates it if it does not.
 //
 // Parameters:
@@ -50,3 +60,41 @@ func CreateSubscriptionIfNotExists(ctx context.Context, client *pubsub.Client, t
 	}
 	return client.CreateSubscription(ctx, subscriptionName, pubsub.SubscriptionConfig{Topic: topic})
 }
+
+// CreateInstanceSubscription creates a subscription receiving every message of the topic
+// for this process only, as needed to fan out messages to all the replicas of a service.
+// The subscription is named after prefix and the host name, receives messages sharing
+// an ordering key in order, and expires after a day without activity in case it is not
+// deleted on shutdown.
+//
+// Parameters:
+//   - ctx: The context for managing the lifecycle of the operation.
+//   - client: The Pub/Sub client used to interact with the Pub/Sub service.
+//   - topic: The Pub/Sub topic to which the subscription will be associated.
+//   - prefix: The prefix of the subscription name.
+//
+// Returns:
+//   - *pubsub.Subscription: A reference to the newly created subscription.
+//   - error: Any error encountered during the operation.
+func CreateInstanceSubscription(ctx context.Context, client *pubsub.Client, topic *pubsub.Topic, prefix string) (*pubsub.Subscription, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "instance"
+	}
+
+	name := fmt.Sprintf("%s-%s-%s", prefix, invalidSubscriptionChars.ReplaceAllString(hostname, "-"), hex.EncodeToString(suffix))
+	if len(name) > maxSubscriptionNameLength {
+		name = name[:maxSubscriptionNameLength]
+	}
+
+	return client.CreateSubscription(ctx, name, pubsub.SubscriptionConfig{
+		Topic:                 topic,
+		ExpirationPolicy:      24 * time.Hour,
+		EnableMessageOrdering: true,
+	})
+}
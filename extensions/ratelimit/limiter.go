@@ -0,0 +1,203 @@
+// Package ratelimit limits the rate of requests with sliding windows counted in a
+// statestore.Store, so that limits hold across the replicas sharing the store.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+// Limit allows Requests per Window. The zero Limit allows everything.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// ParseLimit parses a limit written as requests/window, such as 60/1m. An empty value
+// is the zero Limit.
+func ParseLimit(value string) (Limit, error) {
+	if value == "" {
+		return Limit{}, nil
+	}
+
+	requests, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected requests/window", value)
+	}
+
+	var limit Limit
+	var err error
+	if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: %q is not a positive number of requests", value, requests)
+	}
+	if limit.Window, err = time.ParseDuration(window); err != nil || limit.Window < time.Second {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: %q is not a window of at least a second", value, window)
+	}
+
+	return limit, nil
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return "unlimited"
+	}
+
+	return strconv.Itoa(l.Requests) + "/" + l.Window.String()
+}
+
+// Result is the outcome of a request against a limit.
+type Result struct {
+	Allowed bool
+	Limit   Limit
+	// Remaining is how many requests are left in the window.
+	Remaining int
+	// Reset is when the current window ends.
+	Reset time.Duration
+	// RetryAfter is how long to wait before the request would be allowed, when it is
+	// not.
+	RetryAfter time.Duration
+}
+
+// Limiter counts the requests of each key in fixed windows, and estimates the requests
+// of the sliding window ending now by weighting the previous window with the part of
+// it the sliding window overlaps.
+type Limiter struct {
+	store statestore.Store
+	now   func() time.Time
+}
+
+func NewLimiter(store statestore.Store) *Limiter {
+	return &Limiter{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Allow counts n requests of key against limit, in the namespace of scope, unless they
+// would exceed it. Denied requests are not counted.
+func (l *Limiter) Allow(ctx context.Context, scope, key string, limit Limit, n int) (Result, error) {
+	if !limit.Enabled() {
+		return Result{Allowed: true, Limit: limit, Remaining: math.MaxInt}, nil
+	}
+
+	now := l.now()
+	start := now.Truncate(limit.Window)
+	elapsed := now.Sub(start)
+
+	current, err := l.store.Increment(ctx, windowKey(scope, key, start), int64(n), 2*limit.Window)
+	if err != nil {
+		return Result{}, fmt.Errorf("counting requests: %w", err)
+	}
+
+	previous, err := l.count(ctx, windowKey(scope, key, start.Add(-limit.Window)))
+	if err != nil {
+		return Result{}, err
+	}
+
+	w := window{limit: limit, elapsed: elapsed, previous: previous, current: current}
+	result := Result{
+		Allowed: w.estimate() <= float64(limit.Requests),
+		Limit:   limit,
+		Reset:   limit.Window - elapsed,
+	}
+
+	if !result.Allowed {
+		if _, err := l.store.Increment(ctx, windowKey(scope, key, start), -int64(n), 2*limit.Window); err != nil {
+			return Result{}, fmt.Errorf("uncounting denied requests: %w", err)
+		}
+		w.current -= int64(n)
+		result.RetryAfter = w.retryAfter(n)
+	}
+	result.Remaining = max(0, int(math.Floor(float64(limit.Requests)-w.estimate())))
+
+	return result, nil
+}
+
+// Release uncounts n requests of key allowed by Allow, when they were not served after
+// all.
+func (l *Limiter) Release(ctx context.Context, scope, key string, limit Limit, n int) error {
+	if !limit.Enabled() {
+		return nil
+	}
+
+	start := l.now().Truncate(limit.Window)
+	if _, err := l.store.Increment(ctx, windowKey(scope, key, start), -int64(n), 2*limit.Window); err != nil {
+		return fmt.Errorf("uncounting requests: %w", err)
+	}
+
+	return nil
+}
+
+func (l *Limiter) count(ctx context.Context, key string) (int64, error) {
+	value, err := l.store.Get(ctx, key)
+	if errors.Is(err, statestore.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("counting requests: %w", err)
+	}
+
+	count, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("key %s does not hold a counter", key)
+	}
+
+	return count, nil
+}
+
+func windowKey(scope, key string, start time.Time) string {
+	return "ratelimit/" + scope + "/" + key + "/" + strconv.FormatInt(start.UnixMilli(), 10)
+}
+
+// window holds the counts of the previous and current fixed windows.
+type window struct {
+	limit    Limit
+	elapsed  time.Duration
+	previous int64
+	current  int64
+}
+
+// estimate is the number of requests in the sliding window ending now.
+func (w window) estimate() float64 {
+	overlap := 1 - float64(w.elapsed)/float64(w.limit.Window)
+	return math.Floor(float64(w.previous)*overlap) + float64(w.current)
+}
+
+// retryAfter is how long until n more requests fit in the sliding window.
+func (w window) retryAfter(n int) time.Duration {
+	budget := float64(w.limit.Requests - n)
+	if budget < 0 {
+		// The requests never fit.
+		return w.limit.Window
+	}
+
+	var wait time.Duration
+	previous, current := float64(w.previous), float64(w.current)
+	if current > budget {
+		// The requests of the current window alone exceed the budget: wait for it to
+		// become the previous one.
+		wait = w.limit.Window - w.elapsed
+		previous, current = current, 0
+	} else {
+		wait = -w.elapsed
+	}
+
+	// Wait for the weight of the previous window to drop enough, until the sliding
+	// window overlaps the fraction of it that fits in the budget.
+	overlap := (budget - current) / previous
+	wait += time.Duration((1 - overlap) * float64(w.limit.Window))
+
+	return max(wait, time.Second)
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/httpx"
+)
+
+// CodeRateLimited is the code of the problem reported for rate limited requests.
+const CodeRateLimited = "rate_limited"
+
+// KeyFunc returns the key a request is limited by, and false when it is not limited.
+type KeyFunc func(r *http.Request) (string, bool)
+
+// ClientIP keys requests by the IP address of the client. Behind a proxy, the remote
+// address must first be set from the forwarding headers, such as by chi's
+// middleware.RealIP.
+func ClientIP(r *http.Request) (string, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr, r.RemoteAddr != ""
+	}
+
+	return host, true
+}
+
+// Principal keys requests by the subject of their authenticated principal, leaving
+// anonymous requests unlimited.
+func Principal(r *http.Request) (string, bool) {
+	principal, ok := model.PrincipalFromContext(r.Context())
+	if !ok {
+		return "", false
+	}
+
+	return principal.Subject, true
+}
+
+// Middleware limits the requests of each key to limit, in the namespace of scope.
+// Requests are passed through when the store fails, rather than failing the service.
+func Middleware(limiter *Limiter, scope string, limit Limit, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !limit.Enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k, ok := key(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := limiter.Allow(r.Context(), scope, k, limit, 1)
+			if err != nil {
+				log.Ctx(r.Context()).Error().Err(err).Str("scope", scope).Msg("rate limiting failed")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			WriteHeaders(w, result)
+			if !result.Allowed {
+				WriteProblem(w, r, scope, result)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WriteHeaders reports the quota of a request in the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers. When several limits apply, the
+// headers report the one with the fewest remaining requests.
+func WriteHeaders(w http.ResponseWriter, result Result) {
+	if !result.Limit.Enabled() {
+		return
+	}
+
+	header := w.Header()
+	if reported, err := strconv.Atoi(header.Get("RateLimit-Remaining")); err == nil && reported < result.Remaining {
+		return
+	}
+
+	header.Set("RateLimit-Limit", strconv.Itoa(result.Limit.Requests))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", seconds(result.Reset))
+}
+
+// WriteProblem rejects a request denied by the limit of scope with 429 Too Many
+// Requests, telling the client when to retry in the Retry-After header.
+func WriteProblem(w http.ResponseWriter, r *http.Request, scope string, result Result) {
+	log.Ctx(r.Context()).Warn().Str("scope", scope).Stringer("limit", result.Limit).Msg("rate limit exceeded")
+
+	w.Header().Set("Retry-After", seconds(result.RetryAfter))
+	httpx.WriteProblem(w, r, http.StatusTooManyRequests, CodeRateLimited, "rate limit of "+result.Limit.String()+" exceeded for the "+scope)
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value         string
+		expected      Limit
+		expectedError bool
+	}{
+		{value: "", expected: Limit{}},
+		{value: "60/1m", expected: Limit{Requests: 60, Window: time.Minute}},
+		{value: "5/1s", expected: Limit{Requests: 5, Window: time.Second}},
+		{value: "60", expectedError: true},
+		{value: "0/1m", expectedError: true},
+		{value: "60/minute", expectedError: true},
+		{value: "60/100ms", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			limit, err := ParseLimit(tt.value)
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, limit)
+		})
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	stores := map[string]func(t *testing.T) statestore.Store{
+		"memory": func(t *testing.T) statestore.Store {
+			return statestore.NewMemory()
+		},
+		"redis": func(t *testing.T) statestore.Store {
+			store, err := statestore.Open("redis://" + miniredis.RunT(t).Addr())
+			require.NoError(t, err)
+			return store
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			limit := Limit{Requests: 4, Window: time.Minute}
+			now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+			limiter := NewLimiter(open(t))
+			limiter.now = func() time.Time { return now }
+
+			for i := 0; i < 4; i++ {
+				result, err := limiter.Allow(ctx, "client", "10.0.0.1", limit, 1)
+				require.NoError(t, err)
+				assert.True(t, result.Allowed)
+				assert.Equal(t, 3-i, result.Remaining)
+				assert.Equal(t, time.Minute, result.Reset)
+			}
+
+			result, err := limiter.Allow(ctx, "client", "10.0.0.1", limit, 1)
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.Equal(t, 0, result.Remaining)
+			// The current window must become the previous one, and a quarter of it slide
+			// out of the sliding window.
+			assert.Equal(t, time.Minute+15*time.Second, result.RetryAfter)
+
+			result, err = limiter.Allow(ctx, "client", "10.0.0.2", limit, 1)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed, "keys must be limited separately")
+
+			result, err = limiter.Allow(ctx, "principal", "10.0.0.1", limit, 1)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed, "scopes must be limited separately")
+
+			require.NoError(t, limiter.Release(ctx, "client", "10.0.0.1", limit, 1))
+			result, err = limiter.Allow(ctx, "client", "10.0.0.1", limit, 1)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed, "released requests must not be counted")
+
+			// Half of the previous window, 4 requests, still overlaps the sliding window.
+			now = now.Add(90 * time.Second)
+			result, err = limiter.Allow(ctx, "client", "10.0.0.1", limit, 2)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, 0, result.Remaining)
+			assert.Equal(t, 30*time.Second, result.Reset)
+
+			result, err = limiter.Allow(ctx, "client", "10.0.0.1", limit, 1)
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.Equal(t, 15*time.Second, result.RetryAfter)
+
+			result, err = limiter.Allow(ctx, "client", "10.0.0.3", limit, 5)
+			require.NoError(t, err)
+			assert.False(t, result.Allowed, "requests above the limit must never be allowed")
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	limiter := NewLimiter(statestore.NewMemory())
+	handler := Middleware(limiter, "principal", Limit{Requests: 1, Window: time.Hour}, Principal)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(subject string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/generate-token", nil)
+		if subject != "" {
+			req = req.WithContext(model.ContextWithPrincipal(req.Context(), model.Principal{Subject: subject}))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("ci")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, rec.Header().Get("RateLimit-Reset"))
+
+	rec = serve("ci")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), `"code":"rate_limited"`)
+
+	rec = serve("")
+	assert.Equal(t, http.StatusNoContent, rec.Code, "anonymous requests must not be limited by principal")
+	assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+}
+
+func TestWriteHeaders_MostRestrictive(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	WriteHeaders(rec, Result{Limit: Limit{Requests: 100, Window: time.Minute}, Remaining: 10, Reset: time.Minute})
+	WriteHeaders(rec, Result{Limit: Limit{Requests: 60, Window: time.Hour}, Remaining: 50, Reset: time.Hour})
+
+	assert.Equal(t, "100", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "10", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", rec.Header().Get("RateLimit-Reset"))
+}
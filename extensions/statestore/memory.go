@@ -0,0 +1,166 @@
+package statestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// sweepInterval is how many increments the memory store serves between two sweeps of
+// its expired entries, counters being written under ever changing keys.
+const sweepInterval = 1024
+
+// Memory is a process local Store.
+type Memory struct {
+	mu         sync.Mutex
+	entries    map[string]memoryEntry
+	increments int
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]memoryEntry)}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok || entry.expired(time.Now()) {
+		return nil, ErrNotFound
+	}
+
+	return append([]byte(nil), entry.value...), nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := memoryEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	m.entries[key] = entry
+
+	return nil
+}
+
+func (m *Memory) CompareAndSwap(_ context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if ok && entry.expired(time.Now()) {
+		ok = false
+	}
+	if ok != (old != nil) || (ok && !bytes.Equal(entry.value, old)) {
+		return false, nil
+	}
+
+	entry = memoryEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	m.entries[key] = entry
+
+	return true, nil
+}
+
+func (m *Memory) CompareAndDelete(_ context.Context, key string, old []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok || entry.expired(time.Now()) || !bytes.Equal(entry.value, old) {
+		return false, nil
+	}
+	delete(m.entries, key)
+
+	return true, nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+
+	return nil
+}
+
+func (m *Memory) Keys(_ context.Context, prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var keys []string
+	for key, entry := range m.entries {
+		if entry.expired(now) {
+			delete(m.entries, key)
+			continue
+		}
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (m *Memory) Ping(context.Context) error {
+	return nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+func (m *Memory) Increment(_ context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.increments++
+	if m.increments%sweepInterval == 0 {
+		for key, entry := range m.entries {
+			if entry.expired(now) {
+				delete(m.entries, key)
+			}
+		}
+	}
+
+	var value int64
+	entry, ok := m.entries[key]
+	if ok && !entry.expired(now) {
+		current, err := strconv.ParseInt(string(entry.value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("key %s does not hold a counter", key)
+		}
+		value = current
+	} else {
+		entry = memoryEntry{}
+		if ttl > 0 {
+			entry.expiresAt = now.Add(ttl)
+		}
+	}
+
+	value += delta
+	entry.value = []byte(strconv.FormatInt(value, 10))
+	m.entries[key] = entry
+
+	return value, nil
+}
@@ -0,0 +1,143 @@
+package statestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisScanCount = 100
+
+// incrementScript increments a counter, setting its TTL when it is created.
+var incrementScript = redis.NewScript(`
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+if value == tonumber(ARGV[1]) and tonumber(ARGV[2]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return value
+`)
+
+// compareAndSwapScript sets a key when it holds the expected value, or does not exist
+// when none is expected.
+var compareAndSwapScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if ARGV[1] == "0" then
+	if current then
+		return 0
+	end
+elseif current ~= ARGV[2] then
+	return 0
+end
+if tonumber(ARGV[4]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[3], "PX", ARGV[4])
+else
+	redis.call("SET", KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// compareAndDeleteScript deletes a key when it holds the expected value.
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+return redis.call("DEL", KEYS[1])
+`)
+
+// Redis is a Store shared between instances through a Redis server.
+type Redis struct {
+	client *redis.Client
+}
+
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis get: %w", err)
+	}
+
+	return value, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := r.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("redis set: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("redis del: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Redis) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+
+	iter := r.client.Scan(ctx, 0, prefix+"*", redisScanCount).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("redis scan: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *Redis) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	value, err := incrementScript.Run(ctx, r.client, []string{key}, delta, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("redis incrby: %w", err)
+	}
+
+	return value, nil
+}
+
+func (r *Redis) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	expected := "0"
+	if old != nil {
+		expected = "1"
+	}
+
+	swapped, err := compareAndSwapScript.Run(ctx, r.client, []string{key}, expected, old, value, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis compare and swap: %w", err)
+	}
+
+	return swapped == 1, nil
+}
+
+func (r *Redis) CompareAndDelete(ctx context.Context, key string, old []byte) (bool, error) {
+	deleted, err := compareAndDeleteScript.Run(ctx, r.client, []string{key}, old).Int()
+	if err != nil {
+		return false, fmt.Errorf("redis compare and delete: %w", err)
+	}
+
+	return deleted == 1, nil
+}
+
+func (r *Redis) Ping(ctx context.Context) error {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis ping: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,58 @@
+package statestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNotFound is returned when a key does not exist or expired.
+var ErrNotFound = errors.New("key not found")
+
+// Store is a key/value store holding the state shared by the service replicas.
+// A zero TTL keeps a value until it is deleted.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// Keys lists the keys starting with prefix, in no particular order.
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	// Increment atomically adds delta to the counter at key, created with the given
+	// TTL when it does not exist, and returns its new value.
+	Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	// CompareAndSwap atomically sets key to value when it holds old, or does not exist
+	// when old is nil, and reports whether it did.
+	CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error)
+	// CompareAndDelete atomically deletes key when it holds old, and reports whether it
+	// did.
+	CompareAndDelete(ctx context.Context, key string, old []byte) (bool, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Open creates the store described by rawURL: "memory://" for a process local store,
+// suitable for a single instance, or "redis://[:password@]host:port[/db]" to share
+// state between instances.
+func Open(rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing state store URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "memory":
+		return NewMemory(), nil
+	case "redis", "rediss":
+		options, err := redis.ParseURL(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing redis URL: %w", err)
+		}
+		return NewRedis(redis.NewClient(options)), nil
+	default:
+		return nil, fmt.Errorf("unsupported state store scheme %q", u.Scheme)
+	}
+}
@@ -0,0 +1,116 @@
+package statestore_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+func TestStore(t *testing.T) {
+	stores := map[string]func(t *testing.T) (statestore.Store, func(time.Duration)){
+		"memory": func(t *testing.T) (statestore.Store, func(time.Duration)) {
+			store, err := statestore.Open("memory://")
+			require.NoError(t, err)
+			// The memory store relies on the wall clock for expiration.
+			return store, time.Sleep
+		},
+		"redis": func(t *testing.T) (statestore.Store, func(time.Duration)) {
+			server := miniredis.RunT(t)
+			store, err := statestore.Open("redis://" + server.Addr())
+			require.NoError(t, err)
+			return store, server.FastForward
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store, advance := open(t)
+			defer store.Close()
+
+			require.NoError(t, store.Ping(ctx))
+
+			_, err := store.Get(ctx, "missing")
+			assert.ErrorIs(t, err, statestore.ErrNotFound)
+
+			require.NoError(t, store.Set(ctx, "keys/a", []byte("a"), 0))
+			require.NoError(t, store.Set(ctx, "keys/b", []byte("b"), 0))
+			require.NoError(t, store.Set(ctx, "other", []byte("c"), 0))
+
+			value, err := store.Get(ctx, "keys/a")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("a"), value)
+
+			keys, err := store.Keys(ctx, "keys/")
+			require.NoError(t, err)
+			sort.Strings(keys)
+			assert.Equal(t, []string{"keys/a", "keys/b"}, keys)
+
+			require.NoError(t, store.Delete(ctx, "keys/a"))
+			_, err = store.Get(ctx, "keys/a")
+			assert.ErrorIs(t, err, statestore.ErrNotFound)
+
+			require.NoError(t, store.Set(ctx, "expiring", []byte("d"), 50*time.Millisecond))
+			advance(100 * time.Millisecond)
+			_, err = store.Get(ctx, "expiring")
+			assert.ErrorIs(t, err, statestore.ErrNotFound)
+
+			count, err := store.Increment(ctx, "counter", 2, 50*time.Millisecond)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), count)
+			count, err = store.Increment(ctx, "counter", -1, 50*time.Millisecond)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), count)
+			advance(100 * time.Millisecond)
+			count, err = store.Increment(ctx, "counter", 1, 0)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), count, "the counter must expire")
+
+			swapped, err := store.CompareAndSwap(ctx, "swapped", nil, []byte("v1"), 0)
+			require.NoError(t, err)
+			assert.True(t, swapped, "an absent key is created")
+			swapped, err = store.CompareAndSwap(ctx, "swapped", nil, []byte("v2"), 0)
+			require.NoError(t, err)
+			assert.False(t, swapped, "an existing key is not overwritten")
+			swapped, err = store.CompareAndSwap(ctx, "swapped", []byte("v0"), []byte("v2"), 0)
+			require.NoError(t, err)
+			assert.False(t, swapped, "a changed key is not overwritten")
+			swapped, err = store.CompareAndSwap(ctx, "swapped", []byte("v1"), []byte("v2"), 50*time.Millisecond)
+			require.NoError(t, err)
+			assert.True(t, swapped)
+			value, err = store.Get(ctx, "swapped")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("v2"), value)
+			advance(100 * time.Millisecond)
+			swapped, err = store.CompareAndSwap(ctx, "swapped", []byte("v2"), []byte("v3"), 0)
+			require.NoError(t, err)
+			assert.False(t, swapped, "an expired key is absent")
+
+			require.NoError(t, store.Set(ctx, "taken", []byte("v1"), 0))
+			deleted, err := store.CompareAndDelete(ctx, "taken", []byte("v0"))
+			require.NoError(t, err)
+			assert.False(t, deleted, "a changed key is not deleted")
+			deleted, err = store.CompareAndDelete(ctx, "taken", []byte("v1"))
+			require.NoError(t, err)
+			assert.True(t, deleted)
+			_, err = store.Get(ctx, "taken")
+			assert.ErrorIs(t, err, statestore.ErrNotFound)
+			deleted, err = store.CompareAndDelete(ctx, "taken", []byte("v1"))
+			require.NoError(t, err)
+			assert.False(t, deleted, "a deleted key is deleted once")
+		})
+	}
+}
+
+func TestOpen_UnsupportedScheme(t *testing.T) {
+	_, err := statestore.Open("etcd://localhost:2379")
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package tlsx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/extensions/filex"
+)
+
+const defaultReloadInterval = 30 * time.Second
+
+type ServerConfig struct {
+	// CertFile and KeyFile hold the certificate presented by the server.
+	CertFile string
+	KeyFile  string
+	// MinVersion is the minimum TLS version, "1.2" (default) or "1.3".
+	MinVersion string
+	// ClientCAFile is a PEM bundle of the authorities client certificates are verified
+	// against. When set, clients may present a certificate, and those presenting one
+	// that does not verify are rejected.
+	ClientCAFile string
+	// RequireClientCert rejects the clients presenting no certificate. It requires
+	// ClientCAFile.
+	RequireClientCert bool
+	// ReloadInterval is how often the files above are checked for changes.
+	ReloadInterval time.Duration
+}
+
+// serverCredentials holds the certificate and client authorities of a server, reloaded
+// whenever the files on disk change so rotated certificates are picked up without a
+// restart.
+type serverCredentials struct {
+	config  ServerConfig
+	watcher *filex.Watcher
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// NewServerConfig creates the TLS configuration of a server from cfg. The certificate
+// and the client authorities are read on every handshake from memory, and reloaded
+// when their files change; a failed reload keeps the previous ones.
+//
+// Client certificates are verified against the authorities loaded at the time of the
+// handshake, resumed or not, the peer certificates of the connection state are
+// therefore verified whenever ClientCAFile is set, even though its VerifiedChains is
+// empty.
+func NewServerConfig(cfg ServerConfig) (*tls.Config, error) {
+	minVersion, err := ParseVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("certificate and key files must be provided together")
+	}
+	if cfg.RequireClientCert && cfg.ClientCAFile == "" {
+		return nil, errors.New("requiring client certificates needs a client CA bundle")
+	}
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = defaultReloadInterval
+	}
+
+	credentials := &serverCredentials{config: cfg}
+	if err := credentials.load(); err != nil {
+		return nil, err
+	}
+	credentials.watcher = filex.NewWatcher(cfg.ReloadInterval, cfg.CertFile, cfg.KeyFile, cfg.ClientCAFile)
+
+	config := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: credentials.certificate,
+	}
+	if cfg.ClientCAFile != "" {
+		// The verification is done by verifyClient rather than through ClientCAs, which
+		// cannot be swapped once the server is listening. It runs on resumed sessions
+		// too, so that certificates no longer trusted cannot be resumed with.
+		config.ClientAuth = tls.RequestClientCert
+		if cfg.RequireClientCert {
+			config.ClientAuth = tls.RequireAnyClientCert
+		}
+		config.VerifyConnection = credentials.verifyClient
+	}
+
+	return config, nil
+}
+
+func (c *serverCredentials) load() error {
+	cert, err := LoadKeyPair(c.config.CertFile, c.config.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if c.config.ClientCAFile != "" {
+		if clientCAs, err = LoadCertPool(c.config.ClientCAFile); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	c.cert = cert
+	c.clientCAs = clientCAs
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *serverCredentials) reloadIfChanged() {
+	if !c.watcher.Changed() {
+		return
+	}
+
+	if err := c.load(); err != nil {
+		// Keep serving with the previous material, the files may be mid-rotation.
+		log.Error().Err(err).Msg("reloading server TLS configuration")
+		return
+	}
+	log.Info().Msg("server TLS configuration reloaded")
+}
+
+func (c *serverCredentials) certificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.reloadIfChanged()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.cert, nil
+}
+
+func (c *serverCredentials) verifyClient(state tls.ConnectionState) error {
+	// Whether a certificate is required is enforced by the client authentication type.
+	certs := state.PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+
+	c.reloadIfChanged()
+	c.mu.RLock()
+	clientCAs := c.clientCAs
+	c.mu.RUnlock()
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("verifying client certificate: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,305 @@
+package tlsx_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/extensions/tlsx"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newAuthority(t *testing.T, commonName string) authority {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return authority{cert: cert, key: key}
+}
+
+// issue signs a certificate for commonName, valid for the loopback address.
+func (a authority) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func writeKeyPair(t *testing.T, certFile, keyFile string, cert tls.Certificate) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	require.NoError(t, err)
+
+	writePEM(t, certFile, "CERTIFICATE", cert.Certificate[0])
+	writePEM(t, keyFile, "PRIVATE KEY", keyDER)
+
+	// Make the change visible to the watcher even within the file system time resolution.
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+// serve serves HTTPS with config and returns its URL. Handlers respond with the common
+// name of the client certificate, if any.
+func serve(t *testing.T, config *tls.Config) string {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+	})}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	return "https://" + listener.Addr().String()
+}
+
+func newClient(roots *x509.CertPool, certs ...tls.Certificate) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs},
+	}}
+}
+
+func TestNewServerConfig_ClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.pem")
+	keyFile := filepath.Join(dir, "server-key.pem")
+	clientCAFile := filepath.Join(dir, "client-ca.pem")
+
+	serverCA := newAuthority(t, "server-ca")
+	clientCA := newAuthority(t, "client-ca")
+	writeKeyPair(t, certFile, keyFile, serverCA.issue(t, "server", x509.ExtKeyUsageServerAuth))
+	writePEM(t, clientCAFile, "CERTIFICATE", clientCA.cert.Raw)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	trusted := clientCA.issue(t, "trusted-client", x509.ExtKeyUsageClientAuth)
+	untrusted := newAuthority(t, "other-ca").issue(t, "untrusted-client", x509.ExtKeyUsageClientAuth)
+
+	tests := []struct {
+		name              string
+		requireClientCert bool
+		certs             []tls.Certificate
+		expectedErr       bool
+		expectedClient    string
+	}{
+		{name: "Trusted Client", certs: []tls.Certificate{trusted}, expectedClient: "trusted-client"},
+		{name: "Untrusted Client", certs: []tls.Certificate{untrusted}, expectedErr: true},
+		{name: "Anonymous Client"},
+		{name: "Required Certificate", requireClientCert: true, certs: []tls.Certificate{trusted}, expectedClient: "trusted-client"},
+		{name: "Missing Required Certificate", requireClientCert: true, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := tlsx.NewServerConfig(tlsx.ServerConfig{
+				CertFile:          certFile,
+				KeyFile:           keyFile,
+				ClientCAFile:      clientCAFile,
+				RequireClientCert: tt.requireClientCert,
+			})
+			require.NoError(t, err)
+			url := serve(t, config)
+
+			resp, err := newClient(roots, tt.certs...).Get(url)
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body := make([]byte, 64)
+			n, _ := resp.Body.Read(body)
+			assert.Equal(t, tt.expectedClient, string(body[:n]))
+		})
+	}
+}
+
+func TestNewServerConfig_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.pem")
+	keyFile := filepath.Join(dir, "server-key.pem")
+	clientCAFile := filepath.Join(dir, "client-ca.pem")
+
+	serverCA := newAuthority(t, "server-ca")
+	writeKeyPair(t, certFile, keyFile, serverCA.issue(t, "server-1", x509.ExtKeyUsageServerAuth))
+	clientCA := newAuthority(t, "client-ca-1")
+	writePEM(t, clientCAFile, "CERTIFICATE", clientCA.cert.Raw)
+
+	config, err := tlsx.NewServerConfig(tlsx.ServerConfig{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ClientCAFile:   clientCAFile,
+		ReloadInterval: time.Nanosecond,
+	})
+	require.NoError(t, err)
+	url := serve(t, config)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	servedCertificate := func(certs ...tls.Certificate) (string, error) {
+		resp, err := newClient(roots, certs...).Get(url)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		return resp.TLS.PeerCertificates[0].Subject.CommonName, nil
+	}
+
+	served, err := servedCertificate()
+	require.NoError(t, err)
+	assert.Equal(t, "server-1", served)
+
+	// Rotate the server certificate and the client authority.
+	writeKeyPair(t, certFile, keyFile, serverCA.issue(t, "server-2", x509.ExtKeyUsageServerAuth))
+	rotatedCA := newAuthority(t, "client-ca-2")
+	writePEM(t, clientCAFile, "CERTIFICATE", rotatedCA.cert.Raw)
+
+	served, err = servedCertificate(rotatedCA.issue(t, "client", x509.ExtKeyUsageClientAuth))
+	require.NoError(t, err)
+	assert.Equal(t, "server-2", served)
+
+	_, err = servedCertificate(clientCA.issue(t, "client", x509.ExtKeyUsageClientAuth))
+	assert.Error(t, err, "the previous client authority is still trusted")
+
+	// A broken certificate keeps the previous one.
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0o600))
+	served, err = servedCertificate()
+	require.NoError(t, err)
+	assert.Equal(t, "server-2", served)
+}
+
+func TestNewServerConfig_ResumedSession(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.pem")
+	keyFile := filepath.Join(dir, "server-key.pem")
+	clientCAFile := filepath.Join(dir, "client-ca.pem")
+
+	serverCA := newAuthority(t, "server-ca")
+	writeKeyPair(t, certFile, keyFile, serverCA.issue(t, "server", x509.ExtKeyUsageServerAuth))
+	clientCA := newAuthority(t, "client-ca-1")
+	writePEM(t, clientCAFile, "CERTIFICATE", clientCA.cert.Raw)
+
+	config, err := tlsx.NewServerConfig(tlsx.ServerConfig{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ClientCAFile:   clientCAFile,
+		ReloadInterval: time.Nanosecond,
+	})
+	require.NoError(t, err)
+	url := serve(t, config)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	// Every request opens a connection, resuming the session of the previous one.
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{
+			RootCAs:            roots,
+			Certificates:       []tls.Certificate{clientCA.issue(t, "client", x509.ExtKeyUsageClientAuth)},
+			ClientSessionCache: tls.NewLRUClientSessionCache(1),
+		},
+		DisableKeepAlives: true,
+	}}
+	get := func() (*http.Response, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		return resp, nil
+	}
+
+	_, err = get()
+	require.NoError(t, err)
+	resp, err := get()
+	require.NoError(t, err)
+	require.True(t, resp.TLS.DidResume, "the session is resumed")
+
+	// Distrust the authority of the client certificate.
+	writePEM(t, clientCAFile, "CERTIFICATE", newAuthority(t, "client-ca-2").cert.Raw)
+	future := time.Now().Add(2 * time.Minute)
+	require.NoError(t, os.Chtimes(clientCAFile, future, future))
+
+	_, err = get()
+	assert.Error(t, err, "a session resumed with a distrusted certificate is accepted")
+}
+
+func TestNewServerConfig_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      tlsx.ServerConfig
+		expectedErr string
+	}{
+		{name: "Missing Key", config: tlsx.ServerConfig{CertFile: "server.pem"}, expectedErr: "certificate and key files must be provided together"},
+		{name: "Unsupported Version", config: tlsx.ServerConfig{CertFile: "server.pem", KeyFile: "server-key.pem", MinVersion: "1.0"}, expectedErr: "unsupported TLS version"},
+		{name: "Required Certificate Without CA", config: tlsx.ServerConfig{CertFile: "server.pem", KeyFile: "server-key.pem", RequireClientCert: true}, expectedErr: "client CA bundle"},
+		{name: "Missing Files", config: tlsx.ServerConfig{CertFile: "server.pem", KeyFile: "server-key.pem"}, expectedErr: "loading key pair"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tlsx.NewServerConfig(tt.config)
+			assert.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
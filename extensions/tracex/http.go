@@ -0,0 +1,51 @@
+package tracex
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware traces every request in a server span continuing the trace context of
+// the caller, named after the chi route it matched so that span names stay bounded.
+// The requests to skipPaths, such as health probes, are not traced.
+func Middleware(skipPaths ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		// The route is only known once chi has routed the request.
+		named := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+
+			route := chi.RouteContext(r.Context())
+			if route == nil || route.RoutePattern() == "" {
+				return
+			}
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + route.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(route.RoutePattern()))
+		})
+
+		return otelhttp.NewHandler(named, "http.server",
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return r.Method
+			}),
+			otelhttp.WithFilter(func(r *http.Request) bool {
+				return !slices.Contains(skipPaths, r.URL.Path)
+			}),
+		)
+	}
+}
+
+// Transport traces the requests sent through base in client spans, propagating the
+// trace context to the server. The spans are named after the request path, which must
+// not embed identifiers.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}),
+	)
+}
@@ -0,0 +1,69 @@
+package tracex
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/werbersondev/token-generator-test/extensions/tracex"
+
+// StartPublishSpan starts a producer span publishing to topic. The trace context to
+// inject in the messages is the one of the returned context.
+func StartPublishSpan(ctx context.Context, topic string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "publish "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemGCPPubsub,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingOperationPublish,
+		),
+	)
+}
+
+// Inject adds the trace context of ctx to the attributes of a message, as W3C
+// traceparent and tracestate attributes.
+func Inject(ctx context.Context, attributes map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(attributes))
+}
+
+// StartConsumerSpan starts the consumer span processing a message received from
+// subscription. It continues the trace of the publisher and links to its span, as the
+// messaging conventions recommend, so that a single trace shows the request from its
+// acceptance to its processing. The time spent in the queue since publishTime is
+// recorded in a span of its own in that trace.
+func StartConsumerSpan(ctx context.Context, subscription string, attributes map[string]string, publishTime time.Time) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(attributes))
+	tracer := otel.Tracer(tracerName)
+
+	receivedAt := time.Now()
+	publisher := trace.SpanContextFromContext(ctx)
+
+	var links []trace.Link
+	if publisher.IsValid() {
+		links = append(links, trace.Link{SpanContext: publisher})
+
+		if !publishTime.IsZero() && publishTime.Before(receivedAt) {
+			_, queued := tracer.Start(ctx, "queued "+subscription,
+				trace.WithTimestamp(publishTime),
+				trace.WithAttributes(semconv.MessagingSystemGCPPubsub),
+			)
+			queued.End(trace.WithTimestamp(receivedAt))
+		}
+	}
+
+	return tracer.Start(ctx, "process "+subscription,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithTimestamp(receivedAt),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			semconv.MessagingSystemGCPPubsub,
+			semconv.MessagingDestinationName(subscription),
+			semconv.MessagingOperationDeliver,
+		),
+	)
+}
@@ -0,0 +1,148 @@
+package tracex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+)
+
+const (
+	// ExporterNone records no span, the trace context is still propagated.
+	ExporterNone = "none"
+	// ExporterOTLP exports the spans to an OpenTelemetry collector.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes the spans as JSON lines, for local use.
+	ExporterStdout = "stdout"
+
+	// ProtocolGRPC and ProtocolHTTP are the transports of the OTLP exporter.
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// Config selects where the spans of a service are exported.
+type Config struct {
+	Exporter string
+
+	// OTLPEndpoint is the host:port of the collector. The standard OTEL_EXPORTER_OTLP_*
+	// variables apply when it is empty.
+	OTLPEndpoint string
+	OTLPProtocol string
+	OTLPInsecure bool
+
+	// File is where the stdout exporter writes the spans, the standard output when empty.
+	File string
+
+	// SampleRatio is the ratio of the traces started by the service that are sampled.
+	// Traces started upstream follow the decision of their caller.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider of the service named serviceName, along
+// with the W3C trace context and baggage propagators. The returned function flushes
+// the pending spans and must be called before exiting.
+func Setup(ctx context.Context, serviceName string, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if cfg.Exporter == "" || cfg.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closer, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+// newExporter creates the exporter selected by cfg, along with the file it writes to
+// when it must be closed on shutdown.
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		exporter, err := newOTLPExporter(ctx, cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating otlp exporter: %w", err)
+		}
+		return exporter, nil, nil
+	case ExporterStdout:
+		if cfg.File == "" {
+			exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+			return exporter, nil, err
+		}
+
+		file, err := os.OpenFile(cfg.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return exporter, file, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+}
+
+func newOTLPExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.OTLPProtocol {
+	case "", ProtocolGRPC:
+		var opts []otlptracegrpc.Option
+		if cfg.OTLPEndpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint))
+		}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case ProtocolHTTP:
+		var opts []otlptracehttp.Option
+		if cfg.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.OTLPEndpoint))
+		}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown otlp protocol %q", cfg.OTLPProtocol)
+	}
+}
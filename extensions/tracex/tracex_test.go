@@ -0,0 +1,193 @@
+package tracex_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/werbersondev/token-generator-test/extensions/tracex"
+)
+
+// record installs a tracer provider recording the ended spans for the test.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	_, err := tracex.Setup(context.Background(), "test", tracex.Config{Exporter: tracex.ExporterNone})
+	require.NoError(t, err)
+
+	return recorder
+}
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           tracex.Config
+		expectedError string
+	}{
+		{name: "None", cfg: tracex.Config{}},
+		{name: "Stdout", cfg: tracex.Config{Exporter: tracex.ExporterStdout, SampleRatio: 1}},
+		{name: "Unknown Exporter", cfg: tracex.Config{Exporter: "jaeger"}, expectedError: `unknown trace exporter "jaeger"`},
+		{name: "Unknown Protocol", cfg: tracex.Config{Exporter: tracex.ExporterOTLP, OTLPProtocol: "udp"}, expectedError: `creating otlp exporter: unknown otlp protocol "udp"`},
+	}
+
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cfg.Exporter == tracex.ExporterStdout {
+				tt.cfg.File = filepath.Join(t.TempDir(), "traces.jsonl")
+			}
+
+			shutdown, err := tracex.Setup(context.Background(), "test", tt.cfg)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			_, span := otel.Tracer("test").Start(context.Background(), "operation")
+			span.End()
+			require.NoError(t, shutdown(context.Background()))
+
+			if tt.cfg.File != "" {
+				data, err := os.ReadFile(tt.cfg.File)
+				require.NoError(t, err)
+				assert.Contains(t, string(data), `"Name":"operation"`)
+				assert.Contains(t, string(data), `"Value":"test"`)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	const traceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedName string
+	}{
+		{name: "Route", path: "/v1/requests/8f14e45f", expectedName: "GET /v1/requests/{id}"},
+		{name: "Unknown Route", path: "/unknown", expectedName: "GET"},
+		{name: "Skipped", path: "/liveness"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record(t)
+
+			router := chi.NewRouter()
+			router.Use(tracex.Middleware("/liveness"))
+			router.Get("/v1/requests/{id}", func(w http.ResponseWriter, r *http.Request) {})
+			router.Get("/liveness", func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("traceparent", traceParent)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			if tt.expectedName == "" {
+				assert.Empty(t, spans)
+				return
+			}
+
+			require.Len(t, spans, 1)
+			assert.Equal(t, tt.expectedName, spans[0].Name())
+			assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+			assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", spans[0].SpanContext().TraceID().String())
+			assert.Equal(t, "b7ad6b7169203331", spans[0].Parent().SpanID().String())
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	recorder := record(t)
+
+	var traceParent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get("traceparent")
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: tracex.Transport(http.DefaultTransport)}
+	resp, err := client.Get(server.URL + "/api/system/status?verbose=true")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /api/system/status", spans[0].Name())
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	assert.Contains(t, traceParent, spans[0].SpanContext().SpanID().String())
+}
+
+func TestStartConsumerSpan(t *testing.T) {
+	tests := []struct {
+		name        string
+		traced      bool
+		publishTime time.Time
+		expected    []string
+	}{
+		{name: "Traced", traced: true, publishTime: time.Now().Add(-time.Second), expected: []string{"queued subscription", "process subscription"}},
+		{name: "No Publish Time", traced: true, expected: []string{"process subscription"}},
+		{name: "Untraced", publishTime: time.Now().Add(-time.Second), expected: []string{"process subscription"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record(t)
+
+			attributes := map[string]string{"correlation_id": "build-42"}
+			var publish trace.SpanContext
+			if tt.traced {
+				ctx, span := tracex.StartPublishSpan(context.Background(), "topic")
+				tracex.Inject(ctx, attributes)
+				span.End()
+				publish = span.SpanContext()
+				assert.Contains(t, attributes, "traceparent")
+			}
+
+			_, span := tracex.StartConsumerSpan(context.Background(), "subscription", attributes, tt.publishTime)
+			span.End()
+
+			var names []string
+			for _, s := range recorder.Ended() {
+				if s.SpanKind() == trace.SpanKindProducer {
+					continue
+				}
+				names = append(names, s.Name())
+
+				if !tt.traced {
+					assert.False(t, s.Parent().IsValid())
+					assert.Empty(t, s.Links())
+					continue
+				}
+				assert.Equal(t, publish.TraceID(), s.SpanContext().TraceID())
+				assert.Equal(t, publish.SpanID(), s.Parent().SpanID())
+				if s.SpanKind() == trace.SpanKindConsumer {
+					require.Len(t, s.Links(), 1)
+					assert.Equal(t, publish.SpanID(), s.Links()[0].SpanContext.SpanID())
+				}
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
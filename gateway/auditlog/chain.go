@@ -0,0 +1,129 @@
+package auditlog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+// Record is an audit entry chained to the previous record of its source. Its hash
+// covers the hash of the previous record, so that modifying or deleting a record
+// breaks the chain from there on.
+type Record struct {
+	// Source identifies the service or worker recording the entries, each having a
+	// chain of its own.
+	Source string `json:"source"`
+	// Sequence numbers the records of a chain from 1.
+	Sequence uint64 `json:"seq"`
+	// Entry is kept as encoded, so that the hash can be verified by tools that do not
+	// know every field of the entry.
+	Entry    json.RawMessage `json:"entry"`
+	PrevHash string          `json:"prev_hash,omitempty"`
+	Hash     string          `json:"hash"`
+}
+
+// computeHash returns the hex encoded SHA-256 hash of the record, excluding its own
+// hash.
+func (r Record) computeHash() string {
+	h := sha256.New()
+	for _, field := range [][]byte{
+		[]byte(r.Source),
+		[]byte(strconv.FormatUint(r.Sequence, 10)),
+		[]byte(r.PrevHash),
+		r.Entry,
+	} {
+		h.Write([]byte(strconv.Itoa(len(field)) + ":"))
+		h.Write(field)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Sink stores the records of the audit log.
+type Sink interface {
+	Write(ctx context.Context, record Record) error
+}
+
+// headReader is implemented by the sinks able to return the last record of a source,
+// so that its chain continues across restarts.
+type headReader interface {
+	Head(source string) (Record, bool, error)
+}
+
+// Chain records audit entries as a hash chain, written to every sink. It implements
+// service.AuditRecorder.
+//
+// The chain resumes from the last record of the source found in the first sink able
+// to read it back. Otherwise a new chain starts at sequence 1, which Verify reports as
+// a restart.
+type Chain struct {
+	source string
+	sinks  []Sink
+
+	mu       sync.Mutex
+	sequence uint64
+	head     string
+}
+
+func NewChain(source string, sinks ...Sink) (*Chain, error) {
+	chain := &Chain{source: source, sinks: sinks}
+
+	for _, sink := range sinks {
+		reader, ok := sink.(headReader)
+		if !ok {
+			continue
+		}
+
+		head, found, err := reader.Head(source)
+		if err != nil {
+			return nil, fmt.Errorf("reading the head of the audit chain: %w", err)
+		}
+		if found {
+			chain.sequence = head.Sequence
+			chain.head = head.Hash
+		}
+		break
+	}
+
+	return chain, nil
+}
+
+// Record appends entry to the chain. The sequence is consumed even if a sink fails,
+// so that the missing record shows as a gap in that sink.
+func (c *Chain) Record(ctx context.Context, entry model.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding audit entry: %w", err)
+	}
+
+	// Records are written while holding the lock, so that every sink receives them in
+	// the order of the chain.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	record := Record{
+		Source:   c.source,
+		Sequence: c.sequence + 1,
+		Entry:    data,
+		PrevHash: c.head,
+	}
+	record.Hash = record.computeHash()
+	c.sequence = record.Sequence
+	c.head = record.Hash
+
+	var errs []error
+	for _, sink := range c.sinks {
+		if err := sink.Write(ctx, record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,172 @@
+package auditlog_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/gateway/auditlog"
+)
+
+var requestEntry = model.AuditEntry{
+	Action:    model.AuditActionRequestToken,
+	Outcome:   model.AuditOutcomeAccepted,
+	Principal: &model.Principal{Subject: "apikey:ci", Method: "api_key"},
+	RequestID: "0123",
+	ProjectID: "app<&>",
+}
+
+// writeLog records count entries from source to the audit file at path, resuming its
+// chain, and returns the lines of the file.
+func writeLog(t *testing.T, path, source string, count int, entry model.AuditEntry) []string {
+	t.Helper()
+
+	sink, err := auditlog.NewFileSink(path)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	chain, err := auditlog.NewChain(source, sink)
+	require.NoError(t, err)
+
+	for i := 0; i < count; i++ {
+		entry.Time = time.Date(2024, 5, 1, 10, 0, i, 0, time.UTC)
+		require.NoError(t, chain.Record(context.Background(), entry))
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestChain_Resume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	writeLog(t, path, "httpservice@a", 2, requestEntry)
+	writeLog(t, path, "worker@b", 1, requestEntry)
+	lines := writeLog(t, path, "httpservice@a", 1, requestEntry)
+	require.Len(t, lines, 4)
+
+	report, err := auditlog.Verify(strings.NewReader(strings.Join(lines, "\n")))
+	require.NoError(t, err)
+
+	assert.True(t, report.Valid(), report.Problems)
+	assert.Equal(t, 4, report.Records)
+	assert.Equal(t, map[string]int{"httpservice@a": 1, "worker@b": 1}, report.Chains)
+	assert.Equal(t, uint64(3), report.Heads["httpservice@a"].Sequence)
+	assert.Contains(t, lines[0], `"source":"httpservice@a","seq":1`)
+	assert.Contains(t, lines[3], `"seq":3`)
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name             string
+		tamper           func(lines []string) []string
+		expectedChains   int
+		expectedProblems []string
+	}{
+		{
+			name:           "Intact",
+			tamper:         func(lines []string) []string { return lines },
+			expectedChains: 1,
+		},
+		{
+			name: "Modified",
+			tamper: func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], "apikey:ci", "apikey:admin", 1)
+				return lines
+			},
+			expectedChains:   1,
+			expectedProblems: []string{"line 2: source #2: hash mismatch, the record was modified"},
+		},
+		{
+			name: "Deleted",
+			tamper: func(lines []string) []string {
+				return append(lines[:1], lines[2:]...)
+			},
+			expectedChains:   1,
+			expectedProblems: []string{"line 2: source #3: record #2 is missing"},
+		},
+		{
+			name: "Deleted From Start",
+			tamper: func(lines []string) []string {
+				return lines[2:]
+			},
+			expectedChains:   1,
+			expectedProblems: []string{"line 1: source #3: records #1 to #2 are missing"},
+		},
+		{
+			name: "Reordered",
+			tamper: func(lines []string) []string {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			expectedChains: 1,
+			expectedProblems: []string{
+				"line 2: source #3: record #2 is missing",
+				"line 3: source #2: follows #3, the record is duplicated or out of order",
+			},
+		},
+		{
+			name: "Restarted",
+			tamper: func(lines []string) []string {
+				return append(lines, lines[0])
+			},
+			expectedChains: 2,
+		},
+		{
+			name: "Malformed",
+			tamper: func(lines []string) []string {
+				lines[3] = lines[3][:20]
+				return lines
+			},
+			expectedChains:   1,
+			expectedProblems: []string{"line 4: malformed record"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := writeLog(t, filepath.Join(t.TempDir(), "audit.jsonl"), "source", 4, requestEntry)
+
+			report, err := auditlog.Verify(strings.NewReader(strings.Join(tt.tamper(lines), "\n") + "\n"))
+			require.NoError(t, err)
+
+			var problems []string
+			for _, problem := range report.Problems {
+				problems = append(problems, problem.String())
+			}
+			assert.Equal(t, tt.expectedProblems, problems)
+			assert.Equal(t, len(tt.expectedProblems) == 0, report.Valid())
+			assert.Equal(t, tt.expectedChains, report.Chains["source"])
+		})
+	}
+}
+
+func TestVerify_ReplacedRecord(t *testing.T) {
+	// A record replaced along with its hash is detected by the next record.
+	original := writeLog(t, filepath.Join(t.TempDir(), "audit.jsonl"), "source", 3, requestEntry)
+	forgedEntry := requestEntry
+	forgedEntry.ProjectID = "other"
+	forged := writeLog(t, filepath.Join(t.TempDir(), "forged.jsonl"), "source", 3, forgedEntry)
+
+	var buf bytes.Buffer
+	for _, line := range []string{original[0], forged[1], original[2]} {
+		buf.WriteString(line + "\n")
+	}
+
+	report, err := auditlog.Verify(&buf)
+	require.NoError(t, err)
+
+	require.Len(t, report.Problems, 2)
+	assert.Equal(t, "line 2: source #2: previous hash does not match #1, the previous record was modified or replaced", report.Problems[0].String())
+	assert.Equal(t, "line 3: source #3: previous hash does not match #2, the previous record was modified or replaced", report.Problems[1].String())
+}
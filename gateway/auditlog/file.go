@@ -0,0 +1,87 @@
+package auditlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+const auditFileMode = 0o600
+
+// FileSink appends the records to a JSON Lines file, synced to disk after each of
+// them.
+type FileSink struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, auditFileMode)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit file: %w", err)
+	}
+
+	return &FileSink{path: path, file: file}, nil
+}
+
+func (s *FileSink) Write(_ context.Context, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encoding audit record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing audit file: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("syncing audit file: %w", err)
+	}
+
+	return nil
+}
+
+// Head returns the last record of source in the file, if any.
+func (s *FileSink) Head(source string) (Record, bool, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return Record{}, false, err
+	}
+	defer file.Close()
+
+	var (
+		head  Record
+		found bool
+	)
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var record Record
+			// Lines that cannot be decoded are left to Verify, the chain continues
+			// from the last valid record.
+			if json.Unmarshal(line, &record) == nil && record.Source == source {
+				head, found = record, true
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return head, found, nil
+		}
+		if err != nil {
+			return Record{}, false, err
+		}
+	}
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
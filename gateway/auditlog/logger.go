@@ -0,0 +1,63 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+)
+
+// Logger records audit entries in the service log, tagged with log_type=audit so
+// they can be routed apart from the other logs. It is also a Sink, logging the chain
+// fields of the records along with their entry.
+type Logger struct{}
+
+func NewLogger() *Logger {
+	return &Logger{}
+}
+
+func (l *Logger) Record(ctx context.Context, entry model.AuditEntry) error {
+	entryEvent(ctx, entry).Msg("audit")
+
+	return nil
+}
+
+func (l *Logger) Write(ctx context.Context, record Record) error {
+	var entry model.AuditEntry
+	if err := json.Unmarshal(record.Entry, &entry); err != nil {
+		return fmt.Errorf("decoding audit entry: %w", err)
+	}
+
+	entryEvent(ctx, entry).
+		Str("source", record.Source).
+		Uint64("seq", record.Sequence).
+		Str("prev_hash", record.PrevHash).
+		Str("hash", record.Hash).
+		Msg("audit")
+
+	return nil
+}
+
+func entryEvent(ctx context.Context, entry model.AuditEntry) *zerolog.Event {
+	event := log.Ctx(ctx).Info().
+		Str("log_type", "audit").
+		Time("time", entry.Time).
+		Str("action", entry.Action).
+		Str("outcome", entry.Outcome).
+		Str("request_id", entry.RequestID).
+		Str("project_id", entry.ProjectID).
+		Str("token_type", string(entry.TokenType)).
+		Str("token_name", entry.TokenName).
+		Dur("ttl", entry.TTL).
+		Str("reason", entry.Reason)
+	if entry.Principal != nil {
+		event = event.Str("subject", entry.Principal.Subject).
+			Str("auth_method", entry.Principal.Method)
+	}
+
+	return event
+}
@@ -0,0 +1,92 @@
+package auditlog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// Names of the sinks.
+const (
+	SinkLog   = "log"
+	SinkFile  = "file"
+	SinkTopic = "topic"
+)
+
+type Config struct {
+	// Source identifies the process in the records, DefaultSource of the service when
+	// empty.
+	Source string
+	Sinks  []string
+	// File is the path of the file sink.
+	File string
+	// Topic is the topic of the topic sink.
+	Topic *pubsub.Topic
+}
+
+// DefaultSource identifies the instance of service running on this host.
+func DefaultSource(service string) string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return service
+	}
+
+	return service + "@" + hostname
+}
+
+// Open creates the chain of service recording to the configured sinks, resuming from
+// the file sink if any. The returned function closes the sinks.
+func Open(service string, cfg Config) (*Chain, func() error, error) {
+	if len(cfg.Sinks) == 0 {
+		return nil, nil, errors.New("no audit sink configured")
+	}
+
+	source := cfg.Source
+	if source == "" {
+		source = DefaultSource(service)
+	}
+
+	var (
+		sinks []Sink
+		files []*FileSink
+	)
+	closeSinks := func() error {
+		var errs []error
+		for _, file := range files {
+			errs = append(errs, file.Close())
+		}
+		return errors.Join(errs...)
+	}
+
+	for _, name := range cfg.Sinks {
+		var sink Sink
+		switch name {
+		case SinkLog:
+			sink = NewLogger()
+		case SinkFile:
+			file, err := NewFileSink(cfg.File)
+			if err != nil {
+				return nil, nil, errors.Join(err, closeSinks())
+			}
+			files = append(files, file)
+			sink = file
+		case SinkTopic:
+			if cfg.Topic == nil {
+				return nil, nil, errors.Join(errors.New("the topic audit sink requires a topic"), closeSinks())
+			}
+			sink = NewTopicSink(cfg.Topic)
+		default:
+			return nil, nil, errors.Join(fmt.Errorf("unknown audit sink %q", name), closeSinks())
+		}
+		sinks = append(sinks, sink)
+	}
+
+	chain, err := NewChain(source, sinks...)
+	if err != nil {
+		return nil, nil, errors.Join(err, closeSinks())
+	}
+
+	return chain, closeSinks, nil
+}
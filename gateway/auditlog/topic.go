@@ -0,0 +1,47 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// TopicSink publishes the records to a Pub/Sub topic, in the order of their chain
+// using the source as ordering key. Records cannot be read back from the topic, so a
+// chain published only there restarts with the process.
+type TopicSink struct {
+	topic *pubsub.Topic
+}
+
+func NewTopicSink(topic *pubsub.Topic) *TopicSink {
+	topic.EnableMessageOrdering = true
+
+	return &TopicSink{topic: topic}
+}
+
+func (s *TopicSink) Write(ctx context.Context, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encoding audit record: %w", err)
+	}
+
+	result := s.topic.Publish(ctx, &pubsub.Message{
+		Data: data,
+		Attributes: map[string]string{
+			"source": record.Source,
+			"seq":    strconv.FormatUint(record.Sequence, 10),
+		},
+		OrderingKey: record.Source,
+	})
+	if _, err := result.Get(ctx); err != nil {
+		// Publishing is paused for the key after a failure, resume it for the next
+		// records.
+		s.topic.ResumePublish(record.Source)
+		return fmt.Errorf("publishing audit record: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Report is the outcome of the verification of an audit log.
+type Report struct {
+	Records int
+	// Chains counts the chains of every source, more than one meaning the source
+	// restarted without resuming its chain.
+	Chains map[string]int
+	// Heads are the last records of every source. The records following them cannot
+	// be told apart from records never written, so heads are worth comparing with
+	// those of another sink.
+	Heads    map[string]Record
+	Problems []Problem
+}
+
+// Valid reports whether no record was found modified, missing or out of order.
+func (r Report) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+// Problem is a break in the chain of a source.
+type Problem struct {
+	// Line is the line of the record, from 1.
+	Line     int
+	Source   string
+	Sequence uint64
+	Reason   string
+}
+
+func (p Problem) String() string {
+	if p.Source == "" {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Reason)
+	}
+
+	return fmt.Sprintf("line %d: %s #%d: %s", p.Line, p.Source, p.Sequence, p.Reason)
+}
+
+// Verify checks the chains of the records read from r, one JSON record per line as
+// written by FileSink. Records of several sources may be interleaved. It only fails
+// when r cannot be read, breaks in the chains are reported as problems.
+func Verify(r io.Reader) (Report, error) {
+	report := Report{
+		Chains: make(map[string]int),
+		Heads:  make(map[string]Record),
+	}
+
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if len(data) > 0 {
+			report.check(line, data)
+		}
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return report, err
+		}
+	}
+}
+
+func (r *Report) check(line int, data []byte) {
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil || record.Source == "" || record.Sequence == 0 {
+		r.Problems = append(r.Problems, Problem{Line: line, Reason: "malformed record"})
+		return
+	}
+	r.Records++
+
+	problem := func(format string, args ...any) {
+		r.Problems = append(r.Problems, Problem{
+			Line:     line,
+			Source:   record.Source,
+			Sequence: record.Sequence,
+			Reason:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	if record.computeHash() != record.Hash {
+		problem("hash mismatch, the record was modified")
+	}
+
+	head, known := r.Heads[record.Source]
+	switch {
+	case record.Sequence == 1 && record.PrevHash == "":
+		r.Chains[record.Source]++
+	case record.Sequence == 1:
+		problem("starts a chain with a previous hash")
+		r.Chains[record.Source]++
+	case !known:
+		problem("%s missing", missingRecords(1, record.Sequence-1))
+		r.Chains[record.Source]++
+	case record.Sequence <= head.Sequence:
+		// The chain goes on from the head, the record does not replace it.
+		problem("follows #%d, the record is duplicated or out of order", head.Sequence)
+		return
+	case record.Sequence > head.Sequence+1:
+		problem("%s missing", missingRecords(head.Sequence+1, record.Sequence-1))
+	case record.PrevHash != head.Hash:
+		problem("previous hash does not match #%d, the previous record was modified or replaced", head.Sequence)
+	}
+
+	r.Heads[record.Source] = record
+}
+
+func missingRecords(from, to uint64) string {
+	if from == to {
+		return fmt.Sprintf("record #%d is", from)
+	}
+
+	return fmt.Sprintf("records #%d to #%d are", from, to)
+}
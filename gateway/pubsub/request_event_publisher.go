@@ -0,0 +1,58 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
+	"github.com/werbersondev/token-generator-test/extensions/tracex"
+)
+
+// RequestEventPublisher publishes the events of a request in order, using its ID as
+// ordering key.
+type RequestEventPublisher struct {
+	topic   *pubsub.Topic
+	metrics *metricsx.Metrics
+}
+
+func NewRequestEventPublisher(topic *pubsub.Topic, opts ...Option) *RequestEventPublisher {
+	options := newPublisherOptions(opts)
+	topic.EnableMessageOrdering = true
+
+	return &RequestEventPublisher{
+		topic:   topic,
+		metrics: options.metrics,
+	}
+}
+
+func (r *RequestEventPublisher) PublishRequestEvent(ctx context.Context, event model.RequestEvent) (err error) {
+	ctx, span := tracex.StartPublishSpan(ctx, r.topic.ID())
+	defer func(start time.Time) {
+		endSpan(span, err)
+		r.metrics.ObservePublish(r.topic.ID(), start, err)
+	}(time.Now())
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshalling event data: %w", err)
+	}
+
+	result := r.topic.Publish(ctx, &pubsub.Message{
+		Data:        data,
+		Attributes:  messageAttributes(ctx),
+		OrderingKey: event.RequestID,
+	})
+
+	if _, err := result.Get(ctx); err != nil {
+		// Publishing is paused for the key after a failure, resume it for the next
+		// events of the request.
+		r.topic.ResumePublish(event.RequestID)
+		return fmt.Errorf("publishing message: %w", err)
+	}
+	return nil
+}
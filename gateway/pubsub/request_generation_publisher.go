@@ -4,30 +4,70 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"cloud.google.com/go/pubsub"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/correlation"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
+	"github.com/werbersondev/token-generator-test/extensions/tracex"
 )
 
+// Option configures the publishers.
+type Option func(*publisherOptions)
+
+type publisherOptions struct {
+	metrics *metricsx.Metrics
+}
+
+// WithMetrics records the time taken to publish the messages.
+func WithMetrics(metrics *metricsx.Metrics) Option {
+	return func(o *publisherOptions) {
+		o.metrics = metrics
+	}
+}
+
+func newPublisherOptions(opts []Option) publisherOptions {
+	var options publisherOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
 type RequestTokenGenerationPublisher struct {
-	topic *pubsub.Topic
+	topic   *pubsub.Topic
+	metrics *metricsx.Metrics
 }
 
-func NewRequestTokenGenerationPublisher(topic *pubsub.Topic) *RequestTokenGenerationPublisher {
+func NewRequestTokenGenerationPublisher(topic *pubsub.Topic, opts ...Option) *RequestTokenGenerationPublisher {
+	options := newPublisherOptions(opts)
+
 	return &RequestTokenGenerationPublisher{
-		topic: topic,
+		topic:   topic,
+		metrics: options.metrics,
 	}
 }
 
-func (r *RequestTokenGenerationPublisher) PublishRequestTokenGeneration(ctx context.Context, request model.TokenGenerationRequest) error {
+func (r *RequestTokenGenerationPublisher) PublishRequestTokenGeneration(ctx context.Context, request model.TokenGenerationRequest) (err error) {
+	ctx, span := tracex.StartPublishSpan(ctx, r.topic.ID())
+	defer func(start time.Time) {
+		endSpan(span, err)
+		r.metrics.ObservePublish(r.topic.ID(), start, err)
+	}(time.Now())
+
 	data, err := json.Marshal(request)
 	if err != nil {
 		return fmt.Errorf("marshalling request data: %w", err)
 	}
 
 	result := r.topic.Publish(ctx, &pubsub.Message{
-		Data: data,
+		Data:       data,
+		Attributes: messageAttributes(ctx),
 	})
 
 	if _, err := result.Get(ctx); err != nil {
@@ -35,3 +75,66 @@ func (r *RequestTokenGenerationPublisher) PublishRequestTokenGeneration(ctx cont
 	}
 	return nil
 }
+
+// PublishRequestTokenGenerations publishes every request before waiting for any, so
+// that the topic bundles them in as few publish calls as its batching settings allow.
+func (r *RequestTokenGenerationPublisher) PublishRequestTokenGenerations(ctx context.Context, requests []model.TokenGenerationRequest) []error {
+	ctx, span := tracex.StartPublishSpan(ctx, r.topic.ID())
+	defer span.End()
+
+	start := time.Now()
+	errs := make([]error, len(requests))
+	results := make([]*pubsub.PublishResult, len(requests))
+	for i, request := range requests {
+		data, err := json.Marshal(request)
+		if err != nil {
+			errs[i] = fmt.Errorf("marshalling request data: %w", err)
+			continue
+		}
+
+		results[i] = r.topic.Publish(ctx, &pubsub.Message{
+			Data:       data,
+			Attributes: messageAttributes(ctx),
+		})
+	}
+
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		// Every message is observed from the start of the batch, which they waited for.
+		_, err := result.Get(ctx)
+		r.metrics.ObservePublish(r.topic.ID(), start, err)
+		if err != nil {
+			errs[i] = fmt.Errorf("publishing message: %w", err)
+			span.SetStatus(codes.Error, "publishing message")
+		}
+	}
+
+	return errs
+}
+
+// messageAttributes carries the correlation ID and the trace context of ctx, if any, so
+// that the consumers log and trace the processing of the message along with the
+// request that led to it.
+func messageAttributes(ctx context.Context) map[string]string {
+	attributes := make(map[string]string)
+	if id := correlation.FromContext(ctx); id != "" {
+		attributes[correlation.Attribute] = id
+	}
+	tracex.Inject(ctx, attributes)
+
+	if len(attributes) == 0 {
+		return nil
+	}
+	return attributes
+}
+
+// endSpan ends the publish span, marking it failed when err is not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,298 @@
+package requeststore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+)
+
+const (
+	keyPrefix             = "requests/"
+	batchKeyPrefix        = "batches/"
+	cancellationKeyPrefix = "cancellations/"
+	tokenKeyPrefix        = "tokens/"
+	issuedKeyPrefix       = "issued/"
+	ownerKeyPrefix        = "owners/"
+)
+
+// Store keeps the status of token generation requests, and the batches grouping them,
+// in the shared state store. The tokens issued are kept apart from the statuses, until
+// retrieved. Statuses expire after a TTL, as do tokens, batches and the cancellations of
+// requests, which the worker reads from the same store. Issued tokens are also recorded
+// until they expire themselves, indexed by owner.
+type Store struct {
+	store statestore.Store
+	ttl   time.Duration
+}
+
+func New(store statestore.Store, ttl time.Duration) *Store {
+	return &Store{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// SaveRequestStatus saves status whatever the stored one, bumping its version so that
+// the updates based on a previous version conflict.
+func (s *Store) SaveRequestStatus(ctx context.Context, status model.RequestStatus) error {
+	status.Version++
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("marshalling request status: %w", err)
+	}
+
+	return s.store.Set(ctx, keyPrefix+status.ID, data, s.ttl)
+}
+
+// UpdateRequestStatus saves status if the stored one is still at the version status was
+// read with, or does not exist for a status never saved, and returns
+// model.ErrStatusConflict otherwise.
+func (s *Store) UpdateRequestStatus(ctx context.Context, status model.RequestStatus) error {
+	key := keyPrefix + status.ID
+	current, err := s.store.Get(ctx, key)
+	if errors.Is(err, statestore.ErrNotFound) {
+		current = nil
+	} else if err != nil {
+		return err
+	}
+
+	if current != nil {
+		var stored model.RequestStatus
+		if err := json.Unmarshal(current, &stored); err != nil {
+			return fmt.Errorf("unmarshalling request status: %w", err)
+		}
+		if stored.Version != status.Version {
+			return model.ErrStatusConflict
+		}
+	} else if status.Version != 0 {
+		// Expired since read.
+		return model.ErrStatusConflict
+	}
+
+	status.Version++
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("marshalling request status: %w", err)
+	}
+
+	swapped, err := s.store.CompareAndSwap(ctx, key, current, data, s.ttl)
+	if err != nil {
+		return err
+	}
+	if !swapped {
+		return model.ErrStatusConflict
+	}
+
+	return nil
+}
+
+func (s *Store) GetRequestStatus(ctx context.Context, id string) (model.RequestStatus, error) {
+	data, err := s.store.Get(ctx, keyPrefix+id)
+	if errors.Is(err, statestore.ErrNotFound) {
+		return model.RequestStatus{}, model.ErrRequestNotFound
+	}
+	if err != nil {
+		return model.RequestStatus{}, err
+	}
+
+	var status model.RequestStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return model.RequestStatus{}, fmt.Errorf("unmarshalling request status: %w", err)
+	}
+
+	return status, nil
+}
+
+// ListRequestStatuses returns the status of every request that did not expire, in no
+// particular order.
+func (s *Store) ListRequestStatuses(ctx context.Context) ([]model.RequestStatus, error) {
+	keys, err := s.store.Keys(ctx, keyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("listing request statuses: %w", err)
+	}
+
+	statuses := make([]model.RequestStatus, 0, len(keys))
+	for _, key := range keys {
+		status, err := s.GetRequestStatus(ctx, strings.TrimPrefix(key, keyPrefix))
+		if errors.Is(err, model.ErrRequestNotFound) {
+			// Expired since listed.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// SaveToken keeps the token issued for the request with the given ID until it is taken.
+func (s *Store) SaveToken(ctx context.Context, id, token string) error {
+	return s.store.Set(ctx, tokenKeyPrefix+id, []byte(token), s.ttl)
+}
+
+// TakeToken returns the token issued for the request with the given ID and deletes it,
+// atomically so that concurrent callers cannot all take it. It returns
+// model.ErrTokenAlreadyRetrieved once the token was taken, deleted or expired.
+func (s *Store) TakeToken(ctx context.Context, id string) (string, error) {
+	key := tokenKeyPrefix + id
+	token, err := s.store.Get(ctx, key)
+	if errors.Is(err, statestore.ErrNotFound) {
+		return "", model.ErrTokenAlreadyRetrieved
+	}
+	if err != nil {
+		return "", err
+	}
+
+	deleted, err := s.store.CompareAndDelete(ctx, key, token)
+	if err != nil {
+		return "", err
+	}
+	if !deleted {
+		return "", model.ErrTokenAlreadyRetrieved
+	}
+
+	return string(token), nil
+}
+
+// DeleteToken deletes the token issued for the request with the given ID, if any.
+func (s *Store) DeleteToken(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, tokenKeyPrefix+id)
+}
+
+// SaveIssuedToken records the token issued for status, without the token itself, until
+// the token expires. The record is indexed by the owner of the request.
+func (s *Store) SaveIssuedToken(ctx context.Context, status model.RequestStatus) error {
+	var ttl time.Duration
+	if status.ExpiresAt != nil {
+		if ttl = time.Until(*status.ExpiresAt); ttl <= 0 {
+			return s.DeleteIssuedToken(ctx, status)
+		}
+	}
+
+	status.Events = nil
+	status.Principal = nil
+	status.Version = 0
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("marshalling issued token: %w", err)
+	}
+
+	if err := s.store.Set(ctx, issuedKeyPrefix+status.ID, data, ttl); err != nil {
+		return err
+	}
+
+	return s.store.Set(ctx, ownerKey(status.Owner)+status.ID, []byte{1}, ttl)
+}
+
+// GetIssuedToken returns the record of the token issued for the request with the given
+// ID, or model.ErrRequestNotFound once the token expired or its record was deleted.
+func (s *Store) GetIssuedToken(ctx context.Context, id string) (model.RequestStatus, error) {
+	data, err := s.store.Get(ctx, issuedKeyPrefix+id)
+	if errors.Is(err, statestore.ErrNotFound) {
+		return model.RequestStatus{}, model.ErrRequestNotFound
+	}
+	if err != nil {
+		return model.RequestStatus{}, err
+	}
+
+	var status model.RequestStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return model.RequestStatus{}, fmt.Errorf("unmarshalling issued token: %w", err)
+	}
+
+	return status, nil
+}
+
+// ListIssuedTokens returns the records of the tokens issued for the requests of owner
+// that did not expire, in no particular order.
+func (s *Store) ListIssuedTokens(ctx context.Context, owner string) ([]model.RequestStatus, error) {
+	prefix := ownerKey(owner)
+	keys, err := s.store.Keys(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("listing issued tokens: %w", err)
+	}
+
+	tokens := make([]model.RequestStatus, 0, len(keys))
+	for _, key := range keys {
+		status, err := s.GetIssuedToken(ctx, strings.TrimPrefix(key, prefix))
+		if errors.Is(err, model.ErrRequestNotFound) {
+			// Expired or deleted since listed.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, status)
+	}
+
+	return tokens, nil
+}
+
+// DeleteIssuedToken deletes the record of the token issued for status, if any.
+func (s *Store) DeleteIssuedToken(ctx context.Context, status model.RequestStatus) error {
+	if err := s.store.Delete(ctx, ownerKey(status.Owner)+status.ID); err != nil {
+		return err
+	}
+
+	return s.store.Delete(ctx, issuedKeyPrefix+status.ID)
+}
+
+// ownerKey is the prefix of the index of the tokens issued for owner, escaped so that
+// the prefix of an owner never matches the keys of another.
+func ownerKey(owner string) string {
+	return ownerKeyPrefix + url.PathEscape(owner) + "/"
+}
+
+// CancelRequest flags the request with the given ID as canceled, for the worker to skip
+// it.
+func (s *Store) CancelRequest(ctx context.Context, id string) error {
+	return s.store.Set(ctx, cancellationKeyPrefix+id, []byte{1}, s.ttl)
+}
+
+// RequestCanceled reports whether the request with the given ID was canceled.
+func (s *Store) RequestCanceled(ctx context.Context, id string) (bool, error) {
+	_, err := s.store.Get(ctx, cancellationKeyPrefix+id)
+	if errors.Is(err, statestore.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *Store) SaveRequestBatch(ctx context.Context, batch model.RequestBatch) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("marshalling request batch: %w", err)
+	}
+
+	return s.store.Set(ctx, batchKeyPrefix+batch.ID, data, s.ttl)
+}
+
+func (s *Store) GetRequestBatch(ctx context.Context, id string) (model.RequestBatch, error) {
+	data, err := s.store.Get(ctx, batchKeyPrefix+id)
+	if errors.Is(err, statestore.ErrNotFound) {
+		return model.RequestBatch{}, model.ErrBatchNotFound
+	}
+	if err != nil {
+		return model.RequestBatch{}, err
+	}
+
+	var batch model.RequestBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return model.RequestBatch{}, fmt.Errorf("unmarshalling request batch: %w", err)
+	}
+
+	return batch, nil
+}
@@ -0,0 +1,217 @@
+package requeststore_test
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/statestore"
+	"github.com/werbersondev/token-generator-test/gateway/requeststore"
+)
+
+// stores opens the state stores the request store is tested against.
+var stores = map[string]func(t *testing.T) statestore.Store{
+	"memory": func(t *testing.T) statestore.Store {
+		return statestore.NewMemory()
+	},
+	"redis": func(t *testing.T) statestore.Store {
+		server := miniredis.RunT(t)
+		store, err := statestore.Open("redis://" + server.Addr())
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+		return store
+	},
+}
+
+func TestStore_UpdateRequestStatus(t *testing.T) {
+	tests := []struct {
+		name            string
+		stored          *model.RequestStatus
+		update          model.RequestStatus
+		expectedErr     error
+		expectedVersion int64
+	}{
+		{
+			name:            "New Status",
+			update:          model.RequestStatus{ID: "0123", State: model.RequestStateQueued},
+			expectedVersion: 1,
+		},
+		{
+			name:        "New Status Saved Concurrently",
+			stored:      &model.RequestStatus{ID: "0123", State: model.RequestStateQueued},
+			update:      model.RequestStatus{ID: "0123", State: model.RequestStateQueued},
+			expectedErr: model.ErrStatusConflict,
+		},
+		{
+			name:            "Current Version",
+			stored:          &model.RequestStatus{ID: "0123", State: model.RequestStateQueued},
+			update:          model.RequestStatus{ID: "0123", State: model.RequestStateProcessing, Version: 1},
+			expectedVersion: 2,
+		},
+		{
+			name:        "Stale Version",
+			stored:      &model.RequestStatus{ID: "0123", State: model.RequestStateProcessing, Version: 1},
+			update:      model.RequestStatus{ID: "0123", State: model.RequestStateIssued, Version: 1},
+			expectedErr: model.ErrStatusConflict,
+		},
+		{
+			name:        "Expired Since Read",
+			update:      model.RequestStatus{ID: "0123", State: model.RequestStateIssued, Version: 1},
+			expectedErr: model.ErrStatusConflict,
+		},
+	}
+
+	for name, open := range stores {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				store := requeststore.New(open(t), time.Minute)
+				if tt.stored != nil {
+					require.NoError(t, store.SaveRequestStatus(ctx, *tt.stored))
+				}
+
+				err := store.UpdateRequestStatus(ctx, tt.update)
+
+				status, getErr := store.GetRequestStatus(ctx, "0123")
+				if tt.expectedErr != nil {
+					assert.ErrorIs(t, err, tt.expectedErr)
+					if tt.stored != nil {
+						require.NoError(t, getErr)
+						assert.Equal(t, tt.stored.State, status.State, "the conflicting update is saved")
+					}
+					return
+				}
+
+				require.NoError(t, err)
+				require.NoError(t, getErr)
+				assert.Equal(t, tt.update.State, status.State)
+				assert.Equal(t, tt.expectedVersion, status.Version)
+			})
+		}
+	}
+}
+
+// racingStore runs interleave once, right after the first read of a token, as a
+// concurrent caller would.
+type racingStore struct {
+	statestore.Store
+	interleave func()
+}
+
+func (r *racingStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.Store.Get(ctx, key)
+	if interleave := r.interleave; interleave != nil && strings.HasPrefix(key, "tokens/") {
+		r.interleave = nil
+		interleave()
+	}
+	return value, err
+}
+
+func TestStore_TakeToken(t *testing.T) {
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			racing := &racingStore{Store: open(t)}
+			store := requeststore.New(racing, time.Minute)
+			require.NoError(t, store.SaveToken(ctx, "0123", "sqp_token"))
+
+			// Another caller takes the token between the read of this one and its
+			// deletion.
+			var concurrentToken string
+			var concurrentErr error
+			racing.interleave = func() {
+				concurrentToken, concurrentErr = store.TakeToken(ctx, "0123")
+			}
+
+			_, err := store.TakeToken(ctx, "0123")
+			assert.ErrorIs(t, err, model.ErrTokenAlreadyRetrieved, "the token is taken twice")
+			require.NoError(t, concurrentErr)
+			assert.Equal(t, "sqp_token", concurrentToken)
+
+			_, err = store.TakeToken(ctx, "0123")
+			assert.ErrorIs(t, err, model.ErrTokenAlreadyRetrieved)
+
+			require.NoError(t, store.SaveToken(ctx, "4567", "sqp_other"))
+			require.NoError(t, store.DeleteToken(ctx, "4567"))
+			_, err = store.TakeToken(ctx, "4567")
+			assert.ErrorIs(t, err, model.ErrTokenAlreadyRetrieved, "a deleted token is taken")
+		})
+	}
+}
+
+func TestStore_ListIssuedTokens(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	expired := time.Now().Add(-time.Hour)
+	records := []model.RequestStatus{
+		{ID: "0123", Owner: "jwt:https://issuer.example.com/tenant:alice", State: model.RequestStateIssued, ExpiresAt: &expiresAt},
+		{ID: "4567", Owner: "jwt:https://issuer.example.com:alice", State: model.RequestStateIssued},
+		{ID: "89ab", Owner: "jwt:https://issuer.example.com/tenant:alice", State: model.RequestStateIssued, ExpiresAt: &expired},
+		{ID: "cdef", Owner: "jwt:https://issuer.example.com", State: model.RequestStateIssued},
+	}
+
+	tests := []struct {
+		name        string
+		owner       string
+		expectedIDs []string
+	}{
+		{
+			name:        "Owner With Slashes",
+			owner:       "jwt:https://issuer.example.com/tenant:alice",
+			expectedIDs: []string{"0123"},
+		},
+		{
+			name:        "Owner Sharing A Prefix",
+			owner:       "jwt:https://issuer.example.com:alice",
+			expectedIDs: []string{"4567"},
+		},
+		{
+			name:        "Owner Prefix Of Others",
+			owner:       "jwt:https://issuer.example.com",
+			expectedIDs: []string{"cdef"},
+		},
+		{
+			name:        "Owner Without Tokens",
+			owner:       "jwt:https://issuer.example.com/tenant",
+			expectedIDs: []string{},
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := requeststore.New(open(t), time.Minute)
+			for _, record := range records {
+				require.NoError(t, store.SaveIssuedToken(ctx, record))
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					tokens, err := store.ListIssuedTokens(ctx, tt.owner)
+					require.NoError(t, err)
+
+					ids := make([]string, 0, len(tokens))
+					for _, token := range tokens {
+						assert.Equal(t, tt.owner, token.Owner)
+						ids = append(ids, token.ID)
+					}
+					sort.Strings(ids)
+					assert.Equal(t, tt.expectedIDs, ids)
+				})
+			}
+
+			require.NoError(t, store.DeleteIssuedToken(ctx, records[0]))
+			tokens, err := store.ListIssuedTokens(ctx, records[0].Owner)
+			require.NoError(t, err)
+			assert.Empty(t, tokens, "a deleted record is listed")
+			_, err = store.GetIssuedToken(ctx, records[0].ID)
+			assert.ErrorIs(t, err, model.ErrRequestNotFound)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -13,6 +14,10 @@ import (
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
+	"github.com/werbersondev/token-generator-test/extensions/tracex"
 )
 
 const (
@@ -27,32 +32,67 @@ type Config struct {
 	Timeout   time.Duration
 	BaseURL   string
 	AuthToken string
+	// AuthTokenFile holds the token instead of AuthToken, see Credentials.Watch.
+	AuthTokenFile string
+
+	// Login and Password authenticate with basic auth when no AuthToken is set,
+	// which is only needed to bootstrap an instance that has no token yet.
+	Login    string
+	Password string
+
+	TLS   TLSConfig
+	Proxy ProxyConfig
+
+	// Metrics records the duration and the retries of the calls, when set.
+	Metrics *metricsx.Metrics
 }
 
 type HTTPClient struct {
-	client    *http.Client
-	baseURL   string
-	authToken string
+	client      *http.Client
+	baseURL     string
+	credentials *Credentials
+	login       string
+	password    string
 }
 
-func New(config Config) *HTTPClient {
+func New(config Config) (*HTTPClient, error) {
 	if config.Timeout <= 0 {
 		config.Timeout = defaultTimeout
 	}
 
+	transport, err := newTransport(config.TLS, config.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("configuring transport: %w", err)
+	}
+
 	retryableClient := retryablehttp.NewClient()
+	// Every attempt is traced, so that the retries show in the trace of the request.
 	retryableClient.HTTPClient = &http.Client{
-		Transport: http.DefaultTransport,
+		Transport: tracex.Transport(config.Metrics.Transport(transport)),
 		Timeout:   config.Timeout,
 	}
 
 	retryableClient.Logger = hclog.NewNullLogger()
+	retryableClient.RequestLogHook = func(logger retryablehttp.Logger, req *http.Request, attempt int) {
+		if attempt > 0 {
+			config.Metrics.CountSonarRetry(req.URL.Path)
+		}
+		notifyRetry(logger, req, attempt)
+	}
 
-	return &HTTPClient{
-		client:    retryableClient.StandardClient(),
-		baseURL:   config.BaseURL,
-		authToken: config.AuthToken,
+	httpClient := &HTTPClient{
+		client:   retryableClient.StandardClient(),
+		baseURL:  config.BaseURL,
+		login:    config.Login,
+		password: config.Password,
 	}
+
+	httpClient.credentials, err = newCredentials(config, httpClient.ValidateToken)
+	if err != nil {
+		return nil, fmt.Errorf("loading credentials: %w", err)
+	}
+
+	return httpClient, nil
 }
 
 type TokenGenerationParams struct {
@@ -63,17 +103,38 @@ type TokenGenerationParams struct {
 	Type           string
 }
 
-func (c *HTTPClient) GenerateProjectAnalysisToken(ctx context.Context, projectID, tokenName string) (string, error) {
+// GenerateProjectAnalysisToken generates a token analyzing projectID, expiring on the
+// date of expiresAt unless it is zero.
+func (c *HTTPClient) GenerateProjectAnalysisToken(ctx context.Context, projectID, tokenName string, expiresAt time.Time) (string, error) {
 	return c.GenerateToken(ctx, TokenGenerationParams{
-		Name:       tokenName,
-		ProjectKey: projectID,
-		Type:       ProjectAnalysisTokenType,
+		Name:           tokenName,
+		ExpirationDate: expirationDate(expiresAt),
+		ProjectKey:     projectID,
+		Type:           ProjectAnalysisTokenType,
 	})
 }
 
-func (c *HTTPClient) GenerateToken(ctx context.Context, params TokenGenerationParams) (string, error) {
-	urlTarget := fmt.Sprintf("%s/api/user_tokens/generate", c.baseURL)
+// GenerateGlobalAnalysisToken generates a token analyzing any project, expiring on the
+// date of expiresAt unless it is zero.
+func (c *HTTPClient) GenerateGlobalAnalysisToken(ctx context.Context, tokenName string, expiresAt time.Time) (string, error) {
+	return c.GenerateToken(ctx, TokenGenerationParams{
+		Name:           tokenName,
+		ExpirationDate: expirationDate(expiresAt),
+		Type:           GlobalAnalysisTokenType,
+	})
+}
+
+// expirationDate formats t as expected by Sonar, which expires tokens at the start of
+// a day.
+func expirationDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.DateOnly)
+}
 
+func (c *HTTPClient) GenerateToken(ctx context.Context, params TokenGenerationParams) (string, error) {
 	formData := url.Values{
 		"name": {params.Name},
 	}
@@ -91,17 +152,62 @@ func (c *HTTPClient) GenerateToken(ctx context.Context, params TokenGenerationPa
 		formData.Set("type", params.Type)
 	}
 
+	var response struct {
+		Token string `json:"token"`
+	}
+	if err := c.post(ctx, "/api/user_tokens/generate", formData, &response); err != nil {
+		if isForbidden(err) {
+			return "", fmt.Errorf("%w: %w", model.ErrPermissionDenied, err)
+		}
+		return "", err
+	}
+
+	return response.Token, nil
+}
+
+// get performs a GET request against the Sonar Web API, encoding query as the
+// URL query string and decoding the JSON response into out.
+func (c *HTTPClient) get(ctx context.Context, path string, query url.Values, out any) error {
+	urlTarget := fmt.Sprintf("%s%s", c.baseURL, path)
+	if len(query) > 0 {
+		urlTarget += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlTarget, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	return c.do(req, out)
+}
+
+// post performs a form encoded POST request against the Sonar Web API and decodes
+// the JSON response into out. A nil out discards the response body.
+func (c *HTTPClient) post(ctx context.Context, path string, formData url.Values, out any) error {
+	urlTarget := fmt.Sprintf("%s%s", c.baseURL, path)
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlTarget, strings.NewReader(formData.Encode()))
 	if err != nil {
-		return "", fmt.Errorf("creating request: %w", err)
+		return fmt.Errorf("creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.authToken))
+
+	return c.do(req, out)
+}
+
+func (c *HTTPClient) do(req *http.Request, out any) error {
+	if req.Header.Get("Authorization") == "" {
+		if token := c.credentials.Current().Token; token != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		} else if c.login != "" {
+			req.SetBasicAuth(c.login, c.password)
+		}
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("executing request: %w", err)
+		return fmt.Errorf("executing request: %w", err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -109,17 +215,32 @@ func (c *HTTPClient) GenerateToken(ctx context.Context, params TokenGenerationPa
 		}
 	}()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
 		dumpResponse, _ := httputil.DumpResponse(resp, true)
-		return "", fmt.Errorf("unexpected status code: %d \n dump response: %s ", resp.StatusCode, dumpResponse)
+		return &StatusError{
+			StatusCode: resp.StatusCode,
+			Dump:       dumpResponse,
+		}
 	}
 
-	var response struct {
-		Token string `json:"token"`
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("decoding response body: %w", err)
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response body: %w", err)
 	}
 
-	return response.Token, nil
+	return nil
+}
+
+// StatusError is returned when the Sonar Web API answers with a non 2xx status code.
+type StatusError struct {
+	StatusCode int
+	Dump       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d \n dump response: %s ", e.StatusCode, e.Dump)
 }
@@ -6,10 +6,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/werbersondev/token-generator-test/domain/model"
+	"github.com/werbersondev/token-generator-test/extensions/metricsx"
 )
 
 func TestGenerateToken(t *testing.T) {
@@ -20,6 +26,7 @@ func TestGenerateToken(t *testing.T) {
 		responseBody   string
 		expectedToken  string
 		expectError    bool
+		expectedErr    error
 	}{
 		{
 			name: "successful token generation",
@@ -45,6 +52,18 @@ func TestGenerateToken(t *testing.T) {
 			expectedToken:  "",
 			expectError:    true,
 		},
+		{
+			name: "token generation refused",
+			params: TokenGenerationParams{
+				Name:       "test-token",
+				ProjectKey: "project-id",
+				Type:       ProjectAnalysisTokenType,
+			},
+			responseStatus: http.StatusForbidden,
+			responseBody:   `{"errors": [{"msg": "Insufficient privileges"}]}`,
+			expectError:    true,
+			expectedErr:    model.ErrPermissionDenied,
+		},
 	}
 
 	for _, tt := range tests {
@@ -78,17 +97,21 @@ func TestGenerateToken(t *testing.T) {
 			}))
 			defer server.Close()
 
-			client := New(Config{
+			client, err := New(Config{
 				BaseURL:   server.URL,
 				AuthToken: "dummy-token",
 				Timeout:   5 * time.Second,
 			})
+			assert.NoError(t, err)
 
 			ctx := context.Background()
 			token, err := client.GenerateToken(ctx, tt.params)
 
 			if tt.expectError {
 				assert.Error(t, err)
+				if tt.expectedErr != nil {
+					assert.ErrorIs(t, err, tt.expectedErr)
+				}
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expectedToken, token)
@@ -121,15 +144,109 @@ func TestGenerateProjectAnalysisToken(t *testing.T) {
 	}))
 	defer server.Close()
 
-	client := New(Config{
+	client, err := New(Config{
 		BaseURL:   server.URL,
 		AuthToken: "dummy-token",
 		Timeout:   5 * time.Second,
 	})
+	assert.NoError(t, err)
 
 	ctx := context.Background()
-	token, err := client.GenerateProjectAnalysisToken(ctx, "project-id", "test-token")
+	token, err := client.GenerateProjectAnalysisToken(ctx, "project-id", "test-token", time.Time{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "generated-token", token)
+}
+
+func TestGenerateGlobalAnalysisToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, url.Values{
+			"name":           {"test-token"},
+			"expirationDate": {"2024-07-01"},
+			"type":           {GlobalAnalysisTokenType},
+		}, r.PostForm)
+
+		_, _ = w.Write([]byte(`{"token": "generated-token"}`))
+	}))
+	defer server.Close()
+
+	client, err := New(Config{
+		BaseURL:   server.URL,
+		AuthToken: "dummy-token",
+		Timeout:   5 * time.Second,
+	})
+	assert.NoError(t, err)
+
+	expiresAt := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	token, err := client.GenerateGlobalAnalysisToken(context.Background(), "test-token", expiresAt)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "generated-token", token)
 }
+
+func TestGenerateToken_RetryObserver(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"token": "generated-token"}`))
+	}))
+	defer server.Close()
+
+	client, err := New(Config{
+		BaseURL:   server.URL,
+		AuthToken: "dummy-token",
+		Timeout:   5 * time.Second,
+	})
+	assert.NoError(t, err)
+
+	var attempts []int
+	ctx := ContextWithRetryObserver(context.Background(), func(ctx context.Context, attempt int) {
+		attempts = append(attempts, attempt)
+	})
+	token, err := client.GenerateProjectAnalysisToken(ctx, "project-id", "test-token", time.Time{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "generated-token", token)
+	assert.Equal(t, []int{1}, attempts)
+}
+
+func TestGenerateToken_Metrics(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"token": "generated-token"}`))
+	}))
+	defer server.Close()
+
+	metrics := metricsx.New()
+	client, err := New(Config{
+		BaseURL:   server.URL,
+		AuthToken: "dummy-token",
+		Timeout:   5 * time.Second,
+		Metrics:   metrics,
+	})
+	require.NoError(t, err)
+
+	_, err = client.GenerateProjectAnalysisToken(context.Background(), "project-id", "test-token", time.Time{})
+	require.NoError(t, err)
+
+	expected := `
+# HELP tokengen_sonar_retries_total Calls to the Sonar Web API retried, by endpoint.
+# TYPE tokengen_sonar_retries_total counter
+tokengen_sonar_retries_total{endpoint="/api/user_tokens/generate"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(metrics.Registry(), strings.NewReader(expected), "tokengen_sonar_retries_total"))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.Registry(), "tokengen_sonar_request_duration_seconds"))
+}
@@ -0,0 +1,198 @@
+package sonarclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/extensions/filex"
+)
+
+const (
+	defaultCredentialReloadInterval = 10 * time.Second
+
+	CredentialSourceStatic = "static"
+	CredentialSourceFile   = "file"
+)
+
+// ErrInvalidCredential is returned when Sonar does not accept a token.
+var ErrInvalidCredential = errors.New("sonar rejected the credential")
+
+// Credential is the token the client authenticates with.
+type Credential struct {
+	Token       string
+	Source      string
+	ActivatedAt time.Time
+}
+
+// Fingerprint identifies the credential without disclosing it.
+func (c Credential) Fingerprint() string {
+	if c.Token == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(c.Token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Age is how long the credential has been in use.
+func (c Credential) Age() time.Duration {
+	return time.Since(c.ActivatedAt)
+}
+
+// Credentials holds the active credential of a client. When backed by a file, Watch
+// reloads it whenever the file changes; a new token replaces the active one only
+// once Sonar validated it, so a bad rotation never locks the client out.
+type Credentials struct {
+	path     string
+	watcher  *filex.Watcher
+	validate func(ctx context.Context, token string) error
+
+	mu      sync.RWMutex
+	current Credential
+}
+
+// Current returns the active credential.
+func (c *Credentials) Current() Credential {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.current
+}
+
+// Activate makes token the active credential, without validating it.
+func (c *Credentials) Activate(token, source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current = Credential{
+		Token:       token,
+		Source:      source,
+		ActivatedAt: time.Now(),
+	}
+}
+
+// Watch reloads the credential file whenever it changes until ctx is done.
+// It returns immediately when the credentials are not backed by a file.
+func (c *Credentials) Watch(ctx context.Context, interval time.Duration) {
+	if c.path == "" {
+		return
+	}
+
+	if interval <= 0 {
+		interval = defaultCredentialReloadInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !c.watcher.Changed() {
+				continue
+			}
+
+			if err := c.Reload(ctx); err != nil {
+				log.Ctx(ctx).Error().Err(err).Str("path", c.path).
+					Str("active_fingerprint", c.Current().Fingerprint()).
+					Msg("keeping active sonar credential")
+			}
+		}
+	}
+}
+
+// Reload reads the credential file and activates its token once Sonar validated it.
+func (c *Credentials) Reload(ctx context.Context) error {
+	token, err := readTokenFile(c.path)
+	if err != nil {
+		return err
+	}
+
+	if token == c.Current().Token {
+		return nil
+	}
+
+	if err := c.validate(ctx, token); err != nil {
+		return fmt.Errorf("validating reloaded credential: %w", err)
+	}
+
+	c.Activate(token, CredentialSourceFile)
+	log.Ctx(ctx).Info().Str("fingerprint", c.Current().Fingerprint()).Msg("sonar credential reloaded")
+
+	return nil
+}
+
+// ValidateToken checks against Sonar that token authenticates a user.
+func (c *HTTPClient) ValidateToken(ctx context.Context, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/authentication/validate", nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	var response struct {
+		Valid bool `json:"valid"`
+	}
+	if err := c.do(req, &response); err != nil {
+		return fmt.Errorf("validating credential: %w", err)
+	}
+
+	if !response.Valid {
+		return ErrInvalidCredential
+	}
+
+	return nil
+}
+
+// Credentials returns the credentials the client authenticates with.
+func (c *HTTPClient) Credentials() *Credentials {
+	return c.credentials
+}
+
+func newCredentials(config Config, validate func(ctx context.Context, token string) error) (*Credentials, error) {
+	credentials := &Credentials{validate: validate}
+
+	if config.AuthTokenFile == "" {
+		if config.AuthToken != "" {
+			credentials.Activate(config.AuthToken, CredentialSourceStatic)
+		}
+		return credentials, nil
+	}
+
+	token, err := readTokenFile(config.AuthTokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials.path = config.AuthTokenFile
+	credentials.watcher = filex.NewWatcher(0, config.AuthTokenFile)
+	credentials.Activate(token, CredentialSourceFile)
+
+	return credentials, nil
+}
+
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading credential file: %w", err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("credential file %s is empty", path)
+	}
+
+	return token, nil
+}
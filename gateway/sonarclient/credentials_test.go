@@ -0,0 +1,72 @@
+package sonarclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentials_Reload(t *testing.T) {
+	var usedTokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/authentication/validate":
+			valid := r.Header.Get("Authorization") == "Bearer rotated-token"
+			if valid {
+				_, _ = w.Write([]byte(`{"valid": true}`))
+			} else {
+				_, _ = w.Write([]byte(`{"valid": false}`))
+			}
+		case "/api/user_tokens/generate":
+			usedTokens = append(usedTokens, r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`{"token": "generated-token"}`))
+		}
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "sonar-token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("initial-token\n"), 0o600))
+
+	client, err := New(Config{
+		BaseURL:       server.URL,
+		AuthTokenFile: tokenFile,
+		Timeout:       5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	credentials := client.Credentials()
+	assert.Equal(t, "initial-token", credentials.Current().Token)
+	assert.Equal(t, CredentialSourceFile, credentials.Current().Source)
+
+	// An invalid token is never activated.
+	require.NoError(t, os.WriteFile(tokenFile, []byte("bad-token"), 0o600))
+	err = credentials.Reload(context.Background())
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+	assert.Equal(t, "initial-token", credentials.Current().Token)
+
+	require.NoError(t, os.WriteFile(tokenFile, []byte("rotated-token"), 0o600))
+	err = credentials.Reload(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "rotated-token", credentials.Current().Token)
+
+	_, err = client.GenerateProjectAnalysisToken(context.Background(), "project-id", "token", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Bearer rotated-token"}, usedTokens)
+}
+
+func TestCredential_Fingerprint(t *testing.T) {
+	first := Credential{Token: "first-token"}
+	second := Credential{Token: "second-token"}
+
+	assert.Len(t, first.Fingerprint(), 16)
+	assert.NotContains(t, first.Fingerprint(), "first-token")
+	assert.NotEqual(t, first.Fingerprint(), second.Fingerprint())
+	assert.Empty(t, Credential{}.Fingerprint())
+}
@@ -0,0 +1,185 @@
+package sonarclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+const (
+	// ScanPermission is the Sonar key of the "Execute Analysis" permission.
+	ScanPermission = "scan"
+
+	// anyoneGroup is the Sonar built-in group every user implicitly belongs to.
+	anyoneGroup = "Anyone"
+
+	permissionsPageSize = 100
+)
+
+type User struct {
+	Login       string   `json:"login"`
+	Groups      []string `json:"groups"`
+	Permissions struct {
+		Global []string `json:"global"`
+	} `json:"permissions"`
+}
+
+type paging struct {
+	PageIndex int `json:"pageIndex"`
+	PageSize  int `json:"pageSize"`
+	Total     int `json:"total"`
+}
+
+// hasNext reports whether a page follows the one holding items. Listing stops on an
+// empty page or a page size of zero, which would otherwise never reach the total.
+func (p paging) hasNext(items int) bool {
+	return items > 0 && p.PageSize > 0 && p.PageIndex*p.PageSize < p.Total
+}
+
+// CurrentUser returns the user owning the configured authentication token.
+func (c *HTTPClient) CurrentUser(ctx context.Context) (User, error) {
+	var user User
+	if err := c.get(ctx, "/api/users/current", nil, &user); err != nil {
+		return User{}, fmt.Errorf("getting current user: %w", err)
+	}
+
+	return user, nil
+}
+
+// HasProjectAnalysisPermission reports whether the user owning the configured
+// authentication token may execute analysis on the given project, either globally,
+// directly or through one of its groups. Users not allowed to administer the project
+// cannot look its permissions up, and are reported as allowed.
+func (c *HTTPClient) HasProjectAnalysisPermission(ctx context.Context, projectID string) (bool, error) {
+	user, err := c.CurrentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if slices.Contains(user.Permissions.Global, ScanPermission) {
+		return true, nil
+	}
+
+	logins, err := c.ProjectUsersWithPermission(ctx, projectID, ScanPermission)
+	if isForbidden(err) {
+		// Listing the permissions of a project is reserved to its administrators. Sonar
+		// refuses to generate project analysis tokens to users lacking the permission
+		// anyway, so users only holding it are left to that check.
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if slices.Contains(logins, user.Login) {
+		return true, nil
+	}
+
+	groups, err := c.ProjectGroupsWithPermission(ctx, projectID, ScanPermission)
+	if err != nil {
+		return false, err
+	}
+	for _, group := range groups {
+		if group == anyoneGroup || slices.Contains(user.Groups, group) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GrantProjectAnalysisPermission grants the "Execute Analysis" permission on the given
+// project to the user owning the configured authentication token.
+func (c *HTTPClient) GrantProjectAnalysisPermission(ctx context.Context, projectID string) error {
+	user, err := c.CurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.AddUserPermission(ctx, projectID, user.Login, ScanPermission)
+}
+
+// ProjectUsersWithPermission lists the logins holding the permission on the project.
+func (c *HTTPClient) ProjectUsersWithPermission(ctx context.Context, projectID, permission string) ([]string, error) {
+	var logins []string
+	for page := 1; ; page++ {
+		var response struct {
+			Paging paging `json:"paging"`
+			Users  []struct {
+				Login string `json:"login"`
+			} `json:"users"`
+		}
+
+		query := url.Values{
+			"projectKey": {projectID},
+			"permission": {permission},
+			"p":          {strconv.Itoa(page)},
+			"ps":         {strconv.Itoa(permissionsPageSize)},
+		}
+		if err := c.get(ctx, "/api/permissions/users", query, &response); err != nil {
+			return nil, fmt.Errorf("listing project users permissions: %w", err)
+		}
+
+		for _, user := range response.Users {
+			logins = append(logins, user.Login)
+		}
+
+		if !response.Paging.hasNext(len(response.Users)) {
+			return logins, nil
+		}
+	}
+}
+
+// ProjectGroupsWithPermission lists the group names holding the permission on the project.
+func (c *HTTPClient) ProjectGroupsWithPermission(ctx context.Context, projectID, permission string) ([]string, error) {
+	var groups []string
+	for page := 1; ; page++ {
+		var response struct {
+			Paging paging `json:"paging"`
+			Groups []struct {
+				Name string `json:"name"`
+			} `json:"groups"`
+		}
+
+		query := url.Values{
+			"projectKey": {projectID},
+			"permission": {permission},
+			"p":          {strconv.Itoa(page)},
+			"ps":         {strconv.Itoa(permissionsPageSize)},
+		}
+		if err := c.get(ctx, "/api/permissions/groups", query, &response); err != nil {
+			return nil, fmt.Errorf("listing project groups permissions: %w", err)
+		}
+
+		for _, group := range response.Groups {
+			groups = append(groups, group.Name)
+		}
+
+		if !response.Paging.hasNext(len(response.Groups)) {
+			return groups, nil
+		}
+	}
+}
+
+// AddUserPermission grants the permission to the user on the project.
+func (c *HTTPClient) AddUserPermission(ctx context.Context, projectID, login, permission string) error {
+	formData := url.Values{
+		"projectKey": {projectID},
+		"login":      {login},
+		"permission": {permission},
+	}
+
+	if err := c.post(ctx, "/api/permissions/add_user", formData, nil); err != nil {
+		return fmt.Errorf("adding user permission: %w", err)
+	}
+
+	return nil
+}
+
+func isForbidden(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusForbidden
+}
@@ -0,0 +1,189 @@
+package sonarclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasProjectAnalysisPermission(t *testing.T) {
+	tests := []struct {
+		name            string
+		currentUser     string
+		usersStatus     int
+		usersResponse   string
+		groupsResponse  string
+		expectedAllowed bool
+	}{
+		{
+			name:            "global analysis permission",
+			currentUser:     `{"login": "svc", "groups": [], "permissions": {"global": ["scan"]}}`,
+			expectedAllowed: true,
+		},
+		{
+			name:            "direct project permission",
+			currentUser:     `{"login": "svc", "groups": [], "permissions": {"global": []}}`,
+			usersResponse:   `{"paging": {"pageIndex": 1, "pageSize": 100, "total": 2}, "users": [{"login": "other"}, {"login": "svc"}]}`,
+			expectedAllowed: true,
+		},
+		{
+			name:            "group project permission",
+			currentUser:     `{"login": "svc", "groups": ["ci-bots"], "permissions": {"global": []}}`,
+			usersResponse:   `{"paging": {"pageIndex": 1, "pageSize": 100, "total": 0}, "users": []}`,
+			groupsResponse:  `{"paging": {"pageIndex": 1, "pageSize": 100, "total": 1}, "groups": [{"name": "ci-bots"}]}`,
+			expectedAllowed: true,
+		},
+		{
+			name:            "missing permission",
+			currentUser:     `{"login": "svc", "groups": ["developers"], "permissions": {"global": []}}`,
+			usersResponse:   `{"paging": {"pageIndex": 1, "pageSize": 100, "total": 1}, "users": [{"login": "other"}]}`,
+			groupsResponse:  `{"paging": {"pageIndex": 1, "pageSize": 100, "total": 1}, "groups": [{"name": "sonar-administrators"}]}`,
+			expectedAllowed: false,
+		},
+		{
+			name:            "permissions not visible to the user",
+			currentUser:     `{"login": "svc", "groups": [], "permissions": {"global": []}}`,
+			usersStatus:     http.StatusForbidden,
+			usersResponse:   `{"errors": [{"msg": "Insufficient privileges"}]}`,
+			expectedAllowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer dummy-token", r.Header.Get("Authorization"))
+				assert.Equal(t, http.MethodGet, r.Method)
+
+				switch r.URL.Path {
+				case "/api/users/current":
+					_, _ = w.Write([]byte(tt.currentUser))
+				case "/api/permissions/users":
+					assert.Equal(t, "project-id", r.URL.Query().Get("projectKey"))
+					assert.Equal(t, ScanPermission, r.URL.Query().Get("permission"))
+					if tt.usersStatus != 0 {
+						w.WriteHeader(tt.usersStatus)
+					}
+					_, _ = w.Write([]byte(tt.usersResponse))
+				case "/api/permissions/groups":
+					assert.Equal(t, "project-id", r.URL.Query().Get("projectKey"))
+					assert.Equal(t, ScanPermission, r.URL.Query().Get("permission"))
+					_, _ = w.Write([]byte(tt.groupsResponse))
+				default:
+					t.Errorf("unexpected request path %s", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			client, err := New(Config{
+				BaseURL:   server.URL,
+				AuthToken: "dummy-token",
+				Timeout:   5 * time.Second,
+			})
+			assert.NoError(t, err)
+
+			allowed, err := client.HasProjectAnalysisPermission(context.Background(), "project-id")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedAllowed, allowed)
+		})
+	}
+}
+
+func TestGrantProjectAnalysisPermission(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/users/current":
+			_, _ = w.Write([]byte(`{"login": "svc"}`))
+		case "/api/permissions/add_user":
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+
+			bodyBytes, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, "login=svc&permission=scan&projectKey=project-id", string(bodyBytes))
+
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, err := New(Config{
+		BaseURL:   server.URL,
+		AuthToken: "dummy-token",
+		Timeout:   5 * time.Second,
+	})
+	assert.NoError(t, err)
+
+	err = client.GrantProjectAnalysisPermission(context.Background(), "project-id")
+
+	assert.NoError(t, err)
+}
+
+func TestProjectUsersWithPermission_Paging(t *testing.T) {
+	tests := []struct {
+		name           string
+		pages          []string
+		expectedLogins []string
+	}{
+		{
+			name: "several pages",
+			pages: []string{
+				`{"paging": {"pageIndex": 1, "pageSize": 1, "total": 2}, "users": [{"login": "first"}]}`,
+				`{"paging": {"pageIndex": 2, "pageSize": 1, "total": 2}, "users": [{"login": "second"}]}`,
+			},
+			expectedLogins: []string{"first", "second"},
+		},
+		{
+			name: "zero page size",
+			pages: []string{
+				`{"paging": {"pageIndex": 1, "pageSize": 0, "total": 5}, "users": [{"login": "first"}]}`,
+			},
+			expectedLogins: []string{"first"},
+		},
+		{
+			name: "empty page before the total",
+			pages: []string{
+				`{"paging": {"pageIndex": 1, "pageSize": 1, "total": 5}, "users": [{"login": "first"}]}`,
+				`{"paging": {"pageIndex": 2, "pageSize": 1, "total": 5}, "users": []}`,
+			},
+			expectedLogins: []string{"first"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests >= len(tt.pages) {
+					t.Errorf("unexpected request for page %s", r.URL.Query().Get("p"))
+					_, _ = w.Write([]byte(`{"paging": {}, "users": []}`))
+					return
+				}
+				_, _ = w.Write([]byte(tt.pages[requests]))
+				requests++
+			}))
+			defer server.Close()
+
+			client, err := New(Config{
+				BaseURL:   server.URL,
+				AuthToken: "dummy-token",
+				Timeout:   5 * time.Second,
+			})
+			assert.NoError(t, err)
+
+			logins, err := client.ProjectUsersWithPermission(context.Background(), "project-id", ScanPermission)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedLogins, logins)
+			assert.Equal(t, len(tt.pages), requests)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package sonarclient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// ProjectExists reports whether a project with the given key exists.
+func (c *HTTPClient) ProjectExists(ctx context.Context, projectKey string) (bool, error) {
+	var response struct {
+		Components []struct {
+			Key string `json:"key"`
+		} `json:"components"`
+	}
+	if err := c.get(ctx, "/api/projects/search", url.Values{"projects": {projectKey}}, &response); err != nil {
+		return false, fmt.Errorf("searching projects: %w", err)
+	}
+
+	for _, component := range response.Components {
+		if component.Key == projectKey {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// CreateProject creates a project with the given key and display name.
+func (c *HTTPClient) CreateProject(ctx context.Context, projectKey, name string) error {
+	formData := url.Values{
+		"project": {projectKey},
+		"name":    {name},
+	}
+
+	if err := c.post(ctx, "/api/projects/create", formData, nil); err != nil {
+		return fmt.Errorf("creating project: %w", err)
+	}
+
+	return nil
+}
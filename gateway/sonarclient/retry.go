@@ -0,0 +1,35 @@
+package sonarclient
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+type retryObserverKey struct{}
+
+// RetryObserver is notified before a request to Sonar is retried, with the number of
+// the attempt starting at 1 for the first retry.
+type RetryObserver func(ctx context.Context, attempt int)
+
+// ContextWithRetryObserver returns a context notifying observer of the retries of the
+// requests made with it.
+func ContextWithRetryObserver(ctx context.Context, observer RetryObserver) context.Context {
+	return context.WithValue(ctx, retryObserverKey{}, observer)
+}
+
+// notifyRetry is the request hook of the retryable client, attempt being 0 for the
+// first try.
+func notifyRetry(_ retryablehttp.Logger, req *http.Request, attempt int) {
+	if attempt == 0 {
+		return
+	}
+
+	observer, ok := req.Context().Value(retryObserverKey{}).(RetryObserver)
+	if !ok {
+		return
+	}
+
+	observer(req.Context(), attempt)
+}
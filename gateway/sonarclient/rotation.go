@@ -0,0 +1,238 @@
+package sonarclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/werbersondev/token-generator-test/extensions/filex"
+)
+
+const credentialFileMode = 0o600
+
+type RotationConfig struct {
+	// Interval between two rotations of the service token.
+	Interval time.Duration
+	// RevokeDelay is how long superseded service tokens remain valid after a rotation,
+	// leaving other replicas sharing the credential file time to reload it.
+	RevokeDelay time.Duration
+	// OnRotate, when set, is called with the name of the credential activated by each
+	// rotation, to audit it.
+	OnRotate func(ctx context.Context, name string)
+}
+
+// revokeRetryDelay is how long the rotator waits to revoke superseded tokens again
+// after a failure.
+const revokeRetryDelay = time.Minute
+
+// CredentialRotator periodically replaces the file backed credential of a client with
+// a new user token of the same user, and revokes the service tokens it superseded
+// afterwards.
+//
+// Every step is ordered so that a failure leaves a working credential in place: the
+// new token is validated before being persisted, persisted before being activated,
+// and old tokens are only revoked with the new one active.
+type CredentialRotator struct {
+	client *HTTPClient
+	config RotationConfig
+
+	// activeName is the name of the token activated by the last rotation. The name of
+	// the token loaded at startup is unknown.
+	activeName string
+}
+
+func NewCredentialRotator(client *HTTPClient, config RotationConfig) (*CredentialRotator, error) {
+	if client.credentials.path == "" {
+		return nil, errors.New("credential rotation requires a credential file")
+	}
+	if config.Interval <= 0 {
+		return nil, errors.New("credential rotation interval must be positive")
+	}
+
+	return &CredentialRotator{
+		client: client,
+		config: config,
+	}, nil
+}
+
+// Run rotates the credential every interval and revokes each superseded token once the
+// revoke delay elapsed since it was superseded, until ctx is done.
+func (r *CredentialRotator) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	var revokeC <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Rotate(ctx); err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("rotating sonar credential")
+				continue
+			}
+			revokeC = r.revokeSuperseded(ctx)
+		case <-revokeC:
+			revokeC = r.revokeSuperseded(ctx)
+		}
+	}
+}
+
+// revokeSuperseded revokes the superseded tokens due, and returns when the next ones
+// are, or nil when none is pending.
+func (r *CredentialRotator) revokeSuperseded(ctx context.Context) <-chan time.Time {
+	next, err := r.RevokeSuperseded(ctx)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("revoking superseded sonar credentials")
+		return time.After(revokeRetryDelay)
+	}
+	if next.IsZero() {
+		return nil
+	}
+
+	return time.After(time.Until(next))
+}
+
+// Rotate mints a new user token for the user owning the active credential, persists
+// it to the credential file and switches the client over to it.
+func (r *CredentialRotator) Rotate(ctx context.Context) error {
+	user, err := r.client.CurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	name := ServiceTokenName(user.Login, time.Now())
+	token, err := r.client.GenerateToken(ctx, TokenGenerationParams{
+		Name:  name,
+		Login: user.Login,
+		Type:  UserTokenType,
+	})
+	if err != nil {
+		return fmt.Errorf("generating new credential: %w", err)
+	}
+
+	if err := r.client.ValidateToken(ctx, token); err != nil {
+		return r.rollback(ctx, user.Login, name, fmt.Errorf("validating new credential: %w", err))
+	}
+
+	credentials := r.client.credentials
+	if err := filex.WriteFileAtomic(credentials.path, []byte(token+"\n"), credentialFileMode); err != nil {
+		return r.rollback(ctx, user.Login, name, fmt.Errorf("persisting new credential: %w", err))
+	}
+
+	credentials.Activate(token, CredentialSourceFile)
+	r.activeName = name
+
+	log.Ctx(ctx).Info().Str("name", name).
+		Str("fingerprint", credentials.Current().Fingerprint()).
+		Msg("sonar credential rotated")
+	if r.config.OnRotate != nil {
+		r.config.OnRotate(ctx, name)
+	}
+
+	return nil
+}
+
+// RevokeSuperseded revokes the service tokens of the user superseded for longer than the
+// revoke delay, and returns when the next superseded token is due, or the zero time when
+// none is pending. A service token is superseded once a newer one is created, up to the
+// token activated by the last rotation. They are listed from Sonar, so that the token
+// loaded at startup and the ones superseded before a restart are revoked too. Nothing is
+// revoked until a rotation succeeded, as the active token is not known before.
+func (r *CredentialRotator) RevokeSuperseded(ctx context.Context) (time.Time, error) {
+	if r.activeName == "" {
+		return time.Time{}, nil
+	}
+
+	user, err := r.client.CurrentUser(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	tokens, err := r.serviceTokens(ctx, user.Login)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var next time.Time
+	now := time.Now()
+	for i := 0; i < len(tokens)-1; i++ {
+		name := tokens[i].name
+		// The token is superseded by the next one, created after it.
+		revokeAt := tokens[i+1].createdAt.Add(r.config.RevokeDelay)
+		if revokeAt.After(now) {
+			if next.IsZero() || revokeAt.Before(next) {
+				next = revokeAt
+			}
+			continue
+		}
+
+		if err := r.client.RevokeToken(ctx, user.Login, name); err != nil && !isNotFound(err) {
+			return time.Time{}, err
+		}
+
+		log.Ctx(ctx).Info().Str("name", name).Msg("superseded sonar credential revoked")
+	}
+
+	return next, nil
+}
+
+// serviceToken is a service token of the rotated user.
+type serviceToken struct {
+	name      string
+	createdAt time.Time
+}
+
+// serviceTokens returns the service tokens of login created up to the active one, the
+// oldest first and the active one last. Newer tokens, minted by other replicas, and the
+// tokens not named by ServiceTokenName are left out.
+func (r *CredentialRotator) serviceTokens(ctx context.Context, login string) ([]serviceToken, error) {
+	activeCreatedAt, ok := serviceTokenCreatedAt(login, r.activeName)
+	if !ok {
+		return nil, fmt.Errorf("active credential %s is not a service token of %s", r.activeName, login)
+	}
+
+	userTokens, err := r.client.SearchTokens(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []serviceToken
+	for _, token := range userTokens {
+		if token.Name == r.activeName {
+			continue
+		}
+		createdAt, ok := serviceTokenCreatedAt(login, token.Name)
+		if !ok || createdAt.After(activeCreatedAt) {
+			continue
+		}
+		tokens = append(tokens, serviceToken{name: token.Name, createdAt: createdAt})
+	}
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].createdAt.Before(tokens[j].createdAt)
+	})
+
+	return append(tokens, serviceToken{name: r.activeName, createdAt: activeCreatedAt}), nil
+}
+
+// rollback revokes a token minted by a failed rotation, still authenticated with the
+// active credential, and returns the rotation error.
+func (r *CredentialRotator) rollback(ctx context.Context, login, name string, rotationErr error) error {
+	if err := r.client.RevokeToken(ctx, login, name); err != nil {
+		return errors.Join(rotationErr, fmt.Errorf("rolling back new credential %s: %w", name, err))
+	}
+
+	return rotationErr
+}
+
+// isNotFound reports whether err is a 404 of the Sonar Web API, returned when revoking
+// a token that no longer exists.
+func isNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,272 @@
+package sonarclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTokenServer emulates the Sonar user tokens API for a single user.
+type fakeTokenServer struct {
+	mu        sync.Mutex
+	tokens    map[string]string // token value -> name
+	generated int
+}
+
+func (f *fakeTokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if _, ok := f.tokens[token]; !ok {
+		if r.URL.Path == "/api/authentication/validate" {
+			_, _ = w.Write([]byte(`{"valid": false}`))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api/authentication/validate":
+		_, _ = w.Write([]byte(`{"valid": true}`))
+	case "/api/users/current":
+		_, _ = w.Write([]byte(`{"login": "svc"}`))
+	case "/api/user_tokens/generate":
+		name := r.FormValue("name")
+		f.generated++
+		value := fmt.Sprintf("token-%d", f.generated)
+		f.tokens[value] = name
+		_, _ = fmt.Fprintf(w, `{"token": %q}`, value)
+	case "/api/user_tokens/search":
+		var entries []string
+		for _, name := range f.tokens {
+			entries = append(entries, fmt.Sprintf(`{"name": %q, "type": "USER_TOKEN"}`, name))
+		}
+		_, _ = fmt.Fprintf(w, `{"userTokens": [%s]}`, strings.Join(entries, ","))
+	case "/api/user_tokens/revoke":
+		status := http.StatusNotFound
+		for value, name := range f.tokens {
+			if name == r.FormValue("name") {
+				delete(f.tokens, value)
+				status = http.StatusNoContent
+			}
+		}
+		w.WriteHeader(status)
+	}
+}
+
+func (f *fakeTokenServer) names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var names []string
+	for _, name := range f.tokens {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestCredentialRotator_Rotate(t *testing.T) {
+	bootstrapName := ServiceTokenName("svc", time.Now().Add(-time.Hour))
+	sonar := &fakeTokenServer{tokens: map[string]string{
+		"initial-token":     bootstrapName,
+		"analysis-token":    "project-analysis",
+		"manual-token":      "svc-service-manual",
+		"other-user-prefix": ServiceTokenName("other", time.Now().Add(-time.Hour)),
+	}}
+	server := httptest.NewServer(sonar)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "sonar-token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("initial-token"), 0o600))
+
+	client, err := New(Config{BaseURL: server.URL, AuthTokenFile: tokenFile, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	var rotatedNames []string
+	rotator, err := NewCredentialRotator(client, RotationConfig{
+		Interval: time.Hour,
+		OnRotate: func(ctx context.Context, name string) { rotatedNames = append(rotatedNames, name) },
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, rotator.Rotate(context.Background()))
+
+	rotated := client.Credentials().Current().Token
+	assert.NotEqual(t, "initial-token", rotated)
+	assert.Equal(t, []string{rotator.activeName}, rotatedNames)
+
+	persisted, err := os.ReadFile(tokenFile)
+	require.NoError(t, err)
+	assert.Equal(t, rotated+"\n", string(persisted))
+
+	info, err := os.Stat(tokenFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// The token loaded at startup is a service token superseded by the rotation, the
+	// tokens of other users and the ones not named as service tokens are left alone.
+	next, err := rotator.RevokeSuperseded(context.Background())
+	require.NoError(t, err)
+	assert.True(t, next.IsZero())
+	assert.ElementsMatch(t, []string{rotator.activeName, "project-analysis", "svc-service-manual", sonar.tokens["other-user-prefix"]}, sonar.names())
+
+	superseded := rotator.activeName
+	require.NoError(t, rotator.Rotate(context.Background()))
+	require.NotEqual(t, superseded, rotator.activeName)
+
+	_, err = rotator.RevokeSuperseded(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{rotator.activeName, "project-analysis", "svc-service-manual", sonar.tokens["other-user-prefix"]}, sonar.names())
+}
+
+func TestCredentialRotator_RevokeSupersededDelay(t *testing.T) {
+	now := time.Now()
+	sonar := &fakeTokenServer{tokens: map[string]string{
+		"oldest-token":  ServiceTokenName("svc", now.Add(-3*time.Hour)),
+		"initial-token": ServiceTokenName("svc", now.Add(-90*time.Minute)),
+	}}
+	server := httptest.NewServer(sonar)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "sonar-token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("initial-token"), 0o600))
+
+	client, err := New(Config{BaseURL: server.URL, AuthTokenFile: tokenFile, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	rotator, err := NewCredentialRotator(client, RotationConfig{Interval: time.Hour, RevokeDelay: time.Hour})
+	require.NoError(t, err)
+
+	// Nothing is revoked before a rotation, the active token is not known.
+	next, err := rotator.RevokeSuperseded(context.Background())
+	require.NoError(t, err)
+	assert.True(t, next.IsZero())
+	assert.Len(t, sonar.names(), 2)
+
+	require.NoError(t, rotator.Rotate(context.Background()))
+	activatedAt, ok := serviceTokenCreatedAt("svc", rotator.activeName)
+	require.True(t, ok)
+
+	// The oldest token was superseded 90 minutes ago, by the initial one, which was
+	// only superseded by the rotation and is revoked an hour after it.
+	next, err = rotator.RevokeSuperseded(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, activatedAt.Add(time.Hour), next)
+	assert.ElementsMatch(t, []string{sonar.tokens["initial-token"], rotator.activeName}, sonar.names())
+}
+
+func TestCredentialRotator_RevokeSupersededAlreadyRevoked(t *testing.T) {
+	sonar := &fakeTokenServer{tokens: map[string]string{
+		"initial-token": ServiceTokenName("svc", time.Now().Add(-time.Hour)),
+	}}
+	server := httptest.NewServer(sonar)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "sonar-token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("initial-token"), 0o600))
+
+	client, err := New(Config{BaseURL: server.URL, AuthTokenFile: tokenFile, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	rotator, err := NewCredentialRotator(client, RotationConfig{Interval: time.Hour})
+	require.NoError(t, err)
+
+	require.NoError(t, rotator.Rotate(context.Background()))
+	superseded := rotator.activeName
+	require.NoError(t, rotator.Rotate(context.Background()))
+
+	// Revoked by an operator in the meantime.
+	require.NoError(t, client.RevokeToken(context.Background(), "svc", superseded))
+
+	_, err = rotator.RevokeSuperseded(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{rotator.activeName}, sonar.names())
+}
+
+func TestCredentialRotator_Run(t *testing.T) {
+	initialName := ServiceTokenName("svc", time.Now().Add(-time.Hour))
+	sonar := &fakeTokenServer{tokens: map[string]string{
+		"initial-token": initialName,
+	}}
+	server := httptest.NewServer(sonar)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "sonar-token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("initial-token"), 0o600))
+
+	client, err := New(Config{BaseURL: server.URL, AuthTokenFile: tokenFile, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	// Rotations are more frequent than the revoke delay, which must not postpone the
+	// revocations.
+	rotator, err := NewCredentialRotator(client, RotationConfig{Interval: 20 * time.Millisecond, RevokeDelay: 100 * time.Millisecond})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rotator.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	assert.Eventually(t, func() bool {
+		return !slices.Contains(sonar.names(), initialName)
+	}, 5*time.Second, 10*time.Millisecond, "the initial token is never revoked")
+}
+
+func TestCredentialRotator_RotateRollback(t *testing.T) {
+	sonar := &fakeTokenServer{tokens: map[string]string{
+		"initial-token": "svc-service-initial",
+	}}
+	server := httptest.NewServer(sonar)
+	defer server.Close()
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "sonar-token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("initial-token"), 0o600))
+
+	client, err := New(Config{BaseURL: server.URL, AuthTokenFile: tokenFile, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	rotator, err := NewCredentialRotator(client, RotationConfig{Interval: time.Hour})
+	require.NoError(t, err)
+
+	// Make persisting the new credential fail.
+	require.NoError(t, os.RemoveAll(dir))
+
+	err = rotator.Rotate(context.Background())
+	assert.ErrorContains(t, err, "persisting new credential")
+
+	assert.Equal(t, "initial-token", client.Credentials().Current().Token)
+	assert.Equal(t, []string{"svc-service-initial"}, sonar.names())
+
+	// Nothing is revoked while no rotation succeeded.
+	_, err = rotator.RevokeSuperseded(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"svc-service-initial"}, sonar.names())
+}
+
+func TestNewCredentialRotator_RequiresCredentialFile(t *testing.T) {
+	client, err := New(Config{AuthToken: "static-token"})
+	require.NoError(t, err)
+
+	_, err = NewCredentialRotator(client, RotationConfig{Interval: time.Hour})
+	assert.ErrorContains(t, err, "requires a credential file")
+}
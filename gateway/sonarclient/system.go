@@ -0,0 +1,22 @@
+package sonarclient
+
+import (
+	"context"
+	"fmt"
+)
+
+// SystemStatusUp is the status reported by a Sonar instance ready to serve requests.
+const SystemStatusUp = "UP"
+
+// SystemStatus returns the status of the Sonar instance, such as STARTING or UP.
+// The endpoint does not require authentication.
+func (c *HTTPClient) SystemStatus(ctx context.Context) (string, error) {
+	var response struct {
+		Status string `json:"status"`
+	}
+	if err := c.get(ctx, "/api/system/status", nil, &response); err != nil {
+		return "", fmt.Errorf("getting system status: %w", err)
+	}
+
+	return response.Status, nil
+}
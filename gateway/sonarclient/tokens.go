@@ -0,0 +1,88 @@
+package sonarclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// serviceTokenInfix marks the user tokens the services authenticate with, so they
+// are never mistaken for the analysis tokens generated for projects.
+const serviceTokenInfix = "-service-"
+
+type UserToken struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// ServiceTokenNamePrefix is the name prefix of the service tokens of login.
+func ServiceTokenNamePrefix(login string) string {
+	return login + serviceTokenInfix
+}
+
+// serviceTokenTimeLayout formats the creation time in the names of service tokens.
+const serviceTokenTimeLayout = "20060102T150405.000Z"
+
+// ServiceTokenName names a service token of login created at the given time. A random
+// suffix keeps the names unique, token names being unique per user on Sonar.
+func ServiceTokenName(login string, createdAt time.Time) string {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		panic(err)
+	}
+
+	return ServiceTokenNamePrefix(login) + createdAt.UTC().Format(serviceTokenTimeLayout) + "-" + hex.EncodeToString(suffix)
+}
+
+// serviceTokenCreatedAt returns the creation time in the name of a service token of
+// login, and false for the names not made by ServiceTokenName. Names without the random
+// suffix, made by previous versions, are accepted.
+func serviceTokenCreatedAt(login, name string) (time.Time, bool) {
+	suffix, ok := strings.CutPrefix(name, ServiceTokenNamePrefix(login))
+	if !ok {
+		return time.Time{}, false
+	}
+
+	timestamp, _, _ := strings.Cut(suffix, "-")
+	createdAt, err := time.Parse(serviceTokenTimeLayout, timestamp)
+	return createdAt, err == nil
+}
+
+// SearchTokens lists the tokens of the user. An empty login lists the tokens of the
+// user owning the configured authentication token.
+func (c *HTTPClient) SearchTokens(ctx context.Context, login string) ([]UserToken, error) {
+	query := url.Values{}
+	if login != "" {
+		query.Set("login", login)
+	}
+
+	var response struct {
+		UserTokens []UserToken `json:"userTokens"`
+	}
+	if err := c.get(ctx, "/api/user_tokens/search", query, &response); err != nil {
+		return nil, fmt.Errorf("searching user tokens: %w", err)
+	}
+
+	return response.UserTokens, nil
+}
+
+// RevokeToken revokes the token of the user with the given name.
+func (c *HTTPClient) RevokeToken(ctx context.Context, login, name string) error {
+	formData := url.Values{
+		"name": {name},
+	}
+	if login != "" {
+		formData.Set("login", login)
+	}
+
+	if err := c.post(ctx, "/api/user_tokens/revoke", formData, nil); err != nil {
+		return fmt.Errorf("revoking user token: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package sonarclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceTokenName(t *testing.T) {
+	createdAt := time.Date(2024, 6, 1, 12, 0, 0, 123e6, time.UTC)
+
+	first := ServiceTokenName("svc", createdAt)
+	second := ServiceTokenName("svc", createdAt)
+	assert.NotEqual(t, first, second, "names of tokens created the same millisecond collide")
+
+	tests := []struct {
+		name              string
+		tokenName         string
+		expectedCreatedAt time.Time
+		expectedOK        bool
+	}{
+		{
+			name:              "Service Token",
+			tokenName:         first,
+			expectedCreatedAt: createdAt,
+			expectedOK:        true,
+		},
+		{
+			name:              "Service Token Without Suffix",
+			tokenName:         "svc-service-20240601T120000.123Z",
+			expectedCreatedAt: createdAt,
+			expectedOK:        true,
+		},
+		{
+			name:      "Service Token Of Another User",
+			tokenName: ServiceTokenName("other", createdAt),
+		},
+		{
+			name:      "Token Not Named As A Service Token",
+			tokenName: "svc-service-manual",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createdAt, ok := serviceTokenCreatedAt("svc", tt.tokenName)
+
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.True(t, tt.expectedCreatedAt.Equal(createdAt))
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package sonarclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/werbersondev/token-generator-test/extensions/filex"
+	"github.com/werbersondev/token-generator-test/extensions/tlsx"
+)
+
+const defaultTLSReloadInterval = 30 * time.Second
+
+type TLSConfig struct {
+	// CAFile is a PEM bundle of the authorities trusted to verify the server.
+	// When set, it replaces the system roots.
+	CAFile string
+	// CertFile and KeyFile hold the client certificate presented to mTLS servers.
+	CertFile string
+	KeyFile  string
+	// MinVersion is the minimum TLS version, "1.2" (default) or "1.3".
+	MinVersion string
+	// ReloadInterval is how often the files above are checked for changes.
+	ReloadInterval time.Duration
+}
+
+type ProxyConfig struct {
+	// URL of the proxy requests are routed through. When empty, the standard
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables apply.
+	URL string
+	// NoProxy is a comma separated list of hosts, domains or CIDRs reached directly.
+	NoProxy string
+}
+
+// reloadingTransport rebuilds its underlying transport whenever the TLS material on
+// disk changes, so rotated certificates are picked up without a restart.
+type reloadingTransport struct {
+	watcher *filex.Watcher
+	build   func() (*http.Transport, error)
+
+	mu      sync.RWMutex
+	current *http.Transport
+}
+
+func newTransport(tlsConfig TLSConfig, proxyConfig ProxyConfig) (http.RoundTripper, error) {
+	build := func() (*http.Transport, error) {
+		return buildTransport(tlsConfig, proxyConfig)
+	}
+
+	current, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig.CAFile == "" && tlsConfig.CertFile == "" {
+		return current, nil
+	}
+
+	if tlsConfig.ReloadInterval <= 0 {
+		tlsConfig.ReloadInterval = defaultTLSReloadInterval
+	}
+
+	return &reloadingTransport{
+		watcher: filex.NewWatcher(tlsConfig.ReloadInterval, tlsConfig.CAFile, tlsConfig.CertFile, tlsConfig.KeyFile),
+		build:   build,
+		current: current,
+	}, nil
+}
+
+func (t *reloadingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.watcher.Changed() {
+		t.reload()
+	}
+
+	t.mu.RLock()
+	current := t.current
+	t.mu.RUnlock()
+
+	return current.RoundTrip(req)
+}
+
+func (t *reloadingTransport) reload() {
+	next, err := t.build()
+	if err != nil {
+		// Keep serving with the previous material, the files may be mid-rotation.
+		log.Error().Err(err).Msg("reloading sonar client TLS configuration")
+		return
+	}
+
+	t.mu.Lock()
+	previous := t.current
+	t.current = next
+	t.mu.Unlock()
+
+	previous.CloseIdleConnections()
+	log.Info().Msg("sonar client TLS configuration reloaded")
+}
+
+func buildTransport(tlsConfig TLSConfig, proxyConfig ProxyConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	minVersion, err := tlsx.ParseVersion(tlsConfig.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	clientTLS := &tls.Config{MinVersion: minVersion}
+
+	if tlsConfig.CAFile != "" {
+		pool, err := tlsx.LoadCertPool(tlsConfig.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		clientTLS.RootCAs = pool
+	}
+
+	cert, err := tlsx.LoadKeyPair(tlsConfig.CertFile, tlsConfig.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if cert != nil {
+		clientTLS.Certificates = []tls.Certificate{*cert}
+	}
+
+	transport.TLSClientConfig = clientTLS
+
+	if proxyConfig.URL != "" {
+		if _, err := url.Parse(proxyConfig.URL); err != nil {
+			return nil, fmt.Errorf("parsing proxy URL: %w", err)
+		}
+
+		proxyFunc := (&httpproxy.Config{
+			HTTPProxy:  proxyConfig.URL,
+			HTTPSProxy: proxyConfig.URL,
+			NoProxy:    proxyConfig.NoProxy,
+		}).ProxyFunc()
+
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+	}
+
+	return transport, nil
+}
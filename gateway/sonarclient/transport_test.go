@@ -0,0 +1,117 @@
+package sonarclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_MutualTLSWithCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+
+	var presentedCN string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presentedCN = r.TLS.PeerCertificates[0].Subject.CommonName
+		_, _ = w.Write([]byte(`{"token": "generated-token"}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	writePEM(t, caFile, "CERTIFICATE", server.Certificate().Raw)
+	writeClientCertificate(t, certFile, keyFile, "client-1")
+
+	client, err := New(Config{
+		BaseURL:   server.URL,
+		AuthToken: "dummy-token",
+		TLS: TLSConfig{
+			CAFile:         caFile,
+			CertFile:       certFile,
+			KeyFile:        keyFile,
+			MinVersion:     "1.2",
+			ReloadInterval: time.Nanosecond,
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = client.GenerateProjectAnalysisToken(context.Background(), "project-id", "token", time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "client-1", presentedCN)
+
+	writeClientCertificate(t, certFile, keyFile, "client-2")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+
+	_, err = client.GenerateProjectAnalysisToken(context.Background(), "project-id", "token", time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "client-2", presentedCN)
+}
+
+func TestNew_UntrustedServer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not reach an untrusted server")
+	}))
+	defer server.Close()
+
+	client, err := New(Config{BaseURL: server.URL, AuthToken: "dummy-token"})
+	require.NoError(t, err)
+
+	_, err = client.GenerateProjectAnalysisToken(context.Background(), "project-id", "token", time.Time{})
+	assert.ErrorContains(t, err, "certificate")
+}
+
+func TestNew_InvalidTLSConfig(t *testing.T) {
+	_, err := New(Config{TLS: TLSConfig{CertFile: "client.pem"}})
+	assert.ErrorContains(t, err, "certificate and key files must be provided together")
+
+	_, err = New(Config{TLS: TLSConfig{MinVersion: "1.0"}})
+	assert.ErrorContains(t, err, "unsupported TLS version")
+}
+
+func writeClientCertificate(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "PRIVATE KEY", keyDER)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
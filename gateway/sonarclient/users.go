@@ -0,0 +1,49 @@
+package sonarclient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+type CreateUserParams struct {
+	Login    string
+	Name     string
+	Password string
+}
+
+// UserExists reports whether a user with exactly the given login exists.
+func (c *HTTPClient) UserExists(ctx context.Context, login string) (bool, error) {
+	var response struct {
+		Users []struct {
+			Login string `json:"login"`
+		} `json:"users"`
+	}
+	if err := c.get(ctx, "/api/users/search", url.Values{"q": {login}}, &response); err != nil {
+		return false, fmt.Errorf("searching users: %w", err)
+	}
+
+	for _, user := range response.Users {
+		if user.Login == login {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// CreateUser creates a local user.
+func (c *HTTPClient) CreateUser(ctx context.Context, params CreateUserParams) error {
+	formData := url.Values{
+		"login":    {params.Login},
+		"name":     {params.Name},
+		"password": {params.Password},
+		"local":    {"true"},
+	}
+
+	if err := c.post(ctx, "/api/users/create", formData, nil); err != nil {
+		return fmt.Errorf("creating user: %w", err)
+	}
+
+	return nil
+}